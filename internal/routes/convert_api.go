@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// maxConvertRequestSize limits documents accepted by the conversion API
+const maxConvertRequestSize = 32 << 20
+
+// Content types returned for each output format
+var convertContentTypes = map[string]string{
+	"json":  "application/json",
+	"yaml":  "application/yaml",
+	"toml":  "application/toml",
+	"xml":   "application/xml",
+	"csv":   "text/csv; charset=utf-8",
+	"tsv":   "text/tab-separated-values; charset=utf-8",
+	"query": "application/x-www-form-urlencoded",
+}
+
+// ConvertAPIHandler handles data format conversion API endpoints
+type ConvertAPIHandler struct {
+	convertService *services.ConvertService
+}
+
+// NewConvertAPIHandler creates a new conversion API handler
+func NewConvertAPIHandler() *ConvertAPIHandler {
+	return &ConvertAPIHandler{
+		convertService: services.NewConvertService(),
+	}
+}
+
+// Convert converts the request body between JSON, YAML, TOML, XML, CSV/TSV and query strings.
+// Lossy conversions are listed in the X-Conversion-Warnings header.
+func (h *ConvertAPIHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := services.ConvertOptions{
+		From:       query.Get("from"),
+		To:         query.Get("to"),
+		Separator:  query.Get("separator"),
+		Arrays:     query.Get("arrays"),
+		JoinWith:   query.Get("join_with"),
+		KeepNested: query.Get("keep_nested") == "true",
+		InferTypes: query.Get("infer_types") == "true",
+		Root:       query.Get("root"),
+		QueryStyle: query.Get("query_style"),
+	}
+	if opts.From == "" || opts.To == "" {
+		http.Error(w, "from and to parameters required", http.StatusBadRequest)
+		return
+	}
+	if indent := query.Get("indent"); indent != "" {
+		width, err := strconv.Atoi(indent)
+		if err != nil || width < 1 || width > 8 {
+			http.Error(w, "indent must be 1-8", http.StatusBadRequest)
+			return
+		}
+		opts.Indent = width
+	}
+
+	input, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConvertRequestSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, fmt.Sprintf("Document too large (maximum %d bytes)", maxConvertRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to read document: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.convertService.Convert(input, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Conversion failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if len(result.Warnings) > 0 {
+		w.Header().Set("X-Conversion-Warnings", strings.Join(result.Warnings, "; "))
+	}
+	w.Header().Set("Content-Type", convertContentTypes[strings.ToLower(strings.TrimSpace(opts.To))])
+	w.Write(result.Output)
+}
+
+// RegisterConvertAPIRoutes registers all conversion API routes
+func RegisterConvertAPIRoutes(r chi.Router) {
+	handler := NewConvertAPIHandler()
+
+	// Raw document body in, converted document out
+	r.Post("/convert", handler.Convert)
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// CronAPIHandler handles cron expression API endpoints
+type CronAPIHandler struct {
+	cronService *services.CronService
+}
+
+// NewCronAPIHandler creates a new cron API handler
+func NewCronAPIHandler() *CronAPIHandler {
+	return &CronAPIHandler{
+		cronService: services.NewCronService(),
+	}
+}
+
+// ParseCron explains a cron expression and lists its next runs in a timezone
+func (h *CronAPIHandler) ParseCron(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := services.CronRequest{
+		Expression: query.Get("expr"),
+		Dialect:    query.Get("dialect"),
+		Timezone:   query.Get("tz"),
+		From:       query.Get("from"),
+	}
+	if req.Expression == "" {
+		http.Error(w, "expr parameter required", http.StatusBadRequest)
+		return
+	}
+	if count := query.Get("count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			http.Error(w, "count must be a number", http.StatusBadRequest)
+			return
+		}
+		req.Count = n
+	}
+
+	result, err := h.cronService.Parse(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid cron expression: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding cron response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterCronAPIRoutes registers all cron API routes
+func RegisterCronAPIRoutes(r chi.Router) {
+	handler := NewCronAPIHandler()
+
+	r.Route("/cron", func(r chi.Router) {
+		// Explanation and next run times for a crontab, seconds or Quartz expression
+		r.Get("/parse", handler.ParseCron)
+	})
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// DNSLeakAPIHandler handles DNS leak test API endpoints
+type DNSLeakAPIHandler struct {
+	ipService   *services.IPAnalysisService
+	leakService *services.DNSLeakService
+}
+
+// NewDNSLeakAPIHandler creates a DNS leak handler configured from the environment:
+// DNS_LEAK_ZONE is the zone delegated to this server, DNS_LEAK_LISTEN the responder
+// address (default :53), DNS_LEAK_NS the published name server, and
+// DNS_LEAK_ANSWER_IPV4/DNS_LEAK_ANSWER_IPV6 the addresses returned for probe names
+func NewDNSLeakAPIHandler() *DNSLeakAPIHandler {
+	ipService := services.NewIPAnalysisService()
+	return &DNSLeakAPIHandler{
+		ipService: ipService,
+		leakService: services.NewDNSLeakService(ipService, services.DNSLeakConfig{
+			Zone:       os.Getenv("DNS_LEAK_ZONE"),
+			Listen:     os.Getenv("DNS_LEAK_LISTEN"),
+			Nameserver: os.Getenv("DNS_LEAK_NS"),
+			AnswerIPv4: os.Getenv("DNS_LEAK_ANSWER_IPV4"),
+			AnswerIPv6: os.Getenv("DNS_LEAK_ANSWER_IPV6"),
+		}),
+	}
+}
+
+// StartTest creates a leak test token and the probe hostnames the browser should resolve
+func (h *DNSLeakAPIHandler) StartTest(w http.ResponseWriter, r *http.Request) {
+	if !h.leakService.Enabled() {
+		http.Error(w, "DNS leak testing is not configured on this server", http.StatusServiceUnavailable)
+		return
+	}
+
+	test, err := h.leakService.StartTest(h.ipService.GetClientIP(r))
+	if err != nil {
+		log.Printf("Error starting DNS leak test: %v", err)
+		http.Error(w, "Failed to start DNS leak test", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(test); err != nil {
+		log.Printf("Error encoding DNS leak test response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// GetResults returns the resolvers that queried a test's probe names
+func (h *DNSLeakAPIHandler) GetResults(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if token == "" {
+		http.Error(w, "Test token required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.leakService.GetResults(r.Context(), token)
+	if errors.Is(err, services.ErrDNSLeakTestNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Error getting DNS leak results for %s: %v", token, err)
+		http.Error(w, "Failed to get DNS leak results", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding DNS leak results response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterDNSLeakAPIRoutes registers the DNS leak API routes and starts the
+// authoritative responder when a leak zone is configured
+func RegisterDNSLeakAPIRoutes(r chi.Router) {
+	handler := NewDNSLeakAPIHandler()
+	if handler.leakService.Enabled() {
+		go func() {
+			if err := handler.leakService.ListenAndServe(); err != nil {
+				log.Printf("DNS leak responder stopped: %v", err)
+			}
+		}()
+	}
+
+	r.Route("/dns-leak", func(r chi.Router) {
+		// Start a test and get the probe hostnames to resolve
+		r.Post("/start", handler.StartTest)
+		// Resolvers seen for a test token, with IP analysis
+		r.Get("/results/{token}", handler.GetResults)
+	})
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/miekg/dns"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// maxDoHMessageSize is the largest DNS message accepted in a DoH request
+const maxDoHMessageSize = 65535
+
+// DoHHandler serves RFC 8484 DNS-over-HTTPS queries by forwarding them upstream
+type DoHHandler struct {
+	ipService  *services.IPAnalysisService
+	upstream   services.DNSLookupOptions
+	cache      *dnsCache
+	logQueries bool
+}
+
+// NewDoHHandler creates a DoH handler configured from the environment:
+// DOH_UPSTREAM_TRANSPORT and DOH_UPSTREAM select the upstream (default: UDP to
+// the system name server) and DOH_LOG_QUERIES=true enables query logging
+func NewDoHHandler() *DoHHandler {
+	return &DoHHandler{
+		ipService: services.NewIPAnalysisService(),
+		upstream: services.DNSLookupOptions{
+			Transport: os.Getenv("DOH_UPSTREAM_TRANSPORT"),
+			Server:    os.Getenv("DOH_UPSTREAM"),
+		},
+		cache:      newDNSCache(dohCacheEntries),
+		logQueries: os.Getenv("DOH_LOG_QUERIES") == "true",
+	}
+}
+
+// ServeQuery decodes a GET or POST DoH request and answers it from the DNS
+// cache, forwarding misses upstream. Cached answers have their TTLs reduced by
+// the time spent in the cache and are only served until the smallest TTL expires.
+func (h *DoHHandler) ServeQuery(w http.ResponseWriter, r *http.Request) {
+	query, status, err := readDoHQuery(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	question := query.Question[0]
+	if h.logQueries {
+		log.Printf("DoH query from %s: %s %s", h.ipService.GetClientIP(r), question.Name, dns.TypeToString[question.Qtype])
+	}
+
+	resp, remaining, hit := h.cache.get(query)
+	if !hit {
+		resp, _, err = h.ipService.ForwardDNS(r.Context(), query, h.upstream)
+		if err != nil {
+			log.Printf("Error forwarding DoH query for %s: %v", question.Name, err)
+			http.Error(w, "Upstream DNS query failed", http.StatusBadGateway)
+			return
+		}
+		h.cache.set(query, resp)
+	}
+	resp.Id = query.Id
+
+	// The SOA minimum of a cached negative answer is not decremented, so the
+	// entry's remaining lifetime also bounds the HTTP freshness lifetime
+	maxAge := dohMaxAge(resp)
+	if hit {
+		maxAge = min(maxAge, remaining)
+	}
+
+	out, err := resp.Pack()
+	if err != nil {
+		log.Printf("Error packing DoH response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/dns-message")
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+	w.Write(out)
+}
+
+// readDoHQuery extracts and validates the DNS query from a GET or POST request
+func readDoHQuery(w http.ResponseWriter, r *http.Request) (*dns.Msg, int, error) {
+	var packed []byte
+	switch r.Method {
+	case http.MethodGet:
+		param := r.URL.Query().Get("dns")
+		if param == "" {
+			return nil, http.StatusBadRequest, fmt.Errorf("missing dns parameter")
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(param, "="))
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("dns parameter is not base64url")
+		}
+		packed = decoded
+	case http.MethodPost:
+		if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/dns-message") {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("content type must be application/dns-message")
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDoHMessageSize))
+		if err != nil {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("DNS message too large")
+		}
+		packed = body
+	default:
+		return nil, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")
+	}
+
+	query := new(dns.Msg)
+	if err := query.Unpack(packed); err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid DNS message")
+	}
+	if query.Response || query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 {
+		return nil, http.StatusBadRequest, fmt.Errorf("expected a standard query with one question")
+	}
+	if qtype := query.Question[0].Qtype; qtype == dns.TypeAXFR || qtype == dns.TypeIXFR {
+		return nil, http.StatusBadRequest, fmt.Errorf("zone transfers are not supported over DoH")
+	}
+	return query, http.StatusOK, nil
+}
+
+// dohMaxAge returns the smallest TTL in the answer and authority sections, as
+// RFC 8484 section 5.1 requires for the HTTP freshness lifetime
+func dohMaxAge(resp *dns.Msg) uint32 {
+	var minTTL uint32
+	found := false
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns} {
+		for _, rr := range section {
+			ttl := rr.Header().Ttl
+			if soa, ok := rr.(*dns.SOA); ok && soa.Minttl < ttl {
+				ttl = soa.Minttl
+			}
+			if !found || ttl < minTTL {
+				minTTL, found = ttl, true
+			}
+		}
+	}
+	return minTTL
+}
+
+// RegisterDoHRoutes registers the RFC 8484 DNS-over-HTTPS endpoint
+func RegisterDoHRoutes(r chi.Router) {
+	handler := NewDoHHandler()
+
+	// DoH supports both wireformat GET and POST
+	r.Get("/dns-query", handler.ServeQuery)
+	r.Post("/dns-query", handler.ServeQuery)
+}
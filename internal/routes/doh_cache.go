@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"container/heap"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Bounds for the DoH answer cache
+const (
+	dohCacheEntries = 10000
+	dohCacheMaxTTL  = 24 * time.Hour
+)
+
+// dnsCacheKey identifies a cached answer by question and the flags that change it
+type dnsCacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	do     bool // DNSSEC OK, answers include signatures
+	cd     bool // Checking Disabled, answers may be unvalidated
+}
+
+// dnsCacheEntry is a cached response and the time it was stored
+type dnsCacheEntry struct {
+	key     dnsCacheKey
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+	index   int // position in the expiry heap
+}
+
+// dnsExpiryHeap orders cache entries by expiry, soonest first
+type dnsExpiryHeap []*dnsCacheEntry
+
+func (h dnsExpiryHeap) Len() int           { return len(h) }
+func (h dnsExpiryHeap) Less(i, j int) bool { return h[i].expires.Before(h[j].expires) }
+func (h dnsExpiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *dnsExpiryHeap) Push(x any) {
+	entry := x.(*dnsCacheEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *dnsExpiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// dnsCache caches DNS responses for the smallest TTL in the answer, or the
+// negative caching TTL of RFC 2308 for NXDOMAIN and NODATA. The lock is only
+// held for map and heap access, never while an upstream query is in flight.
+//
+// The replay cache used for the other routes is not reused here: it keys on
+// URL and method only, so every RFC 8484 POST to /dns-query would share one
+// entry whatever question its body holds, and it caches for a fixed TTL rather
+// than the TTLs in the answer.
+type dnsCache struct {
+	mu         sync.Mutex
+	entries    map[dnsCacheKey]*dnsCacheEntry
+	expiry     dnsExpiryHeap
+	maxEntries int
+	now        func() time.Time
+}
+
+// newDNSCache creates a DNS cache holding up to maxEntries answers
+func newDNSCache(maxEntries int) *dnsCache {
+	return &dnsCache{
+		entries:    make(map[dnsCacheKey]*dnsCacheEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// cacheKey builds the cache key for a query
+func cacheKey(query *dns.Msg) dnsCacheKey {
+	question := query.Question[0]
+	key := dnsCacheKey{
+		name:   strings.ToLower(question.Name),
+		qtype:  question.Qtype,
+		qclass: question.Qclass,
+		cd:     query.CheckingDisabled,
+	}
+	if opt := query.IsEdns0(); opt != nil {
+		key.do = opt.Do()
+	}
+	return key
+}
+
+// get returns a copy of the cached response for a query with its TTLs reduced
+// by the time it has spent in the cache, and the seconds left before it expires
+func (c *dnsCache) get(query *dns.Msg) (*dns.Msg, uint32, bool) {
+	key := cacheKey(query)
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !now.Before(entry.expires) {
+		c.remove(entry)
+		ok = false
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, 0, false
+	}
+
+	resp := entry.msg.Copy()
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range section {
+			header := rr.Header()
+			if header.Rrtype == dns.TypeOPT {
+				continue
+			}
+			if header.Ttl > elapsed {
+				header.Ttl -= elapsed
+			} else {
+				header.Ttl = 0
+			}
+		}
+	}
+	return resp, uint32(entry.expires.Sub(now) / time.Second), true
+}
+
+// set stores a response when it is cacheable
+func (c *dnsCache) set(query, resp *dns.Msg) {
+	ttl, ok := cacheTTL(resp)
+	if !ok {
+		return
+	}
+	now := c.now()
+	key := cacheKey(query)
+	entry := &dnsCacheEntry{key: key, msg: resp.Copy(), stored: now, expires: now.Add(ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, exists := c.entries[key]; exists {
+		c.remove(old)
+	} else if len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = entry
+	heap.Push(&c.expiry, entry)
+}
+
+// evict removes expired entries, or the entry closest to expiry when none have expired
+func (c *dnsCache) evict(now time.Time) {
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].expires) {
+		c.remove(c.expiry[0])
+	}
+	if len(c.entries) >= c.maxEntries && len(c.expiry) > 0 {
+		c.remove(c.expiry[0])
+	}
+}
+
+// remove deletes an entry from the map and the expiry heap
+func (c *dnsCache) remove(entry *dnsCacheEntry) {
+	delete(c.entries, entry.key)
+	heap.Remove(&c.expiry, entry.index)
+}
+
+// cacheTTL returns how long a response may be cached: the smallest answer TTL
+// for positive answers, and the SOA TTL capped by its minimum field for
+// NXDOMAIN and NODATA (RFC 2308 section 5). Truncated answers, server failures
+// and responses with a zero TTL are not cached.
+func cacheTTL(resp *dns.Msg) (time.Duration, bool) {
+	if resp.Truncated || (resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError) {
+		return 0, false
+	}
+
+	var ttl uint32
+	found := false
+	if resp.Rcode == dns.RcodeSuccess && len(resp.Answer) > 0 {
+		for _, rr := range resp.Answer {
+			if !found || rr.Header().Ttl < ttl {
+				ttl, found = rr.Header().Ttl, true
+			}
+		}
+	} else {
+		for _, rr := range resp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl, found = min(soa.Hdr.Ttl, soa.Minttl), true
+				break
+			}
+		}
+	}
+	if !found || ttl == 0 {
+		return 0, false
+	}
+
+	return min(time.Duration(ttl)*time.Second, dohCacheMaxTTL), true
+}
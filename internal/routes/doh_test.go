@@ -0,0 +1,217 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// testUpstream is a local DNS server that counts the queries it receives
+type testUpstream struct {
+	addr    string
+	queries atomic.Int32
+}
+
+func startTestUpstream(t *testing.T) *testUpstream {
+	t.Helper()
+	upstream := &testUpstream{}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		upstream.queries.Add(1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		switch strings.ToLower(req.Question[0].Name) {
+		case "example.test.":
+			rr, _ := dns.NewRR("example.test. 60 IN A 192.0.2.1")
+			resp.Answer = append(resp.Answer, rr)
+		case "slow.test.":
+			time.Sleep(time.Second)
+			rr, _ := dns.NewRR("slow.test. 60 IN A 192.0.2.2")
+			resp.Answer = append(resp.Answer, rr)
+		case "broken.test.":
+			resp.Rcode = dns.RcodeServerFailure
+		default:
+			resp.Rcode = dns.RcodeNameError
+			soa, _ := dns.NewRR("test. 3600 IN SOA ns.test. hostmaster.test. 1 3600 900 604800 30")
+			resp.Ns = append(resp.Ns, soa)
+		}
+		w.WriteMsg(resp)
+	})
+
+	started := make(chan struct{})
+	server := &dns.Server{Addr: "127.0.0.1:0", Net: "udp", Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ListenAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	upstream.addr = server.PacketConn.LocalAddr().String()
+	return upstream
+}
+
+// testDoHHandler creates a DoH handler forwarding to upstream with a controllable clock
+func testDoHHandler(upstream *testUpstream, now *time.Time) *DoHHandler {
+	cache := newDNSCache(dohCacheEntries)
+	cache.now = func() time.Time { return *now }
+	return &DoHHandler{
+		ipService: services.NewIPAnalysisService(),
+		upstream:  services.DNSLookupOptions{Transport: "udp", Server: upstream.addr},
+		cache:     cache,
+	}
+}
+
+// dohExchange POSTs a query to the handler and decodes the answer
+func dohExchange(t *testing.T, handler *DoHHandler, name string, id uint16) (*dns.Msg, *httptest.ResponseRecorder) {
+	t.Helper()
+	query := new(dns.Msg)
+	query.SetQuestion(name, dns.TypeA)
+	query.Id = id
+	packed, _ := query.Pack()
+
+	req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+	req.Header.Set("Content-Type", "application/dns-message")
+	rec := httptest.NewRecorder()
+	handler.ServeQuery(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("%s: status %d: %s", name, rec.Code, rec.Body.String())
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(rec.Body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id != id {
+		t.Errorf("%s: id = %d, want %d", name, resp.Id, id)
+	}
+	return resp, rec
+}
+
+func TestDoHCachesForAnswerTTL(t *testing.T) {
+	upstream := startTestUpstream(t)
+	now := time.Now()
+	handler := testDoHHandler(upstream, &now)
+
+	resp, rec := dohExchange(t, handler, "example.test.", 1)
+	if resp.Answer[0].Header().Ttl != 60 || rec.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("miss: ttl %d, %s", resp.Answer[0].Header().Ttl, rec.Header().Get("Cache-Control"))
+	}
+
+	now = now.Add(20 * time.Second)
+	resp, rec = dohExchange(t, handler, "EXAMPLE.test.", 2)
+	if upstream.queries.Load() != 1 {
+		t.Errorf("upstream queries = %d, want 1", upstream.queries.Load())
+	}
+	if resp.Answer[0].Header().Ttl != 40 || rec.Header().Get("Cache-Control") != "max-age=40" {
+		t.Errorf("hit: ttl %d, %s", resp.Answer[0].Header().Ttl, rec.Header().Get("Cache-Control"))
+	}
+
+	now = now.Add(40 * time.Second)
+	dohExchange(t, handler, "example.test.", 3)
+	if upstream.queries.Load() != 2 {
+		t.Errorf("expired entry was served; upstream queries = %d, want 2", upstream.queries.Load())
+	}
+}
+
+func TestDoHNegativeCaching(t *testing.T) {
+	upstream := startTestUpstream(t)
+	now := time.Now()
+	handler := testDoHHandler(upstream, &now)
+
+	resp, rec := dohExchange(t, handler, "missing.test.", 1)
+	if resp.Rcode != dns.RcodeNameError || rec.Header().Get("Cache-Control") != "max-age=30" {
+		t.Errorf("miss: rcode %d, %s", resp.Rcode, rec.Header().Get("Cache-Control"))
+	}
+
+	now = now.Add(10 * time.Second)
+	_, rec = dohExchange(t, handler, "missing.test.", 2)
+	if upstream.queries.Load() != 1 || rec.Header().Get("Cache-Control") != "max-age=20" {
+		t.Errorf("hit: upstream queries %d, %s", upstream.queries.Load(), rec.Header().Get("Cache-Control"))
+	}
+
+	now = now.Add(20 * time.Second)
+	dohExchange(t, handler, "missing.test.", 3)
+	if upstream.queries.Load() != 2 {
+		t.Errorf("negative entry outlived the SOA minimum; upstream queries = %d", upstream.queries.Load())
+	}
+
+	// Server failures are never cached
+	dohExchange(t, handler, "broken.test.", 4)
+	dohExchange(t, handler, "broken.test.", 5)
+	if upstream.queries.Load() != 4 {
+		t.Errorf("SERVFAIL was cached; upstream queries = %d", upstream.queries.Load())
+	}
+}
+
+func TestDoHSlowUpstreamDoesNotBlockHits(t *testing.T) {
+	upstream := startTestUpstream(t)
+	now := time.Now()
+	handler := testDoHHandler(upstream, &now)
+	dohExchange(t, handler, "example.test.", 1)
+
+	slow := new(dns.Msg)
+	slow.SetQuestion("slow.test.", dns.TypeA)
+	packed, _ := slow.Pack()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", "application/dns-message")
+		handler.ServeQuery(httptest.NewRecorder(), req)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	dohExchange(t, handler, "example.test.", 3)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("cache hit waited %v behind a slow upstream query", elapsed)
+	}
+	wg.Wait()
+}
+
+func TestDNSCacheEvictsSoonestExpiry(t *testing.T) {
+	now := time.Now()
+	cache := newDNSCache(3)
+	cache.now = func() time.Time { return now }
+
+	answer := func(name string, ttl uint32) (*dns.Msg, *dns.Msg) {
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		resp := new(dns.Msg)
+		resp.SetReply(query)
+		rr, _ := dns.NewRR(fmt.Sprintf("%s %d IN A 192.0.2.1", name, ttl))
+		resp.Answer = []dns.RR{rr}
+		return query, resp
+	}
+	cached := func(name string) bool {
+		query, _ := answer(name, 1)
+		_, _, ok := cache.get(query)
+		return ok
+	}
+
+	for name, ttl := range map[string]uint32{"a.test.": 300, "b.test.": 30, "c.test.": 600} {
+		cache.set(answer(name, ttl))
+	}
+	// Refreshing an entry replaces it rather than evicting another
+	cache.set(answer("c.test.", 900))
+	cache.set(answer("d.test.", 120))
+	if cached("b.test.") || !cached("a.test.") || !cached("c.test.") || !cached("d.test.") {
+		t.Errorf("the entry closest to expiry was not evicted: %d entries", len(cache.entries))
+	}
+
+	// Expired entries are dropped before live ones
+	now = now.Add(200 * time.Second)
+	cache.set(answer("e.test.", 60))
+	if cached("d.test.") || !cached("a.test.") || !cached("c.test.") || !cached("e.test.") {
+		t.Errorf("the expired entry was not evicted: %d entries", len(cache.entries))
+	}
+	if len(cache.entries) != len(cache.expiry) {
+		t.Errorf("map holds %d entries, heap %d", len(cache.entries), len(cache.expiry))
+	}
+}
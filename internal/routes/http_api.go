@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// HTTPAPIHandler handles HTTP probe API endpoints
+type HTTPAPIHandler struct {
+	probeService *services.HTTPProbeService
+}
+
+// NewHTTPAPIHandler creates a new HTTP API handler
+func NewHTTPAPIHandler() *HTTPAPIHandler {
+	return &HTTPAPIHandler{
+		probeService: services.NewHTTPProbeService(),
+	}
+}
+
+// ProbeURL probes a URL for redirects, timings and security headers
+func (h *HTTPAPIHandler) ProbeURL(w http.ResponseWriter, r *http.Request) {
+	// Parse request body for POST or query params for GET
+	var req services.HTTPProbeRequest
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.URL = query.Get("url")
+		req.Method = query.Get("method")
+		if maxRedirects := query.Get("max_redirects"); maxRedirects != "" {
+			n, err := strconv.Atoi(maxRedirects)
+			if err != nil {
+				http.Error(w, "Invalid max_redirects", http.StatusBadRequest)
+				return
+			}
+			req.MaxRedirects = n
+		}
+	}
+
+	if req.URL == "" {
+		http.Error(w, "URL required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.probeService.ProbeURL(r.Context(), req)
+	if err != nil {
+		log.Printf("Error probing %s: %v", req.URL, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("HTTP probe failed: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding HTTP probe response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterHTTPAPIRoutes registers all HTTP probe API routes
+func RegisterHTTPAPIRoutes(r chi.Router) {
+	handler := NewHTTPAPIHandler()
+
+	r.Route("/http", func(r chi.Router) {
+		// HTTP probe - supports both GET and POST
+		r.Get("/probe", handler.ProbeURL)
+		r.Post("/probe", handler.ProbeURL)
+	})
+}
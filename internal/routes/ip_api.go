@@ -1,26 +1,40 @@
 package routes
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/ztkent/dev-tools/internal/services"
 )
 
+// maxSubdomainRequestSize limits subdomain discovery requests, which may carry a CT export
+const maxSubdomainRequestSize = 16 << 20
+
 // IPAPIHandler handles IP analysis API endpoints
 type IPAPIHandler struct {
-	ipService *services.IPAnalysisService
+	ipService         *services.IPAnalysisService
+	driftService      *services.DNSDriftService
+	delegationService *services.DelegationService
+	subdomainService  *services.SubdomainService
 }
 
 // NewIPAPIHandler creates a new IP API handler
 func NewIPAPIHandler() *IPAPIHandler {
+	ipService := services.NewIPAnalysisService()
 	return &IPAPIHandler{
-		ipService: services.NewIPAnalysisService(),
+		ipService:         ipService,
+		driftService:      services.NewDNSDriftService(ipService),
+		delegationService: services.NewDelegationService(ipService),
+		subdomainService:  services.NewSubdomainService(ipService),
 	}
 }
 
@@ -81,11 +95,14 @@ func (h *IPAPIHandler) AnalyzeIP(w http.ResponseWriter, r *http.Request) {
 func (h *IPAPIHandler) LookupDNS(w http.ResponseWriter, r *http.Request) {
 	// Parse request body for POST or query params for GET
 	var domain, recordType string
+	var opts services.DNSLookupOptions
 
 	if r.Method == http.MethodPost {
 		var req struct {
-			Domain string `json:"domain"`
-			Type   string `json:"type"`
+			Domain    string `json:"domain"`
+			Type      string `json:"type"`
+			Transport string `json:"transport"`
+			Server    string `json:"server"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -95,9 +112,11 @@ func (h *IPAPIHandler) LookupDNS(w http.ResponseWriter, r *http.Request) {
 
 		domain = req.Domain
 		recordType = req.Type
+		opts = services.DNSLookupOptions{Transport: req.Transport, Server: req.Server}
 	} else {
 		domain = r.URL.Query().Get("domain")
 		recordType = r.URL.Query().Get("type")
+		opts = services.DNSLookupOptions{Transport: r.URL.Query().Get("transport"), Server: r.URL.Query().Get("server")}
 	}
 
 	if domain == "" {
@@ -110,10 +129,14 @@ func (h *IPAPIHandler) LookupDNS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Perform DNS lookup
-	result, err := h.ipService.LookupDNS(r.Context(), domain, strings.ToUpper(recordType))
+	result, err := h.ipService.LookupDNSWith(r.Context(), domain, strings.ToUpper(recordType), opts)
 	if err != nil {
 		log.Printf("Error looking up DNS for %s (%s): %v", domain, recordType, err)
-		http.Error(w, fmt.Sprintf("DNS lookup failed: %v", err), http.StatusInternalServerError)
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("DNS lookup failed: %v", err), status)
 		return
 	}
 
@@ -126,6 +149,173 @@ func (h *IPAPIHandler) LookupDNS(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CheckDNSDrift compares live DNS records with an expected record set
+func (h *IPAPIHandler) CheckDNSDrift(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxZoneFileSize)
+
+	// Accept a JSON request or a raw zone file with options in the query string
+	var req services.DNSDriftRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read zone file: %v", err), http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		req.Zone = string(body)
+		req.Domain = query.Get("domain")
+		req.Server = query.Get("server")
+		req.IgnoreTTL = query.Get("ignore_ttl") == "true"
+	}
+
+	if req.Domain == "" {
+		http.Error(w, "Domain required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.driftService.CheckDrift(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("DNS drift check failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding drift response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// CheckDelegation walks a domain's delegation and checks each authoritative server
+func (h *IPAPIHandler) CheckDelegation(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(w, "Domain required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.delegationService.CheckDelegation(r.Context(), domain)
+	if err != nil {
+		log.Printf("Error checking delegation for %s: %v", domain, err)
+		http.Error(w, fmt.Sprintf("Delegation check failed: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding delegation response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// DiscoverSubdomains combines wordlist, AXFR and certificate transparency discovery
+func (h *IPAPIHandler) DiscoverSubdomains(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSubdomainRequestSize)
+
+	var req services.SubdomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if req.Domain == "" {
+		http.Error(w, "Domain required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.subdomainService.Discover(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Subdomain discovery failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding subdomain response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// SweepReverseDNS looks up PTR names for every address in a CIDR range.
+// format=ndjson streams entries as they complete, format=csv downloads a table.
+func (h *IPAPIHandler) SweepReverseDNS(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := services.ReverseSweepRequest{CIDR: query.Get("cidr")}
+	if req.CIDR == "" {
+		http.Error(w, "CIDR required", http.StatusBadRequest)
+		return
+	}
+	req.Concurrency, _ = strconv.Atoi(query.Get("concurrency"))
+	req.Rate, _ = strconv.Atoi(query.Get("rate"))
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		format = "ndjson"
+	}
+
+	var emit func(services.ReverseSweepEntry)
+	if format == "ndjson" {
+		// Stream one JSON entry per line, followed by the summary
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		emit = func(entry services.ReverseSweepEntry) {
+			if w.Header().Get("Content-Type") == "" {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+			}
+			if err := encoder.Encode(entry); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	result, err := h.ipService.SweepReverseDNS(r.Context(), req, emit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch format {
+	case "ndjson":
+		summary := *result
+		summary.Entries = nil
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"summary": summary}); err != nil {
+			log.Printf("Error encoding reverse sweep summary: %v", err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "reverse-dns-"+strings.NewReplacer("/", "_", ":", "-").Replace(result.CIDR)+".csv"))
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"ip", "hostname", "status", "hostnames", "error", "latency_ms"})
+		for _, entry := range result.Entries {
+			writer.Write([]string{
+				entry.IP,
+				entry.Hostname,
+				entry.Status,
+				strings.Join(entry.Hostnames, " "),
+				entry.Error,
+				strconv.FormatFloat(entry.Latency, 'f', 2, 64),
+			})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			log.Printf("Error writing reverse sweep CSV: %v", err)
+		}
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Error encoding reverse sweep response: %v", err)
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
 // BatchAnalyzeIPs handles bulk IP analysis
 func (h *IPAPIHandler) BatchAnalyzeIPs(w http.ResponseWriter, r *http.Request) {
 	var request services.BulkAnalysisRequest
@@ -224,5 +414,17 @@ func RegisterIPAPIRoutes(r chi.Router, cache interface{}) {
 		// DNS lookup - supports both GET and POST
 		r.Get("/lookup", handler.LookupDNS)
 		r.Post("/lookup", handler.LookupDNS)
+
+		// Compare live records with an expected set - JSON or raw zone file body
+		r.Post("/drift", handler.CheckDNSDrift)
+
+		// Delegation health check from the root servers down
+		r.Get("/delegation", handler.CheckDelegation)
+
+		// Reverse DNS sweep over a CIDR - JSON, streamed NDJSON or CSV
+		r.Get("/reverse-sweep", handler.SweepReverseDNS)
+
+		// Subdomain discovery from a wordlist, zone transfers and CT data
+		r.Post("/subdomains", handler.DiscoverSubdomains)
 	})
 }
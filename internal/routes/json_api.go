@@ -0,0 +1,329 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// Limits on request bodies accepted by the JSON API: spooled documents for the
+// streaming tools, and decoded JSON requests for schema, infer, query, diff and patch
+const (
+	maxJSONDocumentSize = 256 << 20
+	maxJSONRequestSize  = 32 << 20
+)
+
+// JSONAPIHandler handles JSON validation, analysis, formatting, schema, query, diff and patch API endpoints
+type JSONAPIHandler struct {
+	jsonService *services.JSONService
+}
+
+// NewJSONAPIHandler creates a new JSON API handler
+func NewJSONAPIHandler() *JSONAPIHandler {
+	return &JSONAPIHandler{
+		jsonService: services.NewJSONService(),
+	}
+}
+
+// ValidateJSON checks that the request body is well-formed JSON, locating any syntax error
+func (h *JSONAPIHandler) ValidateJSON(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.readDocument(w, r)
+	if !ok {
+		return
+	}
+	defer doc.Close()
+
+	result := h.jsonService.Validate(doc)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON validation response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// AnalyzeJSON reports the request body's structure, per-path statistics and
+// hazards such as unsafe integers, duplicate keys and invalid UTF-8
+func (h *JSONAPIHandler) AnalyzeJSON(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.readDocument(w, r)
+	if !ok {
+		return
+	}
+	defer doc.Close()
+
+	result := h.jsonService.Analyze(doc)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON analysis response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// FormatJSON pretty-prints the request body.
+// Options: indent (1-8 or "tab"), sort_keys, escape_html, escape_unicode, escape_slash.
+func (h *JSONAPIHandler) FormatJSON(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseJSONFormatOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Indent == 0 && !opts.UseTabs {
+		opts.Indent = 2
+	}
+	h.writeFormatted(w, r, opts)
+}
+
+// MinifyJSON removes all insignificant whitespace from the request body
+func (h *JSONAPIHandler) MinifyJSON(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseJSONFormatOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Indent = 0
+	opts.UseTabs = false
+	h.writeFormatted(w, r, opts)
+}
+
+// ValidateSchema validates an instance against a draft 2020-12 JSON Schema
+func (h *JSONAPIHandler) ValidateSchema(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+
+	var req services.JSONSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Schema) == 0 || len(req.Instance) == 0 {
+		http.Error(w, "Schema and instance required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jsonService.ValidateSchema(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Schema validation failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON schema response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// InferTypes generates a JSON Schema, Go structs and TypeScript interfaces from samples.
+// format=schema, go or typescript returns just that output as text.
+func (h *JSONAPIHandler) InferTypes(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+
+	var req services.JSONInferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jsonService.InferTypes(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Type inference failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "schema":
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Write(result.Schema)
+	case "go":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, result.Go)
+	case "typescript", "ts":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, result.TypeScript)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Error encoding type inference response: %v", err)
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// QueryJSON runs a JSONPath (RFC 9535) or jq filter against a document, returning values and their paths
+func (h *JSONAPIHandler) QueryJSON(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+
+	var req services.JSONQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Document) == 0 || req.Query == "" {
+		http.Error(w, "Document and query required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jsonService.Query(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Query failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON query response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// DiffJSON compares two documents, returning a change list, a JSON Patch and a Merge Patch
+func (h *JSONAPIHandler) DiffJSON(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+
+	var req services.JSONDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Left) == 0 || len(req.Right) == 0 {
+		http.Error(w, "Left and right documents required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jsonService.Diff(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Diff failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON diff response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// PatchJSON applies a JSON Patch (RFC 6902) or Merge Patch (RFC 7396) to a document
+func (h *JSONAPIHandler) PatchJSON(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+
+	var req services.JSONPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if len(req.Document) == 0 || len(req.Patch) == 0 {
+		http.Error(w, "Document and patch required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.jsonService.ApplyPatch(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Patch failed: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JSON patch response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// writeFormatted validates the document first so syntax errors are reported
+// with a 400 before any output is streamed
+func (h *JSONAPIHandler) writeFormatted(w http.ResponseWriter, r *http.Request, opts services.JSONFormatOptions) {
+	doc, ok := h.readDocument(w, r)
+	if !ok {
+		return
+	}
+	defer doc.Close()
+
+	result := h.jsonService.Validate(doc)
+	if !result.Valid {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Error encoding JSON validation response: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.jsonService.Format(doc, w, opts); err != nil {
+		log.Printf("Error formatting JSON: %v", err)
+	}
+}
+
+// readDocument spools the request body, writing an error response on failure
+func (h *JSONAPIHandler) readDocument(w http.ResponseWriter, r *http.Request) (*services.JSONDocument, bool) {
+	doc, err := services.SpoolJSON(http.MaxBytesReader(w, r.Body, maxJSONDocumentSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, fmt.Sprintf("Document too large (maximum %d bytes)", maxJSONDocumentSize), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		http.Error(w, fmt.Sprintf("Failed to read document: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return doc, true
+}
+
+// parseJSONFormatOptions reads formatting options from the query string
+func parseJSONFormatOptions(r *http.Request) (services.JSONFormatOptions, error) {
+	query := r.URL.Query()
+	opts := services.JSONFormatOptions{
+		SortKeys:      query.Get("sort_keys") == "true",
+		EscapeHTML:    query.Get("escape_html") == "true",
+		EscapeUnicode: query.Get("escape_unicode") == "true",
+		EscapeSlash:   query.Get("escape_slash") == "true",
+	}
+
+	switch indent := strings.ToLower(query.Get("indent")); indent {
+	case "":
+	case "tab", "\t":
+		opts.UseTabs = true
+	default:
+		width, err := strconv.Atoi(indent)
+		if err != nil || width < 1 || width > 8 {
+			return opts, fmt.Errorf("indent must be 1-8 or \"tab\"")
+		}
+		opts.Indent = width
+	}
+	return opts, nil
+}
+
+// RegisterJSONAPIRoutes registers all JSON API routes
+func RegisterJSONAPIRoutes(r chi.Router) {
+	handler := NewJSONAPIHandler()
+
+	r.Route("/json", func(r chi.Router) {
+		// Syntax validation with line, column, offset and excerpt - raw JSON body
+		r.Post("/validate", handler.ValidateJSON)
+		// Size, depth, per-path statistics and interoperability hazards - raw JSON body
+		r.Post("/analyze", handler.AnalyzeJSON)
+		// Pretty printing with indent, key sorting and escaping options
+		r.Post("/format", handler.FormatJSON)
+		// Whitespace removal, streamed for large documents
+		r.Post("/minify", handler.MinifyJSON)
+		// JSON Schema (draft 2020-12) validation of an instance - JSON body with schema and instance
+		r.Post("/schema", handler.ValidateSchema)
+		// Schema, Go struct and TypeScript inference from one or more samples
+		r.Post("/infer", handler.InferTypes)
+		// JSONPath or jq query evaluation - JSON body with document, query and language
+		r.Post("/query", handler.QueryJSON)
+		// Semantic diff as change list, JSON Patch and Merge Patch - JSON body with left, right and array_mode
+		r.Post("/diff", handler.DiffJSON)
+		// JSON Patch or Merge Patch application - JSON body with document, patch and type
+		r.Post("/patch", handler.PatchJSON)
+	})
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// maxJWTRequestSize limits tokens and keys accepted by the JWT API; JWKS documents can hold many keys
+const maxJWTRequestSize = 1 << 20
+
+// JWTAPIHandler handles JWT decoding and verification API endpoints
+type JWTAPIHandler struct {
+	jwtService *services.JWTService
+}
+
+// NewJWTAPIHandler creates a new JWT API handler
+func NewJWTAPIHandler() *JWTAPIHandler {
+	return &JWTAPIHandler{
+		jwtService: services.NewJWTService(),
+	}
+}
+
+// DecodeJWT decodes a token's header and claims and flags problems without checking the signature
+func (h *JWTAPIHandler) DecodeJWT(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.readRequest(w, r)
+	if !ok {
+		return
+	}
+	req.Secret, req.Key = "", ""
+	h.inspect(w, req)
+}
+
+// VerifyJWT decodes a token and verifies its signature with a shared secret, PEM key or JWKS
+func (h *JWTAPIHandler) VerifyJWT(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.readRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Secret == "" && strings.TrimSpace(req.Key) == "" {
+		http.Error(w, "Secret or key required", http.StatusBadRequest)
+		return
+	}
+	h.inspect(w, req)
+}
+
+func (h *JWTAPIHandler) readRequest(w http.ResponseWriter, r *http.Request) (services.JWTRequest, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJWTRequestSize)
+
+	var req services.JWTRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return req, false
+	}
+	if strings.TrimSpace(req.Token) == "" {
+		http.Error(w, "Token required", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+func (h *JWTAPIHandler) inspect(w http.ResponseWriter, req services.JWTRequest) {
+	result, err := h.jwtService.Inspect(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding JWT response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterJWTAPIRoutes registers all JWT API routes
+func RegisterJWTAPIRoutes(r chi.Router) {
+	handler := NewJWTAPIHandler()
+
+	r.Route("/jwt", func(r chi.Router) {
+		// Header, claims and problems without signature verification
+		r.Post("/decode", handler.DecodeJWT)
+		// Decoding plus signature verification - JSON body with token and secret or key
+		r.Post("/verify", handler.VerifyJWT)
+	})
+}
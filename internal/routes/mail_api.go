@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// MailAPIHandler handles email authentication API endpoints
+type MailAPIHandler struct {
+	mailService *services.MailAuthService
+}
+
+// NewMailAPIHandler creates a new mail API handler
+func NewMailAPIHandler() *MailAPIHandler {
+	return &MailAPIHandler{
+		mailService: services.NewMailAuthService(services.NewIPAnalysisService()),
+	}
+}
+
+// CheckDomain reports the SPF, DKIM, DMARC, MTA-STS and BIMI health of a domain
+func (h *MailAPIHandler) CheckDomain(w http.ResponseWriter, r *http.Request) {
+	// Parse request body for POST or query params for GET
+	var req services.MailAuthRequest
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Domain = r.URL.Query().Get("domain")
+		if selectors := r.URL.Query().Get("selectors"); selectors != "" {
+			req.DKIMSelectors = strings.Split(selectors, ",")
+		}
+	}
+
+	if req.Domain == "" {
+		http.Error(w, "Domain required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.DKIMSelectors) > 20 {
+		http.Error(w, "Too many DKIM selectors (maximum 20)", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.mailService.CheckDomain(r.Context(), req)
+	if err != nil {
+		log.Printf("Error checking mail authentication for %s: %v", req.Domain, err)
+		http.Error(w, fmt.Sprintf("Mail authentication check failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Error encoding mail authentication response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// CheckSPF evaluates a domain's SPF policy against a sending IP address
+func (h *MailAPIHandler) CheckSPF(w http.ResponseWriter, r *http.Request) {
+	// Parse request body for POST or query params for GET
+	var req services.SPFCheckRequest
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.IP = query.Get("ip")
+		req.Domain = query.Get("domain")
+		req.Sender = query.Get("sender")
+		req.HELO = query.Get("helo")
+	}
+
+	if req.IP == "" {
+		http.Error(w, "IP address required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Domain == "" && req.Sender == "" {
+		http.Error(w, "Domain or sender required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.mailService.CheckSPF(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("SPF check failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding SPF check response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterMailAPIRoutes registers all email authentication API routes
+func RegisterMailAPIRoutes(r chi.Router) {
+	handler := NewMailAPIHandler()
+
+	r.Route("/mail", func(r chi.Router) {
+		// Domain health check - supports both GET and POST
+		r.Get("/check", handler.CheckDomain)
+		r.Post("/check", handler.CheckDomain)
+
+		// SPF evaluation for a sending IP - supports both GET and POST
+		r.Get("/spf", handler.CheckSPF)
+		r.Post("/spf", handler.CheckSPF)
+	})
+}
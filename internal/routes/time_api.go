@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// TimeAPIHandler handles time conversion API endpoints
+type TimeAPIHandler struct {
+	timeService *services.TimeService
+}
+
+// NewTimeAPIHandler creates a new time API handler
+func NewTimeAPIHandler() *TimeAPIHandler {
+	return &TimeAPIHandler{
+		timeService: services.NewTimeService(),
+	}
+}
+
+// ConvertTime converts a Unix timestamp or date into every precision and format
+// in the requested IANA timezones
+func (h *TimeAPIHandler) ConvertTime(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := services.TimeConvertRequest{
+		Input:     query.Get("input"),
+		Unit:      query.Get("unit"),
+		InputZone: query.Get("in"),
+	}
+	if req.Input == "" {
+		http.Error(w, "input parameter required", http.StatusBadRequest)
+		return
+	}
+	for _, name := range strings.Split(query.Get("tz"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			req.Timezones = append(req.Timezones, name)
+		}
+	}
+
+	result, err := h.timeService.Convert(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Conversion failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding time conversion response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterTimeAPIRoutes registers all time API routes
+func RegisterTimeAPIRoutes(r chi.Router) {
+	handler := NewTimeAPIHandler()
+
+	r.Route("/time", func(r chi.Router) {
+		// Timestamp or date in, every precision and format per timezone out
+		r.Get("/convert", handler.ConvertTime)
+	})
+}
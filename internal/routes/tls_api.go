@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// TLSAPIHandler handles TLS inspection API endpoints
+type TLSAPIHandler struct {
+	tlsService *services.TLSInspectionService
+}
+
+// NewTLSAPIHandler creates a new TLS API handler
+func NewTLSAPIHandler() *TLSAPIHandler {
+	return &TLSAPIHandler{
+		tlsService: services.NewTLSInspectionService(),
+	}
+}
+
+// InspectTLS inspects the certificate chain and handshake of a host:port
+func (h *TLSAPIHandler) InspectTLS(w http.ResponseWriter, r *http.Request) {
+	// Parse request body for POST or query params for GET
+	var req services.TLSInspectRequest
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.Host = query.Get("host")
+		req.Port = query.Get("port")
+		req.SNI = query.Get("sni")
+		if alpn := query.Get("alpn"); alpn != "" {
+			req.ALPN = strings.Split(alpn, ",")
+		}
+	}
+
+	if req.Host == "" {
+		http.Error(w, "Host required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.tlsService.InspectTLS(r.Context(), req)
+	if err != nil {
+		log.Printf("Error inspecting TLS for %s:%s: %v", req.Host, req.Port, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("TLS inspection failed: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding TLS inspection response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// ScanTLS reports the protocol versions and cipher suites a host:port accepts
+func (h *TLSAPIHandler) ScanTLS(w http.ResponseWriter, r *http.Request) {
+	// Parse request body for POST or query params for GET
+	var req services.TLSScanRequest
+
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		req.Host = query.Get("host")
+		req.Port = query.Get("port")
+		req.SNI = query.Get("sni")
+		if concurrency := query.Get("concurrency"); concurrency != "" {
+			n, err := strconv.Atoi(concurrency)
+			if err != nil {
+				http.Error(w, "Invalid concurrency", http.StatusBadRequest)
+				return
+			}
+			req.Concurrency = n
+		}
+	}
+
+	if req.Host == "" {
+		http.Error(w, "Host required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.tlsService.ScanTLS(r.Context(), req)
+	if err != nil {
+		log.Printf("Error scanning TLS for %s:%s: %v", req.Host, req.Port, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("TLS scan failed: %v", err), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding TLS scan response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// RegisterTLSAPIRoutes registers all TLS API routes
+func RegisterTLSAPIRoutes(r chi.Router) {
+	handler := NewTLSAPIHandler()
+
+	r.Route("/tls", func(r chi.Router) {
+		// TLS inspection - supports both GET and POST
+		r.Get("/inspect", handler.InspectTLS)
+		r.Post("/inspect", handler.InspectTLS)
+
+		// Protocol and cipher suite scan - supports both GET and POST
+		r.Get("/scan", handler.ScanTLS)
+		r.Post("/scan", handler.ScanTLS)
+	})
+}
@@ -21,9 +21,13 @@ func getToolTitle(toolName string) string {
 	titles := map[string]string{
 		"index":          "Dev Tools",
 		"unix-time":      "Unix Time Converter - Dev Tools",
+		"cron":           "Cron Expression Parser - Dev Tools",
 		"json-validator": "JSON Validator - Dev Tools",
 		"ip":             "IP Check - Dev Tools",
 		"css-linter":     "CSS Linter - Dev Tools",
+		"zone-linter":    "DNS Zone Linter - Dev Tools",
+		"dns-leak":       "DNS Leak Test - Dev Tools",
+		"jwt":            "JWT Decoder - Dev Tools",
 	}
 
 	if title, exists := titles[toolName]; exists {
@@ -39,9 +43,13 @@ func getToolBreadcrumbs(toolName string) []Breadcrumb {
 
 	toolNames := map[string]string{
 		"unix-time":      "Unix Time Converter",
+		"cron":           "Cron Parser",
 		"json-validator": "JSON Validator",
 		"ip":             "IP Check",
 		"css-linter":     "CSS Linter",
+		"zone-linter":    "DNS Zone Linter",
+		"dns-leak":       "DNS Leak Test",
+		"jwt":            "JWT Decoder",
 	}
 
 	if name, exists := toolNames[toolName]; exists {
@@ -0,0 +1,156 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/ztkent/dev-tools/internal/services"
+)
+
+// maxZoneFileSize limits the size of zone files accepted by the API
+const maxZoneFileSize = 4 << 20
+
+// ZoneAPIHandler handles zone file API endpoints
+type ZoneAPIHandler struct {
+	zoneService     *services.ZoneService
+	transferService *services.ZoneTransferService
+}
+
+// NewZoneAPIHandler creates a new zone API handler
+func NewZoneAPIHandler() *ZoneAPIHandler {
+	ipService := services.NewIPAnalysisService()
+	return &ZoneAPIHandler{
+		zoneService:     services.NewZoneService(ipService),
+		transferService: services.NewZoneTransferService(ipService),
+	}
+}
+
+// LintZone parses a zone file and reports syntax errors and common mistakes
+func (h *ZoneAPIHandler) LintZone(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxZoneFileSize)
+
+	// Accept a JSON request or a raw zone file with options in the query string
+	var req services.ZoneParseRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	} else {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read zone file: %v", err), http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		req.Content = string(body)
+		req.Origin = query.Get("origin")
+		req.DefaultTTL, _ = strconv.Atoi(query.Get("ttl"))
+		req.ResolveTargets = query.Get("resolve") == "true"
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "Zone file content required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.zoneService.ParseZone(r.Context(), req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Zone parsing failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Error encoding zone lint response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// TransferZone requests an AXFR or IXFR from a name server.
+// format=ndjson streams records as they arrive, format=zone returns a zone file.
+func (h *ZoneAPIHandler) TransferZone(w http.ResponseWriter, r *http.Request) {
+	var req services.ZoneTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+		return
+	}
+	if req.Zone == "" || req.Server == "" {
+		http.Error(w, "Zone and server required", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+
+	var emit func(services.DNSRecord)
+	if format == "ndjson" {
+		// Stream one JSON record per line, followed by the summary
+		flusher, _ := w.(http.Flusher)
+		encoder := json.NewEncoder(w)
+		emit = func(record services.DNSRecord) {
+			if w.Header().Get("Content-Type") == "" {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+			}
+			if err := encoder.Encode(record); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+
+	result, err := h.transferService.TransferZone(r.Context(), req, emit)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrNonPublicAddress) {
+			status = http.StatusForbidden
+		}
+		http.Error(w, fmt.Sprintf("Zone transfer failed: %v", err), status)
+		return
+	}
+
+	switch format {
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		summary := *result
+		summary.Records = nil
+		summary.Changes = nil
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"summary": summary}); err != nil {
+			log.Printf("Error encoding zone transfer summary: %v", err)
+		}
+	case "zone":
+		if !result.Allowed {
+			http.Error(w, fmt.Sprintf("Zone transfer was not allowed: %s", result.Error), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.TrimSuffix(result.Zone, ".")+".zone"))
+		io.WriteString(w, result.ZoneFile)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Error encoding zone transfer response: %v", err)
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	}
+}
+
+// RegisterZoneAPIRoutes registers all zone file API routes
+func RegisterZoneAPIRoutes(r chi.Router) {
+	handler := NewZoneAPIHandler()
+
+	r.Route("/zone", func(r chi.Router) {
+		// Zone file parsing and linting - JSON or raw text body
+		r.Post("/lint", handler.LintZone)
+		// AXFR/IXFR client with optional TSIG - JSON, streamed NDJSON or zone file
+		r.Post("/transfer", handler.TransferZone)
+	})
+}
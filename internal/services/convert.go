@@ -0,0 +1,1132 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxYAMLNodes bounds the values a YAML document may expand to once aliases
+// are resolved, so nested aliases cannot grow exponentially ("billion laughs")
+const maxYAMLNodes = 1_000_000
+
+// Formats supported by the conversion service
+var convertFormats = map[string]bool{
+	"json": true, "yaml": true, "toml": true, "xml": true, "csv": true, "tsv": true, "query": true,
+}
+
+// ConvertOptions controls conversion between data formats
+type ConvertOptions struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Indent     int    `json:"indent"`      // JSON, YAML and XML output, default 2
+	Separator  string `json:"separator"`   // CSV path separator for nested keys, default "."
+	Arrays     string `json:"arrays"`      // CSV array handling: "index" (default), "join" or "json"
+	JoinWith   string `json:"join_with"`   // CSV delimiter for arrays=join, default ";"
+	KeepNested bool   `json:"keep_nested"` // CSV: write nested values as JSON cells instead of flattening
+	InferTypes bool   `json:"infer_types"` // CSV, XML and query input: read numbers, booleans and empty values as typed
+	Root       string `json:"root"`        // XML root element name when the data has no single top-level key
+	QueryStyle string `json:"query_style"` // query string nesting: "brackets" (default) or "dots"
+}
+
+// ConvertResult holds converted output and any lossy conversions performed
+type ConvertResult struct {
+	Output   []byte
+	Warnings []string
+}
+
+// ConvertService converts structured data between JSON, YAML, TOML, XML,
+// CSV/TSV and query strings
+type ConvertService struct{}
+
+// NewConvertService creates a new conversion service
+func NewConvertService() *ConvertService {
+	return &ConvertService{}
+}
+
+// convObject is an object that keeps its keys in document order
+type convObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// convDateTime is a TOML or YAML date/time, kept as written
+type convDateTime string
+
+func newConvObject() *convObject {
+	return &convObject{values: make(map[string]any)}
+}
+
+func (o *convObject) get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// set stores a value, keeping the key's original position when it already exists
+func (o *convObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// converter carries options and collects warnings for one conversion
+type converter struct {
+	opts      ConvertOptions
+	warnings  []string
+	yamlNodes int             // YAML nodes expanded so far, bounded by maxYAMLNodes
+	collided  map[string]bool // CSV columns already reported as written by more than one path
+}
+
+func (c *converter) warn(format string, args ...any) {
+	if len(c.warnings) < 100 {
+		c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// Convert parses input in opts.From and writes it as opts.To
+func (s *ConvertService) Convert(input []byte, opts ConvertOptions) (*ConvertResult, error) {
+	opts.From = strings.ToLower(strings.TrimSpace(opts.From))
+	opts.To = strings.ToLower(strings.TrimSpace(opts.To))
+	for _, format := range []string{opts.From, opts.To} {
+		if !convertFormats[format] {
+			return nil, fmt.Errorf("unsupported format %q (supported: json, yaml, toml, xml, csv, tsv, query)", format)
+		}
+	}
+	if opts.Indent < 0 || opts.Indent > maxJSONIndent {
+		return nil, fmt.Errorf("indent must be between 0 and %d", maxJSONIndent)
+	}
+	if opts.Indent == 0 {
+		opts.Indent = 2
+	}
+	if opts.Separator == "" {
+		opts.Separator = "."
+	}
+	if opts.JoinWith == "" {
+		opts.JoinWith = ";"
+	}
+	switch opts.Arrays {
+	case "":
+		opts.Arrays = "index"
+	case "index", "join", "json":
+	default:
+		return nil, fmt.Errorf("arrays must be index, join or json")
+	}
+	switch opts.QueryStyle {
+	case "":
+		opts.QueryStyle = "brackets"
+	case "brackets", "dots":
+	default:
+		return nil, fmt.Errorf("query_style must be brackets or dots")
+	}
+
+	c := &converter{opts: opts}
+
+	var value any
+	var err error
+	switch opts.From {
+	case "json":
+		value, err = c.readJSON(input)
+	case "yaml":
+		value, err = c.readYAML(input)
+	case "toml":
+		value, err = parseTOML(string(input))
+	case "xml":
+		value, err = c.readXML(input)
+	case "csv", "tsv":
+		value, err = c.readCSV(input, opts.From == "tsv")
+	case "query":
+		value, err = c.readQuery(string(input))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s input: %w", strings.ToUpper(opts.From), err)
+	}
+
+	var output []byte
+	switch opts.To {
+	case "json":
+		output, err = c.writeJSON(value)
+	case "yaml":
+		output, err = c.writeYAML(value)
+	case "toml":
+		output, err = c.writeTOML(value)
+	case "xml":
+		output, err = c.writeXML(value)
+	case "csv", "tsv":
+		output, err = c.writeCSV(value, opts.To == "tsv")
+	case "query":
+		output, err = c.writeQuery(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot write %s: %w", strings.ToUpper(opts.To), err)
+	}
+
+	return &ConvertResult{Output: output, Warnings: c.warnings}, nil
+}
+
+// readJSON decodes a JSON document, keeping key order and exact numbers
+func (c *converter) readJSON(input []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	value, err := readJSONToken(dec, tok)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+func readJSONToken(dec *json.Decoder, tok json.Token) (any, error) {
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	if delim == '[' {
+		list := []any{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			item, err := readJSONToken(dec, tok)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+
+	obj := newConvObject()
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		value, err := readJSONToken(dec, tok)
+		if err != nil {
+			return nil, err
+		}
+		obj.set(keyTok.(string), value)
+	}
+	_, err := dec.Token()
+	return obj, err
+}
+
+// writeJSON encodes a value as indented JSON
+func (c *converter) writeJSON(value any) ([]byte, error) {
+	var compact bytes.Buffer
+	c.writeJSONValue(&compact, value)
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "", strings.Repeat(" ", c.opts.Indent)); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// writeJSONValue writes compact JSON. Infinity and NaN have no JSON form and
+// are written as the strings "inf", "-inf" and "nan".
+func (c *converter) writeJSONValue(buf *bytes.Buffer, value any) {
+	f := &jsonFormatter{}
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		buf.WriteString(v.String())
+	case float64:
+		c.warn("%s has no JSON representation; written as a string", scalarText(v))
+		f.writeString(buf, scalarText(v))
+	case string:
+		f.writeString(buf, v)
+	case convDateTime:
+		f.writeString(buf, string(v))
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			c.writeJSONValue(buf, item)
+		}
+		buf.WriteByte(']')
+	case *convObject:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			f.writeString(buf, key)
+			buf.WriteByte(':')
+			c.writeJSONValue(buf, v.values[key])
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// jsonNumberPattern matches numbers written in JSON syntax
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// readYAML decodes YAML; a stream of several documents becomes an array
+func (c *converter) readYAML(input []byte) (any, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(input))
+	var docs []any
+	for {
+		var node yaml.Node
+		if err := dec.Decode(&node); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		value, err := c.yamlValue(&node)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, value)
+	}
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	default:
+		c.warn("%d YAML documents were combined into an array", len(docs))
+		return docs, nil
+	}
+}
+
+func (c *converter) yamlValue(node *yaml.Node) (any, error) {
+	c.yamlNodes++
+	if c.yamlNodes > maxYAMLNodes {
+		return nil, fmt.Errorf("YAML expands to more than %d values; check for nested aliases", maxYAMLNodes)
+	}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return c.yamlValue(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := c.yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.MappingNode:
+		obj := newConvObject()
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			value, err := c.yamlValue(valueNode)
+			if err != nil {
+				return nil, err
+			}
+			if keyNode.ShortTag() == "!!merge" {
+				// << merges mappings without overriding explicit keys
+				merged := []any{value}
+				if list, ok := value.([]any); ok {
+					merged = list
+				}
+				for _, item := range merged {
+					if source, ok := item.(*convObject); ok {
+						for _, key := range source.keys {
+							if _, exists := obj.get(key); !exists {
+								obj.set(key, source.values[key])
+							}
+						}
+					}
+				}
+				continue
+			}
+			key := keyNode.Value
+			if keyNode.Kind != yaml.ScalarNode {
+				keyValue, err := c.yamlValue(keyNode)
+				if err != nil {
+					return nil, err
+				}
+				key = c.compactText(keyValue)
+				c.warn("complex YAML key converted to the string %s", key)
+			}
+			obj.set(key, value)
+		}
+		return obj, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case "!!int":
+		var n any
+		if err := node.Decode(&n); err != nil {
+			return nil, err
+		}
+		return json.Number(fmt.Sprint(n)), nil
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return f, nil
+		}
+		if jsonNumberPattern.MatchString(node.Value) {
+			return json.Number(node.Value), nil
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	case "!!timestamp":
+		return convDateTime(node.Value), nil
+	default:
+		return node.Value, nil
+	}
+}
+
+// yaml11Bools are plain scalars that YAML 1.1 treats as booleans
+var yaml11Bools = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "on": true, "off": true,
+}
+
+// writeYAML encodes a value as YAML, quoting strings that would read back as other types
+func (c *converter) writeYAML(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(c.opts.Indent)
+	if err := enc.Encode(yamlNode(value)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlNode(value any) *yaml.Node {
+	scalar := func(tag, text string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: text}
+	}
+	switch v := value.(type) {
+	case nil:
+		return scalar("!!null", "null")
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v))
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return scalar("!!float", v.String())
+		}
+		return scalar("!!int", v.String())
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return scalar("!!float", ".nan")
+		case v > 0:
+			return scalar("!!float", ".inf")
+		default:
+			return scalar("!!float", "-.inf")
+		}
+	case convDateTime:
+		return scalar("!!timestamp", string(v))
+	case string:
+		node := scalar("!!str", v)
+		switch {
+		case strings.Contains(v, "\n"):
+			node.Style = yaml.LiteralStyle
+		case yaml11Bools[strings.ToLower(v)]:
+			// YAML 1.1 parsers read these as booleans
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case *convObject:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range v.keys {
+			node.Content = append(node.Content, scalar("!!str", key), yamlNode(v.values[key]))
+		}
+		return node
+	}
+	return scalar("!!null", "null")
+}
+
+// readXML converts an XML document to nested objects. Attributes become "@name"
+// keys, text beside attributes or elements becomes "#text" and repeated child
+// elements become arrays.
+func (c *converter) readXML(input []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(input))
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := c.readXMLElement(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			root := newConvObject()
+			root.set(xmlName(start.Name), value)
+			return root, nil
+		}
+	}
+}
+
+func (c *converter) readXMLElement(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	obj := newConvObject()
+	for _, attr := range start.Attr {
+		obj.set("@"+xmlName(attr.Name), c.typed(attr.Value))
+	}
+
+	var text strings.Builder
+	children := false
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("element <%s> is not closed", xmlName(start.Name))
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			children = true
+			child, err := c.readXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			name := xmlName(t.Name)
+			if existing, ok := obj.get(name); ok {
+				if list, ok := existing.([]any); ok {
+					obj.set(name, append(list, child))
+				} else {
+					obj.set(name, []any{existing, child})
+				}
+			} else {
+				obj.set(name, child)
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if xmlName(t.Name) != xmlName(start.Name) {
+				return nil, fmt.Errorf("element <%s> closed by </%s>", xmlName(start.Name), xmlName(t.Name))
+			}
+			content := text.String()
+			if len(obj.keys) == 0 {
+				if strings.TrimSpace(content) == "" && !c.opts.InferTypes {
+					return strings.TrimSpace(content), nil
+				}
+				return c.typed(content), nil
+			}
+			if trimmed := strings.TrimSpace(content); trimmed != "" {
+				if children {
+					obj.set("#text", trimmed)
+				} else {
+					obj.set("#text", c.typed(content))
+				}
+			}
+			return obj, nil
+		}
+	}
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
+
+var xmlNamePattern = regexp.MustCompile(`^[\pL_][\pL\pN._:-]*$`)
+
+// writeXML encodes a value as XML. A single top-level key names the root
+// element; otherwise the data is wrapped in opts.Root.
+func (c *converter) writeXML(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", strings.Repeat(" ", c.opts.Indent))
+
+	rootName := c.opts.Root
+	if rootName == "" {
+		rootName = "root"
+	}
+	if obj, ok := value.(*convObject); ok && len(obj.keys) == 1 && !strings.HasPrefix(obj.keys[0], "@") && !strings.HasPrefix(obj.keys[0], "#") {
+		if _, isList := obj.values[obj.keys[0]].([]any); !isList {
+			rootName, value = obj.keys[0], obj.values[obj.keys[0]]
+		}
+	}
+	if list, ok := value.([]any); ok {
+		// A bare array becomes repeated <item> elements
+		wrapper := newConvObject()
+		wrapper.set("item", list)
+		value = wrapper
+	}
+
+	if err := c.writeXMLElement(enc, rootName, value); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func (c *converter) writeXMLElement(enc *xml.Encoder, name string, value any) error {
+	if list, ok := value.([]any); ok {
+		for _, item := range list {
+			if nested, ok := item.([]any); ok {
+				// Arrays of arrays keep their nesting with <item> children
+				wrapper := newConvObject()
+				wrapper.set("item", nested)
+				item = wrapper
+			}
+			if err := c.writeXMLElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: c.xmlElementName(name)}}
+	obj, isObject := value.(*convObject)
+	if !isObject {
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		if value != nil {
+			if err := enc.EncodeToken(xml.CharData(scalarText(value))); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	var text string
+	var children []string
+	for _, key := range obj.keys {
+		switch {
+		case strings.HasPrefix(key, "@"):
+			attrValue := obj.values[key]
+			if _, ok := attrValue.(*convObject); ok {
+				c.warn("attribute %s has a nested value; written as JSON", key)
+			}
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: c.xmlElementName(key[1:])}, Value: c.cellText(attrValue)})
+		case key == "#text":
+			text = c.cellText(obj.values[key])
+		default:
+			children = append(children, key)
+		}
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if text != "" {
+		if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+	for _, key := range children {
+		if err := c.writeXMLElement(enc, key, obj.values[key]); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlElementName replaces characters that are not allowed in XML names
+func (c *converter) xmlElementName(name string) string {
+	if xmlNamePattern.MatchString(name) {
+		return name
+	}
+	sanitized := []rune(name)
+	for i, r := range sanitized {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == ':') {
+			sanitized[i] = '_'
+		}
+	}
+	result := string(sanitized)
+	if result == "" || !(unicode.IsLetter(sanitized[0]) || sanitized[0] == '_') {
+		result = "_" + result
+	}
+	c.warn("%q is not a valid XML name; written as %q", name, result)
+	return result
+}
+
+// readCSV reads a header row and records into an array of objects, rebuilding
+// nested values from flattened column names
+func (c *converter) readCSV(input []byte, tabs bool) (any, error) {
+	reader := csv.NewReader(bytes.NewReader(input))
+	if tabs {
+		reader.Comma = '\t'
+		reader.LazyQuotes = true
+	}
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []any{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) > len(header) {
+			line, _ := reader.FieldPos(0)
+			c.warn("line %d has %d fields but the header has %d; extra fields dropped", line, len(record), len(header))
+		}
+
+		row := newConvObject()
+		for i, column := range header {
+			cell := ""
+			if i < len(record) {
+				cell = record[i]
+			}
+			value := c.cellValue(cell)
+			if c.opts.KeepNested {
+				row.set(column, value)
+				continue
+			}
+			if err := setPath(row, strings.Split(column, c.opts.Separator), value); err != nil {
+				return nil, fmt.Errorf("column %q: %w", column, err)
+			}
+		}
+		if c.opts.Arrays == "index" && !c.opts.KeepNested {
+			rows = append(rows, listify(row))
+		} else {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// cellValue interprets one CSV cell according to the array and typing options
+func (c *converter) cellValue(cell string) any {
+	trimmed := strings.TrimSpace(cell)
+	if (c.opts.Arrays == "json" || c.opts.KeepNested) && (strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{")) {
+		if value, err := c.readJSON([]byte(trimmed)); err == nil {
+			return value
+		}
+	}
+	if c.opts.Arrays == "join" && strings.Contains(cell, c.opts.JoinWith) {
+		list := []any{}
+		for _, part := range strings.Split(cell, c.opts.JoinWith) {
+			list = append(list, c.typed(part))
+		}
+		return list
+	}
+	return c.typed(cell)
+}
+
+// typed converts text to a number, boolean or null when InferTypes is set
+func (c *converter) typed(text string) any {
+	if !c.opts.InferTypes {
+		return text
+	}
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "" || trimmed == "null":
+		return nil
+	case trimmed == "true":
+		return true
+	case trimmed == "false":
+		return false
+	case jsonNumberPattern.MatchString(trimmed):
+		return json.Number(trimmed)
+	}
+	return text
+}
+
+// setPath stores value at a nested path, creating objects along the way
+func setPath(obj *convObject, path []string, value any) error {
+	for _, segment := range path[:len(path)-1] {
+		next, ok := obj.get(segment)
+		if !ok {
+			child := newConvObject()
+			obj.set(segment, child)
+			obj = child
+			continue
+		}
+		child, ok := next.(*convObject)
+		if !ok {
+			return fmt.Errorf("%q is both a value and a parent", segment)
+		}
+		obj = child
+	}
+	last := path[len(path)-1]
+	if existing, ok := obj.get(last); ok {
+		if _, isObject := existing.(*convObject); isObject {
+			return fmt.Errorf("%q is both a value and a parent", last)
+		}
+	}
+	obj.set(last, value)
+	return nil
+}
+
+// listify turns objects whose keys are exactly 0..n-1 into arrays
+func listify(value any) any {
+	switch v := value.(type) {
+	case *convObject:
+		for _, key := range v.keys {
+			v.values[key] = listify(v.values[key])
+		}
+		if len(v.keys) == 0 {
+			return v
+		}
+		indexes := make([]int, 0, len(v.keys))
+		for _, key := range v.keys {
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || strconv.Itoa(i) != key {
+				return v
+			}
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		for want, got := range indexes {
+			if want != got {
+				return v
+			}
+		}
+		list := make([]any, len(v.keys))
+		for _, key := range v.keys {
+			i, _ := strconv.Atoi(key)
+			list[i] = v.values[key]
+		}
+		return list
+	case []any:
+		for i := range v {
+			v[i] = listify(v[i])
+		}
+	}
+	return value
+}
+
+// writeCSV flattens an array of objects into rows with one column per path
+func (c *converter) writeCSV(value any, tabs bool) ([]byte, error) {
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	default:
+		items = []any{v}
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	rows := make([]*convObject, 0, len(items))
+	for _, item := range items {
+		row := newConvObject()
+		if obj, ok := item.(*convObject); ok {
+			for _, key := range obj.keys {
+				c.flattenCell(row, key, obj.values[key])
+			}
+		} else {
+			c.flattenCell(row, "value", item)
+		}
+		for _, key := range row.keys {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if tabs {
+		writer.Comma = '\t'
+	}
+	if len(columns) > 0 {
+		writer.Write(columns)
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			if value, ok := row.get(column); ok {
+				record[i] = value.(string)
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// setCell stores a flattened value. Keys that contain the separator can flatten
+// to the same column as a nested path, e.g. "a.b" and {"a":{"b":...}}; the last
+// value is kept and the column is reported once.
+func (c *converter) setCell(row *convObject, column, text string) {
+	if _, exists := row.get(column); exists && !c.collided[column] {
+		if c.collided == nil {
+			c.collided = make(map[string]bool)
+		}
+		c.collided[column] = true
+		c.warn("column %q is written by more than one key; only the last value was kept", column)
+	}
+	row.set(column, text)
+}
+
+func (c *converter) flattenCell(row *convObject, path string, value any) {
+	switch v := value.(type) {
+	case *convObject:
+		if c.opts.KeepNested || len(v.keys) == 0 {
+			c.setCell(row, path, c.compactText(v))
+			return
+		}
+		for _, key := range v.keys {
+			c.flattenCell(row, path+c.opts.Separator+key, v.values[key])
+		}
+	case []any:
+		switch {
+		case c.opts.KeepNested || c.opts.Arrays == "json" || len(v) == 0:
+			c.setCell(row, path, c.compactText(v))
+		case c.opts.Arrays == "join" && allScalars(v):
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = c.cellText(item)
+			}
+			c.setCell(row, path, strings.Join(parts, c.opts.JoinWith))
+		default:
+			for i, item := range v {
+				c.flattenCell(row, path+c.opts.Separator+strconv.Itoa(i), item)
+			}
+		}
+	default:
+		c.setCell(row, path, c.cellText(v))
+	}
+}
+
+// readQuery parses a query string, nesting keys written as a[b][c] (or a.b.c
+// with query_style=dots). Repeated keys and a[] collect into arrays.
+func (c *converter) readQuery(input string) (any, error) {
+	input = strings.TrimPrefix(strings.TrimSpace(input), "?")
+	root := newConvObject()
+	for _, pair := range strings.Split(input, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, err
+		}
+		text, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, err
+		}
+
+		path := queryPath(key, c.opts.QueryStyle)
+		if err := addQueryValue(root, path, c.typed(text)); err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+	}
+	return listify(root), nil
+}
+
+// queryPath splits a query key into its nesting segments
+func queryPath(key, style string) []string {
+	var path []string
+	if i := strings.Index(key, "["); i > 0 && strings.HasSuffix(key, "]") {
+		path = append(path, key[:i])
+		for _, segment := range strings.Split(key[i+1:len(key)-1], "][") {
+			path = append(path, segment)
+		}
+	} else {
+		path = []string{key}
+	}
+	if style == "dots" {
+		var dotted []string
+		for _, segment := range path {
+			dotted = append(dotted, strings.Split(segment, ".")...)
+		}
+		path = dotted
+	}
+	return path
+}
+
+func addQueryValue(obj *convObject, path []string, value any) error {
+	for i, segment := range path {
+		if segment == "" {
+			// a[] appends the next index
+			segment = strconv.Itoa(len(obj.keys))
+		}
+		if i == len(path)-1 {
+			existing, ok := obj.get(segment)
+			switch {
+			case !ok:
+				obj.set(segment, value)
+			case isRepeated(existing):
+				obj.set(segment, append(existing.([]any), value))
+			default:
+				if _, isObject := existing.(*convObject); isObject {
+					return fmt.Errorf("%q is both a value and a parent", segment)
+				}
+				obj.set(segment, []any{existing, value})
+			}
+			return nil
+		}
+		next, ok := obj.get(segment)
+		if !ok {
+			child := newConvObject()
+			obj.set(segment, child)
+			obj = child
+			continue
+		}
+		child, ok := next.(*convObject)
+		if !ok {
+			return fmt.Errorf("%q is both a value and a parent", segment)
+		}
+		obj = child
+	}
+	return nil
+}
+
+func isRepeated(value any) bool {
+	_, ok := value.([]any)
+	return ok
+}
+
+// writeQuery encodes an object as a query string
+func (c *converter) writeQuery(value any) ([]byte, error) {
+	obj, ok := value.(*convObject)
+	if !ok {
+		return nil, fmt.Errorf("query strings require an object at the top level")
+	}
+	var pairs []string
+	for _, key := range obj.keys {
+		c.queryPairs(&pairs, url.QueryEscape(key), obj.values[key])
+	}
+	return []byte(strings.Join(pairs, "&")), nil
+}
+
+func (c *converter) queryPairs(pairs *[]string, prefix string, value any) {
+	child := func(key string) string {
+		if c.opts.QueryStyle == "dots" {
+			return prefix + "." + url.QueryEscape(key)
+		}
+		return prefix + "[" + url.QueryEscape(key) + "]"
+	}
+	switch v := value.(type) {
+	case *convObject:
+		for _, key := range v.keys {
+			c.queryPairs(pairs, child(key), v.values[key])
+		}
+	case []any:
+		for i, item := range v {
+			switch {
+			case !allScalars(v):
+				c.queryPairs(pairs, child(strconv.Itoa(i)), item)
+			case c.opts.QueryStyle == "dots":
+				c.queryPairs(pairs, prefix, item)
+			default:
+				c.queryPairs(pairs, prefix+"[]", item)
+			}
+		}
+	default:
+		*pairs = append(*pairs, prefix+"="+url.QueryEscape(c.cellText(v)))
+	}
+}
+
+func allScalars(list []any) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case *convObject, []any:
+			return false
+		}
+	}
+	return true
+}
+
+// cellText renders a value as plain text; nested values become compact JSON
+func (c *converter) cellText(value any) string {
+	switch v := value.(type) {
+	case *convObject, []any:
+		return c.compactText(v)
+	default:
+		return scalarText(v)
+	}
+}
+
+func scalarText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case convDateTime:
+		return string(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan"
+		case v > 0:
+			return "inf"
+		default:
+			return "-inf"
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func (c *converter) compactText(value any) string {
+	var buf bytes.Buffer
+	c.writeJSONValue(&buf, value)
+	return buf.String()
+}
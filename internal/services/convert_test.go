@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertYAMLAliases(t *testing.T) {
+	input := "base: &base {a: 1}\nitems: [*base, *base]\n"
+	result, err := NewConvertService().Convert([]byte(input), ConvertOptions{From: "yaml", To: "json", Indent: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n \"base\": {\n  \"a\": 1\n },\n \"items\": [\n  {\n   \"a\": 1\n  },\n  {\n   \"a\": 1\n  }\n ]\n}\n"
+	if string(result.Output) != want {
+		t.Errorf("output:\n%s\nwant:\n%s", result.Output, want)
+	}
+}
+
+// Nine levels of ten aliases each would expand to a billion values
+func TestConvertYAMLAliasBomb(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= 9; i++ {
+		fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*a%d, ", i-1), 10), ", "))
+	}
+
+	start := time.Now()
+	_, err := NewConvertService().Convert([]byte(b.String()), ConvertOptions{From: "yaml", To: "json"})
+	if err == nil || !strings.Contains(err.Error(), "nested aliases") {
+		t.Fatalf("err = %v, want alias expansion limit", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("conversion took %v", elapsed)
+	}
+}
+
+func TestConvertCSVColumnCollision(t *testing.T) {
+	input := `[{"a.b": 1, "a": {"b": 2}}, {"a.b": 3, "a": {"b": 4}}, {"c": 5}]`
+	result, err := NewConvertService().Convert([]byte(input), ConvertOptions{From: "json", To: "csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a.b,c\n2,\n4,\n,5\n"; string(result.Output) != want {
+		t.Errorf("output:\n%s\nwant:\n%s", result.Output, want)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `"a.b"`) {
+		t.Errorf("warnings = %q, want one naming the a.b column", result.Warnings)
+	}
+
+	// A different separator keeps both values
+	result, err = NewConvertService().Convert([]byte(input), ConvertOptions{From: "json", To: "csv", Separator: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Warnings) != 0 || !strings.HasPrefix(string(result.Output), "a.b,a/b,c\n1,2,\n") {
+		t.Errorf("output:\n%s\nwarnings: %q", result.Output, result.Warnings)
+	}
+}
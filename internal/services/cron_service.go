@@ -0,0 +1,989 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCronRuns = 10
+	maxCronRuns     = 100
+	// cronSearchYears bounds the search for the next runs; weekdays and leap years repeat every 28 years
+	cronSearchYears = 28
+)
+
+// cronMacros are the @ shorthands shared by Vixie cron, cronie and robfig/cron
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	cronMonthNames = []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}
+	cronDayNames   = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+	ordinalWords   = []string{"", "first", "second", "third", "fourth", "fifth"}
+)
+
+// CronService parses cron expressions and previews when they run
+type CronService struct{}
+
+// NewCronService creates a new cron service
+func NewCronService() *CronService {
+	return &CronService{}
+}
+
+// CronRequest is an expression to parse and how to preview its schedule
+type CronRequest struct {
+	Expression string `json:"expression"`
+	Dialect    string `json:"dialect"`  // "auto" (default), "standard", "seconds" or "quartz"
+	Timezone   string `json:"timezone"` // IANA zone the schedule runs in, default UTC
+	Count      int    `json:"count"`    // runs to list, default 10
+	From       string `json:"from"`     // RFC 3339 start of the preview, default now
+}
+
+// CronField explains one field of an expression
+type CronField struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Values      string `json:"values,omitempty"` // matching values as ranges, such as 1-5,7
+	Description string `json:"description"`
+}
+
+// CronRun is one upcoming run
+type CronRun struct {
+	Time     time.Time `json:"time"`
+	Local    string    `json:"local"`
+	Unix     int64     `json:"unix"`
+	Relative string    `json:"relative"`
+	DST      string    `json:"dst,omitempty"` // "skipped" or "repeated"
+	Note     string    `json:"note,omitempty"`
+}
+
+// CronResult is a parsed expression with its explanation and next runs
+type CronResult struct {
+	Expression  string      `json:"expression"`
+	Dialect     string      `json:"dialect"` // "standard", "seconds", "quartz" or "interval"
+	Detection   string      `json:"detection"`
+	Expanded    string      `json:"expanded,omitempty"` // the fields a macro stands for
+	Description string      `json:"description"`
+	Fields      []CronField `json:"fields,omitempty"`
+	Timezone    string      `json:"timezone"`
+	From        time.Time   `json:"from"`
+	Runs        []CronRun   `json:"runs"`
+	Warnings    []string    `json:"warnings,omitempty"`
+}
+
+// cronRange is one comma-separated item of a field, such as 1-5/2
+type cronRange struct {
+	lo, hi, step int
+	star         bool // written as * or ?
+}
+
+// cronField is a parsed field; day fields may instead hold one Quartz special such as L or 6#3
+type cronField struct {
+	name     string
+	unit     string
+	raw      string
+	min, max int
+	ranges   []cronRange
+	set      []bool // indexed by value; weekdays are stored as 0-6 from Sunday whatever the dialect
+	star     bool   // the first item is * or ?, which makes crontab require both day fields to match
+	any      bool   // every value matches
+	label    func(int) string
+
+	special string // "L" last day, "LW" last weekday, "W" nearest weekday, "last" last weekday of month, "nth"
+	day     int    // the day for W, the weekday for last and nth
+	offset  int    // n for L-n, the week for nth
+}
+
+type cronSchedule struct {
+	dialect                               string
+	second, minute, hour, dom, month, dow *cronField
+	year                                  *cronField // Quartz only, nil when absent
+	every                                 time.Duration
+}
+
+// Parse explains an expression and lists its next runs
+func (s *CronService) Parse(req CronRequest) (*CronResult, error) {
+	expr := strings.Join(strings.Fields(req.Expression), " ")
+	if expr == "" {
+		return nil, fmt.Errorf("expression required")
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultCronRuns
+	}
+	if count < 1 || count > maxCronRuns {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxCronRuns)
+	}
+
+	result := &CronResult{Expression: expr}
+
+	// crontab, cronie and robfig/cron accept a leading CRON_TZ= (or TZ=) to pick the zone per line
+	zone := req.Timezone
+	if first, rest, _ := strings.Cut(expr, " "); strings.HasPrefix(first, "CRON_TZ=") || strings.HasPrefix(first, "TZ=") {
+		_, zone, _ = strings.Cut(first, "=")
+		expr = rest
+		if req.Timezone != "" && req.Timezone != zone {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("The expression sets %s, which overrides the requested %s", first, req.Timezone))
+		}
+	}
+	loc, err := loadTimeZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	result.Timezone = loc.String()
+
+	from := time.Now()
+	if req.From != "" {
+		if from, err = time.Parse(time.RFC3339, req.From); err != nil {
+			return nil, fmt.Errorf("from must be an RFC 3339 time such as 2024-01-02T15:04:05Z")
+		}
+	}
+	result.From = from.In(loc)
+
+	sched, err := s.parseSchedule(expr, strings.ToLower(req.Dialect), result)
+	if err != nil {
+		return nil, err
+	}
+	result.Dialect = sched.dialect
+
+	if sched.every > 0 {
+		result.Description = fmt.Sprintf("Every %s, counted from when the scheduler starts", sched.every)
+		result.Runs = s.intervalRuns(sched.every, from, loc, count, result)
+		return result, nil
+	}
+
+	result.Fields = sched.fieldInfo()
+	result.Description = sched.describe()
+	result.Warnings = append(result.Warnings, sched.warnings()...)
+	result.Runs = sched.nextRuns(from, loc, count)
+
+	if len(result.Runs) == 0 {
+		result.Warnings = append(result.Warnings, "The expression never runs: no time in the next 28 years (or the years it names) matches every field")
+	}
+	for _, run := range result.Runs {
+		if run.DST != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Runs marked skipped or repeated land on a daylight saving change in %s; schedulers differ on whether a skipped time runs late or not at all, and whether a repeated time runs once or twice", loc))
+			break
+		}
+	}
+	return result, nil
+}
+
+// parseSchedule expands macros, settles the dialect from the field count and parses each field
+func (s *CronService) parseSchedule(expr, dialect string, result *CronResult) (*cronSchedule, error) {
+	if strings.HasPrefix(expr, "@") {
+		name, arg, _ := strings.Cut(expr, " ")
+		name = strings.ToLower(name)
+		switch {
+		case name == "@every":
+			d, err := time.ParseDuration(strings.TrimSpace(arg))
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("@every needs a positive Go duration such as 90s, 15m or 1h30m")
+			}
+			// robfig/cron rounds intervals down to whole seconds, with a one second minimum
+			rounded := max(d.Truncate(time.Second), time.Second)
+			if rounded != d {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Intervals run in whole seconds, so %s is treated as %s", d, rounded))
+			}
+			result.Detection = "@every repeats a fixed interval from when the scheduler starts (robfig/cron, Go schedulers); the preview counts from the start time"
+			return &cronSchedule{dialect: "interval", every: rounded}, nil
+		case name == "@reboot":
+			return nil, fmt.Errorf("@reboot runs once when the cron daemon starts and has no schedule to preview")
+		case cronMacros[name] != "":
+			if arg != "" {
+				return nil, fmt.Errorf("%s takes no fields, got %q", name, arg)
+			}
+			result.Expanded = cronMacros[name]
+			result.Detection = fmt.Sprintf("%s is shorthand for %s", name, cronMacros[name])
+			expr, dialect = cronMacros[name], "standard"
+		default:
+			return nil, fmt.Errorf("unknown macro %s: use @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly or @every <duration>", name)
+		}
+	}
+
+	fields := strings.Fields(expr)
+	n := len(fields)
+	switch dialect {
+	case "", "auto":
+		switch {
+		case n == 5:
+			dialect = "standard"
+		case n == 6 && strings.Contains(fields[3]+fields[5], "?"):
+			dialect = "quartz"
+		case n == 6:
+			dialect = "seconds"
+		case n == 7:
+			dialect = "quartz"
+		default:
+			return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), 6 with leading seconds or 7 with a Quartz year; got %d", n)
+		}
+	case "standard":
+		if n != 5 {
+			return nil, fmt.Errorf("standard cron takes 5 fields (minute hour day-of-month month day-of-week); got %d", n)
+		}
+	case "seconds":
+		if n != 6 {
+			return nil, fmt.Errorf("cron with seconds takes 6 fields (second minute hour day-of-month month day-of-week); got %d", n)
+		}
+	case "quartz":
+		if n != 6 && n != 7 {
+			return nil, fmt.Errorf("Quartz takes 6 or 7 fields (second minute hour day-of-month month day-of-week [year]); got %d", n)
+		}
+	default:
+		return nil, fmt.Errorf("unknown dialect %q: use auto, standard, seconds or quartz", dialect)
+	}
+
+	if result.Detection == "" {
+		switch dialect {
+		case "standard":
+			result.Detection = "5 fields: minute hour day-of-month month day-of-week, as in crontab"
+		case "seconds":
+			result.Detection = "6 fields: the first is seconds, as in Spring and robfig/cron with seconds; crontab only accepts 5"
+		case "quartz":
+			result.Detection = fmt.Sprintf("%d fields in Quartz style: second minute hour day-of-month month day-of-week [year], with days of week numbered 1-7 from Sunday", n)
+		}
+	}
+
+	sched := &cronSchedule{dialect: dialect}
+	if dialect == "standard" {
+		fields = append([]string{"0"}, fields...)
+	}
+	targets := []**cronField{&sched.second, &sched.minute, &sched.hour, &sched.dom, &sched.month, &sched.dow, &sched.year}
+	for i, raw := range fields {
+		field, err := parseCronField(i, raw, dialect)
+		if err != nil {
+			return nil, err
+		}
+		*targets[i] = field
+	}
+
+	if dialect == "quartz" {
+		domAny, dowAny := sched.dom.raw == "?", sched.dow.raw == "?"
+		if domAny == dowAny {
+			return nil, fmt.Errorf("Quartz needs ? in exactly one of day-of-month and day-of-week, such as 0 0 12 ? * MON or 0 0 12 1 * ?")
+		}
+	}
+	return sched, nil
+}
+
+// parseCronField parses the field at position i of a six or seven field expression
+func parseCronField(i int, raw, dialect string) (*cronField, error) {
+	f := &cronField{raw: raw, label: strconv.Itoa}
+	switch i {
+	case 0:
+		f.name, f.unit, f.min, f.max = "second", "second", 0, 59
+	case 1:
+		f.name, f.unit, f.min, f.max = "minute", "minute", 0, 59
+	case 2:
+		f.name, f.unit, f.min, f.max = "hour", "hour", 0, 23
+	case 3:
+		f.name, f.unit, f.min, f.max = "day-of-month", "day", 1, 31
+	case 4:
+		f.name, f.unit, f.min, f.max = "month", "month", 1, 12
+		f.label = func(v int) string { return time.Month(v).String() }
+	case 5:
+		f.name, f.unit, f.min, f.max = "day-of-week", "day", 0, 7
+		f.label = func(v int) string { return time.Weekday(v % 7).String() }
+		if dialect == "quartz" {
+			f.min = 1
+			f.label = func(v int) string { return time.Weekday(v - 1).String() }
+		}
+	case 6:
+		f.name, f.unit, f.min, f.max = "year", "year", 1970, 2099
+	}
+
+	value := strings.ToUpper(raw)
+	switch f.name {
+	case "month":
+		for i, name := range cronMonthNames {
+			value = strings.ReplaceAll(value, name, strconv.Itoa(i+1))
+		}
+	case "day-of-week":
+		for i, name := range cronDayNames {
+			value = strings.ReplaceAll(value, name, strconv.Itoa(i+f.min))
+		}
+	}
+
+	if (f.name == "day-of-month" || f.name == "day-of-week") && strings.ContainsAny(value, "LW#") {
+		if dialect == "standard" {
+			return nil, fmt.Errorf("%s %q: L, W and # are Quartz and Spring extensions that crontab does not support", f.name, raw)
+		}
+		if err := f.parseSpecial(value); err != nil {
+			return nil, err
+		}
+		f.set = make([]bool, f.max+1)
+		return f, nil
+	}
+
+	f.set = make([]bool, f.max+1)
+	for n, item := range strings.Split(value, ",") {
+		r, err := f.parseRange(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q: %v", f.name, raw, err)
+		}
+		if n == 0 {
+			f.star = r.star
+		}
+		f.ranges = append(f.ranges, r)
+		for v := r.lo; v <= r.hi; v += r.step {
+			f.set[v] = true
+		}
+	}
+
+	if f.name == "day-of-week" {
+		// Store weekdays as 0-6 from Sunday; crontab also accepts 7 for Sunday, Quartz counts 1-7
+		days := make([]bool, 7)
+		for v, ok := range f.set {
+			if ok {
+				days[f.weekday(v)] = true
+			}
+		}
+		f.set = days
+	}
+	first := f.min
+	if f.name == "day-of-week" {
+		first = 0
+	}
+	f.any = !slices.Contains(f.set[first:], false)
+	return f, nil
+}
+
+func (f *cronField) parseRange(item string) (cronRange, error) {
+	r := cronRange{step: 1}
+	if item == "" {
+		return r, fmt.Errorf("empty list item")
+	}
+	span, stepText, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		step, err := strconv.Atoi(stepText)
+		if err != nil || step < 1 {
+			return r, fmt.Errorf("step %q must be a positive number", stepText)
+		}
+		r.step = step
+	}
+
+	switch {
+	case span == "*" || span == "?":
+		if span == "?" && f.name != "day-of-month" && f.name != "day-of-week" {
+			return r, fmt.Errorf("? is only allowed in day-of-month and day-of-week")
+		}
+		r.lo, r.hi, r.star = f.min, f.max, true
+		if f.name == "day-of-week" && f.min == 0 {
+			r.hi = 6
+		}
+	case strings.Contains(span, "-"):
+		loText, hiText, _ := strings.Cut(span, "-")
+		lo, err := f.parseValue(loText)
+		if err != nil {
+			return r, err
+		}
+		hi, err := f.parseValue(hiText)
+		if err != nil {
+			return r, err
+		}
+		if lo > hi {
+			return r, fmt.Errorf("range %s-%s runs backwards", loText, hiText)
+		}
+		r.lo, r.hi = lo, hi
+	default:
+		v, err := f.parseValue(span)
+		if err != nil {
+			return r, err
+		}
+		r.lo, r.hi = v, v
+		if hasStep {
+			// 5/15 means every 15 starting at 5, as in Quartz and robfig/cron
+			r.hi = f.max
+			if f.name == "day-of-week" && f.min == 0 {
+				r.hi = 6
+			}
+		}
+	}
+	return r, nil
+}
+
+func (f *cronField) parseValue(text string) (int, error) {
+	v, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number or name", text)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%d is outside %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// parseSpecial reads Quartz's L, L-n, LW and nW day-of-month forms and nL and n#k day-of-week forms
+func (f *cronField) parseSpecial(value string) error {
+	bad := func(format string) error {
+		return fmt.Errorf("%s %q: expected %s", f.name, f.raw, format)
+	}
+	if f.name == "day-of-month" {
+		switch {
+		case value == "L":
+			f.special = "L"
+		case value == "LW":
+			f.special = "LW"
+		case strings.HasPrefix(value, "L-"):
+			n, err := strconv.Atoi(value[2:])
+			if err != nil || n < 1 || n > 30 {
+				return bad("L-n with n from 1 to 30")
+			}
+			f.special, f.offset = "L", n
+		case strings.HasSuffix(value, "W"):
+			day, err := strconv.Atoi(strings.TrimSuffix(value, "W"))
+			if err != nil || day < 1 || day > 31 {
+				return bad("nW with a day from 1 to 31")
+			}
+			f.special, f.day = "W", day
+		default:
+			return bad("L, L-n, LW or nW on its own")
+		}
+		return nil
+	}
+
+	if value == "L" {
+		// Alone, L in day-of-week is the last day of the week: Saturday
+		f.special, f.day = "set", 6
+		return nil
+	}
+	if day, week, ok := strings.Cut(value, "#"); ok {
+		v, err := f.parseValue(day)
+		n, nerr := strconv.Atoi(week)
+		if err != nil || nerr != nil || n < 1 || n > 5 {
+			return bad("weekday#n with n from 1 to 5, such as FRI#3")
+		}
+		f.special, f.day, f.offset = "nth", f.weekday(v), n
+		return nil
+	}
+	if day, ok := strings.CutSuffix(value, "L"); ok {
+		v, err := f.parseValue(day)
+		if err != nil {
+			return bad("weekdayL, such as FRIL or 5L")
+		}
+		f.special, f.day = "last", f.weekday(v)
+		return nil
+	}
+	return bad("weekdayL or weekday#n on its own")
+}
+
+// weekday maps a day-of-week value in the field's dialect to 0-6 from Sunday
+func (f *cronField) weekday(v int) int {
+	if f.min == 1 {
+		return v - 1
+	}
+	return v % 7
+}
+
+// values lists the matching values in ascending order
+func (f *cronField) values() []int {
+	var out []int
+	for v, ok := range f.set {
+		if ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// singles reports whether the field is a plain list of values with no ranges or steps
+func (f *cronField) singles() bool {
+	for _, r := range f.ranges {
+		if r.lo != r.hi {
+			return false
+		}
+	}
+	return f.special == "" && len(f.ranges) > 0
+}
+
+// matchDay checks the day-of-month or day-of-week field against a date
+func (f *cronField) matchDay(t time.Time) bool {
+	day := t.Day()
+	lastDay := daysIn(t.Year(), t.Month())
+	switch f.special {
+	case "L":
+		return day == lastDay-f.offset
+	case "LW":
+		return day == nearestWeekday(t.Year(), t.Month(), lastDay)
+	case "W":
+		return f.day <= lastDay && day == nearestWeekday(t.Year(), t.Month(), f.day)
+	case "last":
+		return int(t.Weekday()) == f.day && day+7 > lastDay
+	case "nth":
+		return int(t.Weekday()) == f.day && (day-1)/7+1 == f.offset
+	case "set":
+		return int(t.Weekday()) == f.day
+	}
+	if f.name == "day-of-week" {
+		return f.set[t.Weekday()]
+	}
+	return f.set[day]
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 12, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday is Quartz's W: the closest Monday to Friday within the same month
+func nearestWeekday(year int, month time.Month, day int) int {
+	switch time.Date(year, month, day, 12, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return 3
+		}
+		return day - 1
+	case time.Sunday:
+		if day == daysIn(year, month) {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+// matchDate checks the year, month and both day fields; crontab runs when either day field
+// matches unless one of them starts with *, while Quartz always leaves one of them as ?
+func (s *cronSchedule) matchDate(t time.Time) bool {
+	if s.year != nil && !s.year.set[t.Year()] {
+		return false
+	}
+	if !s.month.set[t.Month()] {
+		return false
+	}
+	dom, dow := s.dom.matchDay(t), s.dow.matchDay(t)
+	if s.dialect != "quartz" && !s.dom.star && !s.dow.star {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// nextRuns walks forward day by day in wall clock time, flagging times a DST change skips or
+// repeats. Every time in a skipped hour resolves to the instant the clocks jump to, so those
+// times share one run whose note lists them.
+func (s *cronSchedule) nextRuns(from time.Time, loc *time.Location, count int) []CronRun {
+	start := from.In(loc)
+	lastYear := start.Year() + cronSearchYears
+	if s.year != nil {
+		years := s.year.values()
+		lastYear = years[len(years)-1]
+	}
+	seconds, minutes, hours := s.second.values(), s.minute.values(), s.hour.values()
+
+	runs := make([]CronRun, 0, count)
+	var skipped []string // wall clock times folded into the last run
+	day := time.Date(start.Year(), start.Month(), start.Day(), 12, 0, 0, 0, time.UTC)
+	for day.Year() <= lastYear {
+		if s.year != nil && !s.year.set[day.Year()] {
+			day = time.Date(day.Year()+1, 1, 1, 12, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.month.set[day.Month()] {
+			day = time.Date(day.Year(), day.Month()+1, 1, 12, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.matchDate(day) {
+			for _, h := range hours {
+				for _, m := range minutes {
+					for _, sec := range seconds {
+						run, ok := cronRunAt(day, h, m, sec, from, loc)
+						if !ok {
+							continue
+						}
+						wall := fmt.Sprintf("%02d:%02d:%02d", h, m, sec)
+						if n := len(runs); n > 0 && runs[n-1].Unix == run.Unix {
+							if run.DST == "skipped" {
+								skipped = append(skipped, wall)
+							}
+							if len(skipped) > 0 {
+								runs[n-1].DST = "skipped"
+								runs[n-1].Note = skippedNote(skipped, day, loc, run.Time)
+							}
+							continue
+						}
+						if len(runs) == count {
+							return runs
+						}
+						skipped = nil
+						if run.DST == "skipped" {
+							skipped = []string{wall}
+						}
+						// A skipped run may still absorb later times in the same gap
+						if runs = append(runs, run); len(runs) == count && run.DST != "skipped" {
+							return runs
+						}
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return runs
+}
+
+// cronRunAt resolves a wall clock time on a day in loc, reporting false if it is not after from
+func cronRunAt(day time.Time, h, m, sec int, from time.Time, loc *time.Location) (CronRun, bool) {
+	wall := time.Date(day.Year(), day.Month(), day.Day(), h, m, sec, 0, time.UTC)
+	t := time.Date(day.Year(), day.Month(), day.Day(), h, m, sec, 0, loc)
+	local := t.In(loc)
+	got := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC)
+
+	var dst, note string
+	if !got.Equal(wall) {
+		// The wall clock time does not exist; schedulers that still run it do so once the clock jumps
+		start, end := local.ZoneBounds()
+		t = end
+		if got.After(wall) {
+			t = start
+		}
+		dst = "skipped"
+		note = skippedNote([]string{wall.Format("15:04:05")}, wall, loc, t)
+	} else if alt, ok := otherOccurrence(t, loc); ok {
+		first, second := t, alt
+		if alt.Before(t) {
+			first, second = alt, t
+		}
+		t = first
+		if !t.After(from) {
+			t = second
+		}
+		dst = "repeated"
+		note = fmt.Sprintf("%s happens twice on %s in %s because clocks fall back: at %s and again at %s",
+			wall.Format("15:04:05"), wall.Format("2006-01-02"), loc,
+			first.In(loc).Format("15:04 MST (-07:00)"), second.In(loc).Format("15:04 MST (-07:00)"))
+	}
+	if !t.After(from) {
+		return CronRun{}, false
+	}
+	return newCronRun(t, from, loc, dst, note), true
+}
+
+// skippedNote explains wall clock times on day that do not exist because clocks jump forward at t
+func skippedNote(walls []string, day time.Time, loc *time.Location, t time.Time) string {
+	verb := "does"
+	if len(walls) > 1 {
+		verb = "do"
+	}
+	before, offset := t.Add(-time.Second).In(loc).Zone()
+	return fmt.Sprintf("%s %s not exist on %s in %s because clocks jump from %s to %s",
+		joinEnglish(walls), verb, day.Format("2006-01-02"), loc,
+		t.In(time.FixedZone(before, offset)).Format("15:04 MST"), t.In(loc).Format("15:04 MST"))
+}
+
+func newCronRun(t, from time.Time, loc *time.Location, dst, note string) CronRun {
+	return CronRun{
+		Time:     t.In(loc),
+		Local:    t.In(loc).Format("Mon 2006-01-02 15:04:05 MST"),
+		Unix:     t.Unix(),
+		Relative: relativeTime(t, from),
+		DST:      dst,
+		Note:     note,
+	}
+}
+
+// intervalRuns lists @every runs, which count elapsed time and so ignore wall clock changes
+func (s *CronService) intervalRuns(every time.Duration, from time.Time, loc *time.Location, count int, result *CronResult) []CronRun {
+	runs := make([]CronRun, 0, count)
+	// robfig/cron drops the sub-second part of the start time before adding the interval
+	next := from.Add(every - time.Duration(from.Nanosecond()))
+	for range count {
+		runs = append(runs, newCronRun(next, from, loc, "", ""))
+		next = next.Add(every)
+	}
+	_, first := runs[0].Time.Zone()
+	for _, run := range runs[1:] {
+		if _, offset := run.Time.Zone(); offset != first {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("An interval counts elapsed time, so its runs shift by an hour against the wall clock in %s when daylight saving changes", loc))
+			break
+		}
+	}
+	return runs
+}
+
+func (s *cronSchedule) fields() []*cronField {
+	fields := []*cronField{s.second, s.minute, s.hour, s.dom, s.month, s.dow}
+	if s.dialect == "standard" {
+		fields = fields[1:]
+	}
+	if s.year != nil {
+		fields = append(fields, s.year)
+	}
+	return fields
+}
+
+func (s *cronSchedule) fieldInfo() []CronField {
+	var info []CronField
+	for _, f := range s.fields() {
+		field := CronField{Name: f.name, Value: f.raw}
+		switch {
+		case f.special != "":
+			field.Description = f.describeSpecial()
+		case f.any:
+			field.Description = "every " + f.unit
+		default:
+			field.Description = f.describeRanges()
+		}
+		if f.special == "" {
+			field.Values = f.compactValues()
+		}
+		info = append(info, field)
+	}
+	return info
+}
+
+// compactValues renders the set as runs, such as 0-4,10 or MON-FRI
+func (f *cronField) compactValues() string {
+	name := strconv.Itoa
+	switch f.name {
+	case "month":
+		name = func(v int) string { return cronMonthNames[v-1] }
+	case "day-of-week":
+		name = func(v int) string { return cronDayNames[v] }
+	}
+	var parts []string
+	values := f.values()
+	for i := 0; i < len(values); {
+		j := i
+		for j+1 < len(values) && values[j+1] == values[j]+1 {
+			j++
+		}
+		switch j - i {
+		case 0:
+			parts = append(parts, name(values[i]))
+		case 1:
+			parts = append(parts, name(values[i]), name(values[j]))
+		default:
+			parts = append(parts, name(values[i])+"-"+name(values[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// describeRanges explains the field's list items, such as "every 15 minutes" or "Monday through Friday"
+func (f *cronField) describeRanges() string {
+	named := f.name == "month" || f.name == "day-of-week"
+	var singles, phrases []string
+	for _, r := range f.ranges {
+		switch {
+		case r.star && r.step == 1:
+			phrases = append(phrases, "every "+f.unit)
+		case r.star:
+			phrases = append(phrases, fmt.Sprintf("every %d %ss", r.step, f.unit))
+		case r.lo == r.hi:
+			singles = append(singles, f.label(r.lo))
+		case r.step > 1:
+			phrases = append(phrases, fmt.Sprintf("every %d %ss from %s through %s", r.step, f.unit, f.label(r.lo), f.label(r.hi)))
+		case named:
+			phrases = append(phrases, fmt.Sprintf("%s through %s", f.label(r.lo), f.label(r.hi)))
+		default:
+			phrases = append(phrases, fmt.Sprintf("%ss %s through %s", f.unit, f.label(r.lo), f.label(r.hi)))
+		}
+	}
+	if len(singles) > 0 {
+		list := joinEnglish(singles)
+		if !named {
+			unit := f.unit
+			if len(singles) > 1 {
+				unit += "s"
+			}
+			list = unit + " " + list
+		}
+		phrases = append([]string{list}, phrases...)
+	}
+	return joinEnglish(phrases)
+}
+
+func (f *cronField) describeSpecial() string {
+	switch f.special {
+	case "L":
+		if f.offset > 0 {
+			return fmt.Sprintf("%d day%s before the last day of the month", f.offset, plural(f.offset))
+		}
+		return "the last day of the month"
+	case "LW":
+		return "the last weekday of the month"
+	case "W":
+		return fmt.Sprintf("the weekday nearest day %d of the month", f.day)
+	case "last":
+		return fmt.Sprintf("the last %s of the month", time.Weekday(f.day))
+	case "nth":
+		return fmt.Sprintf("the %s %s of the month", ordinalWords[f.offset], time.Weekday(f.day))
+	case "set":
+		return time.Weekday(f.day).String()
+	}
+	return ""
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// joinEnglish joins items as "a", "a and b" or "a, b and c"
+func joinEnglish(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// describe explains the whole schedule in one English sentence
+func (s *cronSchedule) describe() string {
+	parts := []string{s.describeTime()}
+
+	var days, domText, dowText string
+	if s.dom.special != "" {
+		domText = "on " + s.dom.describeSpecial()
+	} else if !s.dom.any {
+		domText = withPrefix("on", s.dom.describeRanges()) + " of the month"
+	}
+	if s.dow.special != "" {
+		dowText = "on " + s.dow.describeSpecial()
+	} else if !s.dow.any {
+		dowText = withPrefix("on", s.dow.describeRanges())
+	}
+	switch {
+	case domText != "" && dowText != "" && s.dialect != "quartz" && !s.dom.star && !s.dow.star:
+		days = domText + " or " + dowText
+	case domText != "" && dowText != "":
+		days = domText + ", " + dowText
+	default:
+		days = domText + dowText
+	}
+	parts = append(parts, days)
+
+	if !s.month.any {
+		parts = append(parts, withPrefix("in", s.month.describeRanges()))
+	}
+	if s.year != nil && !s.year.any {
+		parts = append(parts, withPrefix("in", s.year.describeRanges()))
+	}
+
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	sentence := strings.Join(kept, ", ")
+	return strings.ToUpper(sentence[:1]) + sentence[1:]
+}
+
+func (s *cronSchedule) describeTime() string {
+	seconds, mins, hours := s.second.values(), s.minute.values(), s.hour.values()
+
+	// A handful of exact times reads best as a list: "at 09:00 and 17:30"
+	if s.second.singles() && s.minute.singles() && s.hour.singles() && len(seconds)*len(mins)*len(hours) <= 8 {
+		layout := "15:04"
+		for _, sec := range seconds {
+			if sec != 0 {
+				layout = "15:04:05"
+			}
+		}
+		var times []string
+		for _, h := range hours {
+			for _, m := range mins {
+				for _, sec := range seconds {
+					times = append(times, time.Date(2000, 1, 1, h, m, sec, 0, time.UTC).Format(layout))
+				}
+			}
+		}
+		return "at " + joinEnglish(times)
+	}
+
+	var parts []string
+	if !(len(seconds) == 1 && seconds[0] == 0) {
+		parts = append(parts, withPrefix("at", s.second.describeRanges()))
+		if s.minute.any && s.hour.any {
+			return parts[0]
+		}
+	}
+
+	minutes := withPrefix("at", s.minute.describeRanges())
+	parts = append(parts, minutes)
+	switch {
+	case s.hour.any:
+		if !strings.HasPrefix(minutes, "every") {
+			parts = append(parts, "every hour")
+		}
+	case len(s.hour.ranges) == 1 && s.hour.ranges[0].step == 1:
+		parts = append(parts, fmt.Sprintf("between %02d:00 and %02d:59", s.hour.ranges[0].lo, s.hour.ranges[0].hi))
+	default:
+		parts = append(parts, withPrefix("at", s.hour.describeRanges()))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// withPrefix adds a preposition to phrases naming specific values rather than a frequency
+func withPrefix(preposition, phrase string) string {
+	if strings.HasPrefix(phrase, "every") {
+		return phrase
+	}
+	return preposition + " " + phrase
+}
+
+// warnings flags the usual cron surprises: OR-ed day fields, uneven steps and days some months lack
+func (s *cronSchedule) warnings() []string {
+	var warnings []string
+	if s.dialect != "quartz" && !s.dom.star && !s.dow.star {
+		warnings = append(warnings, fmt.Sprintf("Both day-of-month (%s) and day-of-week (%s) are restricted, so cron runs on days matching EITHER field, not both", s.dom.raw, s.dow.raw))
+	}
+	if day, _, _ := strings.Cut(s.dow.raw, "#"); s.dialect == "quartz" && strings.ContainsAny(day, "0123456789") {
+		warnings = append(warnings, "Quartz numbers days of week 1-7 from Sunday, so 2 is Monday; crontab and Spring count 0-7 with 1 as Monday")
+	}
+
+	for _, f := range []*cronField{s.second, s.minute, s.hour, s.month} {
+		for _, r := range f.ranges {
+			if !r.star || r.step == 1 {
+				continue
+			}
+			size := f.max - f.min + 1
+			if size%r.step == 0 {
+				continue
+			}
+			last := f.min + (size-1)/r.step*r.step
+			warnings = append(warnings, fmt.Sprintf("*/%d in the %s field restarts at %d each cycle, so the gap from %d back to %d is %d %s%s, not %d",
+				r.step, f.name, f.min, last, f.min, size-(last-f.min), f.unit, plural(size-(last-f.min)), r.step))
+		}
+	}
+	for _, r := range s.dom.ranges {
+		if r.star && r.step > 1 {
+			warnings = append(warnings, fmt.Sprintf("*/%d in day-of-month restarts on the 1st of every month, so the spacing breaks at each month end", r.step))
+		}
+	}
+
+	if s.dom.special == "" && !s.dom.any && (s.dow.star || s.dialect == "quartz") {
+		var missing []string
+		for m := time.January; m <= time.December; m++ {
+			if !s.month.set[m] {
+				continue
+			}
+			fits := false
+			for _, day := range s.dom.values() {
+				fits = fits || day <= daysIn(2024, m)
+			}
+			if !fits {
+				missing = append(missing, m.String())
+			}
+		}
+		if len(missing) > 0 {
+			warnings = append(warnings, fmt.Sprintf("No runs in %s: day %s does not occur there", joinEnglish(missing), s.dom.compactValues()))
+		}
+	}
+	return warnings
+}
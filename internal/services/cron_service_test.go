@@ -0,0 +1,168 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// cronLocals parses a request and returns the local time of each run
+func cronLocals(t *testing.T, req CronRequest) (*CronResult, []string) {
+	t.Helper()
+	result, err := NewCronService().Parse(req)
+	if err != nil {
+		t.Fatalf("%s: %v", req.Expression, err)
+	}
+	locals := make([]string, len(result.Runs))
+	for i, run := range result.Runs {
+		locals[i] = run.Local
+	}
+	return result, locals
+}
+
+func TestCronDialects(t *testing.T) {
+	tests := []struct {
+		expr    string
+		dialect string
+		runs    []string
+	}{
+		{"30 9 * * 1-5", "standard", []string{"Mon 2024-01-01 09:30:00 UTC", "Tue 2024-01-02 09:30:00 UTC"}},
+		{"15 30 9 * * *", "seconds", []string{"Mon 2024-01-01 09:30:15 UTC", "Tue 2024-01-02 09:30:15 UTC"}},
+		{"0 0 12 ? * MON", "quartz", []string{"Mon 2024-01-01 12:00:00 UTC", "Mon 2024-01-08 12:00:00 UTC"}},
+		{"0 0 12 1 * ? 2025", "quartz", []string{"Wed 2025-01-01 12:00:00 UTC", "Sat 2025-02-01 12:00:00 UTC"}},
+		// crontab runs when either restricted day field matches: the 13th or any Friday
+		{"0 0 13 * 5", "standard", []string{"Fri 2024-01-05 00:00:00 UTC", "Fri 2024-01-12 00:00:00 UTC", "Sat 2024-01-13 00:00:00 UTC"}},
+	}
+	for _, tt := range tests {
+		result, runs := cronLocals(t, CronRequest{Expression: tt.expr, From: "2024-01-01T00:00:00Z", Count: len(tt.runs)})
+		if result.Dialect != tt.dialect {
+			t.Errorf("%s: dialect %s, want %s", tt.expr, result.Dialect, tt.dialect)
+		}
+		if strings.Join(runs, ", ") != strings.Join(tt.runs, ", ") {
+			t.Errorf("%s: runs %q, want %q", tt.expr, runs, tt.runs)
+		}
+	}
+
+	invalid := []CronRequest{
+		{Expression: "0 30 9 * * *", Dialect: "standard"},
+		{Expression: "0 0 12 * * MON", Dialect: "quartz"}, // ? in neither day field
+		{Expression: "* * * *"},
+		{Expression: "61 * * * *"},
+	}
+	for _, req := range invalid {
+		if _, err := NewCronService().Parse(req); err == nil {
+			t.Errorf("%s (%s): expected an error", req.Expression, req.Dialect)
+		}
+	}
+}
+
+func TestCronMacros(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expanded string
+		runs     []string
+	}{
+		{"@daily", "0 0 * * *", []string{"Tue 2024-01-02 00:00:00 UTC", "Wed 2024-01-03 00:00:00 UTC"}},
+		{"@weekly", "0 0 * * 0", []string{"Sun 2024-01-07 00:00:00 UTC"}},
+		{"@yearly", "0 0 1 1 *", []string{"Wed 2025-01-01 00:00:00 UTC"}},
+		{"@every 90m", "", []string{"Mon 2024-01-01 01:30:00 UTC", "Mon 2024-01-01 03:00:00 UTC"}},
+	}
+	for _, tt := range tests {
+		result, runs := cronLocals(t, CronRequest{Expression: tt.expr, From: "2024-01-01T00:00:00Z", Count: len(tt.runs)})
+		if result.Expanded != tt.expanded {
+			t.Errorf("%s: expanded %q, want %q", tt.expr, result.Expanded, tt.expanded)
+		}
+		if strings.Join(runs, ", ") != strings.Join(tt.runs, ", ") {
+			t.Errorf("%s: runs %q, want %q", tt.expr, runs, tt.runs)
+		}
+	}
+
+	for _, expr := range []string{"@reboot", "@daily 5", "@fortnightly", "@every -1m"} {
+		if _, err := NewCronService().Parse(CronRequest{Expression: expr}); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestCronQuartzSpecials(t *testing.T) {
+	tests := []struct {
+		expr string
+		runs []string
+	}{
+		{"0 0 12 L * ?", []string{"Wed 2024-01-31 12:00:00 UTC", "Thu 2024-02-29 12:00:00 UTC"}},
+		{"0 0 12 L-2 * ?", []string{"Mon 2024-01-29 12:00:00 UTC", "Tue 2024-02-27 12:00:00 UTC"}},
+		// 31 March 2024 is a Sunday
+		{"0 0 12 LW * ?", []string{"Wed 2024-01-31 12:00:00 UTC", "Thu 2024-02-29 12:00:00 UTC", "Fri 2024-03-29 12:00:00 UTC"}},
+		// 1 June 2024 is a Saturday and W stays within the month; 1 June 2025 is a Sunday
+		{"0 0 12 1W 6 ?", []string{"Mon 2024-06-03 12:00:00 UTC", "Mon 2025-06-02 12:00:00 UTC"}},
+		{"0 0 12 ? * FRI#3", []string{"Fri 2024-01-19 12:00:00 UTC", "Fri 2024-02-16 12:00:00 UTC"}},
+		// Quartz numbers Friday 6
+		{"0 0 12 ? * 6L", []string{"Fri 2024-01-26 12:00:00 UTC", "Fri 2024-02-23 12:00:00 UTC"}},
+	}
+	for _, tt := range tests {
+		_, runs := cronLocals(t, CronRequest{Expression: tt.expr, From: "2024-01-01T00:00:00Z", Count: len(tt.runs)})
+		if strings.Join(runs, ", ") != strings.Join(tt.runs, ", ") {
+			t.Errorf("%s: runs %q, want %q", tt.expr, runs, tt.runs)
+		}
+	}
+
+	for _, expr := range []string{"0 12 L * *", "0 0 12 ? * FRI#6", "0 0 12 32W * ?"} {
+		if _, err := NewCronService().Parse(CronRequest{Expression: expr}); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestCronDaylightSaving(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		runs []string
+		dst  []string
+	}{
+		{
+			// Every time in the skipped hour resolves to 03:00 EDT and is listed once
+			name: "skipped hour",
+			expr: "*/15 2 * * *",
+			from: "2024-03-09T00:00:00Z",
+			runs: []string{
+				"Sat 2024-03-09 02:00:00 EST", "Sat 2024-03-09 02:15:00 EST", "Sat 2024-03-09 02:30:00 EST", "Sat 2024-03-09 02:45:00 EST",
+				"Sun 2024-03-10 03:00:00 EDT", "Mon 2024-03-11 02:00:00 EDT",
+			},
+			dst: []string{"", "", "", "", "skipped", ""},
+		},
+		{
+			name: "skipped hour and the real 03:00",
+			expr: "*/15 2,3 * * *",
+			from: "2024-03-10T05:00:00Z",
+			runs: []string{"Sun 2024-03-10 03:00:00 EDT", "Sun 2024-03-10 03:15:00 EDT", "Sun 2024-03-10 03:30:00 EDT"},
+			dst:  []string{"skipped", "", ""},
+		},
+		{
+			name: "repeated hour",
+			expr: "30 1 * * *",
+			from: "2024-11-02T12:00:00Z",
+			runs: []string{"Sun 2024-11-03 01:30:00 EDT", "Mon 2024-11-04 01:30:00 EST"},
+			dst:  []string{"repeated", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, runs := cronLocals(t, CronRequest{Expression: tt.expr, Timezone: "America/New_York", From: tt.from, Count: len(tt.runs)})
+			if strings.Join(runs, ", ") != strings.Join(tt.runs, ", ") {
+				t.Errorf("runs %q, want %q", runs, tt.runs)
+			}
+			for i, run := range result.Runs {
+				if i < len(tt.dst) && run.DST != tt.dst[i] {
+					t.Errorf("%s: dst %q, want %q", run.Local, run.DST, tt.dst[i])
+				}
+			}
+		})
+	}
+
+	result, _ := cronLocals(t, CronRequest{Expression: "*/15 2 * * *", Timezone: "America/New_York", From: "2024-03-10T00:00:00Z", Count: 1})
+	want := "02:00:00, 02:15:00, 02:30:00 and 02:45:00 do not exist on 2024-03-10 in America/New_York because clocks jump from 02:00 EST to 03:00 EDT"
+	if note := result.Runs[0].Note; note != want {
+		t.Errorf("note = %q, want %q", note, want)
+	}
+}
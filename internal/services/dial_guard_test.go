@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPublicAddressControl(t *testing.T) {
+	tests := []struct {
+		address string
+		public  bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:443", false},
+		{"10.1.2.3:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"169.254.169.254:80", false},
+		{"100.64.0.1:80", false},
+		{"0.0.0.0:80", false},
+		{"255.255.255.255:80", false},
+		{"[::1]:443", false},
+		{"[fe80::1]:443", false},
+		{"[fd00::1]:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+		{"[::ffff:169.254.169.254]:80", false},
+	}
+
+	for _, tt := range tests {
+		err := publicAddressControl("tcp", tt.address, nil)
+		if tt.public && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.address, err)
+		}
+		if !tt.public && !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("%s: error = %v, want ErrNonPublicAddress", tt.address, err)
+		}
+	}
+}
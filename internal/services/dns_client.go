@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dnsQueryTimeout bounds a single query sent directly to a name server
+const dnsQueryTimeout = 5 * time.Second
+
+// exchangeDNS sends a single query to server over UDP, retrying over TCP when
+// the response is truncated
+func exchangeDNS(ctx context.Context, server, name string, qtype uint16, recursive bool) (*dns.Msg, time.Duration, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = recursive
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+
+	client := &dns.Client{Net: "udp", Timeout: dnsQueryTimeout}
+	resp, rtt, err := client.ExchangeContext(ctx, msg, server)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, rtt, err = client.ExchangeContext(ctx, msg, server)
+	}
+	if err != nil {
+		return nil, rtt, fmt.Errorf("query %s %s @%s: %w", name, dns.TypeToString[qtype], server, err)
+	}
+	return resp, rtt, nil
+}
+
+// normalizeDNSServer adds the default port to a name server address
+func normalizeDNSServer(server string) (string, error) {
+	server = strings.TrimSpace(server)
+	if server == "" {
+		return "", fmt.Errorf("server cannot be empty")
+	}
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server, nil
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), "53"), nil
+}
+
+// parseDNSType converts a record type name such as "MX" or "TYPE65" to its code
+func parseDNSType(recordType string) (uint16, error) {
+	recordType = strings.ToUpper(strings.TrimSpace(recordType))
+	if qtype, ok := dns.StringToType[recordType]; ok {
+		return qtype, nil
+	}
+	if strings.HasPrefix(recordType, "TYPE") {
+		if n, err := strconv.ParseUint(recordType[4:], 10, 16); err == nil {
+			return uint16(n), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported record type: %s", recordType)
+}
+
+// answerRecords converts the answer section of msg into DNSRecords, keeping
+// only records of qtype when it is not ANY
+func answerRecords(msg *dns.Msg, qtype uint16) []DNSRecord {
+	records := []DNSRecord{}
+	for _, rr := range msg.Answer {
+		header := rr.Header()
+		if qtype != dns.TypeANY && header.Rrtype != qtype {
+			continue
+		}
+		records = append(records, rrToDNSRecord(rr))
+	}
+	return records
+}
+
+// rrToDNSRecord converts a resource record to the API record form
+func rrToDNSRecord(rr dns.RR) DNSRecord {
+	header := rr.Header()
+	return DNSRecord{
+		Name:  strings.ToLower(header.Name),
+		Type:  dns.TypeToString[header.Rrtype],
+		Value: formatRRValue(rr),
+		TTL:   int(header.Ttl),
+	}
+}
+
+// formatRRValue renders record data the same way the zone parser normalizes it
+func formatRRValue(rr dns.RR) string {
+	switch v := rr.(type) {
+	case *dns.A:
+		return v.A.String()
+	case *dns.AAAA:
+		return v.AAAA.String()
+	case *dns.NS:
+		return strings.ToLower(v.Ns)
+	case *dns.CNAME:
+		return strings.ToLower(v.Target)
+	case *dns.PTR:
+		return strings.ToLower(v.Ptr)
+	case *dns.DNAME:
+		return strings.ToLower(v.Target)
+	case *dns.MX:
+		return fmt.Sprintf("%d %s", v.Preference, strings.ToLower(v.Mx))
+	case *dns.SRV:
+		return fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, strings.ToLower(v.Target))
+	case *dns.SOA:
+		return fmt.Sprintf("%s %s %d %d %d %d %d", strings.ToLower(v.Ns), strings.ToLower(v.Mbox), v.Serial, v.Refresh, v.Retry, v.Expire, v.Minttl)
+	case *dns.TXT:
+		return joinTXTStrings(v.Txt)
+	case *dns.SPF:
+		return joinTXTStrings(v.Txt)
+	case *dns.CAA:
+		return fmt.Sprintf("%d %s %q", v.Flag, strings.ToLower(v.Tag), v.Value)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
+
+// joinTXTStrings concatenates character strings, decoding the presentation
+// escapes the dns package applies to quotes and non-printable bytes
+func joinTXTStrings(txt []string) string {
+	var value strings.Builder
+	for _, part := range txt {
+		if text, err := unescapeZoneString(part); err == nil {
+			value.WriteString(text)
+		} else {
+			value.WriteString(part)
+		}
+	}
+	return value.String()
+}
+
+// DNS lookup transports supported by LookupDNSWith
+const (
+	DNSTransportSystem  = "system"   // Operating system resolver
+	DNSTransportUDP     = "udp"      // Classic DNS over UDP, retried over TCP when truncated
+	DNSTransportTCP     = "tcp"      // Classic DNS over TCP
+	DNSTransportDoT     = "dot"      // DNS over TLS (RFC 7858)
+	DNSTransportDoH     = "doh"      // DNS over HTTPS wireformat POST (RFC 8484)
+	DNSTransportDoHGet  = "doh-get"  // DNS over HTTPS wireformat GET (RFC 8484)
+	DNSTransportDoHJSON = "doh-json" // DNS over HTTPS JSON API (application/dns-json)
+)
+
+// Default upstreams used when a transport is chosen without a server
+const (
+	defaultDNSServer = "1.1.1.1"
+	defaultDoHURL    = "https://cloudflare-dns.com/dns-query"
+)
+
+// dohMaxResponseSize limits the DoH response body read from an upstream
+const dohMaxResponseSize = 64 * 1024
+
+// DNSLookupOptions selects the transport and upstream server for a lookup
+type DNSLookupOptions struct {
+	Transport string `json:"transport,omitempty"`
+	Server    string `json:"server,omitempty"` // host[:port] for udp/tcp/dot, URL for DoH
+}
+
+// normalizeTransportServer validates the transport and fills in its default server
+func normalizeTransportServer(transport, server string) (string, error) {
+	server = strings.TrimSpace(server)
+	switch transport {
+	case DNSTransportUDP, DNSTransportTCP, DNSTransportDoT:
+		if server == "" {
+			server = defaultDNSServer
+		}
+		if _, _, err := net.SplitHostPort(server); err == nil {
+			return server, nil
+		}
+		port := "53"
+		if transport == DNSTransportDoT {
+			port = "853"
+		}
+		return net.JoinHostPort(strings.Trim(server, "[]"), port), nil
+	case DNSTransportDoH, DNSTransportDoHGet, DNSTransportDoHJSON:
+		if server == "" {
+			return defaultDoHURL, nil
+		}
+		if !strings.Contains(server, "://") {
+			server = "https://" + server
+		}
+		u, err := url.Parse(server)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+			return "", fmt.Errorf("invalid DoH URL: %s", server)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/dns-query"
+		}
+		return u.String(), nil
+	}
+	return "", fmt.Errorf("unsupported transport: %s", transport)
+}
+
+// newDoHClient creates the HTTP client for caller-supplied DoH endpoints, which
+// only connects to public addresses unless allowPrivate is set
+func newDoHClient(config *tls.Config, allowPrivate bool) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = newProbeDialer(dnsQueryTimeout, allowPrivate).DialContext
+	if config != nil {
+		transport.TLSClientConfig = config.Clone()
+	}
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// exchangeTransport sends msg to server over the given transport. Servers
+// supplied by callers are only reached on public addresses; trusted is set for
+// operator-configured upstreams, which may be private resolvers.
+func (s *IPAnalysisService) exchangeTransport(ctx context.Context, transport, server string, msg *dns.Msg, trusted bool) (*dns.Msg, time.Duration, error) {
+	dialer := newProbeDialer(dnsQueryTimeout, trusted || s.allowPrivate)
+	httpClient := s.dohClient
+	if trusted {
+		httpClient = s.httpClient
+	}
+
+	switch transport {
+	case DNSTransportUDP, DNSTransportTCP:
+		client := &dns.Client{Net: transport, Timeout: dnsQueryTimeout, Dialer: dialer}
+		resp, rtt, err := client.ExchangeContext(ctx, msg, server)
+		if err == nil && resp.Truncated && transport == DNSTransportUDP {
+			client.Net = "tcp"
+			resp, rtt, err = client.ExchangeContext(ctx, msg, server)
+		}
+		return resp, rtt, err
+	case DNSTransportDoT:
+		host, _, _ := net.SplitHostPort(server)
+		config := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+		if s.tlsConfig != nil {
+			config = s.tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+		}
+		client := &dns.Client{Net: "tcp-tls", Timeout: dnsQueryTimeout, TLSConfig: config, Dialer: dialer}
+		return client.ExchangeContext(ctx, msg, server)
+	case DNSTransportDoH, DNSTransportDoHGet:
+		return exchangeDoH(ctx, httpClient, server, msg, transport == DNSTransportDoHGet)
+	case DNSTransportDoHJSON:
+		return exchangeDoHJSON(ctx, httpClient, server, msg)
+	}
+	return nil, 0, fmt.Errorf("unsupported transport: %s", transport)
+}
+
+// exchangeDoH sends a wireformat query as an RFC 8484 POST or GET request
+func exchangeDoH(ctx context.Context, client *http.Client, endpoint string, msg *dns.Msg, useGet bool) (*dns.Msg, time.Duration, error) {
+	// RFC 8484 section 4.1 recommends ID 0 so responses are cache friendly
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to pack query: %w", err)
+	}
+
+	var req *http.Request
+	if useGet {
+		u, _ := url.Parse(endpoint)
+		values := u.Query()
+		values.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		u.RawQuery = values.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/dns-message")
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dohMaxResponseSize))
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, rtt, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, rtt, fmt.Errorf("DoH server returned HTTP %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/dns-message") {
+		return nil, rtt, fmt.Errorf("DoH server returned unexpected content type %q", contentType)
+	}
+
+	answer := new(dns.Msg)
+	if err := answer.Unpack(body); err != nil {
+		return nil, rtt, fmt.Errorf("invalid DoH response: %w", err)
+	}
+	answer.Id = msg.Id
+	return answer, rtt, nil
+}
+
+// dohJSONResponse is the application/dns-json response format
+type dohJSONResponse struct {
+	Status int  `json:"Status"`
+	TC     bool `json:"TC"`
+	AD     bool `json:"AD"`
+	Answer []struct {
+		Name string `json:"name"`
+		Type uint16 `json:"type"`
+		TTL  uint32 `json:"TTL"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// exchangeDoHJSON queries a DoH JSON API and converts the answer into a dns.Msg
+func exchangeDoHJSON(ctx context.Context, client *http.Client, endpoint string, msg *dns.Msg) (*dns.Msg, time.Duration, error) {
+	question := msg.Question[0]
+	u, _ := url.Parse(endpoint)
+	values := u.Query()
+	values.Set("name", question.Name)
+	values.Set("type", dns.TypeToString[question.Qtype])
+	u.RawQuery = values.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/dns-json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var parsed dohJSONResponse
+	err = json.NewDecoder(io.LimitReader(resp.Body, dohMaxResponseSize)).Decode(&parsed)
+	rtt := time.Since(start)
+	if resp.StatusCode != http.StatusOK {
+		return nil, rtt, fmt.Errorf("DoH server returned HTTP %d", resp.StatusCode)
+	}
+	if err != nil {
+		return nil, rtt, fmt.Errorf("invalid DoH JSON response: %w", err)
+	}
+
+	answer := new(dns.Msg)
+	answer.SetReply(msg)
+	answer.Rcode = parsed.Status
+	answer.Truncated = parsed.TC
+	answer.AuthenticatedData = parsed.AD
+	for _, rec := range parsed.Answer {
+		rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(rec.Name), rec.TTL, dns.TypeToString[rec.Type], rec.Data))
+		if err != nil || rr == nil {
+			continue
+		}
+		answer.Answer = append(answer.Answer, rr)
+	}
+	return answer, rtt, nil
+}
+
+// systemNameserver returns the first name server from /etc/resolv.conf, falling
+// back to the default public resolver
+func systemNameserver() string {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil || len(config.Servers) == 0 {
+		return net.JoinHostPort(defaultDNSServer, "53")
+	}
+	return net.JoinHostPort(config.Servers[0], config.Port)
+}
+
+// ForwardDNS relays a client query to the upstream selected in opts and returns
+// the upstream response with the client's message ID. An empty or "system"
+// transport forwards over UDP to the name server in /etc/resolv.conf.
+func (s *IPAnalysisService) ForwardDNS(ctx context.Context, query *dns.Msg, opts DNSLookupOptions) (*dns.Msg, time.Duration, error) {
+	if len(query.Question) != 1 {
+		return nil, 0, fmt.Errorf("query must contain exactly one question")
+	}
+
+	transport := strings.ToLower(strings.TrimSpace(opts.Transport))
+	server := opts.Server
+	if transport == "" || transport == DNSTransportSystem {
+		transport = DNSTransportUDP
+		if server == "" {
+			server = systemNameserver()
+		}
+	}
+	server, err := normalizeTransportServer(transport, server)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Use a fresh random ID upstream so ID 0 from DoH clients is never sent over UDP
+	upstream := query.Copy()
+	upstream.Id = dns.Id()
+
+	resp, rtt, err := s.exchangeTransport(ctx, transport, server, upstream, true)
+	if err != nil {
+		return nil, rtt, fmt.Errorf("%s query to %s failed: %w", transport, server, err)
+	}
+	resp.Id = query.Id
+	return resp, rtt, nil
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// testDNSHandler answers for example.test and returns NXDOMAIN for other names
+func testDNSHandler(w dns.ResponseWriter, req *dns.Msg) {
+	w.WriteMsg(testDNSAnswer(req))
+}
+
+// testDNSAnswer builds the response testDNSHandler sends for a query
+func testDNSAnswer(req *dns.Msg) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	question := req.Question[0]
+	if !strings.EqualFold(question.Name, "example.test.") {
+		resp.Rcode = dns.RcodeNameError
+		return resp
+	}
+	switch question.Qtype {
+	case dns.TypeA:
+		rr, _ := dns.NewRR("example.test. 300 IN A 192.0.2.1")
+		resp.Answer = append(resp.Answer, rr)
+	case dns.TypeTXT:
+		rr, _ := dns.NewRR(`example.test. 300 IN TXT "hello world"`)
+		resp.Answer = append(resp.Answer, rr)
+	}
+	return resp
+}
+
+// startTestDoHServer serves wireformat and JSON DoH on a local TLS server
+func startTestDoHServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var packed []byte
+		var err error
+		switch {
+		case r.URL.Query().Get("name") != "":
+			query := new(dns.Msg)
+			query.SetQuestion(dns.Fqdn(r.URL.Query().Get("name")), dns.StringToType[r.URL.Query().Get("type")])
+			resp := testDNSAnswer(query)
+			var answers []map[string]interface{}
+			for _, rr := range resp.Answer {
+				answers = append(answers, map[string]interface{}{
+					"name": rr.Header().Name,
+					"type": rr.Header().Rrtype,
+					"TTL":  rr.Header().Ttl,
+					"data": strings.TrimPrefix(rr.String(), rr.Header().String()),
+				})
+			}
+			w.Header().Set("Content-Type", "application/dns-json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"Status": resp.Rcode, "Answer": answers})
+			return
+		case r.Method == http.MethodGet:
+			packed, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		default:
+			packed, err = io.ReadAll(r.Body)
+		}
+		query := new(dns.Msg)
+		if err != nil || query.Unpack(packed) != nil {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		out, _ := testDNSAnswer(query).Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// startTestDoTServer serves DNS over TLS on a local port with the given certificate
+func startTestDoTServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := &dns.Server{Listener: listener, Net: "tcp-tls", Handler: dns.HandlerFunc(testDNSHandler), NotifyStartedFunc: func() { close(started) }}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	return listener.Addr().String()
+}
+
+// testTLSService returns an IP analysis service trusting the test server's certificate
+func testTLSService(server *httptest.Server) *IPAnalysisService {
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return NewIPAnalysisServiceWithTLS(net.DefaultResolver, &tls.Config{RootCAs: roots}, true)
+}
+
+func TestLookupDNSOverTLSAndHTTPS(t *testing.T) {
+	doh := startTestDoHServer(t)
+	dot := startTestDoTServer(t, doh.TLS.Certificates[0])
+	service := testTLSService(doh)
+
+	servers := map[string]string{
+		DNSTransportDoT:     dot,
+		DNSTransportDoH:     doh.URL + "/dns-query",
+		DNSTransportDoHGet:  doh.URL + "/dns-query",
+		DNSTransportDoHJSON: doh.URL + "/resolve",
+	}
+	for transport, server := range servers {
+		t.Run(transport, func(t *testing.T) {
+			opts := DNSLookupOptions{Transport: transport, Server: server}
+			result, err := service.LookupDNSWith(context.Background(), "example.test", "A", opts)
+			if err != nil {
+				t.Fatalf("A lookup: %v", err)
+			}
+			if result.Transport != transport || len(result.Records) != 1 || result.Records[0].Value != "192.0.2.1" {
+				t.Errorf("A result = %+v", result)
+			}
+
+			result, err = service.LookupDNSWith(context.Background(), "example.test", "TXT", opts)
+			if err != nil {
+				t.Fatalf("TXT lookup: %v", err)
+			}
+			if len(result.Records) != 1 || result.Records[0].Value != "hello world" {
+				t.Errorf("TXT result = %+v", result.Records)
+			}
+
+			_, err = service.LookupDNSWith(context.Background(), "missing.test", "A", opts)
+			var dnsErr *net.DNSError
+			if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+				t.Errorf("missing name error = %v, want not found", err)
+			}
+		})
+	}
+}
+
+func TestLookupDNSRefusesNonPublicServers(t *testing.T) {
+	doh := startTestDoHServer(t)
+	dot := startTestDoTServer(t, doh.TLS.Certificates[0])
+
+	service := NewIPAnalysisService()
+	servers := map[string]string{
+		DNSTransportUDP:     "127.0.0.1:53",
+		DNSTransportTCP:     "10.0.0.1:53",
+		DNSTransportDoT:     dot,
+		DNSTransportDoH:     doh.URL,
+		DNSTransportDoHJSON: "https://169.254.169.254/resolve",
+	}
+	for transport, server := range servers {
+		_, err := service.LookupDNSWith(context.Background(), "example.test", "A", DNSLookupOptions{Transport: transport, Server: server})
+		if !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("%s %s: error = %v, want ErrNonPublicAddress", transport, server, err)
+		}
+	}
+}
+
+func TestLookupDNSOverTLSUntrusted(t *testing.T) {
+	doh := startTestDoHServer(t)
+	dot := startTestDoTServer(t, doh.TLS.Certificates[0])
+
+	// A nil configuration uses the system roots, which do not include the test CA
+	service := NewIPAnalysisServiceWithTLS(net.DefaultResolver, nil, true)
+	for transport, server := range map[string]string{DNSTransportDoT: dot, DNSTransportDoH: doh.URL} {
+		_, err := service.LookupDNSWith(context.Background(), "example.test", "A", DNSLookupOptions{Transport: transport, Server: server})
+		var verifyErr *tls.CertificateVerificationError
+		if !errors.As(err, &verifyErr) {
+			t.Errorf("%s: error = %v, want a certificate error", transport, err)
+		}
+	}
+}
+
+func TestLookupDNSAllOverTLS(t *testing.T) {
+	doh := startTestDoHServer(t)
+	service := testTLSService(doh)
+
+	result, err := service.LookupDNSWith(context.Background(), "example.test", "ALL", DNSLookupOptions{Transport: DNSTransportDoH, Server: doh.URL})
+	if err != nil {
+		t.Fatalf("ALL lookup: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, status := range result.Statuses {
+		statuses[status.Type] = status.Status
+	}
+	if statuses["A"] != "ok" || statuses["TXT"] != "ok" || statuses["MX"] != "NODATA" {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
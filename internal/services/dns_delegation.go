@@ -0,0 +1,592 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// delegationMaxDepth bounds the number of referrals followed from the root
+const delegationMaxDepth = 16
+
+// rootServerAddrs are the IPv4 addresses of the root name servers (a-m.root-servers.net)
+var rootServerAddrs = []string{
+	"198.41.0.4", "170.247.170.2", "192.33.4.12", "199.7.91.13", "192.203.230.10",
+	"192.5.5.241", "192.112.36.4", "198.97.190.53", "192.36.148.17", "192.58.128.30",
+	"193.0.14.129", "199.7.83.42", "202.12.27.33",
+}
+
+// delegationCheckTypes are queried at every authoritative server to compare answers
+var delegationCheckTypes = []uint16{dns.TypeSOA, dns.TypeNS, dns.TypeA, dns.TypeAAAA, dns.TypeMX}
+
+// DelegationService checks that a domain's delegation and authoritative servers agree
+type DelegationService struct {
+	dns         *IPAnalysisService
+	rootServers []string
+}
+
+// NewDelegationService creates a new delegation check service
+func NewDelegationService(dns *IPAnalysisService) *DelegationService {
+	roots := make([]string, len(rootServerAddrs))
+	for i, addr := range rootServerAddrs {
+		roots[i] = net.JoinHostPort(addr, "53")
+	}
+	return &DelegationService{
+		dns:         dns,
+		rootServers: roots,
+	}
+}
+
+// DelegationStep represents one referral followed while walking down from the root
+type DelegationStep struct {
+	Zone     string   `json:"zone"`
+	Server   string   `json:"server"`
+	Referral string   `json:"referral,omitempty"`
+	NS       []string `json:"ns,omitempty"`
+	RTT      float64  `json:"rtt_ms"`
+}
+
+// DelegationServer represents the responses of one authoritative server address
+type DelegationServer struct {
+	Name          string              `json:"name"`
+	Address       string              `json:"address"`
+	FromGlue      bool                `json:"from_glue"`
+	Reachable     bool                `json:"reachable"`
+	Authoritative bool                `json:"authoritative"`
+	Lame          bool                `json:"lame"`
+	Serial        uint32              `json:"serial,omitempty"`
+	Answers       map[string][]string `json:"answers,omitempty"`
+	RTT           float64             `json:"rtt_ms"`
+	Error         string              `json:"error,omitempty"`
+}
+
+// DelegationFinding represents a problem found in the delegation
+type DelegationFinding struct {
+	Severity string `json:"severity"` // "error", "warning", "info"
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+// DelegationResult represents the delegation health of a domain
+type DelegationResult struct {
+	Domain     string              `json:"domain"`
+	ParentZone string              `json:"parent_zone"`
+	ParentNS   []string            `json:"parent_ns"`
+	Glue       map[string][]string `json:"glue"`
+	ChildNS    []string            `json:"child_ns"`
+	Servers    []DelegationServer  `json:"servers"`
+	Recursive  map[string][]string `json:"recursive,omitempty"`
+	Trace      []DelegationStep    `json:"trace"`
+	Findings   []DelegationFinding `json:"findings"`
+	Healthy    bool                `json:"healthy"`
+	Timestamp  time.Time           `json:"timestamp"`
+	QueryTime  int                 `json:"query_time_ms"`
+}
+
+// CheckDelegation walks the delegation of domain from the root servers and
+// queries each authoritative server directly
+func (s *DelegationService) CheckDelegation(ctx context.Context, domain string) (*DelegationResult, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if domain == "" || domain == "." {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+	if _, ok := dns.IsDomainName(domain); !ok {
+		return nil, fmt.Errorf("invalid domain name: %s", domain)
+	}
+	domain = dns.Fqdn(domain)
+
+	start := time.Now()
+	result := &DelegationResult{
+		Domain:    domain,
+		Glue:      make(map[string][]string),
+		ChildNS:   []string{},
+		Servers:   []DelegationServer{},
+		Findings:  []DelegationFinding{},
+		Timestamp: start,
+	}
+
+	if err := s.walkDelegation(ctx, domain, result); err != nil {
+		return nil, err
+	}
+
+	addresses := s.serverAddresses(ctx, domain, result)
+	result.Servers = s.queryServers(ctx, domain, addresses)
+	s.compareServers(ctx, domain, result)
+	s.compareRecursive(ctx, domain, result)
+
+	result.Healthy = true
+	for _, finding := range result.Findings {
+		if finding.Severity == "error" {
+			result.Healthy = false
+			break
+		}
+	}
+	result.QueryTime = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
+// walkDelegation follows referrals from the root until a server hands out the
+// delegation for domain, recording the parent NS set and glue
+func (s *DelegationService) walkDelegation(ctx context.Context, domain string, result *DelegationResult) error {
+	servers := s.rootServers
+	zone := "."
+
+	for depth := 0; depth < delegationMaxDepth; depth++ {
+		resp, server, rtt, err := s.queryFirst(ctx, servers, domain, dns.TypeNS)
+		if err != nil {
+			return fmt.Errorf("no %s server answered: %w", zone, err)
+		}
+		step := DelegationStep{Zone: zone, Server: server, RTT: durationMs(rtt)}
+
+		if resp.Rcode == dns.RcodeNameError {
+			result.Trace = append(result.Trace, step)
+			return fmt.Errorf("%s does not exist (NXDOMAIN from the %s zone)", domain, zone)
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			result.Trace = append(result.Trace, step)
+			return fmt.Errorf("%s server %s answered %s", zone, server, dns.RcodeToString[resp.Rcode])
+		}
+
+		// Authoritative answer: the current servers also serve domain itself
+		if resp.Authoritative {
+			result.Trace = append(result.Trace, step)
+			var ns []string
+			for _, rr := range resp.Answer {
+				if rec, ok := rr.(*dns.NS); ok && strings.EqualFold(rec.Header().Name, domain) {
+					ns = append(ns, strings.ToLower(rec.Ns))
+				}
+			}
+			if len(ns) == 0 {
+				return fmt.Errorf("%s is not a delegated zone; it is served as part of the %s zone", domain, zone)
+			}
+			result.ParentZone = zone
+			result.ParentNS = sortedUnique(ns)
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "info",
+				Check:    "shared-servers",
+				Message:  fmt.Sprintf("the %s servers answer authoritatively for %s, so the parent delegation could not be observed separately", zone, domain),
+			})
+			return nil
+		}
+
+		// Referral: NS records in the authority section for a zone closer to domain
+		referral := ""
+		var ns []string
+		for _, rr := range resp.Ns {
+			if rec, ok := rr.(*dns.NS); ok {
+				referral = strings.ToLower(rec.Header().Name)
+				ns = append(ns, strings.ToLower(rec.Ns))
+			}
+		}
+		if referral == "" {
+			result.Trace = append(result.Trace, step)
+			return fmt.Errorf("%s server %s returned neither an answer nor a referral for %s", zone, server, domain)
+		}
+		if !dns.IsSubDomain(referral, domain) || dns.CountLabel(referral) <= dns.CountLabel(zone) {
+			result.Trace = append(result.Trace, step)
+			return fmt.Errorf("%s server %s returned an upward or unrelated referral to %s", zone, server, referral)
+		}
+		step.Referral = referral
+		step.NS = sortedUnique(ns)
+		result.Trace = append(result.Trace, step)
+
+		glue := make(map[string][]string)
+		for _, rr := range resp.Extra {
+			name := strings.ToLower(rr.Header().Name)
+			switch rec := rr.(type) {
+			case *dns.A:
+				glue[name] = append(glue[name], rec.A.String())
+			case *dns.AAAA:
+				glue[name] = append(glue[name], rec.AAAA.String())
+			}
+		}
+
+		if referral == domain {
+			result.ParentZone = zone
+			result.ParentNS = step.NS
+			for _, name := range step.NS {
+				if addrs, ok := glue[name]; ok {
+					result.Glue[name] = sortedUnique(addrs)
+				}
+			}
+			return nil
+		}
+
+		// Descend into the referred zone
+		zone = referral
+		servers = nil
+		for _, name := range step.NS {
+			for _, addr := range glue[name] {
+				servers = append(servers, net.JoinHostPort(addr, "53"))
+			}
+		}
+		if len(servers) == 0 {
+			for _, name := range step.NS {
+				for _, addr := range s.resolveHost(ctx, name) {
+					servers = append(servers, net.JoinHostPort(addr, "53"))
+				}
+				if len(servers) > 0 {
+					break
+				}
+			}
+		}
+		if len(servers) == 0 {
+			return fmt.Errorf("could not find an address for any %s name server", zone)
+		}
+	}
+
+	return fmt.Errorf("delegation for %s exceeds %d referrals", domain, delegationMaxDepth)
+}
+
+// serverAddresses resolves every parent NS name using glue where available,
+// reporting missing glue and unresolvable names
+func (s *DelegationService) serverAddresses(ctx context.Context, domain string, result *DelegationResult) []DelegationServer {
+	var servers []DelegationServer
+
+	if len(result.ParentNS) < 2 {
+		result.Findings = append(result.Findings, DelegationFinding{
+			Severity: "warning",
+			Check:    "single-ns",
+			Message:  fmt.Sprintf("only %d name server is delegated; RFC 2182 recommends at least two", len(result.ParentNS)),
+		})
+	}
+
+	for _, name := range result.ParentNS {
+		glue, hasGlue := result.Glue[name]
+		if dns.IsSubDomain(domain, name) && !hasGlue {
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "error",
+				Check:    "missing-glue",
+				Message:  fmt.Sprintf("%s is inside %s but the %s zone provides no glue for it", name, domain, result.ParentZone),
+			})
+		}
+
+		addrs := glue
+		if !hasGlue {
+			addrs = s.resolveHost(ctx, name)
+		}
+		if len(addrs) == 0 {
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "error",
+				Check:    "unresolvable-ns",
+				Message:  fmt.Sprintf("name server %s has no address records", name),
+			})
+			continue
+		}
+		for _, addr := range addrs {
+			servers = append(servers, DelegationServer{Name: name, Address: addr, FromGlue: hasGlue})
+		}
+	}
+
+	return servers
+}
+
+// queryServers sends the check queries to every authoritative address concurrently
+func (s *DelegationService) queryServers(ctx context.Context, domain string, servers []DelegationServer) []DelegationServer {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10) // Limit concurrent servers
+
+	for i := range servers {
+		wg.Add(1)
+		go func(server *DelegationServer) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			s.queryServer(ctx, domain, server)
+		}(&servers[i])
+	}
+	wg.Wait()
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		if servers[i].Name != servers[j].Name {
+			return servers[i].Name < servers[j].Name
+		}
+		return servers[i].Address < servers[j].Address
+	})
+	if servers == nil {
+		servers = []DelegationServer{}
+	}
+	return servers
+}
+
+// queryServer asks one server for the apex records without recursion
+func (s *DelegationService) queryServer(ctx context.Context, domain string, server *DelegationServer) {
+	addr := net.JoinHostPort(server.Address, "53")
+	server.Answers = make(map[string][]string)
+
+	for _, qtype := range delegationCheckTypes {
+		resp, rtt, err := exchangeDNS(ctx, addr, domain, qtype, false)
+		typeName := dns.TypeToString[qtype]
+		if err != nil {
+			if qtype == dns.TypeSOA {
+				server.Lame = true
+				server.Error = err.Error()
+				return
+			}
+			continue
+		}
+		server.Reachable = true
+
+		if qtype == dns.TypeSOA {
+			server.RTT = durationMs(rtt)
+			server.Authoritative = resp.Authoritative
+			if resp.Rcode != dns.RcodeSuccess {
+				server.Lame = true
+				server.Error = fmt.Sprintf("answered %s", dns.RcodeToString[resp.Rcode])
+				return
+			}
+			if !resp.Authoritative {
+				server.Lame = true
+				server.Error = "answer is not authoritative"
+				return
+			}
+			for _, rr := range resp.Answer {
+				if soa, ok := rr.(*dns.SOA); ok {
+					server.Serial = soa.Serial
+				}
+			}
+		}
+
+		// Serials are compared separately from the other answers
+		if qtype == dns.TypeSOA {
+			continue
+		}
+		var values []string
+		for _, rec := range answerRecords(resp, qtype) {
+			values = append(values, rec.Value)
+		}
+		server.Answers[typeName] = sortedUnique(values)
+	}
+}
+
+// compareServers reports lame servers, NS set differences with the parent,
+// serial mismatches and inconsistent answers
+func (s *DelegationService) compareServers(ctx context.Context, domain string, result *DelegationResult) {
+	var healthy []DelegationServer
+	for _, server := range result.Servers {
+		switch {
+		case !server.Reachable:
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "error",
+				Check:    "unreachable",
+				Message:  fmt.Sprintf("%s (%s) did not respond: %s", server.Name, server.Address, server.Error),
+			})
+		case server.Lame:
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "error",
+				Check:    "lame-delegation",
+				Message:  fmt.Sprintf("%s (%s) is delegated but is not authoritative for %s: %s", server.Name, server.Address, domain, server.Error),
+			})
+		default:
+			healthy = append(healthy, server)
+		}
+	}
+	if len(healthy) == 0 {
+		result.Findings = append(result.Findings, DelegationFinding{
+			Severity: "error",
+			Check:    "no-authoritative",
+			Message:  "no delegated name server answered authoritatively",
+		})
+		return
+	}
+
+	// The child NS set is the union of what the authoritative servers report
+	var childNS []string
+	for _, server := range healthy {
+		childNS = append(childNS, server.Answers["NS"]...)
+	}
+	result.ChildNS = sortedUnique(childNS)
+
+	parentOnly := setDifference(result.ParentNS, result.ChildNS)
+	childOnly := setDifference(result.ChildNS, result.ParentNS)
+	if len(parentOnly) > 0 {
+		result.Findings = append(result.Findings, DelegationFinding{
+			Severity: "error",
+			Check:    "ns-mismatch",
+			Message:  fmt.Sprintf("delegated by %s but missing from the zone's NS records: %s", result.ParentZone, strings.Join(parentOnly, ", ")),
+		})
+	}
+	if len(childOnly) > 0 {
+		result.Findings = append(result.Findings, DelegationFinding{
+			Severity: "warning",
+			Check:    "ns-mismatch",
+			Message:  fmt.Sprintf("listed in the zone's NS records but not delegated by %s: %s", result.ParentZone, strings.Join(childOnly, ", ")),
+		})
+	}
+
+	// SOA serials must agree once zone transfers have completed
+	serials := make(map[uint32][]string)
+	for _, server := range healthy {
+		serials[server.Serial] = append(serials[server.Serial], server.Name+" ("+server.Address+")")
+	}
+	if len(serials) > 1 {
+		var parts []string
+		for serial, names := range serials {
+			parts = append(parts, fmt.Sprintf("%d on %s", serial, strings.Join(names, ", ")))
+		}
+		sort.Strings(parts)
+		result.Findings = append(result.Findings, DelegationFinding{
+			Severity: "error",
+			Check:    "serial-mismatch",
+			Message:  "SOA serials differ: " + strings.Join(parts, "; "),
+		})
+	}
+
+	// Every other answer should be identical across servers
+	for _, qtype := range delegationCheckTypes[1:] {
+		typeName := dns.TypeToString[qtype]
+		answers := make(map[string][]string)
+		for _, server := range healthy {
+			key := strings.Join(server.Answers[typeName], ", ")
+			answers[key] = append(answers[key], server.Address)
+		}
+		if len(answers) > 1 {
+			var parts []string
+			for answer, addrs := range answers {
+				if answer == "" {
+					answer = "no records"
+				}
+				parts = append(parts, fmt.Sprintf("[%s] from %s", answer, strings.Join(addrs, ", ")))
+			}
+			sort.Strings(parts)
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "error",
+				Check:    "inconsistent-answers",
+				Message:  fmt.Sprintf("%s answers differ between servers: %s", typeName, strings.Join(parts, "; ")),
+			})
+		}
+	}
+
+	// Glue must match the address records published in the child zone
+	for _, name := range result.ParentNS {
+		glue, ok := result.Glue[name]
+		if !ok || !dns.IsSubDomain(domain, name) {
+			continue
+		}
+		var published []string
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			resp, _, err := exchangeDNS(ctx, net.JoinHostPort(healthy[0].Address, "53"), name, qtype, false)
+			if err != nil {
+				continue
+			}
+			for _, rec := range answerRecords(resp, qtype) {
+				published = append(published, rec.Value)
+			}
+		}
+		published = sortedUnique(published)
+		if strings.Join(published, ",") != strings.Join(glue, ",") {
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "warning",
+				Check:    "glue-mismatch",
+				Message:  fmt.Sprintf("glue for %s (%s) differs from the zone's address records (%s)", name, strings.Join(glue, ", "), strings.Join(published, ", ")),
+			})
+		}
+	}
+}
+
+// compareRecursive compares the system resolver's view with the authoritative answers
+func (s *DelegationService) compareRecursive(ctx context.Context, domain string, result *DelegationResult) {
+	var reference *DelegationServer
+	for i := range result.Servers {
+		if !result.Servers[i].Lame && result.Servers[i].Reachable {
+			reference = &result.Servers[i]
+			break
+		}
+	}
+	if reference == nil {
+		return
+	}
+
+	result.Recursive = make(map[string][]string)
+	for _, recordType := range []string{"NS", "A", "AAAA", "MX"} {
+		var values []string
+		lookup, err := s.dns.LookupDNS(ctx, strings.TrimSuffix(domain, "."), recordType)
+		if err != nil && !isNotFound(err) {
+			continue
+		}
+		if lookup != nil {
+			for _, rec := range lookup.Records {
+				values = append(values, normalizeDriftValue(recordType, rec.Value))
+			}
+		}
+		values = sortedUnique(values)
+		result.Recursive[recordType] = values
+
+		authoritative := reference.Answers[recordType]
+		if strings.Join(values, ",") != strings.Join(authoritative, ",") {
+			result.Findings = append(result.Findings, DelegationFinding{
+				Severity: "info",
+				Check:    "recursive-mismatch",
+				Message:  fmt.Sprintf("recursive %s answer (%s) differs from the authoritative answer (%s); cached data may not have expired yet", recordType, strings.Join(values, ", "), strings.Join(authoritative, ", ")),
+			})
+		}
+	}
+}
+
+// queryFirst tries servers in order until one responds
+func (s *DelegationService) queryFirst(ctx context.Context, servers []string, name string, qtype uint16) (*dns.Msg, string, time.Duration, error) {
+	var lastErr error
+	for i, server := range servers {
+		if i >= 3 {
+			break
+		}
+		resp, rtt, err := exchangeDNS(ctx, server, name, qtype, false)
+		if err == nil {
+			return resp, server, rtt, nil
+		}
+		lastErr = err
+	}
+	return nil, "", 0, lastErr
+}
+
+// resolveHost returns the addresses of a name server host via the system resolver
+func (s *DelegationService) resolveHost(ctx context.Context, host string) []string {
+	lookupCtx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	ips, err := s.dns.resolver.LookupIP(lookupCtx, "ip", strings.TrimSuffix(host, "."))
+	if err != nil {
+		return nil
+	}
+	var addrs []string
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+	return sortedUnique(addrs)
+}
+
+// sortedUnique returns the sorted distinct values
+func sortedUnique(values []string) []string {
+	out := []string{}
+	for _, value := range values {
+		out = appendUnique(out, value)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// setDifference returns the values in a that are not in b
+func setDifference(a, b []string) []string {
+	var out []string
+	for _, value := range a {
+		found := false
+		for _, other := range b {
+			if value == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, value)
+		}
+	}
+	return out
+}
@@ -0,0 +1,404 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dnsDriftMaxRRsets limits how many name/type pairs a single drift check queries
+const dnsDriftMaxRRsets = 500
+
+// DNSDriftService compares live DNS answers with an expected record set
+type DNSDriftService struct {
+	dns   *IPAnalysisService
+	zones *ZoneService
+}
+
+// NewDNSDriftService creates a new drift detection service
+func NewDNSDriftService(dns *IPAnalysisService) *DNSDriftService {
+	return &DNSDriftService{
+		dns:   dns,
+		zones: NewZoneService(dns),
+	}
+}
+
+// DNSDriftRequest represents the expected records for a domain, given as JSON
+// records, zone file text, or both
+type DNSDriftRequest struct {
+	Domain    string      `json:"domain"`
+	Records   []DNSRecord `json:"records,omitempty"`
+	Zone      string      `json:"zone,omitempty"`
+	Server    string      `json:"server,omitempty"` // Authoritative server to query instead of the system resolver
+	IgnoreTTL bool        `json:"ignore_ttl,omitempty"`
+}
+
+// DNSRecordChange represents an expected record whose live value or TTL differs
+type DNSRecordChange struct {
+	Name         string    `json:"name"`
+	Type         string    `json:"type"`
+	Expected     DNSRecord `json:"expected"`
+	Actual       DNSRecord `json:"actual"`
+	ValueChanged bool      `json:"value_changed"`
+	TTLDelta     int       `json:"ttl_delta,omitempty"` // Actual TTL minus expected TTL
+}
+
+// DNSDriftResult represents the difference between expected and live records
+type DNSDriftResult struct {
+	Domain     string            `json:"domain"`
+	Server     string            `json:"server,omitempty"`
+	Source     string            `json:"source"` // "resolver" or "authoritative"
+	InSync     bool              `json:"in_sync"`
+	Matched    []DNSRecord       `json:"matched"`
+	Missing    []DNSRecord       `json:"missing"`
+	Unexpected []DNSRecord       `json:"unexpected"`
+	Changed    []DNSRecordChange `json:"changed"`
+	Errors     []string          `json:"errors,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Queries    int               `json:"queries"`
+	Timestamp  time.Time         `json:"timestamp"`
+	QueryTime  int               `json:"query_time_ms"`
+}
+
+// driftRRset is the expected and live data for one name and type
+type driftRRset struct {
+	name     string
+	rrtype   string
+	expected []DNSRecord
+	actual   []DNSRecord
+	ttlKnown bool
+	err      error
+	warning  string
+}
+
+// CheckDrift queries each expected RRset and reports missing, unexpected and changed records
+func (s *DNSDriftService) CheckDrift(ctx context.Context, req DNSDriftRequest) (*DNSDriftResult, error) {
+	domain := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(req.Domain), "."))
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+	domain += "."
+
+	expected, err := s.expectedRecords(ctx, domain, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(expected) == 0 {
+		return nil, fmt.Errorf("no expected records provided")
+	}
+
+	start := time.Now()
+	result := &DNSDriftResult{
+		Domain:     domain,
+		Source:     "resolver",
+		Matched:    []DNSRecord{},
+		Missing:    []DNSRecord{},
+		Unexpected: []DNSRecord{},
+		Changed:    []DNSRecordChange{},
+		Timestamp:  start,
+	}
+	if req.Server != "" {
+		server, err := normalizeDNSServer(req.Server)
+		if err != nil {
+			return nil, err
+		}
+		result.Server = server
+		result.Source = "authoritative"
+	}
+
+	// Group expected records into RRsets
+	var sets []*driftRRset
+	index := make(map[string]*driftRRset)
+	for _, rec := range expected {
+		key := rec.Name + "|" + rec.Type
+		set, ok := index[key]
+		if !ok {
+			set = &driftRRset{name: rec.Name, rrtype: rec.Type}
+			index[key] = set
+			sets = append(sets, set)
+		}
+		set.expected = append(set.expected, rec)
+	}
+	if len(sets) > dnsDriftMaxRRsets {
+		return nil, fmt.Errorf("too many RRsets to check (%d, maximum %d)", len(sets), dnsDriftMaxRRsets)
+	}
+
+	// Query RRsets concurrently
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10) // Limit concurrent queries
+	for _, set := range sets {
+		wg.Add(1)
+		go func(set *driftRRset) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if result.Server != "" {
+				s.queryAuthoritative(ctx, result.Server, set)
+			} else {
+				s.queryResolver(ctx, set)
+			}
+		}(set)
+	}
+	wg.Wait()
+	result.Queries = len(sets)
+
+	ttlUnknown := false
+	for _, set := range sets {
+		if set.err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s %s: %v", set.name, set.rrtype, set.err))
+			continue
+		}
+		if set.warning != "" {
+			result.Warnings = appendUnique(result.Warnings, set.warning)
+		}
+		if !set.ttlKnown {
+			ttlUnknown = true
+		}
+		compareRRset(set, !req.IgnoreTTL && set.ttlKnown, result)
+	}
+	if ttlUnknown && !req.IgnoreTTL {
+		result.Warnings = append(result.Warnings, "the system resolver does not report TTLs; specify an authoritative server to compare them")
+	}
+
+	sortDNSRecords(result.Matched)
+	sortDNSRecords(result.Missing)
+	sortDNSRecords(result.Unexpected)
+	sort.SliceStable(result.Changed, func(i, j int) bool {
+		if result.Changed[i].Name != result.Changed[j].Name {
+			return result.Changed[i].Name < result.Changed[j].Name
+		}
+		return result.Changed[i].Type < result.Changed[j].Type
+	})
+
+	result.InSync = len(result.Missing) == 0 && len(result.Unexpected) == 0 && len(result.Changed) == 0 && len(result.Errors) == 0
+	result.QueryTime = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
+// expectedRecords merges and normalizes the JSON and zone file forms of the request
+func (s *DNSDriftService) expectedRecords(ctx context.Context, domain string, req DNSDriftRequest) ([]DNSRecord, error) {
+	var records []DNSRecord
+
+	for _, rec := range req.Records {
+		rrtype := strings.ToUpper(strings.TrimSpace(rec.Type))
+		if _, err := parseDNSType(rrtype); err != nil {
+			return nil, fmt.Errorf("record %s: %v", rec.Name, err)
+		}
+		rec.Name = qualifyDriftName(rec.Name, domain)
+		rec.Type = rrtype
+		rec.Value = normalizeDriftValue(rrtype, rec.Value)
+		records = append(records, rec)
+	}
+
+	if strings.TrimSpace(req.Zone) != "" {
+		parsed, err := s.zones.ParseZone(ctx, ZoneParseRequest{Content: req.Zone, Origin: domain})
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Errors > 0 {
+			for _, issue := range parsed.Issues {
+				if issue.Severity == "error" && issue.Rule == "syntax" {
+					return nil, fmt.Errorf("zone file line %d: %s", issue.Line, issue.Message)
+				}
+			}
+		}
+		for _, rec := range parsed.Records {
+			rec.Value = normalizeDriftValue(rec.Type, rec.Value)
+			records = append(records, rec.DNSRecord)
+		}
+	}
+
+	return records, nil
+}
+
+// queryAuthoritative fetches an RRset directly from a name server, with TTLs
+func (s *DNSDriftService) queryAuthoritative(ctx context.Context, server string, set *driftRRset) {
+	qtype, err := parseDNSType(set.rrtype)
+	if err != nil {
+		set.err = err
+		return
+	}
+
+	resp, _, err := exchangeDNS(ctx, server, set.name, qtype, false)
+	if err != nil {
+		set.err = err
+		return
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		set.err = fmt.Errorf("server answered %s", dns.RcodeToString[resp.Rcode])
+		return
+	}
+	if !resp.Authoritative {
+		set.warning = fmt.Sprintf("%s is not authoritative for some queried names; TTLs may be decremented cache values", server)
+	}
+
+	set.ttlKnown = true
+	for _, rec := range answerRecords(resp, qtype) {
+		if rec.Name == set.name {
+			rec.Value = normalizeDriftValue(rec.Type, rec.Value)
+			set.actual = append(set.actual, rec)
+		}
+	}
+}
+
+// queryResolver fetches an RRset through LookupDNS and the system resolver
+func (s *DNSDriftService) queryResolver(ctx context.Context, set *driftRRset) {
+	switch set.rrtype {
+	case "A", "AAAA", "MX", "NS", "TXT", "CNAME":
+	default:
+		set.err = fmt.Errorf("%s records can only be compared against an authoritative server", set.rrtype)
+		return
+	}
+
+	lookup, err := s.dns.LookupDNS(ctx, strings.TrimSuffix(set.name, "."), set.rrtype)
+	if err != nil {
+		if !isNotFound(err) {
+			set.err = err
+		}
+		return
+	}
+
+	for _, rec := range lookup.Records {
+		rec.Name = qualifyDriftName(rec.Name, set.name)
+		rec.Type = set.rrtype
+		rec.Value = normalizeDriftValue(rec.Type, rec.Value)
+		// The resolver reports the queried name itself when there is no CNAME
+		if rec.Type == "CNAME" && rec.Value == set.name {
+			continue
+		}
+		rec.TTL = 0
+		set.actual = append(set.actual, rec)
+	}
+}
+
+// compareRRset matches expected and live records by value, pairing a single
+// leftover on each side as a changed record
+func compareRRset(set *driftRRset, compareTTL bool, result *DNSDriftResult) {
+	actual := make([]DNSRecord, len(set.actual))
+	copy(actual, set.actual)
+	used := make([]bool, len(actual))
+
+	var missing []DNSRecord
+	for _, exp := range set.expected {
+		found := -1
+		for i, act := range actual {
+			if !used[i] && act.Value == exp.Value {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			missing = append(missing, exp)
+			continue
+		}
+		used[found] = true
+		act := actual[found]
+		if compareTTL && exp.TTL > 0 && act.TTL != exp.TTL {
+			result.Changed = append(result.Changed, DNSRecordChange{
+				Name:     exp.Name,
+				Type:     exp.Type,
+				Expected: exp,
+				Actual:   act,
+				TTLDelta: act.TTL - exp.TTL,
+			})
+			continue
+		}
+		result.Matched = append(result.Matched, act)
+	}
+
+	var unexpected []DNSRecord
+	for i, act := range actual {
+		if !used[i] {
+			unexpected = append(unexpected, act)
+		}
+	}
+
+	if len(missing) == 1 && len(unexpected) == 1 {
+		change := DNSRecordChange{
+			Name:         missing[0].Name,
+			Type:         missing[0].Type,
+			Expected:     missing[0],
+			Actual:       unexpected[0],
+			ValueChanged: true,
+		}
+		if compareTTL && missing[0].TTL > 0 {
+			change.TTLDelta = unexpected[0].TTL - missing[0].TTL
+		}
+		result.Changed = append(result.Changed, change)
+		return
+	}
+
+	result.Missing = append(result.Missing, missing...)
+	result.Unexpected = append(result.Unexpected, unexpected...)
+}
+
+// qualifyDriftName turns "@", relative names and FQDNs without a trailing dot
+// into lowercase FQDNs under domain
+func qualifyDriftName(name, domain string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	apex := strings.TrimSuffix(domain, ".")
+	switch {
+	case name == "" || name == "@":
+		return domain
+	case strings.HasSuffix(name, "."):
+		return name
+	case name == apex || strings.HasSuffix(name, "."+apex):
+		return name + "."
+	default:
+		return name + "." + domain
+	}
+}
+
+// normalizeDriftValue canonicalizes record data so equivalent values compare equal
+func normalizeDriftValue(rrtype, value string) string {
+	value = strings.TrimSpace(value)
+	fqdn := func(name string) string {
+		return dns.Fqdn(strings.ToLower(name))
+	}
+
+	switch rrtype {
+	case "A", "AAAA":
+		if addr, err := netip.ParseAddr(value); err == nil {
+			return addr.String()
+		}
+	case "NS", "CNAME", "PTR", "DNAME":
+		return fqdn(value)
+	case "MX":
+		if fields := strings.Fields(value); len(fields) == 2 {
+			return fields[0] + " " + fqdn(fields[1])
+		}
+	case "SRV":
+		if fields := strings.Fields(value); len(fields) == 4 {
+			return strings.Join(fields[:3], " ") + " " + fqdn(fields[3])
+		}
+	case "SOA":
+		if fields := strings.Fields(value); len(fields) == 7 {
+			return fqdn(fields[0]) + " " + fqdn(fields[1]) + " " + strings.Join(fields[2:], " ")
+		}
+	case "TXT", "SPF":
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+func sortDNSRecords(records []DNSRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		if records[i].Type != records[j].Type {
+			return records[i].Type < records[j].Type
+		}
+		return records[i].Value < records[j].Value
+	})
+}
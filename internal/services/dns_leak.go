@@ -0,0 +1,486 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultLeakProbes   = 6
+	defaultLeakTestTTL  = 10 * time.Minute
+	maxActiveLeakTests  = 10000
+	leakTokenBytes      = 8
+	leakProbeLabelBytes = 6
+)
+
+// ErrDNSLeakTestNotFound is returned for unknown or expired leak test tokens
+var ErrDNSLeakTestNotFound = errors.New("DNS leak test not found or expired")
+
+// DNSLeakConfig configures the authoritative responder used for DNS leak tests
+type DNSLeakConfig struct {
+	Zone       string        // wildcard zone delegated to this responder, e.g. "leak.example.com."
+	Listen     string        // address for the UDP and TCP listeners
+	Nameserver string        // NS host published at the zone apex
+	AnswerIPv4 string        // A record returned for probe names; empty returns NODATA
+	AnswerIPv6 string        // AAAA record returned for probe names; empty returns NODATA
+	Probes     int           // unique probe names per test
+	TestTTL    time.Duration // how long a test token keeps collecting queries
+}
+
+// DNSLeakService answers queries for the leak test zone and records which
+// resolvers ask for each test's randomized probe names
+type DNSLeakService struct {
+	dns    *IPAnalysisService
+	config DNSLeakConfig
+	serial uint32
+
+	mu    sync.Mutex
+	tests map[string]*dnsLeakTest
+}
+
+// dnsLeakTest is the in-memory state of a running leak test
+type dnsLeakTest struct {
+	token     string
+	clientIP  string
+	probes    map[string]bool
+	resolvers map[string]*DNSLeakResolver
+	seen      map[string]map[string]bool // resolver IP -> probe names queried
+	created   time.Time
+	expires   time.Time
+}
+
+// DNSLeakTest is returned when a test starts; the client resolves each probe host
+type DNSLeakTest struct {
+	Token     string    `json:"token"`
+	Zone      string    `json:"zone"`
+	Probes    []string  `json:"probes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DNSLeakResolver describes a resolver that queried the test's probe names
+type DNSLeakResolver struct {
+	IP           string    `json:"ip"`
+	Queries      int       `json:"queries"`
+	Probes       int       `json:"probes"`
+	Transports   []string  `json:"transports"`
+	ClientSubnet string    `json:"client_subnet,omitempty"` // EDNS Client Subnet forwarded by the resolver
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	Info         *IPInfo   `json:"info,omitempty"`
+	SameNetwork  bool      `json:"same_network"`
+}
+
+// DNSLeakResult reports the resolvers seen for a test
+type DNSLeakResult struct {
+	Token        string            `json:"token"`
+	ClientIP     string            `json:"client_ip"`
+	Client       *IPInfo           `json:"client,omitempty"`
+	Resolvers    []DNSLeakResolver `json:"resolvers"`
+	ProbesSent   int               `json:"probes_sent"`
+	ProbesSeen   int               `json:"probes_seen"`
+	Leak         bool              `json:"leak"`
+	Notes        []string          `json:"notes"`
+	StartedAt    time.Time         `json:"started_at"`
+	ExpiresAt    time.Time         `json:"expires_at"`
+	Timestamp    time.Time         `json:"timestamp"`
+	AnalysisTime float64           `json:"analysis_time_ms"`
+}
+
+// NewDNSLeakService creates a DNS leak test service for the configured zone
+func NewDNSLeakService(ipService *IPAnalysisService, config DNSLeakConfig) *DNSLeakService {
+	if config.Zone != "" {
+		config.Zone = dns.Fqdn(strings.ToLower(strings.TrimSpace(config.Zone)))
+	}
+	if config.Nameserver == "" && config.Zone != "" {
+		config.Nameserver = "ns." + config.Zone
+	} else if config.Nameserver != "" {
+		config.Nameserver = dns.Fqdn(strings.ToLower(strings.TrimSpace(config.Nameserver)))
+	}
+	if config.Listen == "" {
+		config.Listen = ":53"
+	}
+	if config.Probes <= 0 {
+		config.Probes = defaultLeakProbes
+	}
+	if config.TestTTL <= 0 {
+		config.TestTTL = defaultLeakTestTTL
+	}
+
+	return &DNSLeakService{
+		dns:    ipService,
+		config: config,
+		serial: uint32(time.Now().Unix()),
+		tests:  make(map[string]*dnsLeakTest),
+	}
+}
+
+// Enabled reports whether a leak test zone has been configured
+func (s *DNSLeakService) Enabled() bool {
+	return s.config.Zone != ""
+}
+
+// ListenAndServe runs the authoritative responder on UDP and TCP until either listener fails
+func (s *DNSLeakService) ListenAndServe() error {
+	if !s.Enabled() {
+		return fmt.Errorf("DNS leak zone is not configured")
+	}
+
+	errs := make(chan error, 2)
+	for _, network := range []string{"udp", "tcp"} {
+		server := &dns.Server{Addr: s.config.Listen, Net: network, Handler: s}
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+	}
+	return <-errs
+}
+
+// StartTest creates a new test token with randomized probe names under the zone
+func (s *DNSLeakService) StartTest(clientIP string) (*DNSLeakTest, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("DNS leak zone is not configured")
+	}
+
+	token, err := randomLabel(leakTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	test := &dnsLeakTest{
+		token:     token,
+		clientIP:  clientIP,
+		probes:    make(map[string]bool),
+		resolvers: make(map[string]*DNSLeakResolver),
+		seen:      make(map[string]map[string]bool),
+		created:   now,
+		expires:   now.Add(s.config.TestTTL),
+	}
+
+	result := &DNSLeakTest{
+		Token:     token,
+		Zone:      strings.TrimSuffix(s.config.Zone, "."),
+		ExpiresAt: test.expires,
+	}
+	for len(result.Probes) < s.config.Probes {
+		label, err := randomLabel(leakProbeLabelBytes)
+		if err != nil {
+			return nil, err
+		}
+		name := "p" + label + "." + token + "." + s.config.Zone
+		if test.probes[name] {
+			continue
+		}
+		test.probes[name] = true
+		result.Probes = append(result.Probes, strings.TrimSuffix(name, "."))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneExpired(now)
+	if len(s.tests) >= maxActiveLeakTests {
+		return nil, fmt.Errorf("too many active DNS leak tests, try again later")
+	}
+	s.tests[token] = test
+
+	return result, nil
+}
+
+// GetResults returns the resolvers seen so far for a test, enriched with IP analysis
+func (s *DNSLeakService) GetResults(ctx context.Context, token string) (*DNSLeakResult, error) {
+	start := time.Now()
+	token = strings.ToLower(strings.TrimSpace(token))
+
+	s.mu.Lock()
+	test, ok := s.tests[token]
+	if !ok || start.After(test.expires) {
+		s.mu.Unlock()
+		return nil, ErrDNSLeakTestNotFound
+	}
+	result := &DNSLeakResult{
+		Token:      test.token,
+		ClientIP:   test.clientIP,
+		Resolvers:  make([]DNSLeakResolver, 0, len(test.resolvers)),
+		ProbesSent: len(test.probes),
+		Notes:      []string{},
+		StartedAt:  test.created,
+		ExpiresAt:  test.expires,
+	}
+	seenProbes := make(map[string]bool)
+	for ip, resolver := range test.resolvers {
+		copied := *resolver
+		copied.Transports = append([]string(nil), resolver.Transports...)
+		copied.Probes = len(test.seen[ip])
+		for name := range test.seen[ip] {
+			seenProbes[name] = true
+		}
+		result.Resolvers = append(result.Resolvers, copied)
+	}
+	s.mu.Unlock()
+
+	result.ProbesSeen = len(seenProbes)
+	sort.Slice(result.Resolvers, func(i, j int) bool {
+		return result.Resolvers[i].FirstSeen.Before(result.Resolvers[j].FirstSeen)
+	})
+
+	// Analyze the client and each resolver concurrently
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10)
+	if result.ClientIP != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if info, err := s.dns.AnalyzeIP(ctx, result.ClientIP); err == nil {
+				result.Client = info
+			}
+		}()
+	}
+	for i := range result.Resolvers {
+		wg.Add(1)
+		go func(resolver *DNSLeakResolver) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if info, err := s.dns.AnalyzeIP(ctx, resolver.IP); err == nil {
+				resolver.Info = info
+			}
+		}(&result.Resolvers[i])
+	}
+	wg.Wait()
+
+	s.assessLeak(result)
+	result.Timestamp = time.Now()
+	result.AnalysisTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// assessLeak compares each resolver's network with the client's
+func (s *DNSLeakService) assessLeak(result *DNSLeakResult) {
+	if len(result.Resolvers) == 0 {
+		result.Notes = append(result.Notes, "No resolver queries received yet; the probes may still be in flight")
+		return
+	}
+	if result.ProbesSeen < result.ProbesSent {
+		result.Notes = append(result.Notes, fmt.Sprintf("Only %d of %d probe names were queried", result.ProbesSeen, result.ProbesSent))
+	}
+
+	clientASN, clientCountry := leakNetwork(result.Client)
+	for i := range result.Resolvers {
+		resolver := &result.Resolvers[i]
+		asn, country := leakNetwork(resolver.Info)
+		switch {
+		case resolver.IP == result.ClientIP:
+			resolver.SameNetwork = true
+		case clientASN != "" && asn != "":
+			resolver.SameNetwork = asn == clientASN
+		case clientCountry != "" && country != "":
+			resolver.SameNetwork = country == clientCountry
+		default:
+			result.Notes = append(result.Notes, fmt.Sprintf("Could not determine the network of resolver %s", resolver.IP))
+			continue
+		}
+
+		if !resolver.SameNetwork {
+			result.Leak = true
+			provider := asn
+			if resolver.Info != nil && resolver.Info.ISP != nil && resolver.Info.ISP.Organization != "" {
+				provider = resolver.Info.ISP.Organization
+			}
+			result.Notes = append(result.Notes, fmt.Sprintf("Resolver %s (%s) is outside your network", resolver.IP, provider))
+		}
+		if resolver.ClientSubnet != "" {
+			result.Notes = append(result.Notes, fmt.Sprintf("Resolver %s forwarded your client subnet %s to the authoritative server", resolver.IP, resolver.ClientSubnet))
+		}
+	}
+
+	if len(result.Resolvers) > 1 {
+		result.Notes = append(result.Notes, fmt.Sprintf("Queries arrived from %d different resolvers", len(result.Resolvers)))
+	}
+}
+
+// leakNetwork returns the ASN and country code reported for an address
+func leakNetwork(info *IPInfo) (string, string) {
+	if info == nil {
+		return "", ""
+	}
+	var asn, country string
+	if info.ISP != nil {
+		asn = info.ISP.ASN
+	}
+	if info.Geolocation != nil {
+		country = info.Geolocation.CountryCode
+	}
+	return asn, country
+}
+
+// ServeDNS answers authoritatively for the leak zone and records probe queries
+func (s *DNSLeakService) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) != 1 || r.Opcode != dns.OpcodeQuery {
+		m.Rcode = dns.RcodeFormatError
+		w.WriteMsg(m)
+		return
+	}
+
+	q := r.Question[0]
+	name := strings.ToLower(q.Name)
+	if !dns.IsSubDomain(s.config.Zone, name) {
+		m.Authoritative = false
+		m.Rcode = dns.RcodeRefused
+		w.WriteMsg(m)
+		return
+	}
+
+	relative := dns.SplitDomainName(strings.TrimSuffix(strings.TrimSuffix(name, s.config.Zone), "."))
+	switch {
+	case len(relative) == 0:
+		s.answerApex(m, q)
+	case name == s.config.Nameserver:
+		s.answerAddress(m, q)
+	default:
+		token := relative[len(relative)-1]
+		if !s.recordQuery(token, name, w, r) {
+			m.Rcode = dns.RcodeNameError
+			m.Ns = append(m.Ns, s.soa())
+			break
+		}
+		if len(relative) > 1 {
+			s.answerAddress(m, q)
+		} else {
+			m.Ns = append(m.Ns, s.soa())
+		}
+	}
+
+	if opt := r.IsEdns0(); opt != nil {
+		m.SetEdns0(dns.DefaultMsgSize, false)
+	}
+	w.WriteMsg(m)
+}
+
+// recordQuery notes the resolver asking about a test token; it reports whether the token is active
+func (s *DNSLeakService) recordQuery(token, name string, w dns.ResponseWriter, r *dns.Msg) bool {
+	host, _, err := net.SplitHostPort(w.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	transport := "udp"
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		transport = "tcp"
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	test, ok := s.tests[token]
+	if !ok || now.After(test.expires) {
+		return false
+	}
+
+	resolver, ok := test.resolvers[host]
+	if !ok {
+		resolver = &DNSLeakResolver{IP: host, FirstSeen: now}
+		test.resolvers[host] = resolver
+		test.seen[host] = make(map[string]bool)
+	}
+	resolver.Queries++
+	resolver.LastSeen = now
+	resolver.Transports = appendUnique(resolver.Transports, transport)
+	if subnet := clientSubnet(r); subnet != "" {
+		resolver.ClientSubnet = subnet
+	}
+	if test.probes[name] {
+		test.seen[host][name] = true
+	}
+	return true
+}
+
+// answerApex answers SOA and NS queries for the zone apex
+func (s *DNSLeakService) answerApex(m *dns.Msg, q dns.Question) {
+	switch q.Qtype {
+	case dns.TypeSOA, dns.TypeANY:
+		m.Answer = append(m.Answer, s.soa())
+	case dns.TypeNS:
+		m.Answer = append(m.Answer, &dns.NS{
+			Hdr: dns.RR_Header{Name: s.config.Zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: 3600},
+			Ns:  s.config.Nameserver,
+		})
+	default:
+		m.Ns = append(m.Ns, s.soa())
+	}
+}
+
+// answerAddress returns the configured probe address, or NODATA when none is set
+func (s *DNSLeakService) answerAddress(m *dns.Msg, q dns.Question) {
+	hdr := dns.RR_Header{Name: q.Name, Class: dns.ClassINET, Ttl: 0}
+	switch {
+	case q.Qtype == dns.TypeA && net.ParseIP(s.config.AnswerIPv4).To4() != nil:
+		hdr.Rrtype = dns.TypeA
+		m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: net.ParseIP(s.config.AnswerIPv4).To4()})
+	case q.Qtype == dns.TypeAAAA && net.ParseIP(s.config.AnswerIPv6) != nil:
+		hdr.Rrtype = dns.TypeAAAA
+		m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP(s.config.AnswerIPv6)})
+	default:
+		m.Ns = append(m.Ns, s.soa())
+	}
+}
+
+// soa builds the zone's SOA record; the zero minimum keeps negative answers uncached
+func (s *DNSLeakService) soa() dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: s.config.Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 0},
+		Ns:      s.config.Nameserver,
+		Mbox:    "hostmaster." + s.config.Zone,
+		Serial:  s.serial,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  0,
+	}
+}
+
+// pruneExpired drops finished tests; the caller must hold s.mu
+func (s *DNSLeakService) pruneExpired(now time.Time) {
+	for token, test := range s.tests {
+		if now.After(test.expires) {
+			delete(s.tests, token)
+		}
+	}
+}
+
+// clientSubnet returns the EDNS Client Subnet option of a query, if present
+func clientSubnet(r *dns.Msg) string {
+	opt := r.IsEdns0()
+	if opt == nil {
+		return ""
+	}
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return fmt.Sprintf("%s/%d", subnet.Address, subnet.SourceNetmask)
+		}
+	}
+	return ""
+}
+
+// randomLabel returns a random lowercase hex DNS label
+func randomLabel(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random label: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
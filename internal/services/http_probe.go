@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits applied to HTTP probes
+const (
+	defaultProbeRedirects = 10
+	maxProbeRedirects     = 20
+	maxProbeBodyBytes     = 10 * 1024 * 1024
+)
+
+// HTTPProbeService probes HTTP endpoints for headers, redirects and timings
+type HTTPProbeService struct {
+	transport *http.Transport
+	timeout   time.Duration
+}
+
+// NewHTTPProbeService creates a new HTTP probe service that only connects to public addresses
+func NewHTTPProbeService() *HTTPProbeService {
+	return NewHTTPProbeServiceWithOptions(false)
+}
+
+// NewHTTPProbeServiceWithOptions creates an HTTP probe service; allowPrivate
+// permits loopback, private and link-local targets
+func NewHTTPProbeServiceWithOptions(allowPrivate bool) *HTTPProbeService {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Compression is requested explicitly so the response encoding can be reported
+	transport.DisableCompression = true
+	// Every hop is dialed directly so the address guard sees the real target
+	transport.Proxy = nil
+	transport.DialContext = newProbeDialer(30*time.Second, allowPrivate).DialContext
+
+	return &HTTPProbeService{
+		transport: transport,
+		timeout:   15 * time.Second,
+	}
+}
+
+// HTTPProbeRequest represents a request to probe a URL
+type HTTPProbeRequest struct {
+	URL          string `json:"url"`
+	Method       string `json:"method"`        // "GET" or "HEAD", defaults to GET
+	MaxRedirects int    `json:"max_redirects"` // defaults to 10
+}
+
+// HTTPProbeResult represents the outcome of probing a URL
+type HTTPProbeResult struct {
+	URL              string                `json:"url"`
+	FinalURL         string                `json:"final_url"`
+	StatusCode       int                   `json:"status_code"`
+	HTTPVersion      string                `json:"http_version"`
+	Hops             []HTTPProbeHop        `json:"hops"`
+	RedirectCount    int                   `json:"redirect_count"`
+	TooManyRedirects bool                  `json:"too_many_redirects,omitempty"`
+	Compression      string                `json:"compression,omitempty"`
+	SecurityHeaders  []SecurityHeaderCheck `json:"security_headers"`
+	Summary          SecurityHeaderSummary `json:"summary"`
+	TotalTime        float64               `json:"total_time_ms"`
+	Timestamp        time.Time             `json:"timestamp"`
+}
+
+// HTTPProbeHop represents a single request in a redirect chain
+type HTTPProbeHop struct {
+	URL           string              `json:"url"`
+	StatusCode    int                 `json:"status_code"`
+	Status        string              `json:"status"`
+	HTTPVersion   string              `json:"http_version"`
+	RemoteAddr    string              `json:"remote_addr,omitempty"`
+	TLSVersion    string              `json:"tls_version,omitempty"`
+	Location      string              `json:"location,omitempty"`
+	Compression   string              `json:"compression,omitempty"`
+	ContentType   string              `json:"content_type,omitempty"`
+	ContentLength int64               `json:"content_length"`
+	Headers       map[string][]string `json:"headers"`
+	Timing        HTTPTiming          `json:"timing"`
+}
+
+// HTTPTiming represents the timing breakdown of a single request
+type HTTPTiming struct {
+	DNS          float64 `json:"dns_ms"`
+	Connect      float64 `json:"connect_ms"`
+	TLSHandshake float64 `json:"tls_handshake_ms"`
+	TTFB         float64 `json:"ttfb_ms"`
+	Download     float64 `json:"download_ms"`
+	Total        float64 `json:"total_ms"`
+	ReusedConn   bool    `json:"reused_connection"`
+}
+
+// SecurityHeaderCheck represents the result of checking a single security header
+type SecurityHeaderCheck struct {
+	Header  string `json:"header"`
+	Present bool   `json:"present"`
+	Value   string `json:"value,omitempty"`
+	Pass    bool   `json:"pass"`
+	Message string `json:"message"`
+}
+
+// SecurityHeaderSummary represents the pass/fail totals of the security header checks
+type SecurityHeaderSummary struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	Total  int `json:"total"`
+}
+
+// ProbeURL requests a URL, following redirects hop by hop, and reports timings and headers
+func (s *HTTPProbeService) ProbeURL(ctx context.Context, req HTTPProbeRequest) (*HTTPProbeResult, error) {
+	target, err := normalizeProbeURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return nil, fmt.Errorf("unsupported method: %s", req.Method)
+	}
+
+	maxRedirects := req.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultProbeRedirects
+	}
+	if maxRedirects > maxProbeRedirects {
+		maxRedirects = maxProbeRedirects
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// A fresh transport per probe keeps pooled connections from hiding the
+	// connect and TLS timings of the first hop
+	transport := s.transport.Clone()
+	defer transport.CloseIdleConnections()
+
+	// Redirects are followed manually so that every hop can be traced
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	result := &HTTPProbeResult{
+		URL:       target.String(),
+		Hops:      []HTTPProbeHop{},
+		Timestamp: start,
+	}
+
+	var last *http.Response
+	current := target
+	for {
+		hop, resp, err := s.probeHop(ctx, client, method, current)
+		if err != nil {
+			return nil, fmt.Errorf("request to %s failed: %w", current, err)
+		}
+		result.Hops = append(result.Hops, *hop)
+		last = resp
+
+		if hop.Location == "" || resp.StatusCode < 300 || resp.StatusCode > 399 {
+			break
+		}
+		if result.RedirectCount == maxRedirects {
+			result.TooManyRedirects = true
+			break
+		}
+
+		next, err := current.Parse(hop.Location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect location %q: %w", hop.Location, err)
+		}
+		current = next
+		result.RedirectCount++
+	}
+
+	final := result.Hops[len(result.Hops)-1]
+	result.FinalURL = final.URL
+	result.StatusCode = final.StatusCode
+	result.HTTPVersion = final.HTTPVersion
+	result.Compression = final.Compression
+	result.SecurityHeaders = checkSecurityHeaders(last.Header, current.Scheme == "https")
+	for _, check := range result.SecurityHeaders {
+		if check.Pass {
+			result.Summary.Passed++
+		} else {
+			result.Summary.Failed++
+		}
+	}
+	result.Summary.Total = len(result.SecurityHeaders)
+	result.TotalTime = durationMs(time.Since(start))
+
+	return result, nil
+}
+
+// probeHop performs a single traced request without following redirects
+func (s *HTTPProbeService) probeHop(ctx context.Context, client *http.Client, method string, target *url.URL) (*HTTPProbeHop, *http.Response, error) {
+	var dnsStart, connectStart, tlsStart, firstByte time.Time
+	hop := &HTTPProbeHop{URL: target.String()}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			hop.Timing.DNS = durationMs(time.Since(dnsStart))
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			hop.Timing.Connect = durationMs(time.Since(connectStart))
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			hop.Timing.TLSHandshake = durationMs(time.Since(tlsStart))
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			hop.Timing.ReusedConn = info.Reused
+			hop.RemoteAddr = info.Conn.RemoteAddr().String()
+		},
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, target.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
+	req.Header.Set("User-Agent", "dev-tools-http-probe/1.0")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the download time and size can be measured
+	size, _ := io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBodyBytes))
+	end := time.Now()
+
+	if !firstByte.IsZero() {
+		hop.Timing.TTFB = durationMs(firstByte.Sub(start))
+		hop.Timing.Download = durationMs(end.Sub(firstByte))
+	}
+	hop.Timing.Total = durationMs(end.Sub(start))
+
+	hop.StatusCode = resp.StatusCode
+	hop.Status = resp.Status
+	hop.HTTPVersion = resp.Proto
+	hop.Location = resp.Header.Get("Location")
+	hop.Compression = resp.Header.Get("Content-Encoding")
+	hop.ContentType = resp.Header.Get("Content-Type")
+	if resp.TLS != nil {
+		hop.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	hop.ContentLength = resp.ContentLength
+	if hop.ContentLength < 0 {
+		hop.ContentLength = size
+	}
+	hop.Headers = resp.Header
+
+	return hop, resp, nil
+}
+
+// checkSecurityHeaders checks a response for the common security headers
+func checkSecurityHeaders(header http.Header, isHTTPS bool) []SecurityHeaderCheck {
+	var checks []SecurityHeaderCheck
+
+	// Strict-Transport-Security
+	hsts := SecurityHeaderCheck{Header: "Strict-Transport-Security", Value: header.Get("Strict-Transport-Security")}
+	hsts.Present = hsts.Value != ""
+	switch {
+	case !isHTTPS:
+		hsts.Message = "site is not served over HTTPS"
+	case !hsts.Present:
+		hsts.Message = "missing; browsers may connect over plain HTTP"
+	default:
+		maxAge := hstsMaxAge(hsts.Value)
+		if maxAge < 15552000 {
+			hsts.Message = fmt.Sprintf("max-age of %d seconds is shorter than the recommended 180 days", maxAge)
+		} else {
+			hsts.Pass = true
+			hsts.Message = "HSTS is enabled"
+		}
+	}
+	checks = append(checks, hsts)
+
+	// Content-Security-Policy
+	csp := SecurityHeaderCheck{Header: "Content-Security-Policy", Value: header.Get("Content-Security-Policy")}
+	csp.Present = csp.Value != ""
+	switch {
+	case !csp.Present:
+		csp.Message = "missing; no protection against injected scripts"
+	case strings.Contains(csp.Value, "'unsafe-inline'") || strings.Contains(csp.Value, "'unsafe-eval'"):
+		csp.Message = "policy allows 'unsafe-inline' or 'unsafe-eval'"
+	default:
+		csp.Pass = true
+		csp.Message = "policy is set"
+	}
+	checks = append(checks, csp)
+
+	// X-Frame-Options, or the CSP frame-ancestors directive that supersedes it
+	xfo := SecurityHeaderCheck{Header: "X-Frame-Options", Value: header.Get("X-Frame-Options")}
+	xfo.Present = xfo.Value != ""
+	switch value := strings.ToUpper(xfo.Value); {
+	case value == "DENY" || value == "SAMEORIGIN":
+		xfo.Pass = true
+		xfo.Message = "framing is restricted"
+	case strings.Contains(csp.Value, "frame-ancestors"):
+		xfo.Pass = true
+		xfo.Message = "framing is restricted by the CSP frame-ancestors directive"
+	case xfo.Present:
+		xfo.Message = "value should be DENY or SAMEORIGIN"
+	default:
+		xfo.Message = "missing; the page can be framed (clickjacking)"
+	}
+	checks = append(checks, xfo)
+
+	// X-Content-Type-Options
+	xcto := SecurityHeaderCheck{Header: "X-Content-Type-Options", Value: header.Get("X-Content-Type-Options")}
+	xcto.Present = xcto.Value != ""
+	if strings.EqualFold(xcto.Value, "nosniff") {
+		xcto.Pass = true
+		xcto.Message = "MIME sniffing is disabled"
+	} else if xcto.Present {
+		xcto.Message = "value should be nosniff"
+	} else {
+		xcto.Message = "missing; browsers may MIME-sniff responses"
+	}
+	checks = append(checks, xcto)
+
+	// Referrer-Policy
+	referrer := SecurityHeaderCheck{Header: "Referrer-Policy", Value: header.Get("Referrer-Policy")}
+	referrer.Present = referrer.Value != ""
+	if strings.EqualFold(referrer.Value, "unsafe-url") {
+		referrer.Message = "unsafe-url leaks full URLs to other origins"
+	} else if referrer.Present {
+		referrer.Pass = true
+		referrer.Message = "referrer policy is set"
+	} else {
+		referrer.Message = "missing; the browser default policy applies"
+	}
+	checks = append(checks, referrer)
+
+	// Permissions-Policy
+	permissions := SecurityHeaderCheck{Header: "Permissions-Policy", Value: header.Get("Permissions-Policy")}
+	permissions.Present = permissions.Value != ""
+	permissions.Pass = permissions.Present
+	if permissions.Present {
+		permissions.Message = "browser features are restricted"
+	} else {
+		permissions.Message = "missing; browser features are not restricted"
+	}
+	checks = append(checks, permissions)
+
+	return checks
+}
+
+// hstsMaxAge extracts the max-age directive from an HSTS header
+func hstsMaxAge(value string) int {
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, found := strings.Cut(strings.TrimSpace(directive), "=")
+		if found && strings.EqualFold(name, "max-age") {
+			if n, err := strconv.Atoi(strings.Trim(arg, `"`)); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// normalizeProbeURL validates a probe URL, defaulting to https when no scheme is given
+func normalizeProbeURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("url cannot be empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported url scheme: %s", target.Scheme)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("url must include a host")
+	}
+
+	return target, nil
+}
+
+// durationMs converts a duration to fractional milliseconds
+func durationMs(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeURLFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", "default-src 'self'")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	service := NewHTTPProbeServiceWithOptions(true)
+	result, err := service.ProbeURL(context.Background(), HTTPProbeRequest{URL: server.URL + "/start"})
+	if err != nil {
+		t.Fatalf("ProbeURL: %v", err)
+	}
+	if result.RedirectCount != 2 || len(result.Hops) != 3 {
+		t.Fatalf("redirects = %d, hops = %d, want 2 and 3", result.RedirectCount, len(result.Hops))
+	}
+	if result.FinalURL != server.URL+"/final" || result.StatusCode != http.StatusOK {
+		t.Errorf("final = %s %d", result.FinalURL, result.StatusCode)
+	}
+	if result.Hops[0].StatusCode != http.StatusMovedPermanently || result.Hops[0].Location != "/middle" {
+		t.Errorf("first hop = %d %q", result.Hops[0].StatusCode, result.Hops[0].Location)
+	}
+
+	checks := make(map[string]SecurityHeaderCheck)
+	for _, check := range result.SecurityHeaders {
+		checks[check.Header] = check
+	}
+	for _, header := range []string{"Content-Security-Policy", "X-Content-Type-Options", "X-Frame-Options"} {
+		if !checks[header].Pass {
+			t.Errorf("%s: expected pass, got %q", header, checks[header].Message)
+		}
+	}
+	for _, header := range []string{"Strict-Transport-Security", "Referrer-Policy", "Permissions-Policy"} {
+		if checks[header].Pass {
+			t.Errorf("%s: expected fail", header)
+		}
+	}
+}
+
+func TestProbeURLRedirectLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+
+	service := NewHTTPProbeServiceWithOptions(true)
+	result, err := service.ProbeURL(context.Background(), HTTPProbeRequest{URL: server.URL, MaxRedirects: 3})
+	if err != nil {
+		t.Fatalf("ProbeURL: %v", err)
+	}
+	if !result.TooManyRedirects || result.RedirectCount != 3 {
+		t.Errorf("too_many_redirects = %v, redirects = %d", result.TooManyRedirects, result.RedirectCount)
+	}
+}
+
+func TestProbeURLRefusesNonPublicTargets(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	service := NewHTTPProbeService()
+	for _, target := range []string{server.URL, "http://169.254.169.254/latest/meta-data/"} {
+		if _, err := service.ProbeURL(context.Background(), HTTPProbeRequest{URL: target}); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("%s: error = %v, want ErrNonPublicAddress", target, err)
+		}
+	}
+}
@@ -2,29 +2,70 @@ package services
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
+// Resolver is the DNS lookup layer used by IPAnalysisService; *net.Resolver satisfies it
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
 // IPAnalysisService provides IP and DNS analysis functionality
 type IPAnalysisService struct {
-	httpClient *http.Client
+	httpClient   *http.Client
+	dohClient    *http.Client // DoH queries to caller-supplied endpoints
+	resolver     Resolver
+	tlsConfig    *tls.Config // DoT and DoH client configuration; nil uses the system roots
+	allowPrivate bool        // caller-supplied servers may be non-public addresses
 }
 
 // NewIPAnalysisService creates a new IP analysis service
 func NewIPAnalysisService() *IPAnalysisService {
+	return NewIPAnalysisServiceWithResolver(net.DefaultResolver)
+}
+
+// NewIPAnalysisServiceWithResolver creates an IP analysis service that performs
+// DNS lookups through the given resolver
+func NewIPAnalysisServiceWithResolver(resolver Resolver) *IPAnalysisService {
 	return &IPAnalysisService{
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		dohClient: newDoHClient(nil, false),
+		resolver:  resolver,
 	}
 }
 
+// NewIPAnalysisServiceWithTLS creates an IP analysis service whose DoT and DoH
+// lookups use the given TLS configuration, e.g. to trust a private CA. When
+// allowPrivate is set, lookups may query servers on non-public addresses.
+func NewIPAnalysisServiceWithTLS(resolver Resolver, config *tls.Config, allowPrivate bool) *IPAnalysisService {
+	s := NewIPAnalysisServiceWithResolver(resolver)
+	s.tlsConfig = config
+	s.allowPrivate = allowPrivate
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.Clone()
+	s.httpClient.Transport = transport
+	s.dohClient = newDoHClient(config, allowPrivate)
+	return s
+}
+
 // IPInfo represents comprehensive IP information
 type IPInfo struct {
 	IP          string    `json:"ip"`
@@ -85,10 +126,35 @@ type DNSRecord struct {
 
 // DNSLookupResult represents DNS lookup results
 type DNSLookupResult struct {
-	Domain    string      `json:"domain"`
-	Records   []DNSRecord `json:"records"`
-	Timestamp time.Time   `json:"timestamp"`
-	QueryTime int         `json:"query_time_ms"`
+	Domain    string          `json:"domain"`
+	Records   []DNSRecord     `json:"records"`
+	Transport string          `json:"transport"`
+	Server    string          `json:"server,omitempty"`
+	Latency   float64         `json:"latency_ms"`         // Slowest upstream round trip
+	Statuses  []DNSTypeStatus `json:"statuses,omitempty"` // Per-type outcome of ALL lookups
+	Timestamp time.Time       `json:"timestamp"`
+	QueryTime int             `json:"query_time_ms"`
+}
+
+// DNS lookup outcomes reported per record type
+const (
+	DNSStatusOK       = "ok"
+	DNSStatusNoData   = "NODATA"
+	DNSStatusNXDomain = "NXDOMAIN"
+	DNSStatusServFail = "SERVFAIL"
+	DNSStatusTimeout  = "timeout"
+	DNSStatusError    = "error"
+)
+
+// DNSTypeStatus reports the outcome of one record type within an ALL lookup
+type DNSTypeStatus struct {
+	Type      string  `json:"type"`
+	Status    string  `json:"status"`
+	Records   int     `json:"records"`
+	QueryTime float64 `json:"query_time_ms"`
+	Error     string  `json:"error,omitempty"`
+	Inferred  bool    `json:"inferred,omitempty"` // NXDOMAIN/NODATA guessed from the other types
+	Note      string  `json:"note,omitempty"`
 }
 
 // TracerouteHop represents a single hop in traceroute
@@ -217,13 +283,19 @@ func (s *IPAnalysisService) AnalyzeIP(ctx context.Context, ipStr string) (*IPInf
 	return info, nil
 }
 
-// LookupDNS performs DNS record lookup
+// LookupDNS performs DNS record lookup through the system resolver
 func (s *IPAnalysisService) LookupDNS(ctx context.Context, domain string, recordType string) (*DNSLookupResult, error) {
+	return s.LookupDNSWith(ctx, domain, recordType, DNSLookupOptions{})
+}
+
+// LookupDNSWith performs DNS record lookup over the transport selected in opts
+func (s *IPAnalysisService) LookupDNSWith(ctx context.Context, domain string, recordType string, opts DNSLookupOptions) (*DNSLookupResult, error) {
 	start := time.Now()
 
 	result := &DNSLookupResult{
 		Domain:    domain,
 		Records:   []DNSRecord{},
+		Transport: DNSTransportSystem,
 		Timestamp: start,
 	}
 
@@ -232,26 +304,60 @@ func (s *IPAnalysisService) LookupDNS(ctx context.Context, domain string, record
 		return nil, fmt.Errorf("domain cannot be empty")
 	}
 
+	transport := strings.ToLower(strings.TrimSpace(opts.Transport))
+	if transport != "" && transport != DNSTransportSystem {
+		server, err := normalizeTransportServer(transport, opts.Server)
+		if err != nil {
+			return nil, err
+		}
+		result.Transport = transport
+		result.Server = server
+
+		var records []DNSRecord
+		var latency time.Duration
+		if strings.ToUpper(recordType) == "ALL" {
+			records, result.Statuses, latency, err = s.lookupAll(ctx, false, func(ctx context.Context, recordType string) ([]DNSRecord, time.Duration, error) {
+				return s.lookupTransport(ctx, domain, recordType, transport, server)
+			})
+		} else {
+			records, latency, err = s.lookupTransport(ctx, domain, recordType, transport, server)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("DNS lookup failed: %w", err)
+		}
+		result.Records = records
+		result.Latency = durationMs(latency)
+		result.QueryTime = int(time.Since(start).Milliseconds())
+		return result, nil
+	}
+
 	var records []DNSRecord
 	var err error
 
 	switch strings.ToUpper(recordType) {
 	case "A":
-		records, err = s.lookupA(domain)
+		records, err = s.lookupA(ctx, domain)
 	case "AAAA":
-		records, err = s.lookupAAAA(domain)
+		records, err = s.lookupAAAA(ctx, domain)
 	case "MX":
-		records, err = s.lookupMX(domain)
+		records, err = s.lookupMX(ctx, domain)
 	case "NS":
-		records, err = s.lookupNS(domain)
+		records, err = s.lookupNS(ctx, domain)
 	case "TXT":
-		records, err = s.lookupTXT(domain)
+		records, err = s.lookupTXT(ctx, domain)
 	case "CNAME":
-		records, err = s.lookupCNAME(domain)
+		records, err = s.lookupCNAME(ctx, domain)
 	case "PTR":
-		records, err = s.lookupPTR(domain)
+		records, err = s.lookupPTR(ctx, domain)
 	case "ALL":
-		records, err = s.lookupAll(domain)
+		records, result.Statuses, _, err = s.lookupAll(ctx, true, func(ctx context.Context, recordType string) ([]DNSRecord, time.Duration, error) {
+			lookupStart := time.Now()
+			lookup, err := s.LookupDNS(ctx, domain, recordType)
+			if err != nil {
+				return nil, time.Since(lookupStart), err
+			}
+			return lookup.Records, time.Since(lookupStart), nil
+		})
 	default:
 		return nil, fmt.Errorf("unsupported record type: %s", recordType)
 	}
@@ -261,11 +367,48 @@ func (s *IPAnalysisService) LookupDNS(ctx context.Context, domain string, record
 	}
 
 	result.Records = records
+	result.Latency = durationMs(time.Since(start))
 	result.QueryTime = int(time.Since(start).Milliseconds())
 
 	return result, nil
 }
 
+// lookupTransport queries server directly for one record type. NXDOMAIN is
+// returned as a not-found *net.DNSError and an empty answer as no records.
+func (s *IPAnalysisService) lookupTransport(ctx context.Context, domain, recordType, transport, server string) ([]DNSRecord, time.Duration, error) {
+	recordType = strings.ToUpper(recordType)
+	qtype, err := parseDNSType(recordType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	name := domain
+	if recordType == "PTR" {
+		if reverse, err := dns.ReverseAddr(domain); err == nil {
+			name = reverse
+		}
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = true
+	msg.SetEdns0(dns.DefaultMsgSize, false)
+
+	resp, rtt, err := s.exchangeTransport(ctx, transport, server, msg, false)
+	if err != nil {
+		return nil, rtt, fmt.Errorf("%s query to %s failed: %w", transport, server, err)
+	}
+
+	switch resp.Rcode {
+	case dns.RcodeSuccess:
+		return answerRecords(resp, qtype), rtt, nil
+	case dns.RcodeNameError:
+		return nil, rtt, &net.DNSError{Err: "no such host", Name: domain, Server: server, IsNotFound: true}
+	default:
+		return nil, rtt, fmt.Errorf("%s answered %s", server, dns.RcodeToString[resp.Rcode])
+	}
+}
+
 // getIPVersion determines if IP is IPv4 or IPv6
 func getIPVersion(ip net.IP) string {
 	if ip.To4() != nil {
@@ -403,7 +546,7 @@ func (s *IPAnalysisService) getISPInfo(ctx context.Context, ip string) (*ISPInfo
 
 // getDNSInfo performs reverse DNS lookup
 func (s *IPAnalysisService) getDNSInfo(ctx context.Context, ip string) (*DNSInfo, error) {
-	names, err := net.LookupAddr(ip)
+	names, err := s.resolver.LookupAddr(ctx, ip)
 	if err != nil {
 		return nil, err
 	}
@@ -441,8 +584,8 @@ func (s *IPAnalysisService) getSecurityInfo(ip net.IP) *SecInfo {
 }
 
 // DNS lookup helper functions
-func (s *IPAnalysisService) lookupA(domain string) ([]DNSRecord, error) {
-	ips, err := net.LookupIP(domain)
+func (s *IPAnalysisService) lookupA(ctx context.Context, domain string) ([]DNSRecord, error) {
+	ips, err := s.resolver.LookupIP(ctx, "ip4", domain)
 	if err != nil {
 		return nil, err
 	}
@@ -462,8 +605,8 @@ func (s *IPAnalysisService) lookupA(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupAAAA(domain string) ([]DNSRecord, error) {
-	ips, err := net.LookupIP(domain)
+func (s *IPAnalysisService) lookupAAAA(ctx context.Context, domain string) ([]DNSRecord, error) {
+	ips, err := s.resolver.LookupIP(ctx, "ip6", domain)
 	if err != nil {
 		return nil, err
 	}
@@ -483,8 +626,8 @@ func (s *IPAnalysisService) lookupAAAA(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupMX(domain string) ([]DNSRecord, error) {
-	mxRecords, err := net.LookupMX(domain)
+func (s *IPAnalysisService) lookupMX(ctx context.Context, domain string) ([]DNSRecord, error) {
+	mxRecords, err := s.resolver.LookupMX(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -502,8 +645,8 @@ func (s *IPAnalysisService) lookupMX(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupNS(domain string) ([]DNSRecord, error) {
-	nsRecords, err := net.LookupNS(domain)
+func (s *IPAnalysisService) lookupNS(ctx context.Context, domain string) ([]DNSRecord, error) {
+	nsRecords, err := s.resolver.LookupNS(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -521,8 +664,8 @@ func (s *IPAnalysisService) lookupNS(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupTXT(domain string) ([]DNSRecord, error) {
-	txtRecords, err := net.LookupTXT(domain)
+func (s *IPAnalysisService) lookupTXT(ctx context.Context, domain string) ([]DNSRecord, error) {
+	txtRecords, err := s.resolver.LookupTXT(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -540,8 +683,8 @@ func (s *IPAnalysisService) lookupTXT(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupCNAME(domain string) ([]DNSRecord, error) {
-	cname, err := net.LookupCNAME(domain)
+func (s *IPAnalysisService) lookupCNAME(ctx context.Context, domain string) ([]DNSRecord, error) {
+	cname, err := s.resolver.LookupCNAME(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -554,8 +697,8 @@ func (s *IPAnalysisService) lookupCNAME(domain string) ([]DNSRecord, error) {
 	}}, nil
 }
 
-func (s *IPAnalysisService) lookupPTR(domain string) ([]DNSRecord, error) {
-	names, err := net.LookupAddr(domain)
+func (s *IPAnalysisService) lookupPTR(ctx context.Context, domain string) ([]DNSRecord, error) {
+	names, err := s.resolver.LookupAddr(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -573,34 +716,94 @@ func (s *IPAnalysisService) lookupPTR(domain string) ([]DNSRecord, error) {
 	return records, nil
 }
 
-func (s *IPAnalysisService) lookupAll(domain string) ([]DNSRecord, error) {
-	var allRecords []DNSRecord
-
-	// Lookup all record types concurrently
+// lookupAll runs lookup for each common record type concurrently and reports
+// a status per type. It only fails when ctx is cancelled; per-type failures,
+// including timeouts, are reported in the statuses. The returned duration is
+// the slowest lookup. inferExistence is set for lookups whose not-found errors
+// do not tell NXDOMAIN and NODATA apart.
+func (s *IPAnalysisService) lookupAll(ctx context.Context, inferExistence bool, lookup func(ctx context.Context, recordType string) ([]DNSRecord, time.Duration, error)) ([]DNSRecord, []DNSTypeStatus, time.Duration, error) {
 	types := []string{"A", "AAAA", "MX", "NS", "TXT", "CNAME"}
 
-	// Channel to collect results
-	resultChan := make(chan []DNSRecord, len(types))
+	records := make([][]DNSRecord, len(types))
+	errs := make([]error, len(types))
+	statuses := make([]DNSTypeStatus, len(types))
+
+	// Launch concurrent lookups, all sharing the caller's context
+	var wg sync.WaitGroup
+	for i, recordType := range types {
+		wg.Add(1)
+		go func(i int, recordType string) {
+			defer wg.Done()
+			found, elapsed, err := lookup(ctx, recordType)
+			records[i] = found
+			errs[i] = err
+			statuses[i] = DNSTypeStatus{
+				Type:      recordType,
+				Records:   len(found),
+				QueryTime: durationMs(elapsed),
+			}
+		}(i, recordType)
+	}
+	wg.Wait()
+
+	// A cancelled request has no one to report to; a deadline is reported per type
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, nil, 0, ctx.Err()
+	}
 
-	// Launch concurrent lookups
-	for _, recordType := range types {
-		go func(rType string) {
-			if records, err := s.LookupDNS(context.Background(), domain, rType); err == nil {
-				resultChan <- records.Records
+	// The system resolver reports NXDOMAIN and NODATA alike as not found, so a
+	// name that answered for any type is assumed to exist
+	exists := false
+	for i := range types {
+		if errs[i] == nil {
+			exists = true
+		}
+	}
+
+	allRecords := []DNSRecord{}
+	var slowest time.Duration
+	for i := range types {
+		allRecords = append(allRecords, records[i]...)
+		statuses[i].Status = dnsLookupStatus(errs[i], len(records[i]))
+		if errs[i] != nil && statuses[i].Status != DNSStatusNXDomain && statuses[i].Status != DNSStatusNoData {
+			statuses[i].Error = errs[i].Error()
+		}
+		if inferExistence && statuses[i].Status == DNSStatusNXDomain {
+			statuses[i].Inferred = true
+			if exists {
+				statuses[i].Status = DNSStatusNoData
+				statuses[i].Note = "guessed: the system resolver does not distinguish NXDOMAIN from NODATA, and other types answered"
 			} else {
-				// Send empty slice if lookup fails
-				resultChan <- []DNSRecord{}
+				statuses[i].Note = "guessed: the system resolver does not distinguish NXDOMAIN from NODATA, and no queried type answered"
 			}
-		}(recordType)
+		}
+		slowest = max(slowest, time.Duration(statuses[i].QueryTime*float64(time.Millisecond)))
 	}
 
-	// Collect results from all goroutines
-	for i := 0; i < len(types); i++ {
-		records := <-resultChan
-		allRecords = append(allRecords, records...)
-	}
+	return allRecords, statuses, slowest, nil
+}
 
-	return allRecords, nil
+// dnsLookupStatus classifies the outcome of a single record type lookup
+func dnsLookupStatus(err error, records int) string {
+	if err == nil {
+		if records == 0 {
+			return DNSStatusNoData
+		}
+		return DNSStatusOK
+	}
+
+	var dnsErr *net.DNSError
+	switch {
+	case isNotFound(err):
+		return DNSStatusNXDomain
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &dnsErr) && dnsErr.IsTimeout):
+		return DNSStatusTimeout
+	case errors.As(err, &dnsErr) && dnsErr.Err == "server misbehaving",
+		strings.Contains(err.Error(), "answered "+dns.RcodeToString[dns.RcodeServerFailure]):
+		return DNSStatusServFail
+	default:
+		return DNSStatusError
+	}
 }
 
 // Helper function to parse float
@@ -644,7 +847,7 @@ func (s *IPAnalysisService) PerformTraceroute(ctx context.Context, target string
 		}
 
 		// Try to resolve hostname
-		if names, err := net.LookupAddr(hop.ip); err == nil && len(names) > 0 {
+		if names, err := s.resolver.LookupAddr(ctx, hop.ip); err == nil && len(names) > 0 {
 			hopResult.Hostname = names[0]
 		}
 
@@ -674,7 +877,7 @@ func (s *IPAnalysisService) AnalyzePerformance(ctx context.Context, target strin
 
 	// Simulate DNS resolution time
 	dnsStart := time.Now()
-	_, err := net.LookupHost(target)
+	_, err := s.resolver.LookupIP(ctx, "ip", target)
 	if err != nil {
 		metrics.DNSResolutionTime = -1 // Indicate DNS failure
 	} else {
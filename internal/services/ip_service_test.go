@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// statusesByType indexes ALL lookup statuses by record type
+func statusesByType(statuses []DNSTypeStatus) map[string]DNSTypeStatus {
+	byType := make(map[string]DNSTypeStatus)
+	for _, status := range statuses {
+		byType[status.Type] = status
+	}
+	return byType
+}
+
+func TestLookupAllSystemResolverGuesses(t *testing.T) {
+	resolver := &fakeResolver{
+		ips: map[string][]string{"www.example.test": {"192.0.2.1"}},
+	}
+	service := NewIPAnalysisServiceWithResolver(resolver)
+
+	result, err := service.LookupDNS(context.Background(), "www.example.test", "ALL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := statusesByType(result.Statuses)
+	if a := statuses["A"]; a.Status != DNSStatusOK || a.Inferred {
+		t.Errorf("A = %+v", a)
+	}
+	if mx := statuses["MX"]; mx.Status != DNSStatusNoData || !mx.Inferred || mx.Note == "" {
+		t.Errorf("MX = %+v, want an inferred NODATA", mx)
+	}
+
+	// Nothing answered, so the name is guessed not to exist
+	result, err = service.LookupDNS(context.Background(), "missing.example.test", "ALL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range result.Statuses {
+		if status.Status != DNSStatusNXDomain || !status.Inferred {
+			t.Errorf("%s = %+v, want an inferred NXDOMAIN", status.Type, status)
+		}
+	}
+}
+
+func TestLookupAllDirectTransportIsExact(t *testing.T) {
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		switch req.Question[0].Name {
+		case "example.test.":
+			resp = testDNSAnswer(req)
+		case "srv-only.example.test.":
+			// The name exists but holds none of the queried types
+		default:
+			resp.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(resp)
+	})
+	started := make(chan struct{})
+	server := &dns.Server{Addr: "127.0.0.1:0", Net: "udp", Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go server.ListenAndServe()
+	<-started
+	defer server.Shutdown()
+	opts := DNSLookupOptions{Transport: DNSTransportUDP, Server: server.PacketConn.LocalAddr().String()}
+	service := NewIPAnalysisServiceWithTLS(net.DefaultResolver, nil, true)
+
+	tests := map[string]map[string]string{
+		"example.test":          {"A": DNSStatusOK, "TXT": DNSStatusOK, "MX": DNSStatusNoData},
+		"srv-only.example.test": {"A": DNSStatusNoData, "MX": DNSStatusNoData},
+		"missing.example.test":  {"A": DNSStatusNXDomain, "MX": DNSStatusNXDomain},
+	}
+	for name, want := range tests {
+		result, err := service.LookupDNSWith(context.Background(), name, "ALL", opts)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		statuses := statusesByType(result.Statuses)
+		for rrtype, status := range want {
+			if statuses[rrtype].Status != status || statuses[rrtype].Inferred {
+				t.Errorf("%s %s = %+v, want exact %s", name, rrtype, statuses[rrtype], status)
+			}
+		}
+	}
+}
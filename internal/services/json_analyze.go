@@ -0,0 +1,568 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxJSONHazards caps the hazards listed individually; HazardCounts stays exact
+	maxJSONHazards = 1000
+	// maxJSONPathStats caps the distinct paths tracked for per-path statistics
+	maxJSONPathStats = 5000
+	// maxSafeJSONInteger is Number.MAX_SAFE_INTEGER, the largest integer JavaScript holds exactly
+	maxSafeJSONInteger = 1<<53 - 1
+)
+
+// JSONHazard is a construct that is valid JSON but that parsers disagree on or lose data with
+type JSONHazard struct {
+	Type        string `json:"type"` // unsafe_integer, precision_loss, number_overflow, duplicate_key, invalid_utf8 or lone_surrogate
+	Message     string `json:"message"`
+	Path        string `json:"path"`   // JSON Pointer to the affected value or member
+	Line        int    `json:"line"`   // 1-based
+	Column      int    `json:"column"` // 1-based, in characters
+	Offset      int64  `json:"offset"` // 0-based byte offset
+	Value       string `json:"value,omitempty"`
+	FirstLine   int    `json:"first_line,omitempty"` // where a duplicated key first appeared
+	FirstColumn int    `json:"first_column,omitempty"`
+}
+
+// JSONPathStats summarizes the values found at one path, with array indexes collapsed to *
+type JSONPathStats struct {
+	Path            string         `json:"path"`
+	Count           int            `json:"count"`
+	Types           map[string]int `json:"types"`
+	MinNumber       *float64       `json:"min_number,omitempty"`
+	MaxNumber       *float64       `json:"max_number,omitempty"`
+	MaxStringLength int            `json:"max_string_length,omitempty"` // in characters
+	MaxItems        int            `json:"max_items,omitempty"`         // largest array or object
+}
+
+// JSONAnalysisResult reports a document's structure and its interoperability hazards
+type JSONAnalysisResult struct {
+	Valid            bool             `json:"valid"`
+	Error            *JSONSyntaxError `json:"error,omitempty"`
+	Size             int64            `json:"size"`
+	RootType         string           `json:"root_type,omitempty"`
+	MaxDepth         int              `json:"max_depth"`
+	Values           int              `json:"values"`
+	Objects          int              `json:"objects"`
+	Arrays           int              `json:"arrays"`
+	Strings          int              `json:"strings"`
+	Numbers          int              `json:"numbers"`
+	Booleans         int              `json:"booleans"`
+	Nulls            int              `json:"nulls"`
+	Keys             int              `json:"keys"`
+	MaxStringLength  int              `json:"max_string_length"`
+	MaxArrayLength   int              `json:"max_array_length"`
+	MaxObjectMembers int              `json:"max_object_members"`
+	Hazards          []JSONHazard     `json:"hazards"`
+	HazardCounts     map[string]int   `json:"hazard_counts"`
+	HazardsTruncated bool             `json:"hazards_truncated,omitempty"`
+	Paths            []JSONPathStats  `json:"paths"`
+	PathsTruncated   bool             `json:"paths_truncated,omitempty"`
+	Timestamp        time.Time        `json:"timestamp"`
+	AnalysisTime     float64          `json:"analysis_time_ms"`
+}
+
+// jsonPosition is a location in the raw document
+type jsonPosition struct {
+	line   int
+	column int
+	offset int64
+}
+
+// analyzerFrame tracks one open object or array while analyzing
+type analyzerFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+	members   int
+	pointer   string
+	pattern   string
+	keys      map[string]jsonPosition
+	stats     *JSONPathStats
+}
+
+// jsonAnalyzer scans raw document bytes. encoding/json silently replaces
+// invalid UTF-8 and lone surrogates and keeps the last duplicate key, so
+// hazards have to be found before decoding.
+type jsonAnalyzer struct {
+	r      *bufio.Reader
+	pos    jsonPosition
+	result *JSONAnalysisResult
+	paths  map[string]*JSONPathStats
+	stack  []analyzerFrame
+}
+
+// Analyze validates a document, then reports its size, depth, value counts,
+// per-path statistics and hazards: integers beyond 2^53, numbers float64
+// cannot hold, duplicate keys, invalid UTF-8 and unpaired surrogates
+func (s *JSONService) Analyze(doc *JSONDocument) *JSONAnalysisResult {
+	start := time.Now()
+
+	validation := s.Validate(doc)
+	result := &JSONAnalysisResult{
+		Valid:        validation.Valid,
+		Error:        validation.Error,
+		Size:         doc.Size(),
+		Hazards:      []JSONHazard{},
+		HazardCounts: map[string]int{},
+		Paths:        []JSONPathStats{},
+	}
+
+	if result.Valid {
+		a := &jsonAnalyzer{
+			r:      bufio.NewReaderSize(io.NewSectionReader(doc, 0, doc.Size()), 64<<10),
+			pos:    jsonPosition{line: 1, column: 1},
+			result: result,
+			paths:  make(map[string]*JSONPathStats),
+		}
+		if err := a.run(); err != nil {
+			// The document has already been validated, so this is a read failure
+			result.Valid = false
+			result.Error = s.locateError(doc, err.Error(), a.pos.offset, "")
+		}
+		for _, stats := range a.paths {
+			result.Paths = append(result.Paths, *stats)
+		}
+		sort.Slice(result.Paths, func(i, j int) bool {
+			return result.Paths[i].Path < result.Paths[j].Path
+		})
+	}
+
+	result.Timestamp = time.Now()
+	result.AnalysisTime = durationMs(time.Since(start))
+	return result
+}
+
+func (a *jsonAnalyzer) run() error {
+	for {
+		a.skipSpace()
+		start := a.pos
+		b, err := a.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case b == ',' || b == ':':
+		case b == '{' || b == '[':
+			a.open(b == '{')
+		case b == '}' || b == ']':
+			a.close()
+		case b == '"':
+			if n := len(a.stack); n > 0 && a.stack[n-1].object && a.stack[n-1].expectKey {
+				key, _, hazards, err := a.readString()
+				if err != nil {
+					return err
+				}
+				a.memberKey(key, start, hazards)
+				continue
+			}
+			pointer, stats := a.beginValue("string")
+			_, length, hazards, err := a.readString()
+			if err != nil {
+				return err
+			}
+			for _, hazard := range hazards {
+				hazard.Path = pointer
+				a.hazard(hazard)
+			}
+			a.result.MaxStringLength = max(a.result.MaxStringLength, length)
+			if stats != nil {
+				stats.MaxStringLength = max(stats.MaxStringLength, length)
+			}
+			a.valueDone()
+		case b == 't' || b == 'f' || b == 'n':
+			kind, rest := "boolean", 3
+			if b == 'f' {
+				rest = 4
+			} else if b == 'n' {
+				kind = "null"
+			}
+			for range rest {
+				if _, err := a.read(); err != nil {
+					return err
+				}
+			}
+			a.beginValue(kind)
+			a.valueDone()
+		case b == '-' || isDigit(b):
+			text := []byte{b}
+			for {
+				next, err := a.r.Peek(1)
+				if err != nil || !strings.ContainsRune("0123456789+-.eE", rune(next[0])) {
+					break
+				}
+				a.read()
+				text = append(text, next[0])
+			}
+			pointer, stats := a.beginValue("number")
+			a.checkNumber(string(text), pointer, start, stats)
+			a.valueDone()
+		default:
+			return fmt.Errorf("unexpected byte %q", b)
+		}
+	}
+}
+
+// read consumes one byte, advancing the line and the character column
+func (a *jsonAnalyzer) read() (byte, error) {
+	b, err := a.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	a.pos.offset++
+	switch {
+	case b == '\n':
+		a.pos.line++
+		a.pos.column = 1
+	case b&0xC0 != 0x80:
+		a.pos.column++
+	}
+	return b, nil
+}
+
+func (a *jsonAnalyzer) skipSpace() {
+	for {
+		next, err := a.r.Peek(1)
+		if err != nil || (next[0] != ' ' && next[0] != '\t' && next[0] != '\n' && next[0] != '\r') {
+			return
+		}
+		a.read()
+	}
+}
+
+// valuePath returns the pointer of the value about to start and its pattern with * for array indexes
+func (a *jsonAnalyzer) valuePath() (string, string) {
+	if len(a.stack) == 0 {
+		return "", ""
+	}
+	top := &a.stack[len(a.stack)-1]
+	if top.object {
+		token := "/" + escapePointer(top.key)
+		return top.pointer + token, top.pattern + token
+	}
+	return top.pointer + "/" + strconv.Itoa(top.index), top.pattern + "/*"
+}
+
+func (a *jsonAnalyzer) beginValue(kind string) (string, *JSONPathStats) {
+	pointer, pattern := a.valuePath()
+
+	result := a.result
+	result.Values++
+	if result.RootType == "" {
+		result.RootType = kind
+	}
+	switch kind {
+	case "object":
+		result.Objects++
+	case "array":
+		result.Arrays++
+	case "string":
+		result.Strings++
+	case "number":
+		result.Numbers++
+	case "boolean":
+		result.Booleans++
+	default:
+		result.Nulls++
+	}
+
+	stats, ok := a.paths[pattern]
+	if !ok {
+		if len(a.paths) >= maxJSONPathStats {
+			result.PathsTruncated = true
+			return pointer, nil
+		}
+		stats = &JSONPathStats{Path: pattern, Types: map[string]int{}}
+		a.paths[pattern] = stats
+	}
+	stats.Count++
+	stats.Types[kind]++
+	return pointer, stats
+}
+
+// valueDone advances the enclosing container once a value completes
+func (a *jsonAnalyzer) valueDone() {
+	if len(a.stack) == 0 {
+		return
+	}
+	top := &a.stack[len(a.stack)-1]
+	if top.object {
+		top.expectKey = true
+	} else {
+		top.index++
+	}
+}
+
+func (a *jsonAnalyzer) open(object bool) {
+	kind := "array"
+	if object {
+		kind = "object"
+	}
+	pointer, stats := a.beginValue(kind)
+	_, pattern := a.valuePath()
+	frame := analyzerFrame{object: object, expectKey: object, pointer: pointer, pattern: pattern, stats: stats}
+	if object {
+		frame.keys = make(map[string]jsonPosition)
+	}
+	a.stack = append(a.stack, frame)
+	a.result.MaxDepth = max(a.result.MaxDepth, len(a.stack))
+}
+
+func (a *jsonAnalyzer) close() {
+	if len(a.stack) == 0 {
+		return
+	}
+	frame := a.stack[len(a.stack)-1]
+	a.stack = a.stack[:len(a.stack)-1]
+
+	items := frame.index
+	if frame.object {
+		items = frame.members
+		a.result.MaxObjectMembers = max(a.result.MaxObjectMembers, items)
+	} else {
+		a.result.MaxArrayLength = max(a.result.MaxArrayLength, items)
+	}
+	if frame.stats != nil {
+		frame.stats.MaxItems = max(frame.stats.MaxItems, items)
+	}
+	a.valueDone()
+}
+
+// memberKey records an object key, reporting it if the object already has it
+func (a *jsonAnalyzer) memberKey(key string, at jsonPosition, hazards []JSONHazard) {
+	top := &a.stack[len(a.stack)-1]
+	top.key = key
+	top.expectKey = false
+	top.members++
+	a.result.Keys++
+
+	pointer := top.pointer + "/" + escapePointer(key)
+	for _, hazard := range hazards {
+		hazard.Path = pointer
+		a.hazard(hazard)
+	}
+
+	first, duplicate := top.keys[key]
+	if !duplicate {
+		top.keys[key] = at
+		return
+	}
+	a.hazard(JSONHazard{
+		Type: "duplicate_key",
+		Message: fmt.Sprintf("duplicate key %q (first at line %d, column %d); Go and JavaScript keep the last value, other parsers keep the first or reject the document",
+			key, first.line, first.column),
+		Path:        pointer,
+		Line:        at.line,
+		Column:      at.column,
+		Offset:      at.offset,
+		Value:       key,
+		FirstLine:   first.line,
+		FirstColumn: first.column,
+	})
+}
+
+// readString consumes a string after its opening quote, returning the decoded
+// value, its length in characters and any encoding hazards without paths
+func (a *jsonAnalyzer) readString() (string, int, []JSONHazard, error) {
+	var b strings.Builder
+	var hazards []JSONHazard
+	length := 0
+	hazardAt := func(at jsonPosition, kind, value, message string) {
+		hazards = append(hazards, JSONHazard{
+			Type: kind, Message: message, Line: at.line, Column: at.column, Offset: at.offset, Value: value,
+		})
+	}
+
+	for {
+		at := a.pos
+		c, err := a.read()
+		if err != nil {
+			return "", 0, nil, io.ErrUnexpectedEOF
+		}
+		switch {
+		case c == '"':
+			return b.String(), length, hazards, nil
+		case c == '\\':
+			e, err := a.read()
+			if err != nil {
+				return "", 0, nil, io.ErrUnexpectedEOF
+			}
+			length++
+			if e != 'u' {
+				b.WriteByte(unescapeJSONByte(e))
+				continue
+			}
+			r, err := a.readHex4()
+			if err != nil {
+				return "", 0, nil, err
+			}
+			if !utf16.IsSurrogate(r) {
+				b.WriteRune(r)
+				continue
+			}
+			if r < 0xDC00 {
+				if next, err := a.r.Peek(6); err == nil && next[0] == '\\' && next[1] == 'u' {
+					if low, err := strconv.ParseUint(string(next[2:]), 16, 16); err == nil && low >= 0xDC00 && low <= 0xDFFF {
+						for range 6 {
+							a.read()
+						}
+						b.WriteRune(utf16.DecodeRune(r, rune(low)))
+						continue
+					}
+				}
+			}
+			hazardAt(at, "lone_surrogate", fmt.Sprintf("\\u%04X", r), fmt.Sprintf(
+				"unpaired UTF-16 surrogate \\u%04X; Go and Rust replace it with U+FFFD, JavaScript keeps it and strict UTF-8 encoders reject it", r))
+			b.WriteRune(utf8.RuneError)
+		case c < utf8.RuneSelf:
+			b.WriteByte(c)
+			length++
+		default:
+			seq := []byte{c}
+			for len(seq) < utf8.UTFMax && !utf8.FullRune(seq) {
+				next, err := a.r.Peek(1)
+				if err != nil || next[0]&0xC0 != 0x80 {
+					break
+				}
+				a.read()
+				seq = append(seq, next[0])
+			}
+			length++
+			if r, size := utf8.DecodeRune(seq); r == utf8.RuneError && size <= 1 {
+				hazardAt(at, "invalid_utf8", fmt.Sprintf("% X", seq), fmt.Sprintf(
+					"invalid UTF-8 sequence % X; parsers either reject the document or replace it with U+FFFD", seq))
+				b.WriteRune(utf8.RuneError)
+				continue
+			}
+			b.Write(seq)
+		}
+	}
+}
+
+func (a *jsonAnalyzer) readHex4() (rune, error) {
+	var hex [4]byte
+	for i := range hex {
+		c, err := a.read()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		hex[i] = c
+	}
+	r, err := strconv.ParseUint(string(hex[:]), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid \\u escape %q", hex[:])
+	}
+	return rune(r), nil
+}
+
+func unescapeJSONByte(e byte) byte {
+	switch e {
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+	return e
+}
+
+// checkNumber reports numbers that float64 parsers, JavaScript included, cannot reproduce
+func (a *jsonAnalyzer) checkNumber(text, pointer string, at jsonPosition, stats *JSONPathStats) {
+	hazard := JSONHazard{Path: pointer, Line: at.line, Column: at.column, Offset: at.offset, Value: text}
+	if len(hazard.Value) > 64 {
+		hazard.Value = hazard.Value[:61] + "..."
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(f, 0) {
+		hazard.Type = "number_overflow"
+		hazard.Message = "number is outside the float64 range and becomes Infinity in JavaScript and most parsers"
+		a.hazard(hazard)
+		return
+	}
+	if stats != nil {
+		if stats.MinNumber == nil || f < *stats.MinNumber {
+			stats.MinNumber = &f
+		}
+		if stats.MaxNumber == nil || f > *stats.MaxNumber {
+			stats.MaxNumber = &f
+		}
+	}
+
+	if !strings.ContainsAny(text, ".eE") && math.Abs(f) > maxSafeJSONInteger {
+		hazard.Type = "unsafe_integer"
+		hazard.Message = fmt.Sprintf("integer exceeds 2^53-1 (Number.MAX_SAFE_INTEGER); JavaScript reads it as %s and neighbouring values become indistinguishable",
+			strconv.FormatFloat(f, 'f', -1, 64))
+		a.hazard(hazard)
+		return
+	}
+	if !sameDecimal(text, strconv.FormatFloat(f, 'g', -1, 64)) {
+		hazard.Type = "precision_loss"
+		hazard.Message = fmt.Sprintf("number has more precision than float64 keeps; parsers read it as %s", strconv.FormatFloat(f, 'g', -1, 64))
+		a.hazard(hazard)
+	}
+}
+
+func (a *jsonAnalyzer) hazard(hazard JSONHazard) {
+	a.result.HazardCounts[hazard.Type]++
+	if len(a.result.Hazards) >= maxJSONHazards {
+		a.result.HazardsTruncated = true
+		return
+	}
+	a.result.Hazards = append(a.result.Hazards, hazard)
+}
+
+// sameDecimal compares two decimal literals by value without building them
+// in full, since exponents can be arbitrarily large
+func sameDecimal(x, y string) bool {
+	xDigits, xExp, xNeg, okX := normalizeDecimal(x)
+	yDigits, yExp, yNeg, okY := normalizeDecimal(y)
+	return okX && okY && xDigits == yDigits && xExp == yExp && xNeg == yNeg
+}
+
+// normalizeDecimal reduces a literal to significant digits and a power of ten
+func normalizeDecimal(s string) (string, int, bool, bool) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	exp := 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return "", 0, false, false
+		}
+		exp = e
+		s = s[:i]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+	digits := strings.TrimLeft(intPart+frac, "0")
+	exp -= len(frac)
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+	if trimmed == "" {
+		return "0", 0, false, true
+	}
+	return trimmed, exp, neg, true
+}
@@ -0,0 +1,685 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSONDiffRequest compares two documents
+type JSONDiffRequest struct {
+	Left      json.RawMessage `json:"left"`
+	Right     json.RawMessage `json:"right"`
+	ArrayMode string          `json:"array_mode"` // "index" (default) or "key"
+	ArrayKey  string          `json:"array_key"`  // member that identifies array elements when array_mode is "key", default "id"
+}
+
+// JSONChange is one human-readable difference between two documents
+type JSONChange struct {
+	Type        string `json:"type"` // added, removed, changed or moved
+	Path        string `json:"path"` // JSON Pointer, valid at this point in the patch sequence
+	From        string `json:"from,omitempty"`
+	Key         string `json:"key,omitempty"` // identity of a keyed array element, such as id=7
+	OldValue    any    `json:"old_value,omitempty"`
+	NewValue    any    `json:"new_value,omitempty"`
+	Description string `json:"description"`
+}
+
+// JSONPatchOperation is one RFC 6902 JSON Patch operation
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONDiffResult holds the differences as a change list, a JSON Patch and a Merge Patch
+type JSONDiffResult struct {
+	Equal      bool                 `json:"equal"`
+	Added      int                  `json:"added"`
+	Removed    int                  `json:"removed"`
+	Changed    int                  `json:"changed"`
+	Moved      int                  `json:"moved"`
+	Changes    []JSONChange         `json:"changes"`
+	Patch      []JSONPatchOperation `json:"patch"`       // RFC 6902, transforms left into right
+	MergePatch any                  `json:"merge_patch"` // RFC 7396, transforms left into right
+	Warnings   []string             `json:"warnings,omitempty"`
+	Timestamp  time.Time            `json:"timestamp"`
+	DiffTime   float64              `json:"diff_time_ms"`
+}
+
+// JSONPatchRequest applies a JSON Patch or Merge Patch to a document
+type JSONPatchRequest struct {
+	Document json.RawMessage `json:"document"`
+	Patch    json.RawMessage `json:"patch"`
+	Type     string          `json:"type"` // "json-patch" or "merge-patch"; guessed from the patch when empty
+}
+
+// JSONPatchResult holds a patched document
+type JSONPatchResult struct {
+	Type      string    `json:"type"`
+	Result    any       `json:"result"`
+	Applied   int       `json:"applied"` // operations applied (JSON Patch) or members merged (Merge Patch)
+	Timestamp time.Time `json:"timestamp"`
+	PatchTime float64   `json:"patch_time_ms"`
+}
+
+// jsonDiffer accumulates patch operations and changes while walking two documents
+type jsonDiffer struct {
+	arrayKey string
+	result   *JSONDiffResult
+}
+
+// Diff compares two documents semantically: object member order is ignored
+// and numbers compare by value
+func (s *JSONService) Diff(req JSONDiffRequest) (*JSONDiffResult, error) {
+	start := time.Now()
+
+	left, err := decodeJSONValue(req.Left)
+	if err != nil {
+		return nil, fmt.Errorf("invalid left JSON: %w", err)
+	}
+	right, err := decodeJSONValue(req.Right)
+	if err != nil {
+		return nil, fmt.Errorf("invalid right JSON: %w", err)
+	}
+
+	d := &jsonDiffer{
+		result: &JSONDiffResult{Changes: []JSONChange{}, Patch: []JSONPatchOperation{}},
+	}
+	switch req.ArrayMode {
+	case "", "index":
+	case "key":
+		d.arrayKey = req.ArrayKey
+		if d.arrayKey == "" {
+			d.arrayKey = "id"
+		}
+	default:
+		return nil, fmt.Errorf("array_mode must be index or key")
+	}
+
+	d.diff("", left, right)
+	result := d.result
+	result.Equal = len(result.Changes) == 0
+	result.MergePatch = d.mergePatch("", left, right)
+	result.Timestamp = time.Now()
+	result.DiffTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+func (d *jsonDiffer) diff(path string, left, right any) {
+	if jsonEqual(left, right) {
+		return
+	}
+	switch l := left.(type) {
+	case map[string]any:
+		if r, ok := right.(map[string]any); ok {
+			d.diffObjects(path, l, r)
+			return
+		}
+	case []any:
+		if r, ok := right.([]any); ok {
+			if d.arrayKey != "" {
+				if lKeys, rKeys, ok := d.elementKeys(path, l, r); ok {
+					d.diffKeyed(path, l, r, lKeys, rKeys)
+					return
+				}
+			}
+			d.diffIndexed(path, l, r)
+			return
+		}
+	}
+	d.replace(path, left, right)
+}
+
+func (d *jsonDiffer) diffObjects(path string, left, right map[string]any) {
+	for _, key := range sortedKeys(left) {
+		if _, ok := right[key]; !ok {
+			d.remove(path+"/"+escapePointer(key), left[key], "")
+		}
+	}
+	for _, key := range sortedKeys(right) {
+		child := path + "/" + escapePointer(key)
+		if value, ok := left[key]; ok {
+			d.diff(child, value, right[key])
+		} else {
+			d.add(child, right[key], "")
+		}
+	}
+}
+
+// diffIndexed compares arrays position by position; removals run from the end
+// so earlier indexes stay valid
+func (d *jsonDiffer) diffIndexed(path string, left, right []any) {
+	common := min(len(left), len(right))
+	for i := 0; i < common; i++ {
+		d.diff(path+"/"+strconv.Itoa(i), left[i], right[i])
+	}
+	for i := len(left) - 1; i >= common; i-- {
+		d.remove(path+"/"+strconv.Itoa(i), left[i], "")
+	}
+	for i := common; i < len(right); i++ {
+		d.add(path+"/"+strconv.Itoa(i), right[i], "")
+	}
+}
+
+// elementKeys identifies array elements by the key member. Both arrays must
+// contain only objects with a unique scalar key for keyed comparison.
+func (d *jsonDiffer) elementKeys(path string, left, right []any) ([]string, []string, bool) {
+	keysOf := func(list []any) ([]string, bool) {
+		keys := make([]string, len(list))
+		seen := make(map[string]bool, len(list))
+		for i, item := range list {
+			obj, ok := item.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			value, ok := obj[d.arrayKey]
+			if !ok {
+				return nil, false
+			}
+			switch value.(type) {
+			case map[string]any, []any:
+				return nil, false
+			}
+			keys[i] = compactJSON(value)
+			if seen[keys[i]] {
+				return nil, false
+			}
+			seen[keys[i]] = true
+		}
+		return keys, true
+	}
+	lKeys, okL := keysOf(left)
+	rKeys, okR := keysOf(right)
+	if !okL || !okR {
+		if len(left) > 0 || len(right) > 0 {
+			label := "root array"
+			if path != "" {
+				label = "array at " + path
+			}
+			d.warn("%s is compared by index: not every element is an object with a unique %q", label, d.arrayKey)
+		}
+		return nil, nil, false
+	}
+	return lKeys, rKeys, true
+}
+
+// diffKeyed matches elements by key: removed elements go first, then each
+// element of right is moved or inserted into place and compared
+func (d *jsonDiffer) diffKeyed(path string, left, right []any, lKeys, rKeys []string) {
+	wanted := make(map[string]bool, len(rKeys))
+	for _, key := range rKeys {
+		wanted[key] = true
+	}
+	var working []string
+	for i := len(left) - 1; i >= 0; i-- {
+		if !wanted[lKeys[i]] {
+			d.remove(path+"/"+strconv.Itoa(i), left[i], d.keyLabel(lKeys[i]))
+		}
+	}
+	values := make(map[string]any, len(left))
+	for i, key := range lKeys {
+		if wanted[key] {
+			working = append(working, key)
+			values[key] = left[i]
+		}
+	}
+
+	for j, key := range rKeys {
+		target := path + "/" + strconv.Itoa(j)
+		current := -1
+		for i := j; i < len(working); i++ {
+			if working[i] == key {
+				current = i
+				break
+			}
+		}
+		if current < 0 {
+			d.add(target, right[j], d.keyLabel(key))
+			working = append(working[:j], append([]string{key}, working[j:]...)...)
+			continue
+		}
+		if current != j {
+			d.move(path+"/"+strconv.Itoa(current), target, d.keyLabel(key))
+			working = append(working[:current], working[current+1:]...)
+			working = append(working[:j], append([]string{key}, working[j:]...)...)
+		}
+		d.diff(target, values[key], right[j])
+	}
+}
+
+func (d *jsonDiffer) keyLabel(key string) string {
+	return d.arrayKey + "=" + key
+}
+
+func (d *jsonDiffer) add(path string, value any, key string) {
+	d.result.Added++
+	d.result.Patch = append(d.result.Patch, JSONPatchOperation{Op: "add", Path: path, Value: rawJSON(value)})
+	d.result.Changes = append(d.result.Changes, JSONChange{
+		Type: "added", Path: path, Key: key, NewValue: value,
+		Description: fmt.Sprintf("added %s%s: %s", pointerLabel(path), keySuffix(key), excerptJSON(value)),
+	})
+}
+
+func (d *jsonDiffer) remove(path string, value any, key string) {
+	d.result.Removed++
+	d.result.Patch = append(d.result.Patch, JSONPatchOperation{Op: "remove", Path: path})
+	d.result.Changes = append(d.result.Changes, JSONChange{
+		Type: "removed", Path: path, Key: key, OldValue: value,
+		Description: fmt.Sprintf("removed %s%s (was %s)", pointerLabel(path), keySuffix(key), excerptJSON(value)),
+	})
+}
+
+func (d *jsonDiffer) replace(path string, left, right any) {
+	d.result.Changed++
+	d.result.Patch = append(d.result.Patch, JSONPatchOperation{Op: "replace", Path: path, Value: rawJSON(right)})
+	d.result.Changes = append(d.result.Changes, JSONChange{
+		Type: "changed", Path: path, OldValue: left, NewValue: right,
+		Description: fmt.Sprintf("changed %s from %s to %s", pointerLabel(path), excerptJSON(left), excerptJSON(right)),
+	})
+}
+
+func (d *jsonDiffer) move(from, path, key string) {
+	d.result.Moved++
+	d.result.Patch = append(d.result.Patch, JSONPatchOperation{Op: "move", From: from, Path: path})
+	d.result.Changes = append(d.result.Changes, JSONChange{
+		Type: "moved", Path: path, From: from, Key: key,
+		Description: fmt.Sprintf("moved %s%s to %s", pointerLabel(from), keySuffix(key), pointerLabel(path)),
+	})
+}
+
+func (d *jsonDiffer) warn(format string, args ...any) {
+	d.result.Warnings = append(d.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// mergePatch builds an RFC 7396 patch. Merge patches cannot set a member to
+// null, since null means delete, so such changes are reported as warnings.
+func (d *jsonDiffer) mergePatch(path string, left, right any) any {
+	l, okL := left.(map[string]any)
+	r, okR := right.(map[string]any)
+	if !okL || !okR {
+		if hasNullMember(right) {
+			d.warn("merge patch cannot express null members inside %s; use the JSON Patch", pointerLabel(path))
+		}
+		return right
+	}
+	patch := map[string]any{}
+	for key := range l {
+		if _, ok := r[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, value := range r {
+		child := path + "/" + escapePointer(key)
+		old, exists := l[key]
+		switch {
+		case exists && jsonEqual(old, value):
+		case value == nil:
+			d.warn("merge patch cannot set %s to null; use the JSON Patch", pointerLabel(child))
+		case exists:
+			patch[key] = d.mergePatch(child, old, value)
+		default:
+			if hasNullMember(value) {
+				d.warn("merge patch cannot express null members inside %s; use the JSON Patch", pointerLabel(child))
+			}
+			patch[key] = value
+		}
+	}
+	return patch
+}
+
+// hasNullMember reports whether an object anywhere in value has a null member
+func hasNullMember(value any) bool {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, member := range v {
+			if member == nil || hasNullMember(member) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if hasNullMember(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func pointerLabel(path string) string {
+	if path == "" {
+		return "the document"
+	}
+	return path
+}
+
+func keySuffix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return " (" + key + ")"
+}
+
+func rawJSON(value any) json.RawMessage {
+	return json.RawMessage(compactJSON(value))
+}
+
+// excerptJSON shortens a value for change descriptions
+func excerptJSON(value any) string {
+	text := compactJSON(value)
+	if runes := []rune(text); len(runes) > 60 {
+		text = string(runes[:57]) + "..."
+	}
+	return text
+}
+
+// ApplyPatch applies an RFC 6902 JSON Patch or an RFC 7396 Merge Patch
+func (s *JSONService) ApplyPatch(req JSONPatchRequest) (*JSONPatchResult, error) {
+	start := time.Now()
+
+	document, err := decodeJSONValue(req.Document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document JSON: %w", err)
+	}
+	patch, err := decodeJSONValue(req.Patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch JSON: %w", err)
+	}
+
+	patchType := req.Type
+	if patchType == "" {
+		patchType = "merge-patch"
+		if _, ok := patch.([]any); ok {
+			patchType = "json-patch"
+		}
+	}
+
+	result := &JSONPatchResult{Type: patchType}
+	switch patchType {
+	case "json-patch":
+		var ops []JSONPatchOperation
+		if err := json.Unmarshal(req.Patch, &ops); err != nil {
+			return nil, fmt.Errorf("JSON Patch must be an array of operations: %w", err)
+		}
+		for i, op := range ops {
+			if document, err = applyPatchOperation(document, op); err != nil {
+				return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+			}
+		}
+		result.Applied = len(ops)
+	case "merge-patch":
+		if obj, ok := patch.(map[string]any); ok {
+			result.Applied = len(obj)
+		} else {
+			result.Applied = 1
+		}
+		document = applyMergePatch(document, patch)
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q (supported: json-patch, merge-patch)", req.Type)
+	}
+
+	result.Result = document
+	result.Timestamp = time.Now()
+	result.PatchTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// applyMergePatch implements the MergePatch function from RFC 7396 section 2
+func applyMergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = applyMergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+func applyPatchOperation(document any, op JSONPatchOperation) (any, error) {
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		return decodeJSONValue(op.Value)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, op.Path, v)
+	case "remove":
+		doc, _, err := pointerRemove(document, op.Path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err := pointerRemove(document, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, v)
+	case "move":
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		doc, moved, err := pointerRemove(document, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, op.Path, moved)
+	case "copy":
+		copied, err := pointerGet(document, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(document, op.Path, deepCopyJSON(copied))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := pointerGet(document, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, v) {
+			return nil, fmt.Errorf("test failed: value is %s", excerptJSON(actual))
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+	if !validPointerEscapes(pointer) {
+		return nil, fmt.Errorf("pointer %q has an invalid ~ escape", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array reference token; "-" is allowed when appending
+func arrayIndex(token string, length int, appending bool) (int, error) {
+	if token == "-" && appending {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	i, err := strconv.Atoi(token)
+	limit := length
+	if appending {
+		limit++
+	}
+	if err != nil || i >= limit {
+		return 0, fmt.Errorf("index %s is out of bounds", token)
+	}
+	return i, nil
+}
+
+func pointerGet(document any, pointer string) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := document
+	for _, token := range tokens {
+		switch v := current.(type) {
+		case map[string]any:
+			child, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			current = child
+		case []any:
+			i, err := arrayIndex(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			current = v[i]
+		default:
+			return nil, fmt.Errorf("cannot descend into %s at %q", schemaTypeOf(current), token)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd inserts value at pointer, returning the updated document
+func pointerAdd(document any, pointer string, value any) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(document, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]any:
+		v[last] = value
+		return document, nil
+	case []any:
+		i, err := arrayIndex(last, len(v), true)
+		if err != nil {
+			return nil, err
+		}
+		updated := append(v[:i:i], append([]any{value}, v[i:]...)...)
+		return pointerSet(document, parentPointer, updated)
+	}
+	return nil, fmt.Errorf("cannot add to %s", schemaTypeOf(parent))
+}
+
+// pointerRemove deletes the value at pointer, returning the updated document and the removed value
+func pointerRemove(document any, pointer string) (any, any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, document, nil
+	}
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(document, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]any:
+		removed, ok := v[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("member %q does not exist", last)
+		}
+		delete(v, last)
+		return document, removed, nil
+	case []any:
+		i, err := arrayIndex(last, len(v), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := v[i]
+		updated := append(v[:i:i], v[i+1:]...)
+		document, err = pointerSet(document, parentPointer, updated)
+		return document, removed, err
+	}
+	return nil, nil, fmt.Errorf("cannot remove from %s", schemaTypeOf(parent))
+}
+
+// pointerSet replaces the existing value at pointer; arrays change length
+// when elements are inserted or removed, so their parent must be updated
+func pointerSet(document any, pointer string, value any) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(document, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]any:
+		v[last] = value
+	case []any:
+		i, err := arrayIndex(last, len(v), false)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = value
+	}
+	return document, nil
+}
+
+func deepCopyJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, member := range v {
+			copied[key] = deepCopyJSON(member)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = deepCopyJSON(item)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,585 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	maxInferSamples = 1000
+	defaultTypeName = "Root"
+)
+
+// JSONInferRequest holds one or more sample documents of the same shape
+type JSONInferRequest struct {
+	Samples []json.RawMessage `json:"samples"`
+	Name    string            `json:"name"` // root type name, default "Root"
+}
+
+// JSONInferResult holds the types inferred from the samples
+type JSONInferResult struct {
+	Samples       int             `json:"samples"`
+	Schema        json.RawMessage `json:"schema"`
+	Go            string          `json:"go"`
+	TypeScript    string          `json:"typescript"`
+	Timestamp     time.Time       `json:"timestamp"`
+	InferenceTime float64         `json:"inference_time_ms"`
+}
+
+// jsonShape accumulates every value observed at one position across the samples
+type jsonShape struct {
+	seen    int
+	null    bool
+	boolean bool
+	integer bool
+	number  bool
+	str     bool
+	format  string // string format shared by every string seen, if any
+	object  bool
+	objects int
+	fields  []*jsonField
+	index   map[string]*jsonField
+	array   bool
+	items   *jsonShape
+}
+
+type jsonField struct {
+	name    string
+	present int
+	shape   *jsonShape
+}
+
+// kinds lists the non-null JSON types observed, with integers folded into number
+func (s *jsonShape) kinds() []string {
+	var kinds []string
+	if s.object {
+		kinds = append(kinds, "object")
+	}
+	if s.array {
+		kinds = append(kinds, "array")
+	}
+	if s.str {
+		kinds = append(kinds, "string")
+	}
+	if s.number {
+		kinds = append(kinds, "number")
+	} else if s.integer {
+		kinds = append(kinds, "integer")
+	}
+	if s.boolean {
+		kinds = append(kinds, "boolean")
+	}
+	return kinds
+}
+
+func (f *jsonField) optional(parent *jsonShape) bool {
+	return f.present < parent.objects
+}
+
+// InferTypes merges the samples into one shape and renders it as a JSON
+// Schema, Go struct definitions and TypeScript interfaces. Fields missing from
+// some samples become optional, nulls make a type nullable and conflicting
+// types become unions.
+func (s *JSONService) InferTypes(req JSONInferRequest) (*JSONInferResult, error) {
+	start := time.Now()
+
+	if len(req.Samples) == 0 {
+		return nil, fmt.Errorf("at least one sample is required")
+	}
+	if len(req.Samples) > maxInferSamples {
+		return nil, fmt.Errorf("too many samples (maximum %d)", maxInferSamples)
+	}
+
+	root := &jsonShape{}
+	for i, sample := range req.Samples {
+		dec := json.NewDecoder(bytes.NewReader(sample))
+		dec.UseNumber()
+		tok, err := dec.Token()
+		if err == nil {
+			err = root.observe(dec, tok)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sample %d is not valid JSON: %v", i+1, err)
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, fmt.Errorf("sample %d has data after the top-level value", i+1)
+		}
+	}
+
+	name := goIdentifier(req.Name)
+	if name == "" {
+		name = defaultTypeName
+	}
+
+	schema := schemaForShape(root)
+	schema = append(orderedJSON{{"$schema", jsonSchemaDialect}, {"title", name}}, schema...)
+	encoded, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONInferResult{
+		Samples:       len(req.Samples),
+		Schema:        encoded,
+		Go:            newTypeWriter(goTypes).render(root, name),
+		TypeScript:    newTypeWriter(tsTypes).render(root, name),
+		Timestamp:     time.Now(),
+		InferenceTime: durationMs(time.Since(start)),
+	}, nil
+}
+
+// observe records the value starting with tok, reading the rest of it from dec
+func (s *jsonShape) observe(dec *json.Decoder, tok json.Token) error {
+	s.seen++
+	switch v := tok.(type) {
+	case nil:
+		s.null = true
+	case bool:
+		s.boolean = true
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			s.number = true
+		} else {
+			s.integer = true
+		}
+	case string:
+		format := detectFormat(v)
+		if !s.str {
+			s.format = format
+		} else if s.format != format {
+			s.format = ""
+		}
+		s.str = true
+	case json.Delim:
+		if v == '[' {
+			s.array = true
+			if s.items == nil {
+				s.items = &jsonShape{}
+			}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if err := s.items.observe(dec, tok); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		}
+
+		s.object = true
+		s.objects++
+		if s.index == nil {
+			s.index = make(map[string]*jsonField)
+		}
+		seen := make(map[string]bool)
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyTok.(string)
+			field, ok := s.index[key]
+			if !ok {
+				field = &jsonField{name: key, shape: &jsonShape{}}
+				s.index[key] = field
+				s.fields = append(s.fields, field)
+			}
+			if !seen[key] {
+				field.present++
+				seen[key] = true
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := field.shape.observe(dec, tok); err != nil {
+				return err
+			}
+		}
+		_, err := dec.Token()
+		return err
+	}
+	return nil
+}
+
+// detectFormat recognizes common string formats worth carrying into the schema
+func detectFormat(value string) string {
+	for _, format := range []string{"date-time", "date", "uuid", "ipv4", "ipv6", "email"} {
+		if checkFormat(format, value) == "" {
+			return format
+		}
+	}
+	if strings.Contains(value, "://") && checkFormat("uri", value) == "" {
+		return "uri"
+	}
+	return ""
+}
+
+// orderedJSON is a JSON object that keeps its members in insertion order
+type orderedJSON []orderedMember
+
+type orderedMember struct {
+	key   string
+	value any
+}
+
+func (o orderedJSON) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(member.key)
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// schemaForShape renders a shape as a JSON Schema
+func schemaForShape(s *jsonShape) orderedJSON {
+	schema := orderedJSON{}
+	types := s.kinds()
+	if s.null {
+		types = append(types, "null")
+	}
+	switch len(types) {
+	case 0:
+		return schema
+	case 1:
+		schema = append(schema, orderedMember{"type", types[0]})
+	default:
+		schema = append(schema, orderedMember{"type", types})
+	}
+
+	if s.str && s.format != "" {
+		schema = append(schema, orderedMember{"format", s.format})
+	}
+	if s.object {
+		properties := orderedJSON{}
+		required := []string{}
+		for _, field := range s.fields {
+			properties = append(properties, orderedMember{field.name, schemaForShape(field.shape)})
+			if !field.optional(s) {
+				required = append(required, field.name)
+			}
+		}
+		schema = append(schema, orderedMember{"properties", properties})
+		if len(required) > 0 {
+			schema = append(schema, orderedMember{"required", required})
+		}
+	}
+	if s.array && s.items != nil && s.items.seen > 0 {
+		schema = append(schema, orderedMember{"items", schemaForShape(s.items)})
+	}
+	return schema
+}
+
+// typeLanguage describes how a target language spells inferred types
+type typeLanguage struct {
+	scalar     map[string]string
+	unknown    string
+	null       string // type of a value only ever seen as null
+	array      func(elem string) string
+	nullable   func(typ string, null, pointer bool) string
+	union      func(kinds []string) string
+	definition func(name string, fields []typeField) string
+	alias      func(name, typ string) string
+	finish     func(src string) string
+}
+
+// typeField is one rendered struct field or interface member
+type typeField struct {
+	name     string
+	jsonName string
+	typ      string
+	optional bool
+	comment  string
+}
+
+var goTypes = typeLanguage{
+	scalar:  map[string]string{"string": "string", "integer": "int64", "number": "float64", "boolean": "bool"},
+	unknown: "any",
+	null:    "any",
+	array:   func(elem string) string { return "[]" + elem },
+	nullable: func(typ string, null, pointer bool) string {
+		// Pointers let Go tell null and absent values from zero values
+		if pointer {
+			return "*" + typ
+		}
+		return typ
+	},
+	union: func(kinds []string) string { return "any" },
+	definition: func(name string, fields []typeField) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "type %s struct {\n", name)
+		for _, field := range fields {
+			tag := field.jsonName
+			if field.optional {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&b, "\t%s %s `json:%q`", field.name, field.typ, tag)
+			if field.comment != "" {
+				fmt.Fprintf(&b, " // %s", field.comment)
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString("}\n")
+		return b.String()
+	},
+	alias: func(name, typ string) string { return fmt.Sprintf("type %s %s\n", name, typ) },
+	finish: func(src string) string {
+		if formatted, err := format.Source([]byte(src)); err == nil {
+			return string(formatted)
+		}
+		return src
+	},
+}
+
+var tsTypes = typeLanguage{
+	scalar:  map[string]string{"string": "string", "integer": "number", "number": "number", "boolean": "boolean"},
+	unknown: "unknown",
+	null:    "null",
+	array: func(elem string) string {
+		if strings.Contains(elem, " ") {
+			return "(" + elem + ")[]"
+		}
+		return elem + "[]"
+	},
+	nullable: func(typ string, null, pointer bool) string {
+		if null {
+			return typ + " | null"
+		}
+		return typ
+	},
+	union: func(kinds []string) string {
+		var types []string
+		for _, kind := range kinds {
+			switch kind {
+			case "object":
+				types = append(types, "Record<string, unknown>")
+			case "array":
+				types = append(types, "unknown[]")
+			case "integer":
+				types = append(types, "number")
+			default:
+				types = append(types, map[string]string{"string": "string", "number": "number", "boolean": "boolean"}[kind])
+			}
+		}
+		return strings.Join(types, " | ")
+	},
+	definition: func(name string, fields []typeField) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "export interface %s {\n", name)
+		for _, field := range fields {
+			key := field.jsonName
+			if !isTSIdentifier(key) {
+				key = fmt.Sprintf("%q", key)
+			}
+			if field.optional {
+				key += "?"
+			}
+			fmt.Fprintf(&b, "  %s: %s;\n", key, field.typ)
+		}
+		b.WriteString("}\n")
+		return b.String()
+	},
+	alias:  func(name, typ string) string { return fmt.Sprintf("export type %s = %s;\n", name, typ) },
+	finish: func(src string) string { return src },
+}
+
+// typeWriter renders a shape tree as named type definitions, one per object shape
+type typeWriter struct {
+	lang        typeLanguage
+	used        map[string]bool
+	definitions []string
+	pending     []func()
+}
+
+func newTypeWriter(lang typeLanguage) *typeWriter {
+	return &typeWriter{lang: lang, used: make(map[string]bool)}
+}
+
+func (w *typeWriter) render(root *jsonShape, name string) string {
+	w.used[name] = true
+	if root.object && !root.null && len(root.kinds()) == 1 {
+		w.defineObject(root, name)
+	} else {
+		w.definitions = append(w.definitions, w.lang.alias(name, w.typeOf(root, name, name, false)))
+	}
+	// Nested definitions are rendered breadth first, after their parents
+	for len(w.pending) > 0 {
+		next := w.pending[0]
+		w.pending = w.pending[1:]
+		next()
+	}
+	return w.lang.finish(strings.Join(w.definitions, "\n"))
+}
+
+func (w *typeWriter) defineObject(s *jsonShape, name string) {
+	index := len(w.definitions)
+	w.definitions = append(w.definitions, "")
+
+	fields := make([]typeField, 0, len(s.fields))
+	names := make(map[string]bool)
+	for _, field := range s.fields {
+		fieldName := goIdentifier(field.name)
+		if fieldName == "" {
+			fieldName = "Field"
+		}
+		for i := 2; names[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", strings.TrimRight(fieldName, "0123456789"), i)
+		}
+		names[fieldName] = true
+
+		optional := field.optional(s)
+		rendered := typeField{
+			name:     fieldName,
+			jsonName: field.name,
+			typ:      w.typeOf(field.shape, fieldName, name, optional),
+			optional: optional,
+		}
+		if kinds := field.shape.kinds(); len(kinds) > 1 {
+			rendered.comment = strings.Join(kinds, " | ")
+		}
+		fields = append(fields, rendered)
+	}
+	w.definitions[index] = w.lang.definition(name, fields)
+}
+
+// typeOf returns the type expression for a shape, queuing definitions for objects
+func (w *typeWriter) typeOf(s *jsonShape, hint, parent string, optional bool) string {
+	kinds := s.kinds()
+	var typ string
+	single := false
+	switch {
+	case len(kinds) == 0 && s.null:
+		return w.lang.null
+	case len(kinds) == 0:
+		return w.lang.unknown
+	case len(kinds) > 1:
+		typ = w.lang.union(kinds)
+	case kinds[0] == "object":
+		typ = w.typeName(hint, parent)
+		w.pending = append(w.pending, func() { w.defineObject(s, typ) })
+		single = true
+	case kinds[0] == "array":
+		elem := w.lang.unknown
+		if s.items != nil && s.items.seen > 0 {
+			elem = w.typeOf(s.items, singular(hint), parent, false)
+		}
+		typ = w.lang.array(elem)
+	default:
+		typ = w.lang.scalar[kinds[0]]
+		single = true
+	}
+	return w.lang.nullable(typ, s.null, single && (s.null || optional))
+}
+
+// typeName picks an unused type name, qualifying it with the parent on collision
+func (w *typeWriter) typeName(hint, parent string) string {
+	name := hint
+	if w.used[name] {
+		name = parent + hint
+	}
+	base := name
+	for i := 2; w.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	w.used[name] = true
+	return name
+}
+
+// goInitialisms are words written in upper case in Go identifiers
+var goInitialisms = map[string]bool{
+	"acl": true, "api": true, "ascii": true, "cpu": true, "css": true, "dns": true, "eof": true,
+	"guid": true, "html": true, "http": true, "https": true, "id": true, "ip": true, "json": true,
+	"sql": true, "ssh": true, "tcp": true, "tls": true, "ttl": true, "udp": true, "ui": true,
+	"uri": true, "url": true, "utf8": true, "uuid": true, "xml": true,
+}
+
+// goIdentifier converts a JSON key into an exported Go identifier
+func goIdentifier(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))):
+			// Split camelCase and the end of an acronym such as "HTTPServer"
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		lower := strings.ToLower(w)
+		if goInitialisms[lower] {
+			b.WriteString(strings.ToUpper(lower))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	name := b.String()
+	if name != "" && unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// singular derives an element type name from a plural field name
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	default:
+		return name + "Item"
+	}
+}
+
+// isTSIdentifier reports whether a key can be written unquoted in TypeScript
+func isTSIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,2214 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"weak"
+)
+
+const (
+	// maxJQSteps bounds the values a filter may produce while evaluating
+	maxJQSteps = 1_000_000
+	// maxJQValueSize bounds the elements and string bytes of any one value a
+	// filter produces, so [.,.] | [.,.] | ... cannot double a value until it
+	// cannot be written out. Larger input documents raise the bound to their
+	// own size so they can still be passed through.
+	maxJQValueSize = 1 << 21
+	// maxJQDepth bounds nesting of recurse(f) and filter recursion
+	maxJQDepth = 512
+)
+
+// jqExpr is a node of a parsed jq filter
+type jqExpr struct {
+	kind  string // identity, recurse, literal, index, slice, iterate, pipe, comma, alt, and, or, binary, neg, try, if, array, object, call
+	op    string // binary operator
+	name  string // function name
+	value any    // literal value
+	left  *jqExpr
+	right *jqExpr
+	args  []*jqExpr // call arguments, if conditions and branches, slice bounds, object keys and values
+}
+
+type jqToken struct {
+	kind  string // field, dot, recurse, ident, number, string, op, eof
+	text  string
+	value any
+	pos   int
+}
+
+// jqLex splits a jq filter into tokens
+func jqLex(src string) ([]jqToken, error) {
+	var tokens []jqToken
+	pos := 0
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+			continue
+		case c == '#':
+			for pos < len(src) && src[pos] != '\n' {
+				pos++
+			}
+			continue
+		}
+
+		start := pos
+		switch {
+		case strings.HasPrefix(src[pos:], ".."):
+			tokens = append(tokens, jqToken{kind: "recurse", text: "..", pos: start})
+			pos += 2
+		case c == '.' && pos+1 < len(src) && isJQIdentStart(src[pos+1]):
+			pos++
+			for pos < len(src) && isJQIdentChar(src[pos]) {
+				pos++
+			}
+			tokens = append(tokens, jqToken{kind: "field", text: src[start+1 : pos], pos: start})
+		case c == '.' && pos+1 < len(src) && src[pos+1] == '"':
+			text, end, err := jqString(src, pos+1)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, jqToken{kind: "field", text: text, pos: start})
+			pos = end
+		case c == '.':
+			tokens = append(tokens, jqToken{kind: "dot", text: ".", pos: start})
+			pos++
+		case c == '"':
+			text, end, err := jqString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, jqToken{kind: "string", text: text, value: text, pos: start})
+			pos = end
+		case isDigit(c):
+			for pos < len(src) && (isDigit(src[pos]) || src[pos] == '.') {
+				pos++
+			}
+			if pos < len(src) && (src[pos] == 'e' || src[pos] == 'E') {
+				pos++
+				if pos < len(src) && (src[pos] == '+' || src[pos] == '-') {
+					pos++
+				}
+				for pos < len(src) && isDigit(src[pos]) {
+					pos++
+				}
+			}
+			f, err := strconv.ParseFloat(src[start:pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[start:pos], start)
+			}
+			var value any = json.Number(src[start:pos])
+			if !jsonNumberPattern.MatchString(src[start:pos]) {
+				value = jqNumber(f)
+			}
+			tokens = append(tokens, jqToken{kind: "number", text: src[start:pos], value: value, pos: start})
+		case isJQIdentStart(c):
+			for pos < len(src) && isJQIdentChar(src[pos]) {
+				pos++
+			}
+			tokens = append(tokens, jqToken{kind: "ident", text: src[start:pos], pos: start})
+		case c == '$' || c == '@':
+			return nil, fmt.Errorf("variables and @formats are not supported (offset %d)", start)
+		default:
+			for _, assign := range []string{"|=", "+=", "-=", "*=", "/=", "%=", "//="} {
+				if strings.HasPrefix(src[pos:], assign) {
+					return nil, fmt.Errorf("assignment operators are not supported (offset %d)", start)
+				}
+			}
+			op := ""
+			for _, candidate := range []string{"//", "==", "!=", "<=", ">="} {
+				if strings.HasPrefix(src[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" && strings.IndexByte("|,()[]{}:;?<>+-*/%", c) >= 0 {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", src[pos:pos+1], start)
+			}
+			tokens = append(tokens, jqToken{kind: "op", text: op, pos: start})
+			pos += len(op)
+		}
+	}
+	return append(tokens, jqToken{kind: "eof", pos: len(src)}), nil
+}
+
+func isJQIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isJQIdentChar(c byte) bool {
+	return isJQIdentStart(c) || isDigit(c)
+}
+
+// jqString reads a double quoted string starting at pos, returning the end offset
+func jqString(src string, pos int) (string, int, error) {
+	end := pos + 1
+	for end < len(src) && src[end] != '"' {
+		if src[end] == '\\' {
+			if end+1 < len(src) && src[end+1] == '(' {
+				return "", 0, fmt.Errorf("string interpolation is not supported (offset %d)", end)
+			}
+			end++
+		}
+		end++
+	}
+	if end >= len(src) {
+		return "", 0, fmt.Errorf("unterminated string at offset %d", pos)
+	}
+	var text string
+	if err := json.Unmarshal([]byte(src[pos:end+1]), &text); err != nil {
+		return "", 0, fmt.Errorf("invalid string at offset %d: %v", pos, err)
+	}
+	return text, end + 1, nil
+}
+
+// jqParser is a recursive descent parser for the supported jq grammar
+type jqParser struct {
+	tokens []jqToken
+	pos    int
+}
+
+func parseJQ(src string) (*jqExpr, error) {
+	tokens, err := jqLex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &jqParser{tokens: tokens}
+	expr, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+func (p *jqParser) peek() jqToken {
+	return p.tokens[p.pos]
+}
+
+func (p *jqParser) next() jqToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes an operator or keyword token with the given text
+func (p *jqParser) accept(text string) bool {
+	if tok := p.peek(); (tok.kind == "op" || tok.kind == "ident") && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *jqParser) expect(text string) error {
+	if !p.accept(text) {
+		tok := p.peek()
+		if tok.kind == "eof" {
+			return fmt.Errorf("expected %q at end of filter", text)
+		}
+		return fmt.Errorf("expected %q at offset %d, found %q", text, tok.pos, tok.text)
+	}
+	return nil
+}
+
+func (p *jqParser) pipe() (*jqExpr, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == "ident" && tok.text == "as" {
+		return nil, fmt.Errorf("variables are not supported (offset %d)", tok.pos)
+	}
+	if !p.accept("|") {
+		return left, nil
+	}
+	right, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	return &jqExpr{kind: "pipe", left: left, right: right}, nil
+}
+
+func (p *jqParser) comma() (*jqExpr, error) {
+	left, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(",") {
+		right, err := p.alternative()
+		if err != nil {
+			return nil, err
+		}
+		left = &jqExpr{kind: "comma", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *jqParser) alternative() (*jqExpr, error) {
+	left, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("//") {
+		return left, nil
+	}
+	right, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	return &jqExpr{kind: "alt", left: left, right: right}, nil
+}
+
+// jqPrecedence lists binary operators from loosest to tightest binding
+var jqPrecedence = [][]string{
+	{"or"},
+	{"and"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *jqParser) binary(level int) (*jqExpr, error) {
+	if level == len(jqPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range jqPrecedence[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "and", "or":
+			left = &jqExpr{kind: op, left: left, right: right}
+		default:
+			left = &jqExpr{kind: "binary", op: op, left: left, right: right}
+		}
+		if level == 2 {
+			// comparisons do not chain
+			return left, nil
+		}
+	}
+}
+
+func (p *jqParser) unary() (*jqExpr, error) {
+	if p.accept("-") {
+		operand, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return &jqExpr{kind: "neg", left: operand}, nil
+	}
+	return p.postfix()
+}
+
+// postfix reads a term followed by .name, [..] and ? suffixes
+func (p *jqParser) postfix() (*jqExpr, error) {
+	term, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == "field":
+			p.next()
+			term = &jqExpr{kind: "index", left: term, right: &jqExpr{kind: "literal", value: tok.text}}
+		case tok.kind == "dot" && p.tokens[p.pos+1].kind == "op" && p.tokens[p.pos+1].text == "[":
+			p.next()
+		case tok.kind == "op" && tok.text == "[":
+			p.next()
+			if term, err = p.bracketSuffix(term); err != nil {
+				return nil, err
+			}
+		case tok.kind == "op" && tok.text == "?":
+			p.next()
+			term = &jqExpr{kind: "try", left: term}
+		default:
+			return term, nil
+		}
+	}
+}
+
+// bracketSuffix reads .[], .[i], .["key"] and .[from:to] after the opening bracket
+func (p *jqParser) bracketSuffix(target *jqExpr) (*jqExpr, error) {
+	if p.accept("]") {
+		return &jqExpr{kind: "iterate", left: target}, nil
+	}
+	var from *jqExpr
+	if !p.accept(":") {
+		var err error
+		if from, err = p.pipe(); err != nil {
+			return nil, err
+		}
+		if p.accept("]") {
+			return &jqExpr{kind: "index", left: target, right: from}, nil
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+	}
+	var to *jqExpr
+	if !p.accept("]") {
+		var err error
+		if to, err = p.pipe(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	return &jqExpr{kind: "slice", left: target, args: []*jqExpr{from, to}}, nil
+}
+
+func (p *jqParser) term() (*jqExpr, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "dot":
+		return &jqExpr{kind: "identity"}, nil
+	case "recurse":
+		return &jqExpr{kind: "recurse"}, nil
+	case "field":
+		return &jqExpr{kind: "index", left: &jqExpr{kind: "identity"}, right: &jqExpr{kind: "literal", value: tok.text}}, nil
+	case "number", "string":
+		return &jqExpr{kind: "literal", value: tok.value}, nil
+	case "ident":
+		return p.identifier(tok)
+	case "eof":
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	switch tok.text {
+	case "(":
+		expr, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	case "[":
+		if p.accept("]") {
+			return &jqExpr{kind: "array"}, nil
+		}
+		expr, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return &jqExpr{kind: "array", left: expr}, p.expect("]")
+	case "{":
+		return p.object()
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+func (p *jqParser) identifier(tok jqToken) (*jqExpr, error) {
+	switch tok.text {
+	case "true":
+		return &jqExpr{kind: "literal", value: true}, nil
+	case "false":
+		return &jqExpr{kind: "literal", value: false}, nil
+	case "null":
+		return &jqExpr{kind: "literal", value: nil}, nil
+	case "if":
+		return p.conditional()
+	case "try":
+		body, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		expr := &jqExpr{kind: "try", left: body}
+		if p.accept("catch") {
+			if expr.right, err = p.postfix(); err != nil {
+				return nil, err
+			}
+		}
+		return expr, nil
+	case "reduce", "foreach", "def", "label", "import", "include":
+		return nil, fmt.Errorf("%s is not supported (offset %d)", tok.text, tok.pos)
+	}
+
+	call := &jqExpr{kind: "call", name: tok.text}
+	if p.accept("(") {
+		for {
+			arg, err := p.pipe()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, ok := jqBuiltins[fmt.Sprintf("%s/%d", call.name, len(call.args))]; !ok {
+		return nil, fmt.Errorf("%s/%d is not defined", call.name, len(call.args))
+	}
+	return call, nil
+}
+
+// conditional reads if ... then ... (elif ... then ...)* (else ...)? end
+func (p *jqParser) conditional() (*jqExpr, error) {
+	cond, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("then"); err != nil {
+		return nil, err
+	}
+	then, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	expr := &jqExpr{kind: "if", args: []*jqExpr{cond, then}}
+	switch {
+	case p.accept("elif"):
+		otherwise, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		expr.args = append(expr.args, otherwise)
+		return expr, nil
+	case p.accept("else"):
+		otherwise, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		expr.args = append(expr.args, otherwise)
+	}
+	return expr, p.expect("end")
+}
+
+// object reads {key: value, ...}; key alone is shorthand for key: .key
+func (p *jqParser) object() (*jqExpr, error) {
+	expr := &jqExpr{kind: "object"}
+	if p.accept("}") {
+		return expr, nil
+	}
+	for {
+		var key *jqExpr
+		tok := p.next()
+		switch {
+		case tok.kind == "ident" || tok.kind == "string":
+			key = &jqExpr{kind: "literal", value: tok.text}
+		case tok.kind == "number":
+			return nil, fmt.Errorf("object keys must be strings (offset %d)", tok.pos)
+		case tok.kind == "op" && tok.text == "(":
+			var err error
+			if key, err = p.pipe(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in object at offset %d", tok.text, tok.pos)
+		}
+
+		var value *jqExpr
+		if p.accept(":") {
+			var err error
+			if value, err = p.alternative(); err != nil {
+				return nil, err
+			}
+		} else if key.kind == "literal" {
+			value = &jqExpr{kind: "index", left: &jqExpr{kind: "identity"}, right: key}
+		} else {
+			return nil, fmt.Errorf("expected : after computed object key")
+		}
+		expr.args = append(expr.args, key, value)
+
+		if p.accept("}") {
+			return expr, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// jqEvaluator runs a parsed filter, counting produced values against maxJQSteps
+// and the size of each against limit
+type jqEvaluator struct {
+	steps int
+	depth int
+	limit int               // largest value size allowed, at least maxJQValueSize
+	sizes map[jqSizeKey]int // sizes of arrays and objects already measured
+}
+
+// jqSizeKey identifies an array or object by a weak reference to its storage.
+// Unlike a plain address, a weak reference never matches a later value that
+// reuses the storage of one the collector has freed.
+type jqSizeKey struct {
+	ref weak.Pointer[byte]
+	len int
+}
+
+// newJQEvaluator creates an evaluator for a filter run against input
+func newJQEvaluator(input any) *jqEvaluator {
+	e := &jqEvaluator{limit: math.MaxInt, sizes: make(map[jqSizeKey]int)}
+	e.limit = max(maxJQValueSize, e.size(input))
+	return e
+}
+
+// size counts the values and string bytes in v, stopping once the count
+// exceeds the limit. Filters share unchanged values between results, so sizes
+// of arrays and objects are remembered and a value built from copies of one
+// input is measured without expanding it.
+func (e *jqEvaluator) size(v any) int {
+	var n int
+	switch x := v.(type) {
+	case string:
+		return max(1, len(x))
+	case []any:
+		n = len(x)
+	case map[string]any:
+		n = len(x)
+	default:
+		return 1
+	}
+	if n == 0 {
+		return 1
+	}
+	key := jqSizeKey{ref: weak.Make((*byte)(reflect.ValueOf(v).UnsafePointer())), len: n}
+	if size, ok := e.sizes[key]; ok {
+		return size
+	}
+
+	size := 1
+	switch x := v.(type) {
+	case []any:
+		for _, item := range x {
+			if size += e.size(item); size > e.limit {
+				break
+			}
+		}
+	case map[string]any:
+		for k, item := range x {
+			if size += len(k) + e.size(item); size > e.limit {
+				break
+			}
+		}
+	}
+	e.sizes[key] = size
+	return size
+}
+
+// errValueSize reports a value over the evaluator's size limit
+func (e *jqEvaluator) errValueSize() error {
+	return fmt.Errorf("filter produced a value larger than %d elements", e.limit)
+}
+
+func (e *jqEvaluator) eval(expr *jqExpr, in queryNode) ([]queryNode, error) {
+	e.depth++
+	defer func() { e.depth-- }()
+	if e.depth > maxJQDepth {
+		return nil, fmt.Errorf("filter nests too deeply")
+	}
+
+	out, err := e.evalExpr(expr, in)
+	e.steps += len(out)
+	if e.steps > maxJQSteps {
+		return nil, fmt.Errorf("filter produced more than %d values", maxJQSteps)
+	}
+	for _, node := range out {
+		if e.size(node.value) > e.limit {
+			return nil, e.errValueSize()
+		}
+	}
+	return out, err
+}
+
+func (e *jqEvaluator) evalExpr(expr *jqExpr, in queryNode) ([]queryNode, error) {
+	switch expr.kind {
+	case "identity":
+		return []queryNode{in}, nil
+	case "recurse":
+		return descendants(in, nil), nil
+	case "literal":
+		return []queryNode{{value: expr.value}}, nil
+
+	case "pipe":
+		left, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, node := range left {
+			right, err := e.eval(expr.right, node)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, right...)
+		}
+		return out, nil
+
+	case "comma":
+		left, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(expr.right, in)
+		return append(left, right...), err
+
+	case "alt":
+		left, _ := e.eval(expr.left, in)
+		var out []queryNode
+		for _, node := range left {
+			if jqTruthy(node.value) {
+				out = append(out, node)
+			}
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+		return e.eval(expr.right, in)
+
+	case "and", "or":
+		left, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, node := range left {
+			truthy := jqTruthy(node.value)
+			if truthy == (expr.kind == "or") {
+				out = append(out, queryNode{value: truthy})
+				continue
+			}
+			right, err := e.eval(expr.right, in)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range right {
+				out = append(out, queryNode{value: jqTruthy(r.value)})
+			}
+		}
+		return out, nil
+
+	case "try":
+		out, err := e.eval(expr.left, in)
+		if err != nil {
+			if expr.right != nil {
+				return e.eval(expr.right, queryNode{value: err.Error()})
+			}
+			return nil, nil
+		}
+		return out, nil
+
+	case "if":
+		conds, err := e.eval(expr.args[0], in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, cond := range conds {
+			var branch []queryNode
+			switch {
+			case jqTruthy(cond.value):
+				branch, err = e.eval(expr.args[1], in)
+			case len(expr.args) > 2:
+				branch, err = e.eval(expr.args[2], in)
+			default:
+				branch = []queryNode{in}
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, branch...)
+		}
+		return out, nil
+
+	case "array":
+		list := []any{}
+		if expr.left != nil {
+			items, err := e.eval(expr.left, in)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				list = append(list, item.value)
+			}
+		}
+		return []queryNode{{value: list}}, nil
+
+	case "object":
+		return e.evalObject(expr, in)
+
+	case "neg":
+		operands, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, operand := range operands {
+			n, ok := operand.value.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be negated", jqDescribe(operand.value))
+			}
+			f, _ := n.Float64()
+			out = append(out, queryNode{value: jqNumber(-f)})
+		}
+		return out, nil
+
+	case "binary":
+		return e.cartesian(expr.left, expr.right, in, func(a, b any) (any, error) {
+			return e.binary(expr.op, a, b)
+		})
+
+	case "index":
+		targets, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, target := range targets {
+			keys, err := e.eval(expr.right, in)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range keys {
+				node, err := jqIndex(target, key.value)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, node)
+			}
+		}
+		return out, nil
+
+	case "slice":
+		targets, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, target := range targets {
+			bounds := [2]any{}
+			for i, bound := range expr.args {
+				if bound == nil {
+					continue
+				}
+				values, err := e.eval(bound, in)
+				if err != nil {
+					return nil, err
+				}
+				if len(values) != 1 {
+					return nil, fmt.Errorf("slice bounds must produce one value")
+				}
+				bounds[i] = values[0].value
+			}
+			value, err := jqSlice(target.value, bounds[0], bounds[1])
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, queryNode{value: value})
+		}
+		return out, nil
+
+	case "iterate":
+		targets, err := e.eval(expr.left, in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, target := range targets {
+			switch target.value.(type) {
+			case []any, map[string]any:
+				out = append(out, children(target)...)
+			default:
+				return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(target.value))
+			}
+		}
+		return out, nil
+
+	case "call":
+		return jqBuiltins[fmt.Sprintf("%s/%d", expr.name, len(expr.args))](e, expr.args, in)
+	}
+	return nil, fmt.Errorf("unknown expression %s", expr.kind)
+}
+
+// cartesian evaluates a binary operator over every pair of outputs, right operand outermost as jq does
+func (e *jqEvaluator) cartesian(left, right *jqExpr, in queryNode, op func(a, b any) (any, error)) ([]queryNode, error) {
+	rights, err := e.eval(right, in)
+	if err != nil {
+		return nil, err
+	}
+	lefts, err := e.eval(left, in)
+	if err != nil {
+		return nil, err
+	}
+	var out []queryNode
+	for _, r := range rights {
+		for _, l := range lefts {
+			value, err := op(l.value, r.value)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, queryNode{value: value})
+		}
+	}
+	return out, nil
+}
+
+func (e *jqEvaluator) evalObject(expr *jqExpr, in queryNode) ([]queryNode, error) {
+	objects := []map[string]any{{}}
+	for i := 0; i < len(expr.args); i += 2 {
+		keys, err := e.eval(expr.args[i], in)
+		if err != nil {
+			return nil, err
+		}
+		values, err := e.eval(expr.args[i+1], in)
+		if err != nil {
+			return nil, err
+		}
+		var next []map[string]any
+		for _, obj := range objects {
+			for _, key := range keys {
+				name, ok := key.value.(string)
+				if !ok {
+					return nil, fmt.Errorf("object keys must be strings, not %s", jqDescribe(key.value))
+				}
+				for _, value := range values {
+					extended := make(map[string]any, len(obj)+1)
+					for k, v := range obj {
+						extended[k] = v
+					}
+					extended[name] = value.value
+					next = append(next, extended)
+				}
+			}
+		}
+		objects = next
+	}
+	out := make([]queryNode, len(objects))
+	for i, obj := range objects {
+		out[i] = queryNode{value: obj}
+	}
+	return out, nil
+}
+
+// values evaluates an argument filter and returns the plain values
+func (e *jqEvaluator) values(expr *jqExpr, in queryNode) ([]any, error) {
+	nodes, err := e.eval(expr, in)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.value
+	}
+	return values, nil
+}
+
+// jqIndex implements .[key] on objects, arrays and null
+func jqIndex(target queryNode, key any) (queryNode, error) {
+	switch v := target.value.(type) {
+	case nil:
+		switch k := key.(type) {
+		case string:
+			return target.child(k, nil), nil
+		case json.Number:
+			f, _ := k.Float64()
+			return target.child(int(math.Floor(f)), nil), nil
+		}
+	case map[string]any:
+		if k, ok := key.(string); ok {
+			return target.child(k, v[k]), nil
+		}
+	case []any:
+		if k, ok := key.(json.Number); ok {
+			f, _ := k.Float64()
+			i := int(math.Floor(f))
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return queryNode{value: nil}, nil
+			}
+			return target.child(i, v[i]), nil
+		}
+	}
+	return queryNode{}, fmt.Errorf("cannot index %s with %s", jqTypeName(target.value), jqDescribe(key))
+}
+
+// jqSlice implements .[from:to] on arrays, strings and null
+func jqSlice(value, from, to any) (any, error) {
+	var length int
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		length = len(v)
+	case string:
+		length = utf8.RuneCountInString(v)
+	default:
+		return nil, fmt.Errorf("cannot slice %s", jqTypeName(value))
+	}
+
+	bound := func(b any, fallback int) (int, error) {
+		if b == nil {
+			return fallback, nil
+		}
+		n, ok := b.(json.Number)
+		if !ok {
+			return 0, fmt.Errorf("slice bounds must be numbers")
+		}
+		f, _ := n.Float64()
+		i := int(math.Floor(f))
+		if i < 0 {
+			i += length
+		}
+		return min(max(i, 0), length), nil
+	}
+	start, err := bound(from, 0)
+	if err != nil {
+		return nil, err
+	}
+	end, err := bound(to, length)
+	if err != nil {
+		return nil, err
+	}
+	end = max(end, start)
+
+	if list, ok := value.([]any); ok {
+		return append([]any{}, list[start:end]...), nil
+	}
+	return string([]rune(value.(string))[start:end]), nil
+}
+
+func jqTruthy(value any) bool {
+	return value != nil && value != false
+}
+
+func jqTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jqDescribe names a value's type and a short excerpt for error messages
+func jqDescribe(value any) string {
+	text := compactJSON(value)
+	if len(text) > 20 {
+		text = text[:17] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", jqTypeName(value), text)
+}
+
+// jqNumber converts a computed float back to an exact decimal JSON number
+func jqNumber(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return nil
+	case math.IsInf(f, 1):
+		f = math.MaxFloat64
+	case math.IsInf(f, -1):
+		f = -math.MaxFloat64
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e17 {
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+}
+
+func jqFloat(value any) (float64, bool) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil || math.IsInf(f, 0)
+}
+
+// jqCompare orders values as jq does: null < false < true < numbers < strings < arrays < objects
+func jqCompare(a, b any) int {
+	rank := func(value any) int {
+		switch value {
+		case nil:
+			return 0
+		case false:
+			return 1
+		case true:
+			return 2
+		}
+		switch value.(type) {
+		case json.Number:
+			return 3
+		case string:
+			return 4
+		case []any:
+			return 5
+		}
+		return 6
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+
+	switch x := a.(type) {
+	case json.Number:
+		ra, okA := new(big.Rat).SetString(x.String())
+		rb, okB := new(big.Rat).SetString(b.(json.Number).String())
+		if okA && okB {
+			return ra.Cmp(rb)
+		}
+		fa, _ := jqFloat(x)
+		fb, _ := jqFloat(b)
+		return compareFloats(fa, fb)
+	case string:
+		return strings.Compare(x, b.(string))
+	case []any:
+		y := b.([]any)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := jqCompare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case map[string]any:
+		y := b.(map[string]any)
+		keysA, keysB := sortedKeys(x), sortedKeys(y)
+		listA, listB := make([]any, len(keysA)), make([]any, len(keysB))
+		for i, key := range keysA {
+			listA[i] = key
+		}
+		for i, key := range keysB {
+			listB[i] = key
+		}
+		if c := jqCompare(listA, listB); c != 0 {
+			return c
+		}
+		for _, key := range keysA {
+			if c := jqCompare(x[key], y[key]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// binary applies an arithmetic or comparison operator. Concatenations are
+// measured before the result is allocated.
+func (e *jqEvaluator) binary(op string, a, b any) (any, error) {
+	switch op {
+	case "==":
+		return jqCompare(a, b) == 0, nil
+	case "!=":
+		return jqCompare(a, b) != 0, nil
+	case "<":
+		return jqCompare(a, b) < 0, nil
+	case "<=":
+		return jqCompare(a, b) <= 0, nil
+	case ">":
+		return jqCompare(a, b) > 0, nil
+	case ">=":
+		return jqCompare(a, b) >= 0, nil
+	}
+
+	fa, aNum := jqFloat(a)
+	fb, bNum := jqFloat(b)
+	if aNum && bNum {
+		switch op {
+		case "+":
+			return jqNumber(fa + fb), nil
+		case "-":
+			return jqNumber(fa - fb), nil
+		case "*":
+			return jqNumber(fa * fb), nil
+		case "/":
+			if fb == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", jqDescribe(a), jqDescribe(b))
+			}
+			return jqNumber(fa / fb), nil
+		case "%":
+			if int64(fb) == 0 {
+				return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", jqDescribe(a), jqDescribe(b))
+			}
+			return jqNumber(float64(int64(fa) % int64(fb))), nil
+		}
+	}
+
+	switch op {
+	case "+":
+		if a == nil {
+			return b, nil
+		}
+		if b == nil {
+			return a, nil
+		}
+		switch x := a.(type) {
+		case string:
+			if y, ok := b.(string); ok {
+				if len(x)+len(y) > e.limit {
+					return nil, e.errValueSize()
+				}
+				return x + y, nil
+			}
+		case []any:
+			if y, ok := b.([]any); ok {
+				if e.size(x)+e.size(y) > e.limit {
+					return nil, e.errValueSize()
+				}
+				return append(append([]any{}, x...), y...), nil
+			}
+		case map[string]any:
+			if y, ok := b.(map[string]any); ok {
+				size := e.size(x)
+				for k, v := range y {
+					if old, replaced := x[k]; replaced {
+						size -= len(k) + e.size(old)
+					}
+					size += len(k) + e.size(v)
+				}
+				if size > e.limit {
+					return nil, e.errValueSize()
+				}
+				merged := make(map[string]any, len(x)+len(y))
+				for k, v := range x {
+					merged[k] = v
+				}
+				for k, v := range y {
+					merged[k] = v
+				}
+				return merged, nil
+			}
+		}
+	case "-":
+		x, okA := a.([]any)
+		y, okB := b.([]any)
+		if okA && okB {
+			out := []any{}
+			for _, item := range x {
+				found := false
+				for _, remove := range y {
+					if jqCompare(item, remove) == 0 {
+						found = true
+						break
+					}
+				}
+				if !found {
+					out = append(out, item)
+				}
+			}
+			return out, nil
+		}
+	case "*":
+		x, okA := a.(map[string]any)
+		y, okB := b.(map[string]any)
+		if okA && okB {
+			return jqDeepMerge(x, y), nil
+		}
+	case "/":
+		x, okA := a.(string)
+		y, okB := b.(string)
+		if okA && okB {
+			return jqSplit(x, y), nil
+		}
+	}
+	return nil, fmt.Errorf("%s and %s cannot be combined with %s", jqDescribe(a), jqDescribe(b), op)
+}
+
+func jqDeepMerge(a, b map[string]any) map[string]any {
+	merged := make(map[string]any, len(a)+len(b))
+	for k, v := range a {
+		merged[k] = v
+	}
+	for k, v := range b {
+		x, okA := merged[k].(map[string]any)
+		y, okB := v.(map[string]any)
+		if okA && okB {
+			merged[k] = jqDeepMerge(x, y)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func jqSplit(s, sep string) []any {
+	out := []any{}
+	if s == "" {
+		return out
+	}
+	for _, part := range strings.Split(s, sep) {
+		out = append(out, part)
+	}
+	return out
+}
+
+// jqContains implements contains(b): substrings, array subsets and object subsets
+func jqContains(a, b any) (bool, error) {
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		for key, value := range y {
+			inner, exists := x[key]
+			if !exists {
+				return false, nil
+			}
+			if ok, err := jqContains(inner, value); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case []any:
+		y, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for _, want := range y {
+			found := false
+			for _, item := range x {
+				if ok, _ := jqContains(item, want); ok {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Contains(x, y), nil
+		}
+	default:
+		if jqTypeName(a) == jqTypeName(b) {
+			return jqCompare(a, b) == 0, nil
+		}
+	}
+	return false, fmt.Errorf("%s and %s cannot have their containment checked", jqDescribe(a), jqDescribe(b))
+}
+
+type jqBuiltin func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error)
+
+// jqValueFunc adapts a function of the input value to a builtin
+func jqValueFunc(fn func(value any) (any, error)) jqBuiltin {
+	return func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+		value, err := fn(in.value)
+		if err != nil {
+			return nil, err
+		}
+		return []queryNode{{value: value}}, nil
+	}
+}
+
+// jqArgFunc adapts a function of the input and each output of its argument
+func jqArgFunc(fn func(value, arg any) (any, error)) jqBuiltin {
+	return func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+		argValues, err := e.values(args[0], in)
+		if err != nil {
+			return nil, err
+		}
+		var out []queryNode
+		for _, arg := range argValues {
+			value, err := fn(in.value, arg)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, queryNode{value: value})
+		}
+		return out, nil
+	}
+}
+
+// jqTypeSelector keeps the input when it has one of the given types
+func jqTypeSelector(types ...string) jqBuiltin {
+	return func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+		for _, t := range types {
+			if jqTypeName(in.value) == t {
+				return []queryNode{in}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// jqStringFunc adapts a function of a string input and a string argument
+func jqStringFunc(name string, fn func(s, arg string) any) jqBuiltin {
+	return jqArgFunc(func(value, arg any) (any, error) {
+		s, okA := value.(string)
+		a, okB := arg.(string)
+		if !okA || !okB {
+			return nil, fmt.Errorf("%s() requires string inputs", name)
+		}
+		return fn(s, a), nil
+	})
+}
+
+// jqMath adapts a numeric function
+func jqMath(name string, fn func(float64) float64) jqBuiltin {
+	return jqValueFunc(func(value any) (any, error) {
+		f, ok := jqFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("%s has no %s", jqDescribe(value), name)
+		}
+		return jqNumber(fn(f)), nil
+	})
+}
+
+// keyedBy evaluates f for each array element, giving the sort key used by the *_by builtins
+func (e *jqEvaluator) keyedBy(name string, f *jqExpr, in queryNode) ([]any, []any, error) {
+	list, ok := in.value.([]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s cannot be passed to %s", jqDescribe(in.value), name)
+	}
+	keys := make([]any, len(list))
+	for i, item := range list {
+		values, err := e.values(f, queryNode{value: item})
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = values
+	}
+	return list, keys, nil
+}
+
+// jqSortedBy returns the array's elements stably sorted by their keys
+func jqSortedBy(list, keys []any) ([]any, []any) {
+	order := make([]int, len(list))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return jqCompare(keys[order[i]], keys[order[j]]) < 0
+	})
+	sortedList := make([]any, len(list))
+	sortedKeys := make([]any, len(list))
+	for i, index := range order {
+		sortedList[i], sortedKeys[i] = list[index], keys[index]
+	}
+	return sortedList, sortedKeys
+}
+
+func jqLength(value any) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return json.Number("0"), nil
+	case bool:
+		return nil, fmt.Errorf("%s has no length", jqDescribe(value))
+	case json.Number:
+		f, _ := jqFloat(v)
+		return jqNumber(math.Abs(f)), nil
+	case string:
+		return json.Number(strconv.Itoa(utf8.RuneCountInString(v))), nil
+	case []any:
+		return json.Number(strconv.Itoa(len(v))), nil
+	case map[string]any:
+		return json.Number(strconv.Itoa(len(v))), nil
+	}
+	return nil, nil
+}
+
+func jqKeys(value any) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := []any{}
+		for _, key := range sortedKeys(v) {
+			keys = append(keys, key)
+		}
+		return keys, nil
+	case []any:
+		keys := make([]any, len(v))
+		for i := range v {
+			keys[i] = json.Number(strconv.Itoa(i))
+		}
+		return keys, nil
+	}
+	return nil, fmt.Errorf("%s has no keys", jqDescribe(value))
+}
+
+func jqToEntries(value any) (any, error) {
+	obj, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s has no keys", jqDescribe(value))
+	}
+	entries := []any{}
+	for _, key := range sortedKeys(obj) {
+		entries = append(entries, map[string]any{"key": key, "value": obj[key]})
+	}
+	return entries, nil
+}
+
+func jqFromEntries(value any) (any, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be converted from entries", jqDescribe(value))
+	}
+	obj := map[string]any{}
+	for _, item := range list {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an entry", jqDescribe(item))
+		}
+		var key any
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if k, ok := entry[name]; ok && jqTruthy(k) {
+				key = k
+				break
+			}
+		}
+		var entryValue any
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if v, ok := entry[name]; ok {
+				entryValue = v
+				break
+			}
+		}
+		switch k := key.(type) {
+		case string:
+			obj[k] = entryValue
+		case json.Number, bool:
+			obj[compactJSON(k)] = entryValue
+		default:
+			return nil, fmt.Errorf("entry %s has no string key", jqDescribe(item))
+		}
+	}
+	return obj, nil
+}
+
+func jqFlatten(value any, depth float64) (any, error) {
+	list, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be flattened", jqDescribe(value))
+	}
+	if depth < 0 {
+		return nil, fmt.Errorf("flatten depth must not be negative")
+	}
+	out := []any{}
+	for _, item := range list {
+		if nested, ok := item.([]any); ok && depth > 0 {
+			flat, _ := jqFlatten(nested, depth-1)
+			out = append(out, flat.([]any)...)
+		} else {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+func jqTest(value, pattern, flags any) (any, error) {
+	s, okA := value.(string)
+	re, okB := pattern.(string)
+	if !okA || !okB {
+		return nil, fmt.Errorf("%s cannot be matched, as it is not a string", jqDescribe(value))
+	}
+	prefix := ""
+	if f, ok := flags.(string); ok {
+		for _, flag := range f {
+			switch flag {
+			case 'i':
+				prefix += "i"
+			case 's':
+				prefix += "s"
+			case 'x', 'g', 'n':
+			default:
+				return nil, fmt.Errorf("%c is not a valid regex flag", flag)
+			}
+		}
+	}
+	if prefix != "" {
+		re = "(?" + prefix + ")" + re
+	}
+	compiled, err := regexp.Compile(re)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %v", pattern, err)
+	}
+	return compiled.MatchString(s), nil
+}
+
+// jqPaths lists the paths below a node as arrays, as jq's paths builtin does
+func jqPaths(node queryNode) []queryNode {
+	var out []queryNode
+	for _, descendant := range descendants(queryNode{value: node.value, path: []any{}}, nil)[1:] {
+		out = append(out, queryNode{value: jqPathValue(descendant.path)})
+	}
+	return out
+}
+
+func jqPathValue(path []any) []any {
+	value := make([]any, len(path))
+	for i, segment := range path {
+		if index, ok := segment.(int); ok {
+			value[i] = json.Number(strconv.Itoa(index))
+		} else {
+			value[i] = segment
+		}
+	}
+	return value
+}
+
+// jqBuiltins are the supported functions, keyed by name/arity
+var jqBuiltins map[string]jqBuiltin
+
+func init() {
+	jqBuiltins = map[string]jqBuiltin{
+		"empty/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return nil, nil
+		},
+		"error/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			values, err := e.values(args[0], in)
+			if err != nil || len(values) == 0 {
+				return nil, err
+			}
+			if message, ok := values[0].(string); ok {
+				return nil, fmt.Errorf("%s", message)
+			}
+			return nil, fmt.Errorf("%s (not a string)", compactJSON(values[0]))
+		},
+		"not/0": jqValueFunc(func(value any) (any, error) {
+			return !jqTruthy(value), nil
+		}),
+		"length/0": jqValueFunc(jqLength),
+		"utf8bytelength/0": jqValueFunc(func(value any) (any, error) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s only strings have UTF-8 byte length", jqDescribe(value))
+			}
+			return json.Number(strconv.Itoa(len(s))), nil
+		}),
+		"keys/0":          jqValueFunc(jqKeys),
+		"keys_unsorted/0": jqValueFunc(jqKeys),
+		"type/0": jqValueFunc(func(value any) (any, error) {
+			return jqTypeName(value), nil
+		}),
+		"values/0":    jqTypeSelector("boolean", "number", "string", "array", "object"),
+		"nulls/0":     jqTypeSelector("null"),
+		"booleans/0":  jqTypeSelector("boolean"),
+		"numbers/0":   jqTypeSelector("number"),
+		"strings/0":   jqTypeSelector("string"),
+		"arrays/0":    jqTypeSelector("array"),
+		"objects/0":   jqTypeSelector("object"),
+		"iterables/0": jqTypeSelector("array", "object"),
+		"scalars/0":   jqTypeSelector("null", "boolean", "number", "string"),
+		"add/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			var items []any
+			switch v := in.value.(type) {
+			case []any:
+				items = v
+			case map[string]any:
+				for _, key := range sortedKeys(v) {
+					items = append(items, v[key])
+				}
+			case nil:
+				return []queryNode{{value: nil}}, nil
+			default:
+				return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(in.value))
+			}
+			var sum any
+			for _, item := range items {
+				var err error
+				if sum, err = e.binary("+", sum, item); err != nil {
+					return nil, err
+				}
+			}
+			return []queryNode{{value: sum}}, nil
+		},
+		"first/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			node, err := jqIndex(in, json.Number("0"))
+			return []queryNode{node}, err
+		},
+		"last/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			node, err := jqIndex(in, json.Number("-1"))
+			return []queryNode{node}, err
+		},
+		"reverse/0": jqValueFunc(func(value any) (any, error) {
+			switch v := value.(type) {
+			case nil:
+				return []any{}, nil
+			case string:
+				runes := []rune(v)
+				for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+					runes[i], runes[j] = runes[j], runes[i]
+				}
+				return string(runes), nil
+			case []any:
+				out := make([]any, len(v))
+				for i, item := range v {
+					out[len(v)-1-i] = item
+				}
+				return out, nil
+			}
+			return nil, fmt.Errorf("cannot reverse %s", jqDescribe(value))
+		}),
+		"sort/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", jqDescribe(value))
+			}
+			sorted, _ := jqSortedBy(list, list)
+			return sorted, nil
+		}),
+		"unique/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", jqDescribe(value))
+			}
+			sorted, _ := jqSortedBy(list, list)
+			out := []any{}
+			for i, item := range sorted {
+				if i == 0 || jqCompare(item, sorted[i-1]) != 0 {
+					out = append(out, item)
+				}
+			}
+			return out, nil
+		}),
+		"min/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s has no minimum", jqDescribe(value))
+			}
+			if len(list) == 0 {
+				return nil, nil
+			}
+			sorted, _ := jqSortedBy(list, list)
+			return sorted[0], nil
+		}),
+		"max/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s has no maximum", jqDescribe(value))
+			}
+			if len(list) == 0 {
+				return nil, nil
+			}
+			sorted, _ := jqSortedBy(list, list)
+			return sorted[len(sorted)-1], nil
+		}),
+		"floor/0": jqMath("floor", math.Floor),
+		"ceil/0":  jqMath("ceil", math.Ceil),
+		"round/0": jqMath("round", math.Round),
+		"sqrt/0":  jqMath("sqrt", math.Sqrt),
+		"abs/0":   jqMath("abs", math.Abs),
+		"tostring/0": jqValueFunc(func(value any) (any, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return compactJSON(value), nil
+		}),
+		"tonumber/0": jqValueFunc(func(value any) (any, error) {
+			switch v := value.(type) {
+			case json.Number:
+				return v, nil
+			case string:
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					return jqNumber(f), nil
+				}
+			}
+			return nil, fmt.Errorf("%s cannot be parsed as a number", jqDescribe(value))
+		}),
+		"tojson/0": jqValueFunc(func(value any) (any, error) {
+			return compactJSON(value), nil
+		}),
+		"fromjson/0": jqValueFunc(func(value any) (any, error) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be parsed as JSON", jqDescribe(value))
+			}
+			return decodeJSONValue(json.RawMessage(s))
+		}),
+		"to_entries/0":   jqValueFunc(jqToEntries),
+		"from_entries/0": jqValueFunc(jqFromEntries),
+		"ascii_downcase/0": jqValueFunc(func(value any) (any, error) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("ascii_downcase input must be a string")
+			}
+			return strings.Map(func(r rune) rune {
+				if r >= 'A' && r <= 'Z' {
+					return r + 'a' - 'A'
+				}
+				return r
+			}, s), nil
+		}),
+		"ascii_upcase/0": jqValueFunc(func(value any) (any, error) {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("ascii_upcase input must be a string")
+			}
+			return strings.Map(func(r rune) rune {
+				if r >= 'a' && r <= 'z' {
+					return r - 'a' + 'A'
+				}
+				return r
+			}, s), nil
+		}),
+		"any/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(value))
+			}
+			for _, item := range list {
+				if jqTruthy(item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}),
+		"all/0": jqValueFunc(func(value any) (any, error) {
+			list, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(value))
+			}
+			for _, item := range list {
+				if !jqTruthy(item) {
+					return false, nil
+				}
+			}
+			return true, nil
+		}),
+		"flatten/0": jqValueFunc(func(value any) (any, error) {
+			return jqFlatten(value, math.Inf(1))
+		}),
+		"flatten/1": jqArgFunc(func(value, arg any) (any, error) {
+			depth, ok := jqFloat(arg)
+			if !ok {
+				return nil, fmt.Errorf("flatten depth must be a number")
+			}
+			return jqFlatten(value, depth)
+		}),
+		"recurse/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return descendants(in, nil), nil
+		},
+		"recurse/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.recurseWith(args[0], in)
+		},
+		"paths/0": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return jqPaths(in), nil
+		},
+		"path/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			nodes, err := e.eval(args[0], queryNode{value: in.value, path: []any{}})
+			if err != nil {
+				return nil, err
+			}
+			var out []queryNode
+			for _, node := range nodes {
+				if node.path == nil {
+					return nil, fmt.Errorf("invalid path expression with result %s", jqDescribe(node.value))
+				}
+				out = append(out, queryNode{value: jqPathValue(node.path)})
+			}
+			return out, nil
+		},
+		"getpath/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			paths, err := e.values(args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			var out []queryNode
+			for _, path := range paths {
+				segments, ok := path.([]any)
+				if !ok {
+					return nil, fmt.Errorf("path must be specified as an array")
+				}
+				node := in
+				for _, segment := range segments {
+					if node.value == nil {
+						node = node.child(segment, nil)
+						continue
+					}
+					if node, err = jqIndex(node, segment); err != nil {
+						return nil, err
+					}
+				}
+				out = append(out, node)
+			}
+			return out, nil
+		},
+		"select/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			conds, err := e.values(args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			var out []queryNode
+			for _, cond := range conds {
+				if jqTruthy(cond) {
+					out = append(out, in)
+				}
+			}
+			return out, nil
+		},
+		"map/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			switch in.value.(type) {
+			case []any, map[string]any:
+			default:
+				return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(in.value))
+			}
+			list := []any{}
+			for _, child := range children(in) {
+				values, err := e.values(args[0], child)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, values...)
+			}
+			return []queryNode{{value: list}}, nil
+		},
+		"map_values/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			switch v := in.value.(type) {
+			case []any:
+				list := []any{}
+				for _, item := range v {
+					values, err := e.values(args[0], queryNode{value: item})
+					if err != nil {
+						return nil, err
+					}
+					if len(values) > 0 {
+						list = append(list, values[0])
+					}
+				}
+				return []queryNode{{value: list}}, nil
+			case map[string]any:
+				obj := map[string]any{}
+				for key, item := range v {
+					values, err := e.values(args[0], queryNode{value: item})
+					if err != nil {
+						return nil, err
+					}
+					if len(values) > 0 {
+						obj[key] = values[0]
+					}
+				}
+				return []queryNode{{value: obj}}, nil
+			}
+			return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(in.value))
+		},
+		"with_entries/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			entries, err := jqToEntries(in.value)
+			if err != nil {
+				return nil, err
+			}
+			mapped := []any{}
+			for _, entry := range entries.([]any) {
+				values, err := e.values(args[0], queryNode{value: entry})
+				if err != nil {
+					return nil, err
+				}
+				mapped = append(mapped, values...)
+			}
+			obj, err := jqFromEntries(mapped)
+			return []queryNode{{value: obj}}, err
+		},
+		"has/1": jqArgFunc(func(value, arg any) (any, error) {
+			switch v := value.(type) {
+			case map[string]any:
+				if key, ok := arg.(string); ok {
+					_, exists := v[key]
+					return exists, nil
+				}
+			case []any:
+				if f, ok := jqFloat(arg); ok {
+					return f >= 0 && f < float64(len(v)), nil
+				}
+			}
+			return nil, fmt.Errorf("cannot check whether %s has a %s key", jqTypeName(value), jqTypeName(arg))
+		}),
+		"contains/1": jqArgFunc(func(value, arg any) (any, error) {
+			return jqContains(value, arg)
+		}),
+		"join/1": jqArgFunc(func(value, arg any) (any, error) {
+			list, ok := value.([]any)
+			sep, sepOK := arg.(string)
+			if !ok || !sepOK {
+				return nil, fmt.Errorf("join requires an array input and a string separator")
+			}
+			parts := make([]string, len(list))
+			for i, item := range list {
+				switch v := item.(type) {
+				case nil:
+				case string:
+					parts[i] = v
+				case json.Number, bool:
+					parts[i] = compactJSON(v)
+				default:
+					return nil, fmt.Errorf("cannot join with %s", jqDescribe(item))
+				}
+			}
+			return strings.Join(parts, sep), nil
+		}),
+		"split/1": jqStringFunc("split", func(s, sep string) any {
+			return jqSplit(s, sep)
+		}),
+		"startswith/1": jqStringFunc("startswith", func(s, prefix string) any {
+			return strings.HasPrefix(s, prefix)
+		}),
+		"endswith/1": jqStringFunc("endswith", func(s, suffix string) any {
+			return strings.HasSuffix(s, suffix)
+		}),
+		"ltrimstr/1": jqArgFunc(func(value, arg any) (any, error) {
+			s, okA := value.(string)
+			prefix, okB := arg.(string)
+			if okA && okB {
+				return strings.TrimPrefix(s, prefix), nil
+			}
+			return value, nil
+		}),
+		"rtrimstr/1": jqArgFunc(func(value, arg any) (any, error) {
+			s, okA := value.(string)
+			suffix, okB := arg.(string)
+			if okA && okB {
+				return strings.TrimSuffix(s, suffix), nil
+			}
+			return value, nil
+		}),
+		"test/1": jqArgFunc(func(value, arg any) (any, error) {
+			return jqTest(value, arg, nil)
+		}),
+		"test/2": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			flags, err := e.values(args[1], in)
+			if err != nil {
+				return nil, err
+			}
+			return jqArgFunc(func(value, arg any) (any, error) {
+				var flag any
+				if len(flags) > 0 {
+					flag = flags[0]
+				}
+				return jqTest(value, arg, flag)
+			})(e, args, in)
+		},
+		"first/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			nodes, err := e.eval(args[0], in)
+			if err != nil || len(nodes) == 0 {
+				return nil, err
+			}
+			return nodes[:1], nil
+		},
+		"last/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			nodes, err := e.eval(args[0], in)
+			if err != nil || len(nodes) == 0 {
+				return nil, err
+			}
+			return nodes[len(nodes)-1:], nil
+		},
+		"limit/2": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			counts, err := e.values(args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			nodes, err := e.eval(args[1], in)
+			if err != nil {
+				return nil, err
+			}
+			var out []queryNode
+			for _, count := range counts {
+				n, ok := jqFloat(count)
+				if !ok {
+					return nil, fmt.Errorf("limit count must be a number")
+				}
+				out = append(out, nodes[:min(max(int(n), 0), len(nodes))]...)
+			}
+			return out, nil
+		},
+		"range/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.rangeOf(in, &jqExpr{kind: "literal", value: json.Number("0")}, args[0], nil)
+		},
+		"range/2": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.rangeOf(in, args[0], args[1], nil)
+		},
+		"range/3": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.rangeOf(in, args[0], args[1], args[2])
+		},
+		"any/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.quantify(args[0], in, true)
+		},
+		"all/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			return e.quantify(args[0], in, false)
+		},
+		"sort_by/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			list, keys, err := e.keyedBy("sort_by", args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			sorted, _ := jqSortedBy(list, keys)
+			return []queryNode{{value: sorted}}, nil
+		},
+		"group_by/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			list, keys, err := e.keyedBy("group_by", args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			sorted, sortedKeys := jqSortedBy(list, keys)
+			groups := []any{}
+			for i, item := range sorted {
+				if i == 0 || jqCompare(sortedKeys[i], sortedKeys[i-1]) != 0 {
+					groups = append(groups, []any{})
+				}
+				last := len(groups) - 1
+				groups[last] = append(groups[last].([]any), item)
+			}
+			return []queryNode{{value: groups}}, nil
+		},
+		"unique_by/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			list, keys, err := e.keyedBy("unique_by", args[0], in)
+			if err != nil {
+				return nil, err
+			}
+			sorted, sortedKeys := jqSortedBy(list, keys)
+			out := []any{}
+			for i, item := range sorted {
+				if i == 0 || jqCompare(sortedKeys[i], sortedKeys[i-1]) != 0 {
+					out = append(out, item)
+				}
+			}
+			return []queryNode{{value: out}}, nil
+		},
+		"min_by/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			list, keys, err := e.keyedBy("min_by", args[0], in)
+			if err != nil || len(list) == 0 {
+				return []queryNode{{value: nil}}, err
+			}
+			sorted, _ := jqSortedBy(list, keys)
+			return []queryNode{{value: sorted[0]}}, nil
+		},
+		"max_by/1": func(e *jqEvaluator, args []*jqExpr, in queryNode) ([]queryNode, error) {
+			list, keys, err := e.keyedBy("max_by", args[0], in)
+			if err != nil || len(list) == 0 {
+				return []queryNode{{value: nil}}, err
+			}
+			sorted, _ := jqSortedBy(list, keys)
+			return []queryNode{{value: sorted[len(sorted)-1]}}, nil
+		},
+	}
+}
+
+// rangeOf produces numbers from from up to (not including) upto
+func (e *jqEvaluator) rangeOf(in queryNode, fromExpr, uptoExpr, byExpr *jqExpr) ([]queryNode, error) {
+	bound := func(expr *jqExpr, fallback float64) (float64, error) {
+		if expr == nil {
+			return fallback, nil
+		}
+		values, err := e.values(expr, in)
+		if err != nil {
+			return 0, err
+		}
+		if len(values) != 1 {
+			return 0, fmt.Errorf("range arguments must produce one value")
+		}
+		f, ok := jqFloat(values[0])
+		if !ok {
+			return 0, fmt.Errorf("range arguments must be numbers")
+		}
+		return f, nil
+	}
+	from, err := bound(fromExpr, 0)
+	if err != nil {
+		return nil, err
+	}
+	upto, err := bound(uptoExpr, 0)
+	if err != nil {
+		return nil, err
+	}
+	by, err := bound(byExpr, 1)
+	if err != nil {
+		return nil, err
+	}
+	if by == 0 || math.Abs((upto-from)/by) > maxJQSteps {
+		return nil, fmt.Errorf("range is too large")
+	}
+	var out []queryNode
+	for f := from; by > 0 && f < upto || by < 0 && f > upto; f += by {
+		out = append(out, queryNode{value: jqNumber(f)})
+	}
+	return out, nil
+}
+
+// quantify implements any(f) and all(f) over the elements of an array
+func (e *jqEvaluator) quantify(f *jqExpr, in queryNode, want bool) ([]queryNode, error) {
+	list, ok := in.value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(in.value))
+	}
+	for _, item := range list {
+		values, err := e.values(f, queryNode{value: item})
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if jqTruthy(value) == want {
+				return []queryNode{{value: want}}, nil
+			}
+		}
+	}
+	return []queryNode{{value: !want}}, nil
+}
+
+// recurseWith implements recurse(f): the input, then recurse(f) on each output of f
+func (e *jqEvaluator) recurseWith(f *jqExpr, in queryNode) ([]queryNode, error) {
+	e.depth++
+	defer func() { e.depth-- }()
+	if e.depth > maxJQDepth {
+		return nil, fmt.Errorf("recurse nests too deeply")
+	}
+
+	out := []queryNode{in}
+	next, err := e.eval(f, in)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range next {
+		nested, err := e.recurseWith(f, node)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, nested...)
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runJQ(t *testing.T, document, filter string) (*JSONQueryResult, error) {
+	t.Helper()
+	return NewJSONService().Query(JSONQueryRequest{Document: json.RawMessage(document), Query: filter, Language: "jq"})
+}
+
+func TestJQ(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`.a`, `[1]`},
+		{`.b[] | .x`, `[2,3]`},
+		{`[.b[].x] | add`, `[5]`},
+		{`[.,.] | length`, `[2]`},
+		{`.b | map(.x * 2)`, `[[4,6]]`},
+	}
+	for _, tt := range tests {
+		result, err := runJQ(t, `{"a": 1, "b": [{"x": 2}, {"x": 3}]}`, tt.filter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.filter, err)
+			continue
+		}
+		values := make([]any, len(result.Results))
+		for i, match := range result.Results {
+			values[i] = match.Value
+		}
+		got, _ := json.Marshal(values)
+		if string(got) != tt.want {
+			t.Errorf("%s = %s, want %s", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// Each step doubles the value, so 30 steps would build a billion leaves
+func TestJQBoundsValueSize(t *testing.T) {
+	filters := []string{
+		"1" + strings.Repeat(" | [.,.]", 30),
+		"[1]" + strings.Repeat(" | . + .", 30),
+		`"x"` + strings.Repeat(" | . + .", 30),
+		"{}" + strings.Repeat(" | {a: ., b: .}", 30),
+	}
+	for _, filter := range filters {
+		start := time.Now()
+		_, err := runJQ(t, `null`, filter)
+		if err == nil || !strings.Contains(err.Error(), "larger than") {
+			t.Errorf("%.20s...: err = %v, want value size limit", filter, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%.20s...: took %v", filter, elapsed)
+		}
+	}
+
+	// A document over the bound raises it to its own size, so it can still be
+	// passed through but not doubled
+	document := make([]any, maxJQValueSize+1)
+	e := newJQEvaluator(document)
+	if e.limit <= maxJQValueSize {
+		t.Errorf("limit = %d for a document of %d values", e.limit, len(document))
+	}
+	if _, err := e.eval(&jqExpr{kind: "identity"}, queryNode{value: document}); err != nil {
+		t.Errorf("large document: err = %v", err)
+	}
+	if _, err := e.binary("+", document, document); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("large document doubled: err = %v, want value size limit", err)
+	}
+}
@@ -0,0 +1,1010 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// maxQueryResults caps the matches returned for one query
+	maxQueryResults = 10000
+	// maxJSONPathInt is the largest integer JSONPath allows (I-JSON range)
+	maxJSONPathInt = 1<<53 - 1
+)
+
+// JSONQueryRequest runs a JSONPath or jq query against a document
+type JSONQueryRequest struct {
+	Document json.RawMessage `json:"document"`
+	Query    string          `json:"query"`
+	Language string          `json:"language"` // "jsonpath" or "jq"; guessed from the query when empty
+}
+
+// JSONQueryMatch is one value produced by a query
+type JSONQueryMatch struct {
+	Path    string `json:"path,omitempty"`    // normalized JSONPath, when the value comes from the document
+	Pointer string `json:"pointer,omitempty"` // JSON Pointer to the same location
+	Value   any    `json:"value"`
+}
+
+// JSONQueryResult holds the values a query produced, in order
+type JSONQueryResult struct {
+	Language  string           `json:"language"`
+	Count     int              `json:"count"`
+	Truncated bool             `json:"truncated,omitempty"`
+	Results   []JSONQueryMatch `json:"results"`
+	Timestamp time.Time        `json:"timestamp"`
+	QueryTime float64          `json:"query_time_ms"`
+}
+
+// queryNode is a value with its location in the document. path is nil for
+// values computed by a jq filter rather than selected from the input.
+type queryNode struct {
+	value any
+	path  []any // string member names and int array indexes
+}
+
+func (n queryNode) child(key any, value any) queryNode {
+	if n.path == nil {
+		return queryNode{value: value}
+	}
+	path := make([]any, len(n.path), len(n.path)+1)
+	copy(path, n.path)
+	return queryNode{value: value, path: append(path, key)}
+}
+
+// Query evaluates an RFC 9535 JSONPath expression or a jq filter
+func (s *JSONService) Query(req JSONQueryRequest) (*JSONQueryResult, error) {
+	start := time.Now()
+
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, fmt.Errorf("query is empty")
+	}
+	document, err := decodeJSONValue(req.Document)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document JSON: %w", err)
+	}
+
+	language := strings.ToLower(req.Language)
+	if language == "" {
+		language = "jq"
+		if strings.HasPrefix(query, "$") {
+			language = "jsonpath"
+		}
+	}
+
+	root := queryNode{value: document, path: []any{}}
+	var nodes []queryNode
+	switch language {
+	case "jsonpath":
+		path, err := parseJSONPath(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath: %w", err)
+		}
+		nodes = path.selectNodes(root, root)
+	case "jq":
+		filter, err := parseJQ(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jq filter: %w", err)
+		}
+		nodes, err = newJQEvaluator(root.value).eval(filter, root)
+		if err != nil {
+			return nil, fmt.Errorf("jq error: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported query language %q (supported: jsonpath, jq)", req.Language)
+	}
+
+	result := &JSONQueryResult{Language: language, Count: len(nodes), Results: []JSONQueryMatch{}}
+	if len(nodes) > maxQueryResults {
+		nodes = nodes[:maxQueryResults]
+		result.Truncated = true
+	}
+	for _, node := range nodes {
+		match := JSONQueryMatch{Value: node.value}
+		if node.path != nil {
+			match.Path = normalizedPath(node.path)
+			match.Pointer = pointerPath(node.path)
+		}
+		result.Results = append(result.Results, match)
+	}
+	result.Timestamp = time.Now()
+	result.QueryTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// normalizedPath writes a location as an RFC 9535 normalized path such as $['a'][0]
+func normalizedPath(path []any) string {
+	var sb strings.Builder
+	sb.WriteByte('$')
+	for _, segment := range path {
+		switch v := segment.(type) {
+		case int:
+			sb.WriteString("[" + strconv.Itoa(v) + "]")
+		case string:
+			sb.WriteString("['")
+			for _, r := range v {
+				switch r {
+				case '\'':
+					sb.WriteString(`\'`)
+				case '\\':
+					sb.WriteString(`\\`)
+				case '\b':
+					sb.WriteString(`\b`)
+				case '\f':
+					sb.WriteString(`\f`)
+				case '\n':
+					sb.WriteString(`\n`)
+				case '\r':
+					sb.WriteString(`\r`)
+				case '\t':
+					sb.WriteString(`\t`)
+				default:
+					if r < 0x20 {
+						fmt.Fprintf(&sb, `\u%04x`, r)
+					} else {
+						sb.WriteRune(r)
+					}
+				}
+			}
+			sb.WriteString("']")
+		}
+	}
+	return sb.String()
+}
+
+// pointerPath writes a location as a JSON Pointer
+func pointerPath(path []any) string {
+	var sb strings.Builder
+	for _, segment := range path {
+		sb.WriteByte('/')
+		switch v := segment.(type) {
+		case int:
+			sb.WriteString(strconv.Itoa(v))
+		case string:
+			sb.WriteString(escapePointer(v))
+		}
+	}
+	return sb.String()
+}
+
+// jsonPathQuery is a parsed JSONPath: $ or @ followed by segments
+type jsonPathQuery struct {
+	relative bool // starts with @ inside a filter
+	segments []jsonPathSegment
+}
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelectorKind int
+
+const (
+	selectName jsonPathSelectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type jsonPathSelector struct {
+	kind   jsonPathSelectorKind
+	name   string
+	index  int
+	slice  [3]*int // start, end, step
+	filter *jsonPathExpr
+}
+
+// jsonPathExpr is a node of a filter expression
+type jsonPathExpr struct {
+	op       string // "||", "&&", "!", "group", comparison operators, "test", "literal", "query" or "func"
+	operands []*jsonPathExpr
+	value    any            // literal value
+	query    *jsonPathQuery // query operand or existence test
+	function string
+}
+
+// Function result types from RFC 9535 section 2.4.1
+var jsonPathFunctions = map[string]string{
+	"length": "value",
+	"count":  "value",
+	"value":  "value",
+	"match":  "logical",
+	"search": "logical",
+}
+
+// singular reports whether a query selects at most one node
+func (q *jsonPathQuery) singular() bool {
+	for _, segment := range q.segments {
+		if segment.descendant || len(segment.selectors) != 1 {
+			return false
+		}
+		if kind := segment.selectors[0].kind; kind != selectName && kind != selectIndex {
+			return false
+		}
+	}
+	return true
+}
+
+// selectNodes applies the query to root ($) or current (@)
+func (q *jsonPathQuery) selectNodes(root, current queryNode) []queryNode {
+	nodes := []queryNode{root}
+	if q.relative {
+		nodes = []queryNode{current}
+	}
+	for _, segment := range q.segments {
+		var next []queryNode
+		for _, node := range nodes {
+			if segment.descendant {
+				for _, descendant := range descendants(node, nil) {
+					next = segment.apply(next, root, descendant)
+				}
+			} else {
+				next = segment.apply(next, root, node)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// descendants lists a node and everything below it, pre-order with object
+// members in key order
+func descendants(node queryNode, out []queryNode) []queryNode {
+	out = append(out, node)
+	switch v := node.value.(type) {
+	case []any:
+		for i, item := range v {
+			out = descendants(node.child(i, item), out)
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			out = descendants(node.child(key, v[key]), out)
+		}
+	}
+	return out
+}
+
+func (s jsonPathSegment) apply(out []queryNode, root, node queryNode) []queryNode {
+	for _, sel := range s.selectors {
+		switch sel.kind {
+		case selectName:
+			if obj, ok := node.value.(map[string]any); ok {
+				if value, ok := obj[sel.name]; ok {
+					out = append(out, node.child(sel.name, value))
+				}
+			}
+		case selectWildcard:
+			out = append(out, children(node)...)
+		case selectIndex:
+			if list, ok := node.value.([]any); ok {
+				i := sel.index
+				if i < 0 {
+					i += len(list)
+				}
+				if i >= 0 && i < len(list) {
+					out = append(out, node.child(i, list[i]))
+				}
+			}
+		case selectSlice:
+			if list, ok := node.value.([]any); ok {
+				for _, i := range sliceIndexes(len(list), sel.slice) {
+					out = append(out, node.child(i, list[i]))
+				}
+			}
+		case selectFilter:
+			for _, child := range children(node) {
+				if sel.filter.test(root, child) {
+					out = append(out, child)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func children(node queryNode) []queryNode {
+	var out []queryNode
+	switch v := node.value.(type) {
+	case []any:
+		for i, item := range v {
+			out = append(out, node.child(i, item))
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			out = append(out, node.child(key, v[key]))
+		}
+	}
+	return out
+}
+
+// sliceIndexes implements array slice selection from RFC 9535 section 2.3.4.2
+func sliceIndexes(length int, bounds [3]*int) []int {
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return nil
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	start, end := 0, length
+	if step < 0 {
+		start, end = length-1, -length-1
+	}
+	if bounds[0] != nil {
+		start = normalize(*bounds[0])
+	}
+	if bounds[1] != nil {
+		end = normalize(*bounds[1])
+	}
+
+	var indexes []int
+	if step > 0 {
+		lower, upper := min(max(start, 0), length), min(max(end, 0), length)
+		for i := lower; i < upper; i += step {
+			indexes = append(indexes, i)
+		}
+	} else {
+		upper, lower := min(max(start, -1), length-1), min(max(end, -1), length-1)
+		for i := upper; lower < i; i += step {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// test evaluates a logical filter expression against the current node
+func (e *jsonPathExpr) test(root, current queryNode) bool {
+	switch e.op {
+	case "||":
+		return e.operands[0].test(root, current) || e.operands[1].test(root, current)
+	case "&&":
+		return e.operands[0].test(root, current) && e.operands[1].test(root, current)
+	case "!":
+		return !e.operands[0].test(root, current)
+	case "group":
+		return e.operands[0].test(root, current)
+	case "test":
+		return len(e.query.selectNodes(root, current)) > 0
+	case "func":
+		result, _ := e.call(root, current)
+		return result == true
+	}
+
+	left, leftOK := e.operands[0].comparable(root, current)
+	right, rightOK := e.operands[1].comparable(root, current)
+	switch e.op {
+	case "==":
+		return jsonPathEqual(left, leftOK, right, rightOK)
+	case "!=":
+		return !jsonPathEqual(left, leftOK, right, rightOK)
+	case "<":
+		return leftOK && rightOK && jsonPathLess(left, right)
+	case ">":
+		return leftOK && rightOK && jsonPathLess(right, left)
+	case "<=":
+		return leftOK && rightOK && jsonPathLess(left, right) || jsonPathEqual(left, leftOK, right, rightOK)
+	case ">=":
+		return leftOK && rightOK && jsonPathLess(right, left) || jsonPathEqual(left, leftOK, right, rightOK)
+	}
+	return false
+}
+
+// comparable evaluates a literal, singular query or value function. ok is
+// false for Nothing, the result of a query that selects no node.
+func (e *jsonPathExpr) comparable(root, current queryNode) (any, bool) {
+	switch e.op {
+	case "literal":
+		return e.value, true
+	case "query":
+		nodes := e.query.selectNodes(root, current)
+		if len(nodes) != 1 {
+			return nil, false
+		}
+		return nodes[0].value, true
+	case "func":
+		return e.call(root, current)
+	}
+	return nil, false
+}
+
+// call evaluates one of the RFC 9535 function extensions
+func (e *jsonPathExpr) call(root, current queryNode) (any, bool) {
+	switch e.function {
+	case "length":
+		value, ok := e.operands[0].comparable(root, current)
+		if !ok {
+			return nil, false
+		}
+		switch v := value.(type) {
+		case string:
+			return json.Number(strconv.Itoa(utf8.RuneCountInString(v))), true
+		case []any:
+			return json.Number(strconv.Itoa(len(v))), true
+		case map[string]any:
+			return json.Number(strconv.Itoa(len(v))), true
+		}
+		return nil, false
+	case "count":
+		return json.Number(strconv.Itoa(len(e.operands[0].query.selectNodes(root, current)))), true
+	case "value":
+		nodes := e.operands[0].query.selectNodes(root, current)
+		if len(nodes) != 1 {
+			return nil, false
+		}
+		return nodes[0].value, true
+	case "match", "search":
+		value, ok := e.operands[0].comparable(root, current)
+		pattern, patternOK := e.operands[1].comparable(root, current)
+		text, isString := value.(string)
+		expr, exprIsString := pattern.(string)
+		if !ok || !patternOK || !isString || !exprIsString {
+			return false, true
+		}
+		re, err := iRegexp(expr, e.function == "match")
+		if err != nil {
+			return false, true
+		}
+		return re.MatchString(text), true
+	}
+	return nil, false
+}
+
+// iRegexp compiles an RFC 9485 I-Regexp, where "." excludes line breaks and
+// match() must cover the whole string
+func iRegexp(expr string, whole bool) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	inClass := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && i+1 < len(expr):
+			sb.WriteString(expr[i : i+2])
+			i++
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '.' && !inClass:
+			sb.WriteString(`[^\n\r]`)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	if whole {
+		return regexp.Compile(`^(?:` + sb.String() + `)$`)
+	}
+	return regexp.Compile(sb.String())
+}
+
+func jsonPathEqual(left any, leftOK bool, right any, rightOK bool) bool {
+	if !leftOK || !rightOK {
+		return leftOK == rightOK
+	}
+	return jsonEqual(left, right)
+}
+
+// jsonPathLess orders numbers numerically and strings by code point
+func jsonPathLess(left, right any) bool {
+	switch x := left.(type) {
+	case json.Number:
+		y, ok := right.(json.Number)
+		if !ok {
+			return false
+		}
+		a, okA := new(big.Rat).SetString(x.String())
+		b, okB := new(big.Rat).SetString(y.String())
+		return okA && okB && a.Cmp(b) < 0
+	case string:
+		y, ok := right.(string)
+		return ok && x < y
+	}
+	return false
+}
+
+// jsonPathParser parses RFC 9535 JSONPath queries
+type jsonPathParser struct {
+	src string
+	pos int
+}
+
+func parseJSONPath(src string) (*jsonPathQuery, error) {
+	p := &jsonPathParser{src: src}
+	if !p.consume("$") {
+		return nil, fmt.Errorf("query must start with $")
+	}
+	query, err := p.segments(false)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.rest())
+	}
+	return query, nil
+}
+
+func (p *jsonPathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *jsonPathParser) rest() string {
+	rest := p.src[p.pos:]
+	if len(rest) > 10 {
+		rest = rest[:10] + "..."
+	}
+	return rest
+}
+
+func (p *jsonPathParser) peek(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *jsonPathParser) consume(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipBlank() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n\r", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// segments reads the segments following $ or @
+func (p *jsonPathParser) segments(relative bool) (*jsonPathQuery, error) {
+	query := &jsonPathQuery{relative: relative}
+	for {
+		mark := p.pos
+		p.skipBlank()
+		var segment jsonPathSegment
+		switch {
+		case p.consume(".."):
+			segment.descendant = true
+			if p.peek("[") {
+				selectors, err := p.bracket()
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = selectors
+			} else {
+				selector, err := p.dotSelector()
+				if err != nil {
+					return nil, err
+				}
+				segment.selectors = []jsonPathSelector{selector}
+			}
+		case p.consume("."):
+			selector, err := p.dotSelector()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = []jsonPathSelector{selector}
+		case p.peek("["):
+			selectors, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			segment.selectors = selectors
+		default:
+			p.pos = mark
+			return query, nil
+		}
+		query.segments = append(query.segments, segment)
+	}
+}
+
+// dotSelector reads the * or member name after . or ..
+func (p *jsonPathParser) dotSelector() (jsonPathSelector, error) {
+	if p.consume("*") {
+		return jsonPathSelector{kind: selectWildcard}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		nameFirst := r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= 0x80 && r != utf8.RuneError
+		if !nameFirst && !(p.pos > start && r >= '0' && r <= '9') {
+			break
+		}
+		p.pos += size
+	}
+	if start == p.pos {
+		return jsonPathSelector{}, fmt.Errorf("expected a member name or * after .")
+	}
+	return jsonPathSelector{kind: selectName, name: p.src[start:p.pos]}, nil
+}
+
+// bracket reads a bracketed, comma separated selector list
+func (p *jsonPathParser) bracket() ([]jsonPathSelector, error) {
+	p.pos++
+	var selectors []jsonPathSelector
+	for {
+		p.skipBlank()
+		selector, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+		p.skipBlank()
+		if p.consume("]") {
+			return selectors, nil
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expected , or ] in selector list")
+		}
+	}
+}
+
+func (p *jsonPathParser) selector() (jsonPathSelector, error) {
+	if p.pos >= len(p.src) {
+		return jsonPathSelector{}, fmt.Errorf("unterminated selector list")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '\'' || c == '"':
+		name, err := p.stringLiteral()
+		return jsonPathSelector{kind: selectName, name: name}, err
+	case c == '*':
+		p.pos++
+		return jsonPathSelector{kind: selectWildcard}, nil
+	case c == '?':
+		p.pos++
+		p.skipBlank()
+		filter, err := p.logicalOr()
+		return jsonPathSelector{kind: selectFilter, filter: filter}, err
+	}
+
+	// index or slice
+	var bounds [3]*int
+	for part := 0; part < 3; part++ {
+		p.skipBlank()
+		if p.pos < len(p.src) && (p.src[p.pos] == '-' || isDigit(p.src[p.pos])) {
+			n, err := p.integer()
+			if err != nil {
+				return jsonPathSelector{}, err
+			}
+			bounds[part] = &n
+			p.skipBlank()
+		}
+		if part == 0 && !p.peek(":") {
+			if bounds[0] == nil {
+				return jsonPathSelector{}, fmt.Errorf("invalid selector %q", p.rest())
+			}
+			return jsonPathSelector{kind: selectIndex, index: *bounds[0]}, nil
+		}
+		if part < 2 && !p.consume(":") {
+			break
+		}
+	}
+	return jsonPathSelector{kind: selectSlice, slice: bounds}, nil
+}
+
+// integer reads an integer without leading zeros in the I-JSON range
+func (p *jsonPathParser) integer() (int, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	if p.pos == digits || (p.src[digits] == '0' && (p.pos-digits > 1 || text == "-0")) {
+		return 0, fmt.Errorf("invalid integer %q", text)
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n > maxJSONPathInt || n < -maxJSONPathInt {
+		return 0, fmt.Errorf("integer %s out of range", text)
+	}
+	return n, nil
+}
+
+// stringLiteral reads a single or double quoted string with JSON escapes
+func (p *jsonPathParser) stringLiteral() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c < 0x20:
+			return "", fmt.Errorf("control character in string")
+		case c != '\\':
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		if p.pos+1 >= len(p.src) {
+			return "", fmt.Errorf("unterminated escape")
+		}
+		escape := p.src[p.pos+1]
+		p.pos += 2
+		switch escape {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '/', '\\':
+			sb.WriteByte(escape)
+		case '\'', '"':
+			if escape != quote {
+				return "", fmt.Errorf("invalid escape \\%c", escape)
+			}
+			sb.WriteByte(escape)
+		case 'u':
+			r, err := p.unicodeEscape()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", escape)
+		}
+	}
+}
+
+// unicodeEscape reads the hex digits of \uXXXX, joining surrogate pairs
+func (p *jsonPathParser) unicodeEscape() (rune, error) {
+	hex := func() (rune, error) {
+		if p.pos+4 > len(p.src) {
+			return 0, fmt.Errorf("short \\u escape")
+		}
+		n, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid \\u escape")
+		}
+		p.pos += 4
+		return rune(n), nil
+	}
+	r, err := hex()
+	if err != nil || !utf16.IsSurrogate(r) {
+		return r, err
+	}
+	if r >= 0xDC00 || !p.consume(`\u`) {
+		return 0, fmt.Errorf("unpaired surrogate in \\u escape")
+	}
+	low, err := hex()
+	if err != nil {
+		return 0, err
+	}
+	if decoded := utf16.DecodeRune(r, low); decoded != utf8.RuneError {
+		return decoded, nil
+	}
+	return 0, fmt.Errorf("unpaired surrogate in \\u escape")
+}
+
+func (p *jsonPathParser) logicalOr() (*jsonPathExpr, error) {
+	left, err := p.logicalAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipBlank()
+		if !p.consume("||") {
+			return left, nil
+		}
+		p.skipBlank()
+		right, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathExpr{op: "||", operands: []*jsonPathExpr{left, right}}
+	}
+}
+
+func (p *jsonPathParser) logicalAnd() (*jsonPathExpr, error) {
+	left, err := p.basicExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipBlank()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		p.skipBlank()
+		right, err := p.basicExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = &jsonPathExpr{op: "&&", operands: []*jsonPathExpr{left, right}}
+	}
+}
+
+// basicExpr reads a parenthesized expression, a negation, a comparison or a test
+func (p *jsonPathParser) basicExpr() (*jsonPathExpr, error) {
+	if p.consume("!") {
+		p.skipBlank()
+		operand, err := p.basicExpr()
+		if err != nil {
+			return nil, err
+		}
+		if isComparison(operand.op) {
+			return nil, fmt.Errorf("! cannot be applied to a comparison without parentheses")
+		}
+		return &jsonPathExpr{op: "!", operands: []*jsonPathExpr{operand}}, nil
+	}
+	if p.consume("(") {
+		p.skipBlank()
+		expr, err := p.logicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipBlank()
+		if !p.consume(")") {
+			return nil, fmt.Errorf("expected )")
+		}
+		return &jsonPathExpr{op: "group", operands: []*jsonPathExpr{expr}}, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.consume(op) {
+			continue
+		}
+		p.skipBlank()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		for _, side := range []*jsonPathExpr{left, right} {
+			if err := checkComparable(side); err != nil {
+				return nil, err
+			}
+		}
+		return &jsonPathExpr{op: op, operands: []*jsonPathExpr{left, right}}, nil
+	}
+
+	switch {
+	case left.op == "query":
+		return &jsonPathExpr{op: "test", query: left.query}, nil
+	case left.op == "func" && jsonPathFunctions[left.function] == "logical":
+		return left, nil
+	case left.op == "func":
+		return nil, fmt.Errorf("%s() must be compared to a value", left.function)
+	}
+	return nil, fmt.Errorf("a literal must be compared to a value")
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// checkComparable enforces that compared queries are singular and functions return values
+func checkComparable(e *jsonPathExpr) error {
+	switch {
+	case e.op == "query" && !e.query.singular():
+		return fmt.Errorf("only singular queries can be compared")
+	case e.op == "func" && jsonPathFunctions[e.function] != "value":
+		return fmt.Errorf("%s() returns a logical value and cannot be compared", e.function)
+	}
+	return nil
+}
+
+// operand reads a literal, a query or a function call
+func (p *jsonPathParser) operand() (*jsonPathExpr, error) {
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '@' || c == '$':
+		p.pos++
+		query, err := p.segments(c == '@')
+		if err != nil {
+			return nil, err
+		}
+		return &jsonPathExpr{op: "query", query: query}, nil
+	case c == '\'' || c == '"':
+		text, err := p.stringLiteral()
+		return &jsonPathExpr{op: "literal", value: text}, err
+	case c == '-' || isDigit(c):
+		start := p.pos
+		p.consume("-")
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		number := p.src[start:p.pos]
+		if !jsonNumberPattern.MatchString(number) && number != "-0" {
+			return nil, fmt.Errorf("invalid number %q", number)
+		}
+		return &jsonPathExpr{op: "literal", value: json.Number(number)}, nil
+	}
+
+	for _, literal := range []struct {
+		text  string
+		value any
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if p.consume(literal.text) {
+			return &jsonPathExpr{op: "literal", value: literal.value}, nil
+		}
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] >= 'a' && p.src[p.pos] <= 'z' || p.src[p.pos] == '_' || p.pos > start && isDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	name := p.src[start:p.pos]
+	if name == "" || !p.consume("(") {
+		return nil, fmt.Errorf("unexpected %q in filter", p.rest())
+	}
+	if _, ok := jsonPathFunctions[name]; !ok {
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	var args []*jsonPathExpr
+	for {
+		p.skipBlank()
+		arg, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipBlank()
+		if p.consume(")") {
+			break
+		}
+		if !p.consume(",") {
+			return nil, fmt.Errorf("expected , or ) in %s()", name)
+		}
+	}
+	return checkFunction(&jsonPathExpr{op: "func", function: name, operands: args})
+}
+
+// checkFunction validates argument counts and types
+func checkFunction(e *jsonPathExpr) (*jsonPathExpr, error) {
+	want := 1
+	if e.function == "match" || e.function == "search" {
+		want = 2
+	}
+	if len(e.operands) != want {
+		return nil, fmt.Errorf("%s() takes %d argument(s)", e.function, want)
+	}
+	for _, arg := range e.operands {
+		if e.function == "count" || e.function == "value" {
+			if arg.op != "query" {
+				return nil, fmt.Errorf("%s() requires a query argument", e.function)
+			}
+			continue
+		}
+		if err := checkComparable(arg); err != nil {
+			return nil, fmt.Errorf("%s(): %w", e.function, err)
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,955 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	// maxSchemaDepth bounds recursion through $ref cycles that never consume the instance
+	maxSchemaDepth = 512
+	// maxSchemaEvaluations bounds the subschema applications of one validation,
+	// since repeated $ref targets can make the work exponential in the depth
+	maxSchemaEvaluations = 1_000_000
+	// maxSchemaErrors bounds the errors kept for one schema and instance location
+	maxSchemaErrors = 1000
+)
+
+// JSONSchemaRequest pairs a JSON Schema with an instance to validate
+type JSONSchemaRequest struct {
+	Schema        json.RawMessage `json:"schema"`
+	Instance      json.RawMessage `json:"instance"`
+	IgnoreFormats bool            `json:"ignore_formats"` // treat "format" as an annotation only
+}
+
+// JSONSchemaError is one violation of a schema keyword
+type JSONSchemaError struct {
+	InstanceLocation string            `json:"instance_location"` // JSON Pointer into the instance
+	KeywordLocation  string            `json:"keyword_location"`  // JSON Pointer into the schema, through $ref
+	Keyword          string            `json:"keyword"`
+	Message          string            `json:"message"`
+	Causes           []JSONSchemaError `json:"causes,omitempty"` // failures of anyOf/oneOf branches
+}
+
+// JSONSchemaResult reports every violation found while validating an instance
+type JSONSchemaResult struct {
+	Valid          bool              `json:"valid"`
+	Errors         []JSONSchemaError `json:"errors"`
+	Warnings       []string          `json:"warnings,omitempty"`
+	Timestamp      time.Time         `json:"timestamp"`
+	ValidationTime float64           `json:"validation_time_ms"`
+}
+
+// ValidateSchema validates an instance against a draft 2020-12 JSON Schema.
+// An error is returned when either document is not JSON or the schema is unusable.
+func (s *JSONService) ValidateSchema(req JSONSchemaRequest) (*JSONSchemaResult, error) {
+	start := time.Now()
+
+	schema, err := decodeJSONValue(req.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	instance, err := decodeJSONValue(req.Instance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid instance JSON: %w", err)
+	}
+
+	v := &schemaValidator{
+		root:    schema,
+		ids:     make(map[string]any),
+		anchors: make(map[string]any),
+		regexps: make(map[string]*regexp.Regexp),
+		formats: !req.IgnoreFormats,
+		memo:    make(map[schemaMemoKey]schemaMemo),
+	}
+	if err := v.index(schema, ""); err != nil {
+		return nil, err
+	}
+
+	result := &JSONSchemaResult{Errors: []JSONSchemaError{}}
+	if root, ok := schema.(map[string]any); ok {
+		if dialect, ok := root["$schema"].(string); ok && strings.TrimSuffix(dialect, "#") != jsonSchemaDialect {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("$schema %s is not draft 2020-12; validating with 2020-12 rules", dialect))
+		}
+	}
+
+	eval := v.validate(schema, instance, "", "")
+	if v.err != nil {
+		return nil, v.err
+	}
+
+	result.Errors = append(result.Errors, eval.errors...)
+	if v.truncated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("error list truncated to %d entries per location", maxSchemaErrors))
+	}
+	result.Valid = len(result.Errors) == 0
+	result.Timestamp = time.Now()
+	result.ValidationTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// decodeJSONValue decodes a single JSON value, keeping numbers exact
+func decodeJSONValue(raw json.RawMessage) (any, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, fmt.Errorf("document is empty")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+type schemaValidator struct {
+	root      any
+	ids       map[string]any // subschemas by $id
+	anchors   map[string]any // subschemas by $anchor and $dynamicAnchor
+	regexps   map[string]*regexp.Regexp
+	formats   bool
+	depth     int
+	memo      map[schemaMemoKey]schemaMemo // finished evaluations, reused when a subschema is reached again
+	steps     int                          // subschema applications and reused errors, bounded by maxSchemaEvaluations
+	truncated bool                         // some error lists were cut to maxSchemaErrors
+	err       error                        // first fatal schema problem, such as an unresolvable $ref
+}
+
+// schemaMemoKey identifies one subschema object applied at one instance location
+type schemaMemoKey struct {
+	node    uintptr
+	instLoc string
+}
+
+// schemaMemo is a finished evaluation and the schema path it was reached through
+type schemaMemo struct {
+	eval  *schemaEval
+	kwLoc string
+}
+
+// schemaEval is the outcome of applying one schema to one instance location,
+// including the properties and items it evaluated for unevaluated* keywords
+type schemaEval struct {
+	errors   []JSONSchemaError
+	props    map[string]bool
+	items    map[int]bool
+	allItems bool
+}
+
+func (e *schemaEval) fail(instLoc, kwLoc, keyword, format string, args ...any) {
+	e.errors = append(e.errors, JSONSchemaError{
+		InstanceLocation: instLoc,
+		KeywordLocation:  kwLoc + "/" + keyword,
+		Keyword:          keyword,
+		Message:          fmt.Sprintf(format, args...),
+	})
+}
+
+// merge adopts the evaluation annotations of a successful subschema
+func (e *schemaEval) merge(sub *schemaEval) {
+	for name := range sub.props {
+		e.props[name] = true
+	}
+	for i := range sub.items {
+		e.items[i] = true
+	}
+	e.allItems = e.allItems || sub.allItems
+}
+
+// index records $id and $anchor locations so $ref can resolve them
+func (v *schemaValidator) index(schema any, location string) error {
+	switch node := schema.(type) {
+	case map[string]any:
+		if id, ok := node["$id"].(string); ok {
+			v.ids[strings.TrimSuffix(id, "#")] = node
+		}
+		for _, keyword := range []string{"$anchor", "$dynamicAnchor"} {
+			if anchor, ok := node[keyword].(string); ok {
+				v.anchors[anchor] = node
+			}
+		}
+		for key, child := range node {
+			// Values of enum, const and examples are data, not schemas
+			if key == "enum" || key == "const" || key == "examples" || key == "default" {
+				continue
+			}
+			if err := v.index(child, location+"/"+escapePointer(key)); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, child := range node {
+			if err := v.index(child, fmt.Sprintf("%s/%d", location, i)); err != nil {
+				return err
+			}
+		}
+	case bool, nil, string, json.Number:
+	default:
+		return fmt.Errorf("unexpected schema value at %q", location)
+	}
+	return nil
+}
+
+// resolve finds the subschema a $ref points to
+func (v *schemaValidator) resolve(ref string) (any, error) {
+	base, fragment, _ := strings.Cut(ref, "#")
+
+	target := v.root
+	if base != "" {
+		doc, ok := v.ids[base]
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q: only local references are supported", ref)
+		}
+		target = doc
+	}
+
+	if fragment == "" {
+		return target, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		anchor, ok := v.anchors[fragment]
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q: unknown anchor", ref)
+		}
+		return anchor, nil
+	}
+
+	unescaped, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %v", ref, err)
+	}
+	for _, token := range strings.Split(unescaped[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch node := target.(type) {
+		case map[string]any:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("cannot resolve $ref %q: %q not found", ref, token)
+			}
+			target = child
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("cannot resolve $ref %q: bad index %q", ref, token)
+			}
+			target = node[i]
+		default:
+			return nil, fmt.Errorf("cannot resolve $ref %q", ref)
+		}
+	}
+	return target, nil
+}
+
+// step charges n units of work against maxSchemaEvaluations, failing validation once it is spent
+func (v *schemaValidator) step(n int) bool {
+	v.steps += n
+	if v.steps > maxSchemaEvaluations {
+		v.err = fmt.Errorf("schema validation exceeds %d subschema evaluations", maxSchemaEvaluations)
+		return false
+	}
+	return true
+}
+
+// relocate reuses an earlier evaluation of the same subschema and instance
+// location reached through another schema path, rewriting its keyword locations
+func (v *schemaValidator) relocate(memo schemaMemo, kwLoc string) *schemaEval {
+	if kwLoc == memo.kwLoc || len(memo.eval.errors) == 0 {
+		return memo.eval
+	}
+	eval := *memo.eval
+	eval.errors = v.relocateErrors(memo.eval.errors, memo.kwLoc, kwLoc)
+	return &eval
+}
+
+func (v *schemaValidator) relocateErrors(errs []JSONSchemaError, from, to string) []JSONSchemaError {
+	if len(errs) == 0 || !v.step(len(errs)) {
+		return nil
+	}
+	moved := make([]JSONSchemaError, len(errs))
+	for i, e := range errs {
+		e.KeywordLocation = to + strings.TrimPrefix(e.KeywordLocation, from)
+		e.Causes = v.relocateErrors(e.Causes, from, to)
+		moved[i] = e
+	}
+	return moved
+}
+
+// validate applies schema to instance. instLoc and kwLoc are JSON Pointers to
+// the instance value and the schema being applied.
+func (v *schemaValidator) validate(schema, instance any, instLoc, kwLoc string) *schemaEval {
+	eval := &schemaEval{props: make(map[string]bool), items: make(map[int]bool)}
+
+	switch node := schema.(type) {
+	case bool:
+		if !node {
+			eval.errors = append(eval.errors, JSONSchemaError{
+				InstanceLocation: instLoc,
+				KeywordLocation:  kwLoc,
+				Keyword:          "false",
+				Message:          "no value is allowed here",
+			})
+		}
+		return eval
+	case map[string]any:
+		if v.err != nil {
+			return eval
+		}
+		v.depth++
+		defer func() { v.depth-- }()
+		if v.depth > maxSchemaDepth {
+			v.err = fmt.Errorf("schema recursion exceeds %d levels; check for a $ref cycle", maxSchemaDepth)
+			return eval
+		}
+		key := schemaMemoKey{node: reflect.ValueOf(node).Pointer(), instLoc: instLoc}
+		if memo, ok := v.memo[key]; ok {
+			return v.relocate(memo, kwLoc)
+		}
+		if !v.step(1) {
+			return eval
+		}
+
+		v.applyRefs(node, instance, instLoc, kwLoc, eval)
+		v.applyGeneric(node, instance, instLoc, kwLoc, eval)
+		v.applyCombinators(node, instance, instLoc, kwLoc, eval)
+
+		switch value := instance.(type) {
+		case json.Number:
+			v.applyNumber(node, value, instLoc, kwLoc, eval)
+		case string:
+			v.applyString(node, value, instLoc, kwLoc, eval)
+		case []any:
+			v.applyArray(node, value, instLoc, kwLoc, eval)
+		case map[string]any:
+			v.applyObject(node, value, instLoc, kwLoc, eval)
+		}
+
+		if len(eval.errors) > maxSchemaErrors {
+			eval.errors = eval.errors[:maxSchemaErrors:maxSchemaErrors]
+			v.truncated = true
+		}
+		if v.err == nil {
+			v.memo[key] = schemaMemo{eval: eval, kwLoc: kwLoc}
+		}
+		return eval
+	default:
+		v.err = fmt.Errorf("schema at %q must be an object or boolean", kwLoc)
+		return eval
+	}
+}
+
+func (v *schemaValidator) applyRefs(node map[string]any, instance any, instLoc, kwLoc string, eval *schemaEval) {
+	// $dynamicRef is resolved statically, which matches $ref for non-extending schemas
+	for _, keyword := range []string{"$ref", "$dynamicRef"} {
+		ref, ok := node[keyword].(string)
+		if !ok {
+			continue
+		}
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.err = err
+			return
+		}
+		sub := v.validate(target, instance, instLoc, kwLoc+"/"+keyword)
+		eval.errors = append(eval.errors, sub.errors...)
+		if len(sub.errors) == 0 {
+			eval.merge(sub)
+		}
+	}
+}
+
+func (v *schemaValidator) applyGeneric(node map[string]any, instance any, instLoc, kwLoc string, eval *schemaEval) {
+	if types, ok := node["type"]; ok {
+		var allowed []string
+		switch t := types.(type) {
+		case string:
+			allowed = []string{t}
+		case []any:
+			for _, item := range t {
+				if name, ok := item.(string); ok {
+					allowed = append(allowed, name)
+				}
+			}
+		}
+		matched := false
+		for _, name := range allowed {
+			if schemaTypeMatches(name, instance) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			eval.fail(instLoc, kwLoc, "type", "expected %s, got %s", strings.Join(allowed, " or "), schemaTypeOf(instance))
+		}
+	}
+
+	if enum, ok := node["enum"].([]any); ok {
+		matched := false
+		for _, option := range enum {
+			if jsonEqual(option, instance) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			eval.fail(instLoc, kwLoc, "enum", "value must be one of %s", compactJSON(enum))
+		}
+	}
+
+	if constant, ok := node["const"]; ok && !jsonEqual(constant, instance) {
+		eval.fail(instLoc, kwLoc, "const", "value must be %s", compactJSON(constant))
+	}
+}
+
+func (v *schemaValidator) applyCombinators(node map[string]any, instance any, instLoc, kwLoc string, eval *schemaEval) {
+	if schemas, ok := node["allOf"].([]any); ok {
+		for i, sub := range schemas {
+			result := v.validate(sub, instance, instLoc, fmt.Sprintf("%s/allOf/%d", kwLoc, i))
+			eval.errors = append(eval.errors, result.errors...)
+			if len(result.errors) == 0 {
+				eval.merge(result)
+			}
+		}
+	}
+
+	if schemas, ok := node["anyOf"].([]any); ok {
+		var causes []JSONSchemaError
+		matched := 0
+		// Every branch is evaluated so its annotations are collected
+		for i, sub := range schemas {
+			result := v.validate(sub, instance, instLoc, fmt.Sprintf("%s/anyOf/%d", kwLoc, i))
+			if len(result.errors) == 0 {
+				matched++
+				eval.merge(result)
+			} else {
+				causes = append(causes, result.errors...)
+			}
+		}
+		if matched == 0 {
+			eval.fail(instLoc, kwLoc, "anyOf", "value does not match any of the %d schemas", len(schemas))
+			eval.errors[len(eval.errors)-1].Causes = causes
+		}
+	}
+
+	if schemas, ok := node["oneOf"].([]any); ok {
+		var causes []JSONSchemaError
+		var matches []int
+		for i, sub := range schemas {
+			result := v.validate(sub, instance, instLoc, fmt.Sprintf("%s/oneOf/%d", kwLoc, i))
+			if len(result.errors) == 0 {
+				matches = append(matches, i)
+				eval.merge(result)
+			} else {
+				causes = append(causes, result.errors...)
+			}
+		}
+		switch len(matches) {
+		case 1:
+		case 0:
+			eval.fail(instLoc, kwLoc, "oneOf", "value does not match any of the %d schemas", len(schemas))
+			eval.errors[len(eval.errors)-1].Causes = causes
+		default:
+			eval.fail(instLoc, kwLoc, "oneOf", "value matches %d schemas (indexes %v) but must match exactly one", len(matches), matches)
+		}
+	}
+
+	if sub, ok := node["not"]; ok {
+		if result := v.validate(sub, instance, instLoc, kwLoc+"/not"); len(result.errors) == 0 {
+			eval.fail(instLoc, kwLoc, "not", "value must not match the schema")
+		}
+	}
+
+	if condition, ok := node["if"]; ok {
+		result := v.validate(condition, instance, instLoc, kwLoc+"/if")
+		branch := "else"
+		if len(result.errors) == 0 {
+			branch = "then"
+			eval.merge(result)
+		}
+		if sub, ok := node[branch]; ok {
+			result := v.validate(sub, instance, instLoc, kwLoc+"/"+branch)
+			eval.errors = append(eval.errors, result.errors...)
+			if len(result.errors) == 0 {
+				eval.merge(result)
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) applyNumber(node map[string]any, value json.Number, instLoc, kwLoc string, eval *schemaEval) {
+	number, ok := new(big.Rat).SetString(value.String())
+	if !ok {
+		return
+	}
+	bound := func(keyword string) (*big.Rat, bool) {
+		limit, ok := node[keyword].(json.Number)
+		if !ok {
+			return nil, false
+		}
+		return new(big.Rat).SetString(limit.String())
+	}
+
+	if divisor, ok := bound("multipleOf"); ok && divisor.Sign() > 0 {
+		if !new(big.Rat).Quo(number, divisor).IsInt() {
+			eval.fail(instLoc, kwLoc, "multipleOf", "%s is not a multiple of %s", value, node["multipleOf"])
+		}
+	}
+	if limit, ok := bound("maximum"); ok && number.Cmp(limit) > 0 {
+		eval.fail(instLoc, kwLoc, "maximum", "%s is greater than the maximum %s", value, node["maximum"])
+	}
+	if limit, ok := bound("exclusiveMaximum"); ok && number.Cmp(limit) >= 0 {
+		eval.fail(instLoc, kwLoc, "exclusiveMaximum", "%s must be less than %s", value, node["exclusiveMaximum"])
+	}
+	if limit, ok := bound("minimum"); ok && number.Cmp(limit) < 0 {
+		eval.fail(instLoc, kwLoc, "minimum", "%s is less than the minimum %s", value, node["minimum"])
+	}
+	if limit, ok := bound("exclusiveMinimum"); ok && number.Cmp(limit) <= 0 {
+		eval.fail(instLoc, kwLoc, "exclusiveMinimum", "%s must be greater than %s", value, node["exclusiveMinimum"])
+	}
+}
+
+func (v *schemaValidator) applyString(node map[string]any, value string, instLoc, kwLoc string, eval *schemaEval) {
+	length := utf8.RuneCountInString(value)
+	if limit, ok := schemaInt(node["maxLength"]); ok && length > limit {
+		eval.fail(instLoc, kwLoc, "maxLength", "string has %d characters, more than the maximum %d", length, limit)
+	}
+	if limit, ok := schemaInt(node["minLength"]); ok && length < limit {
+		eval.fail(instLoc, kwLoc, "minLength", "string has %d characters, fewer than the minimum %d", length, limit)
+	}
+	if pattern, ok := node["pattern"].(string); ok {
+		if re := v.regexp(pattern); re != nil && !re.MatchString(value) {
+			eval.fail(instLoc, kwLoc, "pattern", "string does not match pattern %s", pattern)
+		}
+	}
+	if format, ok := node["format"].(string); ok && v.formats {
+		if err := checkFormat(format, value); err != "" {
+			eval.fail(instLoc, kwLoc, "format", "%q is not a valid %s: %s", value, format, err)
+		}
+	}
+}
+
+func (v *schemaValidator) applyArray(node map[string]any, value []any, instLoc, kwLoc string, eval *schemaEval) {
+	if limit, ok := schemaInt(node["maxItems"]); ok && len(value) > limit {
+		eval.fail(instLoc, kwLoc, "maxItems", "array has %d items, more than the maximum %d", len(value), limit)
+	}
+	if limit, ok := schemaInt(node["minItems"]); ok && len(value) < limit {
+		eval.fail(instLoc, kwLoc, "minItems", "array has %d items, fewer than the minimum %d", len(value), limit)
+	}
+	if unique, ok := node["uniqueItems"].(bool); ok && unique {
+	duplicates:
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if jsonEqual(value[i], value[j]) {
+					eval.fail(instLoc, kwLoc, "uniqueItems", "items %d and %d are equal", i, j)
+					break duplicates
+				}
+			}
+		}
+	}
+
+	prefix, _ := node["prefixItems"].([]any)
+	for i, sub := range prefix {
+		if i >= len(value) {
+			break
+		}
+		result := v.validate(sub, value[i], fmt.Sprintf("%s/%d", instLoc, i), fmt.Sprintf("%s/prefixItems/%d", kwLoc, i))
+		eval.errors = append(eval.errors, result.errors...)
+		eval.items[i] = true
+	}
+
+	if items, ok := node["items"]; ok {
+		for i := len(prefix); i < len(value); i++ {
+			result := v.validate(items, value[i], fmt.Sprintf("%s/%d", instLoc, i), kwLoc+"/items")
+			eval.errors = append(eval.errors, result.errors...)
+		}
+		eval.allItems = true
+	}
+
+	if contains, ok := node["contains"]; ok {
+		matches := 0
+		for i, item := range value {
+			if result := v.validate(contains, item, fmt.Sprintf("%s/%d", instLoc, i), kwLoc+"/contains"); len(result.errors) == 0 {
+				matches++
+				eval.items[i] = true
+			}
+		}
+		minimum, hasMin := schemaInt(node["minContains"])
+		if !hasMin {
+			minimum = 1
+		}
+		if matches < minimum {
+			if hasMin {
+				eval.fail(instLoc, kwLoc, "minContains", "array contains %d matching items, fewer than %d", matches, minimum)
+			} else {
+				eval.fail(instLoc, kwLoc, "contains", "array does not contain a matching item")
+			}
+		}
+		if maximum, ok := schemaInt(node["maxContains"]); ok && matches > maximum {
+			eval.fail(instLoc, kwLoc, "maxContains", "array contains %d matching items, more than %d", matches, maximum)
+		}
+	}
+
+	// unevaluatedItems runs last so it sees every sibling's annotations
+	if unevaluated, ok := node["unevaluatedItems"]; ok && !eval.allItems {
+		for i := range value {
+			if eval.items[i] {
+				continue
+			}
+			result := v.validate(unevaluated, value[i], fmt.Sprintf("%s/%d", instLoc, i), kwLoc+"/unevaluatedItems")
+			eval.errors = append(eval.errors, result.errors...)
+		}
+		eval.allItems = true
+	}
+}
+
+func (v *schemaValidator) applyObject(node map[string]any, value map[string]any, instLoc, kwLoc string, eval *schemaEval) {
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if limit, ok := schemaInt(node["maxProperties"]); ok && len(value) > limit {
+		eval.fail(instLoc, kwLoc, "maxProperties", "object has %d properties, more than the maximum %d", len(value), limit)
+	}
+	if limit, ok := schemaInt(node["minProperties"]); ok && len(value) < limit {
+		eval.fail(instLoc, kwLoc, "minProperties", "object has %d properties, fewer than the minimum %d", len(value), limit)
+	}
+	if required, ok := node["required"].([]any); ok {
+		for _, item := range required {
+			if name, ok := item.(string); ok {
+				if _, present := value[name]; !present {
+					eval.fail(instLoc, kwLoc, "required", "missing required property %q", name)
+				}
+			}
+		}
+	}
+	if dependent, ok := node["dependentRequired"].(map[string]any); ok {
+		for _, trigger := range sortedKeys(dependent) {
+			if _, present := value[trigger]; !present {
+				continue
+			}
+			required, _ := dependent[trigger].([]any)
+			for _, item := range required {
+				if name, ok := item.(string); ok {
+					if _, present := value[name]; !present {
+						eval.fail(instLoc, kwLoc, "dependentRequired", "property %q is required when %q is present", name, trigger)
+					}
+				}
+			}
+		}
+	}
+
+	if names := node["propertyNames"]; names != nil {
+		for name := range value {
+			result := v.validate(names, name, instLoc+"/"+escapePointer(name), kwLoc+"/propertyNames")
+			eval.errors = append(eval.errors, result.errors...)
+		}
+	}
+
+	properties, _ := node["properties"].(map[string]any)
+	patterns, _ := node["patternProperties"].(map[string]any)
+	additional, hasAdditional := node["additionalProperties"]
+	for _, name := range names {
+		location := instLoc + "/" + escapePointer(name)
+		matched := false
+		if sub, ok := properties[name]; ok {
+			result := v.validate(sub, value[name], location, kwLoc+"/properties/"+escapePointer(name))
+			eval.errors = append(eval.errors, result.errors...)
+			matched = true
+		}
+		for _, pattern := range sortedKeys(patterns) {
+			if re := v.regexp(pattern); re != nil && re.MatchString(name) {
+				result := v.validate(patterns[pattern], value[name], location, kwLoc+"/patternProperties/"+escapePointer(pattern))
+				eval.errors = append(eval.errors, result.errors...)
+				matched = true
+			}
+		}
+		if !matched && hasAdditional {
+			result := v.validate(additional, value[name], location, kwLoc+"/additionalProperties")
+			if len(result.errors) > 0 && additional == false {
+				eval.fail(instLoc, kwLoc, "additionalProperties", "property %q is not allowed", name)
+			} else {
+				eval.errors = append(eval.errors, result.errors...)
+			}
+			matched = true
+		}
+		if matched {
+			eval.props[name] = true
+		}
+	}
+
+	if dependent, ok := node["dependentSchemas"].(map[string]any); ok {
+		for _, trigger := range sortedKeys(dependent) {
+			if _, present := value[trigger]; !present {
+				continue
+			}
+			result := v.validate(dependent[trigger], value, instLoc, kwLoc+"/dependentSchemas/"+escapePointer(trigger))
+			eval.errors = append(eval.errors, result.errors...)
+			if len(result.errors) == 0 {
+				eval.merge(result)
+			}
+		}
+	}
+
+	// unevaluatedProperties runs last so it sees every sibling's annotations
+	if unevaluated, ok := node["unevaluatedProperties"]; ok {
+		for _, name := range names {
+			if eval.props[name] {
+				continue
+			}
+			result := v.validate(unevaluated, value[name], instLoc+"/"+escapePointer(name), kwLoc+"/unevaluatedProperties")
+			if len(result.errors) > 0 && unevaluated == false {
+				eval.fail(instLoc, kwLoc, "unevaluatedProperties", "property %q is not allowed", name)
+			} else {
+				eval.errors = append(eval.errors, result.errors...)
+			}
+			eval.props[name] = true
+		}
+	}
+}
+
+// regexp compiles and caches a schema pattern. Patterns Go's RE2 syntax
+// cannot express stop validation with an error.
+func (v *schemaValidator) regexp(pattern string) *regexp.Regexp {
+	if re, ok := v.regexps[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		if v.err == nil {
+			v.err = fmt.Errorf("unsupported pattern %q: %v", pattern, err)
+		}
+		return nil
+	}
+	v.regexps[pattern] = re
+	return re
+}
+
+// schemaTypeMatches reports whether instance is of the named JSON Schema type
+func schemaTypeMatches(name string, instance any) bool {
+	if name == "integer" {
+		number, ok := instance.(json.Number)
+		if !ok {
+			return false
+		}
+		rat, ok := new(big.Rat).SetString(number.String())
+		return ok && rat.IsInt()
+	}
+	if name == "number" {
+		_, ok := instance.(json.Number)
+		return ok
+	}
+	return schemaTypeOf(instance) == name
+}
+
+// schemaTypeOf names the JSON type of a decoded value
+func schemaTypeOf(instance any) string {
+	switch instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// jsonEqual compares decoded values structurally, treating 1 and 1.0 as equal
+func jsonEqual(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ra, okA := new(big.Rat).SetString(x.String())
+		rb, okB := new(big.Rat).SetString(y.String())
+		return okA && okB && ra.Cmp(rb) == 0
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// schemaInt reads a non-negative integer keyword value
+func schemaInt(value any) (int, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := number.Float64()
+	if err != nil || f < 0 || f != float64(int(f)) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func compactJSON(value any) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// escapePointer escapes one JSON Pointer reference token
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+var (
+	uuidPattern          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	durationPattern      = regexp.MustCompile(`^P(\d+Y)?(\d+M)?(\d+D)?(T(\d+H)?(\d+M)?(\d+S)?)?$|^P\d+W$`)
+	relativePointerRegex = regexp.MustCompile(`^(0|[1-9][0-9]*)(#|(/([^~]|~[01])*)*)$`)
+	hostnameLabel        = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// checkFormat validates a string against a format, returning a reason when it
+// does not conform. Unknown formats are accepted.
+func checkFormat(format, value string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, strings.ToUpper(value)); err != nil {
+			return "expected RFC 3339 date-time"
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return "expected YYYY-MM-DD"
+		}
+	case "time":
+		if _, err := time.Parse(time.RFC3339Nano, "2000-01-01T"+strings.ToUpper(value)); err != nil {
+			return "expected RFC 3339 time with offset"
+		}
+	case "duration":
+		if !durationPattern.MatchString(value) || value == "P" || strings.HasSuffix(value, "T") {
+			return "expected ISO 8601 duration"
+		}
+	case "email", "idn-email":
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Address != value {
+			return "expected an email address"
+		}
+	case "hostname", "idn-hostname":
+		name := strings.TrimSuffix(value, ".")
+		if name == "" || len(name) > 253 {
+			return "expected a hostname"
+		}
+		for _, label := range strings.Split(name, ".") {
+			if format == "idn-hostname" && !isASCII(label) {
+				if label == "" || utf8.RuneCountInString(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+					return "invalid label " + strconv.Quote(label)
+				}
+				continue
+			}
+			if !hostnameLabel.MatchString(label) {
+				return "invalid label " + strconv.Quote(label)
+			}
+		}
+	case "ipv4":
+		if addr, err := netip.ParseAddr(value); err != nil || !addr.Is4() {
+			return "expected dotted-quad IPv4 address"
+		}
+	case "ipv6":
+		if addr, err := netip.ParseAddr(value); err != nil || !addr.Is6() || addr.Zone() != "" {
+			return "expected IPv6 address"
+		}
+	case "uri", "iri":
+		if parsed, err := url.Parse(value); err != nil || !parsed.IsAbs() {
+			return "expected an absolute URI"
+		}
+	case "uri-reference", "iri-reference":
+		if _, err := url.Parse(value); err != nil {
+			return "expected a URI reference"
+		}
+	case "uri-template":
+		if strings.Count(value, "{") != strings.Count(value, "}") {
+			return "unbalanced braces"
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			return "expected 8-4-4-4-12 hex digits"
+		}
+	case "regex":
+		if _, err := regexp.Compile(value); err != nil {
+			return err.Error()
+		}
+	case "json-pointer":
+		if value != "" && (!strings.HasPrefix(value, "/") || !validPointerEscapes(value)) {
+			return "expected a JSON Pointer"
+		}
+	case "relative-json-pointer":
+		if !relativePointerRegex.MatchString(value) {
+			return "expected a relative JSON Pointer"
+		}
+	}
+	return ""
+}
+
+// validPointerEscapes reports whether every ~ in a JSON Pointer is followed by 0 or 1
+func validPointerEscapes(pointer string) bool {
+	for i := 0; i < len(pointer); i++ {
+		if pointer[i] == '~' && (i+1 >= len(pointer) || (pointer[i+1] != '0' && pointer[i+1] != '1')) {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validateSchemaFixture(t *testing.T, schema, instance string) (*JSONSchemaResult, error) {
+	t.Helper()
+	return NewJSONService().ValidateSchema(JSONSchemaRequest{
+		Schema:   json.RawMessage(schema),
+		Instance: json.RawMessage(instance),
+	})
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}}
+		},
+		"$defs": {"tag": {"enum": ["a", "b"]}}
+	}`
+	tests := []struct {
+		instance string
+		errors   []string // keyword locations
+	}{
+		{`{"name": "x", "tags": ["a", "b"]}`, nil},
+		{`{"tags": []}`, []string{"/required"}},
+		{`{"name": "", "tags": ["c"]}`, []string{"/properties/name/minLength", "/properties/tags/items/$ref/enum"}},
+	}
+	for _, tt := range tests {
+		result, err := validateSchemaFixture(t, schema, tt.instance)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.instance, err)
+		}
+		var got []string
+		for _, e := range result.Errors {
+			got = append(got, e.KeywordLocation)
+		}
+		if result.Valid != (len(tt.errors) == 0) || strings.Join(got, " ") != strings.Join(tt.errors, " ") {
+			t.Errorf("%s: valid=%v errors %v, want %v", tt.instance, result.Valid, got, tt.errors)
+		}
+	}
+}
+
+// Each level references the next twice, so naive evaluation doubles per level
+func TestValidateSchemaBoundsRepeatedRefs(t *testing.T) {
+	const levels = 22
+	defs := make([]string, 0, levels+1)
+	for i := 0; i < levels; i++ {
+		defs = append(defs, fmt.Sprintf(`"a%d": {"allOf": [{"$ref": "#/$defs/a%d"}, {"$ref": "#/$defs/a%d"}]}`, i, i+1, i+1))
+	}
+	defs = append(defs, fmt.Sprintf(`"a%d": {"type": "string"}`, levels))
+	schema := `{"$ref": "#/$defs/a0", "$defs": {` + strings.Join(defs, ",") + `}}`
+
+	start := time.Now()
+	result, err := validateSchemaFixture(t, schema, `1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("validation took %v", elapsed)
+	}
+	if result.Valid || len(result.Errors) != maxSchemaErrors || len(result.Warnings) != 1 {
+		t.Errorf("valid=%v with %d errors and warnings %v", result.Valid, len(result.Errors), result.Warnings)
+	}
+}
+
+// A subschema reached through two paths reports each path in its errors
+func TestValidateSchemaReusedRefLocations(t *testing.T) {
+	schema := `{"allOf": [{"$ref": "#/$defs/s"}, {"$ref": "#/$defs/s"}], "$defs": {"s": {"type": "string"}}}`
+	result, err := validateSchemaFixture(t, schema, `1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range result.Errors {
+		got = append(got, e.KeywordLocation)
+	}
+	want := "/allOf/0/$ref/type /allOf/1/$ref/type"
+	if strings.Join(got, " ") != want {
+		t.Errorf("keyword locations %v, want %s", got, want)
+	}
+}
+
+func TestValidateSchemaTruncatesErrors(t *testing.T) {
+	items := strings.Repeat(`1,`, maxSchemaErrors+10) + `1`
+	result, err := validateSchemaFixture(t, `{"items": {"type": "string"}}`, `[`+items+`]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Errors) != maxSchemaErrors {
+		t.Errorf("valid=%v with %d errors, want %d", result.Valid, len(result.Errors), maxSchemaErrors)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "truncated") {
+		t.Errorf("warnings = %v", result.Warnings)
+	}
+}
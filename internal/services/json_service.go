@@ -0,0 +1,598 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// jsonSpoolMemory is how much of a document is kept in memory before spilling to disk
+	jsonSpoolMemory = 1 << 20
+	// jsonExcerptRadius is how many bytes of context are shown either side of an error
+	jsonExcerptRadius = 40
+	maxJSONIndent     = 8
+)
+
+// JSONService validates and reformats JSON documents of any size
+type JSONService struct{}
+
+// NewJSONService creates a new JSON service
+func NewJSONService() *JSONService {
+	return &JSONService{}
+}
+
+// JSONDocument is a JSON document spooled from a stream. Small documents are
+// held in memory and larger ones in a temporary file, so a document can be
+// read more than once without holding all of it in memory.
+type JSONDocument struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// SpoolJSON reads a document from r, spilling to a temporary file once it
+// exceeds jsonSpoolMemory. The caller must Close the document.
+func SpoolJSON(r io.Reader) (*JSONDocument, error) {
+	doc := &JSONDocument{}
+
+	head, err := io.ReadAll(io.LimitReader(r, jsonSpoolMemory+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(head) <= jsonSpoolMemory {
+		doc.data = head
+		doc.size = int64(len(head))
+		return doc, nil
+	}
+
+	doc.file, err = os.CreateTemp("", "json-document-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool document: %w", err)
+	}
+	if _, err := doc.file.Write(head); err != nil {
+		doc.Close()
+		return nil, fmt.Errorf("failed to spool document: %w", err)
+	}
+	copied, err := io.Copy(doc.file, r)
+	if err != nil {
+		doc.Close()
+		return nil, err
+	}
+	doc.size = int64(len(head)) + copied
+	return doc, nil
+}
+
+// Size returns the document length in bytes
+func (d *JSONDocument) Size() int64 {
+	return d.size
+}
+
+// ReadAt implements io.ReaderAt over the spooled document
+func (d *JSONDocument) ReadAt(p []byte, off int64) (int, error) {
+	if d.file != nil {
+		return d.file.ReadAt(p, off)
+	}
+	return bytes.NewReader(d.data).ReadAt(p, off)
+}
+
+// Reader returns a new reader positioned at the start of the document
+func (d *JSONDocument) Reader() io.Reader {
+	return bufio.NewReaderSize(io.NewSectionReader(d, 0, d.size), 64<<10)
+}
+
+// Close releases the temporary file backing a large document
+func (d *JSONDocument) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	name := d.file.Name()
+	err := d.file.Close()
+	os.Remove(name)
+	d.file = nil
+	return err
+}
+
+// JSONSyntaxError locates a syntax error within a document
+type JSONSyntaxError struct {
+	Message       string `json:"message"`
+	Line          int    `json:"line"`   // 1-based
+	Column        int    `json:"column"` // 1-based, in characters
+	Offset        int64  `json:"offset"` // 0-based byte offset of the offending input
+	Path          string `json:"path"`   // JSON Pointer to the value being parsed
+	Excerpt       string `json:"excerpt"`
+	ExcerptColumn int    `json:"excerpt_column"` // 1-based position of the error within the excerpt
+}
+
+// JSONValidationResult reports whether a document is well-formed JSON
+type JSONValidationResult struct {
+	Valid          bool             `json:"valid"`
+	Error          *JSONSyntaxError `json:"error,omitempty"`
+	Size           int64            `json:"size"`
+	RootType       string           `json:"root_type,omitempty"`
+	MaxDepth       int              `json:"max_depth"`
+	Values         int              `json:"values"`
+	Timestamp      time.Time        `json:"timestamp"`
+	ValidationTime float64          `json:"validation_time_ms"`
+}
+
+// JSONFormatOptions controls how documents are re-serialized
+type JSONFormatOptions struct {
+	Indent        int  `json:"indent"`         // spaces per level; 0 with UseTabs false minifies
+	UseTabs       bool `json:"use_tabs"`       // indent with one tab per level
+	SortKeys      bool `json:"sort_keys"`      // sort object members by key
+	EscapeHTML    bool `json:"escape_html"`    // escape <, > and & as \u003c, \u003e and \u0026
+	EscapeUnicode bool `json:"escape_unicode"` // escape all non-ASCII characters
+	EscapeSlash   bool `json:"escape_slash"`   // escape / as \/
+}
+
+// jsonFrame tracks one open object or array while scanning
+type jsonFrame struct {
+	object    bool
+	expectKey bool
+	key       string
+	index     int
+}
+
+// Validate scans a document token by token, reporting the first syntax error
+// with its line, column, byte offset and an excerpt of the surrounding input
+func (s *JSONService) Validate(doc *JSONDocument) *JSONValidationResult {
+	start := time.Now()
+	result := &JSONValidationResult{Size: doc.Size()}
+
+	dec := json.NewDecoder(doc.Reader())
+	dec.UseNumber()
+
+	var stack []jsonFrame
+	fail := func(message string, offset int64) *JSONValidationResult {
+		result.Error = s.locateError(doc, message, offset, jsonPointer(stack))
+		result.Timestamp = time.Now()
+		result.ValidationTime = durationMs(time.Since(start))
+		return result
+	}
+
+	// valueDone advances the enclosing container once a value completes
+	valueDone := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := &stack[len(stack)-1]
+		if top.object {
+			top.expectKey = true
+		} else {
+			top.index++
+		}
+	}
+
+	for {
+		if result.RootType != "" && len(stack) == 0 {
+			// The root value is complete; only whitespace may follow
+			offset := skipJSONSpace(doc, dec.InputOffset())
+			if offset < doc.Size() {
+				return fail("unexpected data after top-level value", offset)
+			}
+			break
+		}
+
+		tok, err := dec.Token()
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			switch {
+			case errors.As(err, &syntaxErr):
+				return fail(syntaxErr.Error(), errorOffset(doc, syntaxErr))
+			case errors.Is(err, io.EOF) && result.RootType == "":
+				return fail("empty document", doc.Size())
+			case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+				return fail("unexpected end of JSON input", doc.Size())
+			default:
+				return fail(err.Error(), dec.InputOffset())
+			}
+		}
+
+		if len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].expectKey {
+			if key, ok := tok.(string); ok {
+				stack[len(stack)-1].key = key
+				stack[len(stack)-1].expectKey = false
+				continue
+			}
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			valueDone()
+			continue
+		}
+
+		result.Values++
+		if result.RootType == "" {
+			result.RootType = jsonTokenType(tok)
+		}
+		if delim, ok := tok.(json.Delim); ok {
+			stack = append(stack, jsonFrame{object: delim == '{', expectKey: true})
+			result.MaxDepth = max(result.MaxDepth, len(stack))
+			continue
+		}
+		valueDone()
+	}
+
+	result.Valid = true
+	result.Timestamp = time.Now()
+	result.ValidationTime = durationMs(time.Since(start))
+	return result
+}
+
+// Format re-serializes a valid document to w. Members are streamed in input
+// order; with SortKeys each object is buffered so its members can be sorted.
+func (s *JSONService) Format(doc *JSONDocument, w io.Writer, opts JSONFormatOptions) error {
+	if opts.Indent < 0 || opts.Indent > maxJSONIndent {
+		return fmt.Errorf("indent must be between 0 and %d", maxJSONIndent)
+	}
+
+	dec := json.NewDecoder(doc.Reader())
+	dec.UseNumber()
+
+	f := &jsonFormatter{dec: dec, opts: opts}
+	switch {
+	case opts.UseTabs:
+		f.indent = "\t"
+	case opts.Indent > 0:
+		f.indent = strings.Repeat(" ", opts.Indent)
+	}
+
+	out := bufio.NewWriterSize(w, 64<<10)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if err := f.writeValue(out, tok, 0); err != nil {
+		return err
+	}
+	if f.indent != "" {
+		out.WriteByte('\n')
+	}
+	return out.Flush()
+}
+
+// jsonOutput is satisfied by both the streaming writer and member buffers
+type jsonOutput interface {
+	io.Writer
+	io.StringWriter
+	io.ByteWriter
+}
+
+type jsonFormatter struct {
+	dec    *json.Decoder
+	opts   JSONFormatOptions
+	indent string
+}
+
+type jsonMember struct {
+	key   string
+	value []byte
+}
+
+func (f *jsonFormatter) writeValue(out jsonOutput, tok json.Token, depth int) error {
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return f.writeObject(out, depth)
+		}
+		return f.writeArray(out, depth)
+	case string:
+		f.writeString(out, v)
+	case json.Number:
+		out.WriteString(v.String())
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func (f *jsonFormatter) writeObject(out jsonOutput, depth int) error {
+	var members []jsonMember
+	first := true
+	for f.dec.More() {
+		keyTok, err := f.dec.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := keyTok.(string)
+		valueTok, err := f.dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if f.opts.SortKeys {
+			var buf bytes.Buffer
+			if err := f.writeValue(&buf, valueTok, depth+1); err != nil {
+				return err
+			}
+			members = append(members, jsonMember{key: key, value: buf.Bytes()})
+			continue
+		}
+
+		f.openMember(out, &first, "{", depth)
+		f.writeKey(out, key)
+		if err := f.writeValue(out, valueTok, depth+1); err != nil {
+			return err
+		}
+	}
+	if _, err := f.dec.Token(); err != nil {
+		return err
+	}
+
+	sort.SliceStable(members, func(i, j int) bool { return members[i].key < members[j].key })
+	for _, member := range members {
+		f.openMember(out, &first, "{", depth)
+		f.writeKey(out, member.key)
+		out.Write(member.value)
+	}
+
+	f.close(out, first, "{}", depth)
+	return nil
+}
+
+func (f *jsonFormatter) writeArray(out jsonOutput, depth int) error {
+	first := true
+	for f.dec.More() {
+		tok, err := f.dec.Token()
+		if err != nil {
+			return err
+		}
+		f.openMember(out, &first, "[", depth)
+		if err := f.writeValue(out, tok, depth+1); err != nil {
+			return err
+		}
+	}
+	if _, err := f.dec.Token(); err != nil {
+		return err
+	}
+
+	f.close(out, first, "[]", depth)
+	return nil
+}
+
+// openMember writes the opening delimiter or separator before a member
+func (f *jsonFormatter) openMember(out jsonOutput, first *bool, open string, depth int) {
+	if *first {
+		out.WriteString(open)
+		*first = false
+	} else {
+		out.WriteByte(',')
+	}
+	f.newline(out, depth+1)
+}
+
+// close writes the closing delimiter, or an empty pair for empty containers
+func (f *jsonFormatter) close(out jsonOutput, empty bool, pair string, depth int) {
+	if empty {
+		out.WriteString(pair)
+		return
+	}
+	f.newline(out, depth)
+	out.WriteByte(pair[1])
+}
+
+func (f *jsonFormatter) newline(out jsonOutput, depth int) {
+	if f.indent == "" {
+		return
+	}
+	out.WriteByte('\n')
+	for range depth {
+		out.WriteString(f.indent)
+	}
+}
+
+func (f *jsonFormatter) writeKey(out jsonOutput, key string) {
+	f.writeString(out, key)
+	out.WriteByte(':')
+	if f.indent != "" {
+		out.WriteByte(' ')
+	}
+}
+
+// writeString quotes s, applying the configured escaping
+func (f *jsonFormatter) writeString(out jsonOutput, s string) {
+	const hex = "0123456789abcdef"
+	out.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			out.WriteString(`\"`)
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r == '\b':
+			out.WriteString(`\b`)
+		case r == '\f':
+			out.WriteString(`\f`)
+		case r == '/' && f.opts.EscapeSlash:
+			out.WriteString(`\/`)
+		case r < 0x20, r == '\u2028', r == '\u2029',
+			f.opts.EscapeHTML && (r == '<' || r == '>' || r == '&'),
+			f.opts.EscapeUnicode && r > 0x7e:
+			if r > 0xffff {
+				// Characters outside the BMP are written as a surrogate pair
+				r -= 0x10000
+				f.writeEscape(out, 0xd800+(r>>10), hex)
+				f.writeEscape(out, 0xdc00+(r&0x3ff), hex)
+				continue
+			}
+			f.writeEscape(out, r, hex)
+		default:
+			var buf [utf8.UTFMax]byte
+			n := utf8.EncodeRune(buf[:], r)
+			out.Write(buf[:n])
+		}
+	}
+	out.WriteByte('"')
+}
+
+func (f *jsonFormatter) writeEscape(out jsonOutput, r rune, hex string) {
+	out.WriteString(`\u`)
+	out.WriteByte(hex[r>>12&0xf])
+	out.WriteByte(hex[r>>8&0xf])
+	out.WriteByte(hex[r>>4&0xf])
+	out.WriteByte(hex[r&0xf])
+}
+
+// locateError converts a byte offset into a line, column and excerpt
+func (s *JSONService) locateError(doc *JSONDocument, message string, offset int64, path string) *JSONSyntaxError {
+	offset = min(max(offset, 0), doc.Size())
+	syntaxErr := &JSONSyntaxError{
+		Message: message,
+		Line:    1,
+		Column:  1,
+		Offset:  offset,
+		Path:    path,
+	}
+
+	// Count lines and characters up to the error
+	lineStart := int64(0)
+	reader := bufio.NewReader(io.NewSectionReader(doc, 0, offset))
+	for pos := int64(0); ; pos++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			break
+		}
+		switch {
+		case b == '\n':
+			syntaxErr.Line++
+			syntaxErr.Column = 1
+			lineStart = pos + 1
+		case b&0xc0 != 0x80:
+			// Count the first byte of each UTF-8 sequence
+			syntaxErr.Column++
+		}
+	}
+
+	// Excerpt the error line, limited to jsonExcerptRadius bytes either side
+	from := max(lineStart, offset-jsonExcerptRadius)
+	window := make([]byte, offset-from+jsonExcerptRadius)
+	n, _ := doc.ReadAt(window, from)
+	window = window[:n]
+	before := window[:offset-from]
+	after := window[offset-from:]
+	if i := bytes.IndexByte(after, '\n'); i >= 0 {
+		after = after[:i]
+	}
+	// Avoid starting the excerpt in the middle of a UTF-8 sequence
+	for len(before) > 0 && !utf8.RuneStart(before[0]) {
+		before = before[1:]
+	}
+
+	prefix := ""
+	if from > lineStart {
+		prefix = "..."
+	}
+	syntaxErr.Excerpt = strings.TrimRight(prefix+string(before)+string(after), "\r")
+	syntaxErr.ExcerptColumn = utf8.RuneCountInString(prefix+string(before)) + 1
+	return syntaxErr
+}
+
+// errorOffset returns the offset of the byte a decoder syntax error refers to.
+// Scanner errors report the offset after the offending byte while token errors
+// report the offset of the byte itself, so the quoted character is checked.
+func errorOffset(doc *JSONDocument, err *json.SyntaxError) int64 {
+	if c, ok := offendingByte(err.Error()); ok {
+		for _, offset := range []int64{err.Offset - 1, err.Offset} {
+			var b [1]byte
+			if offset >= 0 {
+				if _, readErr := doc.ReadAt(b[:], offset); readErr == nil && b[0] == c {
+					return offset
+				}
+			}
+		}
+	}
+	if strings.HasPrefix(err.Error(), "object member name") && err.Offset > 0 {
+		// Reported after the first byte of the offending key
+		return err.Offset - 1
+	}
+	return skipJSONSpace(doc, err.Offset)
+}
+
+// offendingByte extracts the character quoted in an "invalid character" message
+func offendingByte(message string) (byte, bool) {
+	rest, ok := strings.CutPrefix(message, "invalid character '")
+	if !ok {
+		return 0, false
+	}
+	if strings.HasPrefix(rest, `\''`) {
+		return '\'', true
+	}
+	end := strings.Index(rest, "' ")
+	if end < 0 {
+		return 0, false
+	}
+	quoted := rest[:end]
+	if quoted == `"` {
+		return '"', true
+	}
+	char, err := strconv.Unquote(`"` + quoted + `"`)
+	if err != nil || len(char) != 1 {
+		return 0, false
+	}
+	return char[0], true
+}
+
+// skipJSONSpace returns the offset of the first non-whitespace byte at or after offset
+func skipJSONSpace(doc *JSONDocument, offset int64) int64 {
+	reader := bufio.NewReader(io.NewSectionReader(doc, offset, doc.Size()-offset))
+	for {
+		b, err := reader.ReadByte()
+		if err != nil || (b != ' ' && b != '\t' && b != '\n' && b != '\r') {
+			return offset
+		}
+		offset++
+	}
+}
+
+// jsonPointer renders the scan stack as an RFC 6901 JSON Pointer
+func jsonPointer(stack []jsonFrame) string {
+	var b strings.Builder
+	for _, frame := range stack {
+		switch {
+		case !frame.object:
+			fmt.Fprintf(&b, "/%d", frame.index)
+		case !frame.expectKey:
+			b.WriteByte('/')
+			b.WriteString(escapePointer(frame.key))
+		}
+	}
+	return b.String()
+}
+
+// jsonTokenType names the JSON type a value token starts
+func jsonTokenType(tok json.Token) string {
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			return "object"
+		}
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
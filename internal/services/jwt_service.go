@@ -0,0 +1,709 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWTService decodes JSON Web Tokens and verifies their signatures
+type JWTService struct{}
+
+// NewJWTService creates a new JWT service
+func NewJWTService() *JWTService {
+	return &JWTService{}
+}
+
+// JWTRequest is a token to decode, with optional verification inputs
+type JWTRequest struct {
+	Token          string `json:"token"`
+	Secret         string `json:"secret,omitempty"`          // shared secret for HS256/384/512
+	SecretEncoding string `json:"secret_encoding,omitempty"` // "text" (default), "base64" or "hex"
+	Key            string `json:"key,omitempty"`             // PEM public key, certificate, JWK or JWKS document
+	Audience       string `json:"audience,omitempty"`        // expected aud value
+	Leeway         int    `json:"leeway,omitempty"`          // clock skew allowed for exp, nbf and iat, in seconds
+}
+
+// JWTClaim is a payload member, with registered claims described and NumericDates rendered
+type JWTClaim struct {
+	Name        string     `json:"name"`
+	Value       any        `json:"value"`
+	Description string     `json:"description,omitempty"`
+	Time        *time.Time `json:"time,omitempty"`
+	Relative    string     `json:"relative,omitempty"` // such as "in 2h 5m" or "3d 4h ago"
+}
+
+// JWTIssue is a problem found in a token
+type JWTIssue struct {
+	Severity string `json:"severity"` // "error", "warning", "info"
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// JWTResult is a decoded token with its claims, verification outcome and issues
+type JWTResult struct {
+	Header           any        `json:"header"`
+	Payload          any        `json:"payload"`
+	HeaderJSON       string     `json:"header_json"`  // indented, in token member order
+	PayloadJSON      string     `json:"payload_json"` // indented, in token member order
+	Algorithm        string     `json:"algorithm"`
+	KeyID            string     `json:"key_id,omitempty"`
+	Claims           []JWTClaim `json:"claims"`
+	Signature        string     `json:"signature"`
+	SignatureChecked bool       `json:"signature_checked"`
+	SignatureValid   bool       `json:"signature_valid"`
+	VerifiedWith     string     `json:"verified_with,omitempty"`
+	Expired          bool       `json:"expired"`
+	NotYetValid      bool       `json:"not_yet_valid"`
+	Issues           []JWTIssue `json:"issues"`
+	Errors           int        `json:"errors"`
+	Warnings         int        `json:"warnings"`
+	Timestamp        time.Time  `json:"timestamp"`
+}
+
+// jwtAlgorithm describes how a JWS "alg" value signs
+type jwtAlgorithm struct {
+	family string // HS, RS, PS, ES or EdDSA
+	hash   crypto.Hash
+	curve  elliptic.Curve
+}
+
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {family: "HS", hash: crypto.SHA256},
+	"HS384": {family: "HS", hash: crypto.SHA384},
+	"HS512": {family: "HS", hash: crypto.SHA512},
+	"RS256": {family: "RS", hash: crypto.SHA256},
+	"RS384": {family: "RS", hash: crypto.SHA384},
+	"RS512": {family: "RS", hash: crypto.SHA512},
+	"PS256": {family: "PS", hash: crypto.SHA256},
+	"PS384": {family: "PS", hash: crypto.SHA384},
+	"PS512": {family: "PS", hash: crypto.SHA512},
+	"ES256": {family: "ES", hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384": {family: "ES", hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512": {family: "ES", hash: crypto.SHA512, curve: elliptic.P521()},
+	"EdDSA": {family: "EdDSA"},
+}
+
+// Registered claim names from RFC 7519 section 4.1
+var jwtClaimDescriptions = map[string]string{
+	"iss": "Issuer",
+	"sub": "Subject",
+	"aud": "Audience",
+	"exp": "Expiration Time",
+	"nbf": "Not Before",
+	"iat": "Issued At",
+	"jti": "JWT ID",
+}
+
+// jwtKey is a verification key along with where it came from
+type jwtKey struct {
+	public any // *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or []byte for HMAC
+	source string
+	kid    string
+	alg    string
+}
+
+// Inspect decodes a token and checks its time claims and audience. When a
+// secret or key is supplied the signature is verified as well.
+func (s *JWTService) Inspect(req JWTRequest) (*JWTResult, error) {
+	token := strings.TrimSpace(req.Token)
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+
+	parts := strings.Split(token, ".")
+	switch len(parts) {
+	case 3:
+	case 5:
+		return nil, fmt.Errorf("token has 5 parts: encrypted tokens (JWE) cannot be decoded without the recipient's key")
+	default:
+		return nil, fmt.Errorf("token must have 3 dot-separated parts, found %d", len(parts))
+	}
+
+	result := &JWTResult{Claims: []JWTClaim{}, Issues: []JWTIssue{}, Signature: parts[2]}
+
+	headerBytes, err := decodeJWTSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid header encoding: %w", err)
+	}
+	payloadBytes, err := decodeJWTSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload encoding: %w", err)
+	}
+
+	header, err := decodeJWTObject(headerBytes)
+	if err != nil {
+		return nil, fmt.Errorf("header is not a JSON object: %w", err)
+	}
+	payload, err := decodeJWTObject(payloadBytes)
+	if err != nil {
+		return nil, fmt.Errorf("payload is not a JSON object: %w", err)
+	}
+	result.Header = header
+	result.Payload = payload
+	result.HeaderJSON = indentJWTSegment(headerBytes)
+	result.PayloadJSON = indentJWTSegment(payloadBytes)
+	result.Algorithm, _ = header["alg"].(string)
+	result.KeyID, _ = header["kid"].(string)
+
+	s.checkHeader(result, header)
+	s.checkClaims(result, payload, req)
+
+	if req.Secret != "" || strings.TrimSpace(req.Key) != "" {
+		s.verify(result, parts, req)
+	}
+
+	for _, issue := range result.Issues {
+		switch issue.Severity {
+		case "error":
+			result.Errors++
+		case "warning":
+			result.Warnings++
+		}
+	}
+	result.Timestamp = time.Now()
+	return result, nil
+}
+
+func (r *JWTResult) issue(severity, rule, format string, args ...any) {
+	r.Issues = append(r.Issues, JWTIssue{Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *JWTService) checkHeader(result *JWTResult, header map[string]any) {
+	alg, ok := header["alg"].(string)
+	switch {
+	case !ok:
+		result.issue("error", "missing-alg", "header has no alg member")
+	case strings.EqualFold(alg, "none"):
+		result.issue("error", "alg-none", "alg is %q: the token is unsigned and anyone can forge it; never accept it", alg)
+	default:
+		if _, known := jwtAlgorithms[alg]; !known {
+			result.issue("warning", "unsupported-alg", "alg %q is not a supported JWS algorithm", alg)
+		}
+	}
+	if typ, ok := header["typ"].(string); ok && !strings.EqualFold(typ, "JWT") && !strings.HasSuffix(strings.ToLower(typ), "+jwt") {
+		result.issue("info", "unusual-typ", "typ is %q rather than JWT", typ)
+	}
+	if _, ok := header["crit"]; ok {
+		result.issue("warning", "crit-header", "header lists critical extensions; verifiers that do not understand them must reject the token")
+	}
+	for _, member := range []string{"jku", "x5u", "jwk"} {
+		if _, ok := header[member]; ok {
+			result.issue("warning", "embedded-key-reference", "header carries %s; verifiers must not trust keys chosen by the token itself", member)
+		}
+	}
+}
+
+func (s *JWTService) checkClaims(result *JWTResult, payload map[string]any, req JWTRequest) {
+	now := time.Now()
+	leeway := time.Duration(req.Leeway) * time.Second
+
+	for _, name := range sortedKeys(payload) {
+		claim := JWTClaim{Name: name, Value: payload[name], Description: jwtClaimDescriptions[name]}
+		if name == "exp" || name == "nbf" || name == "iat" || name == "auth_time" {
+			if t, ok := numericDate(payload[name]); ok {
+				claim.Time = &t
+				claim.Relative = relativeTime(t, now)
+			} else {
+				result.issue("error", "invalid-"+name, "%s must be a NumericDate (seconds since the epoch), found %s", name, excerptJSON(payload[name]))
+			}
+		}
+		result.Claims = append(result.Claims, claim)
+	}
+
+	if exp, ok := numericDate(payload["exp"]); ok {
+		if now.After(exp.Add(leeway)) {
+			result.Expired = true
+			result.issue("error", "expired", "token expired %s (%s)", relativeTime(exp, now), exp.UTC().Format(time.RFC3339))
+		}
+	} else if _, present := payload["exp"]; !present {
+		result.issue("warning", "missing-exp", "token has no exp claim and never expires")
+	}
+	if nbf, ok := numericDate(payload["nbf"]); ok && now.Add(leeway).Before(nbf) {
+		result.NotYetValid = true
+		result.issue("error", "not-yet-valid", "token is not valid until %s (%s)", nbf.UTC().Format(time.RFC3339), relativeTime(nbf, now))
+	}
+	if iat, ok := numericDate(payload["iat"]); ok && now.Add(leeway).Before(iat) {
+		result.issue("warning", "issued-in-future", "iat is %s, in the future; check the issuer's clock", iat.UTC().Format(time.RFC3339))
+	}
+
+	audiences, present := payload["aud"], false
+	var values []string
+	switch aud := audiences.(type) {
+	case string:
+		present, values = true, []string{aud}
+	case []any:
+		present = len(aud) > 0
+		for _, item := range aud {
+			if value, ok := item.(string); ok {
+				values = append(values, value)
+			} else {
+				result.issue("error", "invalid-aud", "aud entries must be strings, found %s", excerptJSON(item))
+			}
+		}
+	case nil:
+		if _, exists := payload["aud"]; exists {
+			result.issue("error", "invalid-aud", "aud must be a string or an array of strings")
+		}
+	default:
+		result.issue("error", "invalid-aud", "aud must be a string or an array of strings")
+	}
+	switch {
+	case !present && req.Audience != "":
+		result.issue("error", "missing-aud", "token has no aud claim but %q is expected", req.Audience)
+	case !present:
+		result.issue("warning", "missing-aud", "token has no aud claim, so any service that trusts the issuer will accept it")
+	case req.Audience != "":
+		matched := false
+		for _, value := range values {
+			matched = matched || value == req.Audience
+		}
+		if !matched {
+			result.issue("error", "aud-mismatch", "aud %s does not include %q", excerptJSON(audiences), req.Audience)
+		}
+	}
+}
+
+// verify checks the signature over the first two token parts
+func (s *JWTService) verify(result *JWTResult, parts []string, req JWTRequest) {
+	result.SignatureChecked = true
+	algorithm, known := jwtAlgorithms[result.Algorithm]
+	if !known {
+		result.issue("error", "signature-unverifiable", "cannot verify a token signed with alg %q", result.Algorithm)
+		return
+	}
+	signature, err := decodeJWTSegment(parts[2])
+	if err != nil {
+		result.issue("error", "invalid-signature", "signature is not valid base64url: %v", err)
+		return
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	var keys []jwtKey
+	if algorithm.family == "HS" {
+		if req.Secret == "" {
+			if strings.TrimSpace(req.Key) != "" && !strings.HasPrefix(strings.TrimSpace(req.Key), "{") {
+				result.issue("error", "algorithm-confusion", "%s needs a shared secret; refusing to use a public key as an HMAC secret", result.Algorithm)
+				return
+			}
+		} else {
+			secret, err := decodeJWTSecret(req.Secret, req.SecretEncoding)
+			if err != nil {
+				result.issue("error", "invalid-secret", "%v", err)
+				return
+			}
+			keys = append(keys, jwtKey{public: secret, source: "shared secret"})
+		}
+	}
+	if strings.TrimSpace(req.Key) != "" {
+		parsed, err := parseJWTKeys(req.Key)
+		if err != nil {
+			result.issue("error", "invalid-key", "%v", err)
+			return
+		}
+		keys = append(keys, parsed...)
+	}
+
+	candidates := selectJWTKeys(keys, result.KeyID, result.Algorithm, algorithm)
+	if len(candidates) == 0 {
+		if result.KeyID != "" && len(keys) > 1 {
+			result.issue("error", "no-matching-key", "no %s key with kid %q among the %d supplied", algorithm.family, result.KeyID, len(keys))
+		} else {
+			result.issue("error", "no-matching-key", "no supplied key can verify %s signatures", result.Algorithm)
+		}
+		return
+	}
+
+	for _, key := range candidates {
+		if err := verifyJWTSignature(algorithm, key.public, signingInput, signature); err == nil {
+			result.SignatureValid = true
+			result.VerifiedWith = key.source
+			if secret, ok := key.public.([]byte); ok && len(secret) < algorithm.hash.Size() {
+				result.issue("warning", "weak-secret", "secret is %d bytes; %s requires at least %d (RFC 7518 section 3.2)", len(secret), result.Algorithm, algorithm.hash.Size())
+			}
+			return
+		}
+	}
+	result.issue("error", "invalid-signature", "signature does not verify with the supplied %s", pluralKeys(len(candidates)))
+}
+
+func pluralKeys(n int) string {
+	if n == 1 {
+		return "key"
+	}
+	return fmt.Sprintf("%d keys", n)
+}
+
+// selectJWTKeys narrows keys to those of the right type, preferring a kid match
+func selectJWTKeys(keys []jwtKey, kid, alg string, algorithm jwtAlgorithm) []jwtKey {
+	var usable []jwtKey
+	for _, key := range keys {
+		if key.alg != "" && key.alg != alg {
+			continue
+		}
+		switch public := key.public.(type) {
+		case []byte:
+			if algorithm.family != "HS" {
+				continue
+			}
+		case *rsa.PublicKey:
+			if algorithm.family != "RS" && algorithm.family != "PS" {
+				continue
+			}
+		case *ecdsa.PublicKey:
+			if algorithm.family != "ES" || public.Curve != algorithm.curve {
+				continue
+			}
+		case ed25519.PublicKey:
+			if algorithm.family != "EdDSA" {
+				continue
+			}
+		default:
+			// X25519, DSA and other keys that cannot verify a JWS
+			continue
+		}
+		usable = append(usable, key)
+	}
+	if kid == "" {
+		return usable
+	}
+	var matched []jwtKey
+	for _, key := range usable {
+		if key.kid == kid {
+			matched = append(matched, key)
+		}
+	}
+	if len(matched) == 0 && len(keys) == 1 {
+		// A single key without a kid is what the caller means to check
+		return usable
+	}
+	return matched
+}
+
+func verifyJWTSignature(algorithm jwtAlgorithm, public any, input, signature []byte) error {
+	var digest []byte
+	if algorithm.hash != 0 {
+		h := algorithm.hash.New()
+		h.Write(input)
+		digest = h.Sum(nil)
+	}
+
+	switch algorithm.family {
+	case "HS":
+		secret, ok := public.([]byte)
+		if !ok {
+			return fmt.Errorf("%s needs a shared secret", algorithm.family)
+		}
+		mac := hmac.New(algorithm.hash.New, secret)
+		mac.Write(input)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("HMAC mismatch")
+		}
+		return nil
+	case "RS", "PS":
+		key, ok := public.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an RSA key", algorithm.family)
+		}
+		if algorithm.family == "PS" {
+			return rsa.VerifyPSS(key, algorithm.hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(key, algorithm.hash, digest, signature)
+	case "ES":
+		key, ok := public.(*ecdsa.PublicKey)
+		if !ok || key.Curve != algorithm.curve {
+			return fmt.Errorf("ES needs an ECDSA key on %s", algorithm.curve.Params().Name)
+		}
+		// JWS ECDSA signatures are R and S concatenated, not ASN.1
+		size := (algorithm.curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("ECDSA signature must be %d bytes", 2*size)
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		sig := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, sig) {
+			return fmt.Errorf("ECDSA verification failed")
+		}
+		return nil
+	case "EdDSA":
+		key, ok := public.(ed25519.PublicKey)
+		if !ok || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("EdDSA needs an Ed25519 key")
+		}
+		if !ed25519.Verify(key, input, signature) {
+			return fmt.Errorf("Ed25519 verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm")
+}
+
+// parseJWTKeys reads a JWKS document, a single JWK or PEM blocks
+func parseJWTKeys(text string) ([]jwtKey, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "{") {
+		var doc struct {
+			Keys []json.RawMessage `json:"keys"`
+		}
+		if err := json.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, fmt.Errorf("invalid JWK or JWKS JSON: %w", err)
+		}
+		raw := doc.Keys
+		if raw == nil {
+			raw = []json.RawMessage{json.RawMessage(text)}
+		}
+		var keys []jwtKey
+		for i, item := range raw {
+			key, err := parseJWK(item)
+			if err != nil {
+				return nil, fmt.Errorf("key %d: %w", i, err)
+			}
+			if key.public != nil {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("JWKS contains no usable signing keys")
+		}
+		return keys, nil
+	}
+
+	var keys []jwtKey
+	rest := []byte(text)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		public, err := parsePEMPublicKey(block)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, jwtKey{public: public, source: "PEM " + strings.ToLower(block.Type)})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("key must be a PEM public key or certificate, a JWK or a JWKS document")
+	}
+	return keys, nil
+}
+
+func parsePEMPublicKey(block *pem.Block) (any, error) {
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+		return nil, fmt.Errorf("%s supplied: paste the public key instead, private keys never need to leave the signer", strings.ToLower(block.Type))
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// parseJWK converts an RFC 7517 JWK into a verification key. Encryption-only
+// keys are skipped by returning a zero jwtKey.
+func parseJWK(raw json.RawMessage) (jwtKey, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		K   string `json:"k"`
+	}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return jwtKey{}, err
+	}
+	if jwk.Use == "enc" {
+		return jwtKey{}, nil
+	}
+	key := jwtKey{kid: jwk.Kid, alg: jwk.Alg, source: "JWK"}
+	if jwk.Kid != "" {
+		key.source = fmt.Sprintf("JWK kid %q", jwk.Kid)
+	}
+
+	field := func(name, value string) ([]byte, error) {
+		if value == "" {
+			return nil, fmt.Errorf("%s JWK is missing %q", jwk.Kty, name)
+		}
+		decoded, err := decodeJWTSegment(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s JWK member %q: %w", jwk.Kty, name, err)
+		}
+		return decoded, nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := field("n", jwk.N)
+		if err != nil {
+			return key, err
+		}
+		e, err := field("e", jwk.E)
+		if err != nil {
+			return key, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return key, fmt.Errorf("RSA exponent is too large")
+		}
+		key.public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[jwk.Crv]
+		if !ok {
+			return key, fmt.Errorf("unsupported EC curve %q", jwk.Crv)
+		}
+		x, err := field("x", jwk.X)
+		if err != nil {
+			return key, err
+		}
+		y, err := field("y", jwk.Y)
+		if err != nil {
+			return key, err
+		}
+		public := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(public.X, public.Y) {
+			return key, fmt.Errorf("EC point is not on %s", jwk.Crv)
+		}
+		key.public = public
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return key, fmt.Errorf("unsupported OKP curve %q", jwk.Crv)
+		}
+		x, err := field("x", jwk.X)
+		if err != nil {
+			return key, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return key, fmt.Errorf("Ed25519 key must be %d bytes", ed25519.PublicKeySize)
+		}
+		key.public = ed25519.PublicKey(x)
+	case "oct":
+		k, err := field("k", jwk.K)
+		if err != nil {
+			return key, err
+		}
+		key.public = k
+	default:
+		return key, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+	return key, nil
+}
+
+// decodeJWTSegment decodes base64url, tolerating padding some encoders add
+func decodeJWTSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+}
+
+func decodeJWTSecret(secret, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "text":
+		return []byte(secret), nil
+	case "base64":
+		trimmed := strings.TrimRight(strings.TrimSpace(secret), "=")
+		if decoded, err := base64.RawStdEncoding.DecodeString(trimmed); err == nil {
+			return decoded, nil
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("secret is not valid base64")
+		}
+		return decoded, nil
+	case "hex":
+		decoded, err := hex.DecodeString(strings.TrimSpace(secret))
+		if err != nil {
+			return nil, fmt.Errorf("secret is not valid hex")
+		}
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("unsupported secret_encoding %q (supported: text, base64, hex)", encoding)
+}
+
+func decodeJWTObject(data []byte) (map[string]any, error) {
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("found %s", schemaTypeOf(value))
+	}
+	return object, nil
+}
+
+func indentJWTSegment(data []byte) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return string(data)
+	}
+	return buf.String()
+}
+
+// numericDate reads an RFC 7519 NumericDate, which may have a fraction
+func numericDate(value any) (time.Time, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil || math.IsInf(seconds, 0) || math.Abs(seconds) > 1e11 {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC(), true
+}
+
+// relativeTime describes t relative to now, such as "in 2h 5m" or "3d 4h ago"
+func relativeTime(t, now time.Time) string {
+	d := t.Sub(now)
+	future := d > 0
+	if !future {
+		d = -d
+	}
+	d = d.Round(time.Second)
+
+	var text string
+	switch days := int(d.Hours()) / 24; {
+	case d < time.Second:
+		return "now"
+	case days >= 365:
+		text = fmt.Sprintf("%dy %dd", days/365, days%365)
+	case days > 0:
+		text = fmt.Sprintf("%dd %dh", days, int(d.Hours())%24)
+	case d >= time.Hour:
+		text = fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		text = fmt.Sprintf("%dm %ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		text = fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if future {
+		return "in " + text
+	}
+	return text + " ago"
+}
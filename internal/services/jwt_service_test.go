@@ -0,0 +1,183 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"testing"
+)
+
+// testJWT builds a token with the given header alg and kid, signed by sign
+func testJWT(t *testing.T, alg, kid string, sign func(input []byte) []byte) string {
+	t.Helper()
+	header := map[string]any{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, _ := json.Marshal(header)
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"test"}`))
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func testPEM(t *testing.T, public any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func testDigest(hash crypto.Hash, input []byte) []byte {
+	h := hash.New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+func hasJWTIssue(result *JWTResult, rule string) bool {
+	for _, issue := range result.Issues {
+		if issue.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestJWTVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("a-shared-secret-of-at-least-32-bytes!")
+
+	hmacSign := func(hash crypto.Hash, key []byte) func([]byte) []byte {
+		return func(input []byte) []byte {
+			mac := hmac.New(hash.New, key)
+			mac.Write(input)
+			return mac.Sum(nil)
+		}
+	}
+	rsaSign := func(hash crypto.Hash, pss bool) func([]byte) []byte {
+		return func(input []byte) []byte {
+			var sig []byte
+			var err error
+			if pss {
+				sig, err = rsa.SignPSS(rand.Reader, rsaKey, hash, testDigest(hash, input), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			} else {
+				sig, err = rsa.SignPKCS1v15(rand.Reader, rsaKey, hash, testDigest(hash, input))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		}
+	}
+	esSign := func(input []byte) []byte {
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, testDigest(crypto.SHA256, input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+	edSign := func(input []byte) []byte { return ed25519.Sign(edPrivate, input) }
+	ecJWK := fmt.Sprintf(`{"keys":[{"kty":"EC","crv":"P-256","kid":"ec1","x":%q,"y":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+		base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))))
+	rsaPEM := testPEM(t, &rsaKey.PublicKey)
+
+	tests := []struct {
+		name  string
+		token string
+		req   JWTRequest
+		valid bool
+		rule  string // issue expected when the signature does not verify
+	}{
+		{"HS256", testJWT(t, "HS256", "", hmacSign(crypto.SHA256, secret)), JWTRequest{Secret: string(secret)}, true, ""},
+		{"HS512", testJWT(t, "HS512", "", hmacSign(crypto.SHA512, secret)), JWTRequest{Key: fmt.Sprintf(`{"kty":"oct","k":%q}`, base64.RawURLEncoding.EncodeToString(secret))}, true, ""},
+		{"HS256 wrong secret", testJWT(t, "HS256", "", hmacSign(crypto.SHA256, secret)), JWTRequest{Secret: "another secret"}, false, "invalid-signature"},
+		{"RS256", testJWT(t, "RS256", "", rsaSign(crypto.SHA256, false)), JWTRequest{Key: rsaPEM}, true, ""},
+		{"RS256 other key", testJWT(t, "RS256", "", rsaSign(crypto.SHA256, false)), JWTRequest{Key: testPEM(t, &otherRSA.PublicKey)}, false, "invalid-signature"},
+		{"PS384", testJWT(t, "PS384", "", rsaSign(crypto.SHA384, true)), JWTRequest{Key: rsaPEM}, true, ""},
+		{"ES256", testJWT(t, "ES256", "ec1", esSign), JWTRequest{Key: ecJWK}, true, ""},
+		{"ES384 with a P-256 key", testJWT(t, "ES384", "ec1", esSign), JWTRequest{Key: ecJWK}, false, "no-matching-key"},
+		{"EdDSA", testJWT(t, "EdDSA", "", edSign), JWTRequest{Key: testPEM(t, edPublic)}, true, ""},
+		{"alg none", testJWT(t, "none", "", func([]byte) []byte { return nil }), JWTRequest{Key: rsaPEM}, false, "signature-unverifiable"},
+		// HS256 signed with the public key text as the secret, the classic algorithm confusion
+		{"HS256 with a public key", testJWT(t, "HS256", "", hmacSign(crypto.SHA256, []byte(rsaPEM))), JWTRequest{Key: rsaPEM}, false, "algorithm-confusion"},
+		{"RS256 with a secret", testJWT(t, "RS256", "", rsaSign(crypto.SHA256, false)), JWTRequest{Secret: string(secret)}, false, "no-matching-key"},
+		{"EdDSA with an X25519 key", testJWT(t, "EdDSA", "", edSign), JWTRequest{Key: testPEM(t, x25519.PublicKey())}, false, "no-matching-key"},
+		{"ES256 with an X25519 key", testJWT(t, "ES256", "", esSign), JWTRequest{Key: testPEM(t, x25519.PublicKey())}, false, "no-matching-key"},
+	}
+	for _, tt := range tests {
+		tt.req.Token = tt.token
+		result, err := NewJWTService().Inspect(tt.req)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !result.SignatureChecked || result.SignatureValid != tt.valid {
+			t.Errorf("%s: checked=%v valid=%v, want valid=%v (issues %v)", tt.name, result.SignatureChecked, result.SignatureValid, tt.valid, result.Issues)
+		}
+		if tt.rule != "" && !hasJWTIssue(result, tt.rule) {
+			t.Errorf("%s: issues %v, want %s", tt.name, result.Issues, tt.rule)
+		}
+	}
+
+	// An unsigned token is reported even without verification inputs
+	result, err := NewJWTService().Inspect(JWTRequest{Token: testJWT(t, "none", "", func([]byte) []byte { return nil })})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.SignatureChecked || !hasJWTIssue(result, "alg-none") {
+		t.Errorf("alg none: checked=%v issues %v", result.SignatureChecked, result.Issues)
+	}
+}
+
+// Keys of the wrong type, and a zero signature, fail verification rather than panicking
+func TestVerifyJWTSignatureKeyTypes(t *testing.T) {
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []any{nil, []byte("secret"), x25519.PublicKey(), &ecKey.PublicKey, ed25519.PublicKey{1, 2, 3}}
+	for alg, algorithm := range jwtAlgorithms {
+		for _, key := range keys {
+			if err := verifyJWTSignature(algorithm, key, []byte("input"), make([]byte, 96)); err == nil {
+				t.Errorf("%s verified with a %T key", alg, key)
+			}
+		}
+	}
+}
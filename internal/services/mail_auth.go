@@ -0,0 +1,956 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SPF limits from RFC 7208 section 4.6.4
+const (
+	spfMaxLookups     = 10
+	spfMaxVoidLookups = 2
+	spfMaxDepth       = 20
+)
+
+// MailAuthService analyzes the email authentication records of a domain
+type MailAuthService struct {
+	dns        *IPAnalysisService
+	httpClient *http.Client
+}
+
+// NewMailAuthService creates a new mail authentication service on top of the DNS lookup layer
+func NewMailAuthService(dns *IPAnalysisService) *MailAuthService {
+	return &MailAuthService{
+		dns: dns,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+			// MTA-STS policies must not be fetched through redirects (RFC 8461 section 3.3)
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// MailAuthRequest represents a request to check the mail authentication of a domain
+type MailAuthRequest struct {
+	Domain        string   `json:"domain"`
+	DKIMSelectors []string `json:"dkim_selectors"`
+}
+
+// MailAuthReport represents the SPF, DKIM, DMARC, MTA-STS and BIMI state of a domain
+type MailAuthReport struct {
+	Domain    string        `json:"domain"`
+	SPF       *SPFReport    `json:"spf"`
+	DMARC     *DMARCReport  `json:"dmarc"`
+	DKIM      []DKIMReport  `json:"dkim"`
+	MTASTS    *MTASTSReport `json:"mta_sts"`
+	BIMI      *BIMIReport   `json:"bimi"`
+	Errors    []string      `json:"errors"`
+	Warnings  []string      `json:"warnings"`
+	QueryTime int           `json:"query_time_ms"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// SPFReport represents a parsed SPF record and its include tree
+type SPFReport struct {
+	Record      string       `json:"record,omitempty"`
+	Parsed      *SPFRecord   `json:"parsed,omitempty"`
+	Includes    []SPFInclude `json:"includes,omitempty"`
+	LookupCount int          `json:"lookup_count"`
+	VoidLookups int          `json:"void_lookups"`
+	Errors      []string     `json:"errors,omitempty"`
+	Warnings    []string     `json:"warnings,omitempty"`
+}
+
+// SPFInclude represents a record reached through include or redirect
+type SPFInclude struct {
+	Domain      string       `json:"domain"`
+	Via         string       `json:"via"` // "include" or "redirect"
+	Record      string       `json:"record,omitempty"`
+	LookupCount int          `json:"lookup_count"` // lookups made by this record and its includes
+	Includes    []SPFInclude `json:"includes,omitempty"`
+	Repeated    bool         `json:"repeated,omitempty"` // expanded earlier in the walk; its includes are listed there
+	Error       string       `json:"error,omitempty"`
+}
+
+// SPFRecord represents a parsed SPF record
+type SPFRecord struct {
+	Mechanisms  []SPFMechanism `json:"mechanisms"`
+	Redirect    string         `json:"redirect,omitempty"`
+	Explanation string         `json:"exp,omitempty"`
+	Unknown     []string       `json:"unknown_modifiers,omitempty"`
+}
+
+// SPFMechanism represents a single SPF mechanism
+type SPFMechanism struct {
+	Qualifier string `json:"qualifier"` // "+", "-", "~" or "?"
+	Name      string `json:"name"`      // "all", "include", "a", "mx", "ptr", "ip4", "ip6", "exists"
+	Value     string `json:"value,omitempty"`
+	IP4Prefix int    `json:"ip4_prefix,omitempty"` // a and mx only
+	IP6Prefix int    `json:"ip6_prefix,omitempty"` // a and mx only
+}
+
+// DMARCReport represents a parsed DMARC record
+type DMARCReport struct {
+	Record          string            `json:"record,omitempty"`
+	Tags            map[string]string `json:"tags,omitempty"`
+	Policy          string            `json:"policy,omitempty"`
+	SubdomainPolicy string            `json:"subdomain_policy,omitempty"`
+	Percent         int               `json:"percent"`
+	AlignmentDKIM   string            `json:"alignment_dkim,omitempty"` // "relaxed" or "strict"
+	AlignmentSPF    string            `json:"alignment_spf,omitempty"`
+	AggregateURIs   []string          `json:"rua,omitempty"`
+	ForensicURIs    []string          `json:"ruf,omitempty"`
+	Errors          []string          `json:"errors,omitempty"`
+	Warnings        []string          `json:"warnings,omitempty"`
+}
+
+// DKIMReport represents the DKIM key published for a selector
+type DKIMReport struct {
+	Selector string            `json:"selector"`
+	Name     string            `json:"name"`
+	Found    bool              `json:"found"`
+	Record   string            `json:"record,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	KeyType  string            `json:"key_type,omitempty"`
+	KeySize  int               `json:"key_size,omitempty"`
+	Revoked  bool              `json:"revoked,omitempty"`
+	Testing  bool              `json:"testing,omitempty"`
+	Errors   []string          `json:"errors,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+}
+
+// MTASTSReport represents the MTA-STS record and policy of a domain
+type MTASTSReport struct {
+	Record    string   `json:"record,omitempty"`
+	ID        string   `json:"id,omitempty"`
+	PolicyURL string   `json:"policy_url,omitempty"`
+	Policy    string   `json:"policy,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	Mode      string   `json:"mode,omitempty"` // "enforce", "testing" or "none"
+	MX        []string `json:"mx,omitempty"`
+	MaxAge    int      `json:"max_age,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+// BIMIReport represents the BIMI record of a domain
+type BIMIReport struct {
+	Record      string            `json:"record,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	LogoURL     string            `json:"logo_url,omitempty"`
+	Certificate string            `json:"certificate_url,omitempty"`
+	Errors      []string          `json:"errors,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+}
+
+// CheckDomain analyzes the SPF, DMARC, DKIM, MTA-STS and BIMI records of a domain
+func (s *MailAuthService) CheckDomain(ctx context.Context, req MailAuthRequest) (*MailAuthReport, error) {
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+
+	start := time.Now()
+	report := &MailAuthReport{
+		Domain:    domain,
+		DKIM:      []DKIMReport{},
+		Errors:    []string{},
+		Warnings:  []string{},
+		Timestamp: start,
+	}
+
+	report.SPF = s.checkSPF(ctx, domain)
+	report.DMARC = s.checkDMARC(ctx, domain)
+	for _, selector := range req.DKIMSelectors {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			report.DKIM = append(report.DKIM, s.checkDKIM(ctx, domain, selector))
+		}
+	}
+	report.MTASTS = s.checkMTASTS(ctx, domain)
+	report.BIMI = s.checkBIMI(ctx, domain, report.DMARC)
+
+	// Collect every section's findings at the top level for quick display
+	collect := func(section string, errs, warnings []string) {
+		for _, e := range errs {
+			report.Errors = append(report.Errors, section+": "+e)
+		}
+		for _, w := range warnings {
+			report.Warnings = append(report.Warnings, section+": "+w)
+		}
+	}
+	collect("SPF", report.SPF.Errors, report.SPF.Warnings)
+	collect("DMARC", report.DMARC.Errors, report.DMARC.Warnings)
+	for _, dkim := range report.DKIM {
+		collect("DKIM "+dkim.Selector, dkim.Errors, dkim.Warnings)
+	}
+	collect("MTA-STS", report.MTASTS.Errors, report.MTASTS.Warnings)
+	collect("BIMI", report.BIMI.Errors, report.BIMI.Warnings)
+
+	report.QueryTime = int(time.Since(start).Milliseconds())
+	return report, nil
+}
+
+// checkSPF parses the SPF record of a domain and walks its includes
+func (s *MailAuthService) checkSPF(ctx context.Context, domain string) *SPFReport {
+	report := &SPFReport{}
+
+	record, err := s.spfRecord(ctx, domain)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	if record == "" {
+		report.Errors = append(report.Errors, "no SPF record found")
+		return report
+	}
+	report.Record = record
+
+	parsed, err := parseSPF(record)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.Parsed = parsed
+
+	walk := &spfWalk{report: report, done: make(map[string]int), active: map[string]bool{domain: true}}
+	report.LookupCount, report.Includes = s.walkSPF(ctx, parsed, walk, 0)
+
+	if walk.stopped {
+		report.Errors = append(report.Errors, fmt.Sprintf("record requires more than %d DNS lookups; receivers will return permerror, and the remaining includes were not resolved", spfMaxLookups))
+	} else if report.LookupCount > spfMaxLookups {
+		report.Errors = append(report.Errors, fmt.Sprintf("record requires %d DNS lookups; the limit is %d and receivers will return permerror", report.LookupCount, spfMaxLookups))
+	} else if report.LookupCount >= spfMaxLookups-2 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("record requires %d of the %d allowed DNS lookups", report.LookupCount, spfMaxLookups))
+	}
+	if report.VoidLookups > spfMaxVoidLookups {
+		report.Errors = append(report.Errors, fmt.Sprintf("%d lookups return no records; more than %d causes permerror", report.VoidLookups, spfMaxVoidLookups))
+	}
+
+	report.Warnings = append(report.Warnings, spfRecordWarnings(parsed)...)
+	return report
+}
+
+// spfWalk is the state shared by every record reached from one SPF record
+type spfWalk struct {
+	report  *SPFReport
+	done    map[string]int  // lookups of each target already expanded
+	active  map[string]bool // targets on the current include chain
+	lookups int             // lookups counted so far
+	stopped bool            // the walk passed spfMaxLookups and stopped resolving
+}
+
+// walkSPF counts the DNS lookups of a record and recursively resolves its
+// includes. Each target is queried once; the walk stops resolving as soon as
+// the lookups exceed spfMaxLookups, as receivers do.
+func (s *MailAuthService) walkSPF(ctx context.Context, record *SPFRecord, walk *spfWalk, depth int) (int, []SPFInclude) {
+	report := walk.report
+	lookups := 0
+	var includes []SPFInclude
+
+	var targets []SPFInclude
+	for _, mechanism := range record.Mechanisms {
+		switch mechanism.Name {
+		case "a", "mx", "ptr", "exists":
+			lookups++
+		case "include":
+			lookups++
+			targets = append(targets, SPFInclude{Domain: mechanism.Value, Via: "include"})
+		}
+	}
+	if record.Redirect != "" {
+		lookups++
+		targets = append(targets, SPFInclude{Domain: record.Redirect, Via: "redirect"})
+	}
+	walk.lookups += lookups
+
+	for _, include := range targets {
+		target := strings.TrimSuffix(strings.ToLower(include.Domain), ".")
+		previous, expanded := walk.done[target]
+		switch {
+		case strings.Contains(target, "%{"):
+			include.Error = "target uses macros and is expanded per message"
+		case walk.active[target]:
+			include.Error = "include loop detected"
+			report.Errors = append(report.Errors, fmt.Sprintf("%s %s forms a loop", include.Via, target))
+		case expanded:
+			// Receivers evaluate a repeated include again, so its lookups count again
+			include.Repeated = true
+			include.LookupCount = previous
+			lookups += previous
+			walk.lookups += previous
+		case walk.lookups > spfMaxLookups:
+			include.Error = "not resolved: the lookup limit is already exceeded"
+			walk.stopped = true
+		case depth >= spfMaxDepth:
+			include.Error = "include depth limit reached"
+		default:
+			walk.active[target] = true
+			text, err := s.spfRecord(ctx, target)
+			switch {
+			case err != nil:
+				include.Error = err.Error()
+				report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", include.Via, target, err))
+			case text == "":
+				include.Error = "no SPF record found"
+				report.VoidLookups++
+				report.Errors = append(report.Errors, fmt.Sprintf("%s %s has no SPF record and causes permerror", include.Via, target))
+			default:
+				include.Record = text
+				parsed, err := parseSPF(text)
+				if err != nil {
+					include.Error = err.Error()
+					report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", include.Via, target, err))
+					break
+				}
+				include.LookupCount, include.Includes = s.walkSPF(ctx, parsed, walk, depth+1)
+				lookups += include.LookupCount
+			}
+			delete(walk.active, target)
+			walk.done[target] = include.LookupCount
+		}
+		includes = append(includes, include)
+	}
+
+	return lookups, includes
+}
+
+// spfRecord returns the single SPF record published for a domain, or "" if there is none
+func (s *MailAuthService) spfRecord(ctx context.Context, domain string) (string, error) {
+	texts, err := s.txtStrings(ctx, domain)
+	if err != nil {
+		return "", &spfError{SPFTempError, err.Error()}
+	}
+
+	var records []string
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		if lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			records = append(records, text)
+		}
+	}
+
+	switch len(records) {
+	case 0:
+		return "", nil
+	case 1:
+		return records[0], nil
+	default:
+		return "", &spfError{SPFPermError, fmt.Sprintf("%s publishes %d SPF records; exactly one is allowed", domain, len(records))}
+	}
+}
+
+// parseSPF parses an SPF record into its mechanisms and modifiers
+func parseSPF(record string) (*SPFRecord, error) {
+	fields := strings.Fields(record)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "v=spf1") {
+		return nil, fmt.Errorf("record does not start with v=spf1")
+	}
+
+	parsed := &SPFRecord{Mechanisms: []SPFMechanism{}}
+	for _, term := range fields[1:] {
+		// Modifiers are name=value; mechanisms use ':' or '/' separators
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			switch strings.ToLower(name) {
+			case "redirect":
+				if parsed.Redirect != "" {
+					return nil, fmt.Errorf("redirect modifier appears more than once")
+				}
+				parsed.Redirect = value
+			case "exp":
+				if parsed.Explanation != "" {
+					return nil, fmt.Errorf("exp modifier appears more than once")
+				}
+				parsed.Explanation = value
+			default:
+				parsed.Unknown = append(parsed.Unknown, term)
+			}
+			continue
+		}
+
+		mechanism, err := parseSPFMechanism(term)
+		if err != nil {
+			return nil, err
+		}
+		parsed.Mechanisms = append(parsed.Mechanisms, mechanism)
+	}
+
+	return parsed, nil
+}
+
+// parseSPFMechanism parses a single SPF mechanism term
+func parseSPFMechanism(term string) (SPFMechanism, error) {
+	mechanism := SPFMechanism{Qualifier: "+"}
+	if strings.ContainsRune("+-~?", rune(term[0])) {
+		mechanism.Qualifier = term[:1]
+		term = term[1:]
+	}
+
+	name := term
+	rest := ""
+	if i := strings.IndexAny(term, ":/"); i >= 0 {
+		name, rest = term[:i], term[i:]
+	}
+	mechanism.Name = strings.ToLower(name)
+
+	switch mechanism.Name {
+	case "all":
+		if rest != "" {
+			return mechanism, fmt.Errorf("invalid mechanism %q: all takes no arguments", term)
+		}
+	case "include", "exists", "ptr":
+		if strings.HasPrefix(rest, ":") {
+			mechanism.Value = rest[1:]
+		} else if rest != "" {
+			return mechanism, fmt.Errorf("invalid mechanism %q", term)
+		}
+		if mechanism.Name != "ptr" && mechanism.Value == "" {
+			return mechanism, fmt.Errorf("invalid mechanism %q: %s requires a domain", term, mechanism.Name)
+		}
+	case "a", "mx":
+		mechanism.IP4Prefix, mechanism.IP6Prefix = 32, 128
+		domain := rest
+		if i := strings.Index(rest, "/"); i >= 0 {
+			domain = rest[:i]
+			if err := parseDualCIDR(rest[i:], &mechanism); err != nil {
+				return mechanism, fmt.Errorf("invalid mechanism %q: %v", term, err)
+			}
+		}
+		if strings.HasPrefix(domain, ":") {
+			mechanism.Value = domain[1:]
+		} else if domain != "" {
+			return mechanism, fmt.Errorf("invalid mechanism %q", term)
+		}
+	case "ip4", "ip6":
+		if !strings.HasPrefix(rest, ":") {
+			return mechanism, fmt.Errorf("invalid mechanism %q: %s requires an address", term, mechanism.Name)
+		}
+		value := rest[1:]
+		address := value
+		if !strings.Contains(value, "/") {
+			if mechanism.Name == "ip4" {
+				address += "/32"
+			} else {
+				address += "/128"
+			}
+		}
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil || prefix.Addr().Is4() != (mechanism.Name == "ip4") {
+			return mechanism, fmt.Errorf("invalid mechanism %q: bad %s network", term, mechanism.Name)
+		}
+		mechanism.Value = value
+	default:
+		return mechanism, fmt.Errorf("unknown mechanism %q", term)
+	}
+
+	return mechanism, nil
+}
+
+// parseDualCIDR parses the "/n", "//n" or "/n//m" suffix of a and mx mechanisms
+func parseDualCIDR(suffix string, mechanism *SPFMechanism) error {
+	v4, v6, hasV6 := strings.Cut(strings.TrimPrefix(suffix, "/"), "//")
+	if strings.HasPrefix(suffix, "//") {
+		v4, v6, hasV6 = "", strings.TrimPrefix(suffix, "//"), true
+	}
+
+	if v4 != "" {
+		n, err := strconv.Atoi(v4)
+		if err != nil || n < 0 || n > 32 {
+			return fmt.Errorf("bad IPv4 prefix length %q", v4)
+		}
+		mechanism.IP4Prefix = n
+	}
+	if hasV6 {
+		n, err := strconv.Atoi(v6)
+		if err != nil || n < 0 || n > 128 {
+			return fmt.Errorf("bad IPv6 prefix length %q", v6)
+		}
+		mechanism.IP6Prefix = n
+	}
+	return nil
+}
+
+// spfRecordWarnings flags risky but valid constructs in an SPF record
+func spfRecordWarnings(record *SPFRecord) []string {
+	var warnings []string
+	hasAll := false
+
+	for i, mechanism := range record.Mechanisms {
+		switch mechanism.Name {
+		case "all":
+			hasAll = true
+			switch mechanism.Qualifier {
+			case "+":
+				warnings = append(warnings, "+all allows any host to send mail for the domain")
+			case "?":
+				warnings = append(warnings, "?all is neutral and provides no protection")
+			}
+			if i != len(record.Mechanisms)-1 {
+				warnings = append(warnings, "mechanisms after all are never evaluated")
+			}
+		case "ptr":
+			warnings = append(warnings, "the ptr mechanism is slow and unreliable; RFC 7208 says it should not be used")
+		}
+	}
+
+	if hasAll && record.Redirect != "" {
+		warnings = append(warnings, "redirect is ignored when the record contains all")
+	}
+	if !hasAll && record.Redirect == "" {
+		warnings = append(warnings, "record has no all mechanism; unmatched senders get a neutral result")
+	}
+
+	return warnings
+}
+
+// checkDMARC parses the DMARC record of a domain
+func (s *MailAuthService) checkDMARC(ctx context.Context, domain string) *DMARCReport {
+	report := &DMARCReport{}
+
+	texts, err := s.txtStrings(ctx, "_dmarc."+domain)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	var records []string
+	for _, text := range texts {
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(text)), "V=DMARC1") {
+			records = append(records, text)
+		}
+	}
+	switch len(records) {
+	case 0:
+		report.Errors = append(report.Errors, "no DMARC record found at _dmarc."+domain)
+		if parent := parentDomain(domain); parent != "" {
+			report.Warnings = append(report.Warnings, "receivers fall back to the organizational domain policy; check "+parent+" if it is the organizational domain")
+		}
+		return report
+	case 1:
+		report.Record = records[0]
+	default:
+		report.Errors = append(report.Errors, fmt.Sprintf("%d DMARC records found; receivers ignore all of them", len(records)))
+		return report
+	}
+
+	tags, err := parseTagList(report.Record)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.Tags = tags
+
+	// Defaults from RFC 7489 section 6.3
+	report.Percent = 100
+	report.AlignmentDKIM = "relaxed"
+	report.AlignmentSPF = "relaxed"
+
+	report.Policy = strings.ToLower(tags["p"])
+	switch report.Policy {
+	case "none":
+		report.Warnings = append(report.Warnings, "p=none only monitors; failing mail is still delivered")
+	case "quarantine", "reject":
+	case "":
+		report.Errors = append(report.Errors, "required p tag is missing")
+	default:
+		report.Errors = append(report.Errors, fmt.Sprintf("invalid policy p=%s", tags["p"]))
+	}
+
+	report.SubdomainPolicy = report.Policy
+	if sp, ok := tags["sp"]; ok {
+		report.SubdomainPolicy = strings.ToLower(sp)
+		if report.SubdomainPolicy != "none" && report.SubdomainPolicy != "quarantine" && report.SubdomainPolicy != "reject" {
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid subdomain policy sp=%s", sp))
+		}
+	}
+
+	if pct, ok := tags["pct"]; ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil || n < 0 || n > 100 {
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid pct=%s; it must be between 0 and 100", pct))
+		} else {
+			report.Percent = n
+			if n < 100 {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("policy applies to only %d%% of failing mail", n))
+			}
+		}
+	}
+
+	for tag, alignment := range map[string]*string{"adkim": &report.AlignmentDKIM, "aspf": &report.AlignmentSPF} {
+		switch strings.ToLower(tags[tag]) {
+		case "":
+		case "r":
+			*alignment = "relaxed"
+		case "s":
+			*alignment = "strict"
+		default:
+			report.Errors = append(report.Errors, fmt.Sprintf("invalid %s=%s; it must be r or s", tag, tags[tag]))
+		}
+	}
+
+	report.AggregateURIs = splitDMARCURIs(tags["rua"])
+	report.ForensicURIs = splitDMARCURIs(tags["ruf"])
+	if len(report.AggregateURIs) == 0 {
+		report.Warnings = append(report.Warnings, "no rua tag; you will not receive aggregate reports")
+	}
+	for _, uri := range append(append([]string{}, report.AggregateURIs...), report.ForensicURIs...) {
+		if !strings.HasPrefix(strings.ToLower(uri), "mailto:") && !strings.HasPrefix(strings.ToLower(uri), "https:") {
+			report.Errors = append(report.Errors, fmt.Sprintf("report URI %q must use mailto: or https:", uri))
+		}
+	}
+
+	return report
+}
+
+// checkDKIM looks up and validates the DKIM key for a selector
+func (s *MailAuthService) checkDKIM(ctx context.Context, domain, selector string) DKIMReport {
+	report := DKIMReport{
+		Selector: selector,
+		Name:     selector + "._domainkey." + domain,
+	}
+
+	texts, err := s.txtStrings(ctx, report.Name)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	for _, text := range texts {
+		if strings.Contains(text, "p=") {
+			report.Record = text
+			break
+		}
+	}
+	if report.Record == "" {
+		report.Errors = append(report.Errors, "no DKIM key found for selector")
+		return report
+	}
+	report.Found = true
+
+	tags, err := parseTagList(report.Record)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.Tags = tags
+
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		report.Errors = append(report.Errors, fmt.Sprintf("invalid version v=%s", v))
+	}
+	for _, flag := range strings.Split(tags["t"], ":") {
+		if strings.TrimSpace(flag) == "y" {
+			report.Testing = true
+			report.Warnings = append(report.Warnings, "selector is in testing mode (t=y); verifiers may ignore failures")
+		}
+	}
+
+	report.KeyType = strings.ToLower(tags["k"])
+	if report.KeyType == "" {
+		report.KeyType = "rsa"
+	}
+
+	key := strings.Join(strings.Fields(tags["p"]), "")
+	if key == "" {
+		report.Revoked = true
+		report.Warnings = append(report.Warnings, "key is revoked (empty p tag)")
+		return report
+	}
+
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		report.Errors = append(report.Errors, "public key is not valid base64")
+		return report
+	}
+
+	switch report.KeyType {
+	case "rsa":
+		pub, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			// Some signers publish a bare PKCS#1 key
+			pub, err = x509.ParsePKCS1PublicKey(der)
+		}
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if err != nil || !ok {
+			report.Errors = append(report.Errors, "public key is not a valid RSA key")
+			return report
+		}
+		report.KeySize = rsaKey.N.BitLen()
+		if report.KeySize < 1024 {
+			report.Errors = append(report.Errors, fmt.Sprintf("%d-bit RSA keys are rejected by verifiers", report.KeySize))
+		} else if report.KeySize < 2048 {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%d-bit RSA key; 2048 bits is recommended", report.KeySize))
+		}
+	case "ed25519":
+		if len(der) != 32 {
+			report.Errors = append(report.Errors, "public key is not a valid Ed25519 key")
+			return report
+		}
+		report.KeySize = 256
+	default:
+		report.Errors = append(report.Errors, fmt.Sprintf("unknown key type k=%s", tags["k"]))
+	}
+
+	return report
+}
+
+// checkMTASTS looks up the MTA-STS record and fetches the published policy
+func (s *MailAuthService) checkMTASTS(ctx context.Context, domain string) *MTASTSReport {
+	report := &MTASTSReport{}
+
+	texts, err := s.txtStrings(ctx, "_mta-sts."+domain)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	for _, text := range texts {
+		if strings.HasPrefix(text, "v=STSv1") {
+			report.Record = text
+			break
+		}
+	}
+	if report.Record == "" {
+		report.Warnings = append(report.Warnings, "no MTA-STS record; inbound SMTP TLS is not enforced")
+		return report
+	}
+
+	tags, err := parseTagList(report.Record)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.ID = tags["id"]
+	if report.ID == "" {
+		report.Errors = append(report.Errors, "record is missing the id tag")
+	}
+
+	report.PolicyURL = "https://mta-sts." + domain + "/.well-known/mta-sts.txt"
+	policy, err := s.fetchPolicy(ctx, report.PolicyURL)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to fetch policy: %v", err))
+		return report
+	}
+	report.Policy = policy
+
+	scanner := bufio.NewScanner(strings.NewReader(policy))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			report.Version = value
+		case "mode":
+			report.Mode = value
+		case "mx":
+			report.MX = append(report.MX, value)
+		case "max_age":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("invalid max_age %q", value))
+			}
+			report.MaxAge = n
+		}
+	}
+
+	if report.Version != "STSv1" {
+		report.Errors = append(report.Errors, "policy version must be STSv1")
+	}
+	switch report.Mode {
+	case "enforce":
+	case "testing":
+		report.Warnings = append(report.Warnings, "policy is in testing mode; failures are only reported")
+	case "none":
+		report.Warnings = append(report.Warnings, "policy mode is none")
+	default:
+		report.Errors = append(report.Errors, fmt.Sprintf("invalid policy mode %q", report.Mode))
+	}
+	if report.MaxAge > 31557600 {
+		report.Errors = append(report.Errors, "max_age exceeds the one year maximum")
+	} else if report.MaxAge < 86400 {
+		report.Warnings = append(report.Warnings, "max_age is shorter than one day")
+	}
+	if len(report.MX) == 0 && report.Mode != "none" {
+		report.Errors = append(report.Errors, "policy lists no mx patterns")
+	}
+
+	// Every MX host must match a policy pattern or delivery fails in enforce mode
+	if mxRecords, err := s.dns.lookupMX(ctx, domain); err == nil {
+		for _, record := range mxRecords {
+			fields := strings.Fields(record.Value)
+			host := strings.TrimSuffix(fields[len(fields)-1], ".")
+			if !matchesMXPattern(host, report.MX) {
+				report.Errors = append(report.Errors, fmt.Sprintf("MX host %s is not covered by the policy", host))
+			}
+		}
+	}
+
+	return report
+}
+
+// fetchPolicy fetches an MTA-STS policy file
+func (s *MailAuthService) fetchPolicy(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("policy host returned status %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		return "", fmt.Errorf("policy is served as %q instead of text/plain", contentType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// checkBIMI looks up the default BIMI record of a domain
+func (s *MailAuthService) checkBIMI(ctx context.Context, domain string, dmarc *DMARCReport) *BIMIReport {
+	report := &BIMIReport{}
+
+	texts, err := s.txtStrings(ctx, "default._bimi."+domain)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	for _, text := range texts {
+		if strings.HasPrefix(text, "v=BIMI1") {
+			report.Record = text
+			break
+		}
+	}
+	if report.Record == "" {
+		report.Warnings = append(report.Warnings, "no BIMI record at default._bimi."+domain)
+		return report
+	}
+
+	tags, err := parseTagList(report.Record)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.Tags = tags
+	report.LogoURL = tags["l"]
+	report.Certificate = tags["a"]
+
+	if report.LogoURL == "" {
+		report.Warnings = append(report.Warnings, "record has no logo location (l tag)")
+	} else if !strings.HasPrefix(report.LogoURL, "https://") || !strings.HasSuffix(strings.ToLower(report.LogoURL), ".svg") {
+		report.Errors = append(report.Errors, "logo must be an SVG served over https")
+	}
+	if report.Certificate == "" {
+		report.Warnings = append(report.Warnings, "no verified mark certificate (a tag); most mailbox providers require one")
+	}
+
+	// BIMI is only honored for domains with an enforced DMARC policy
+	if dmarc == nil || (dmarc.Policy != "quarantine" && dmarc.Policy != "reject") || dmarc.Percent < 100 {
+		report.Errors = append(report.Errors, "BIMI requires a DMARC policy of quarantine or reject at pct=100")
+	}
+
+	return report
+}
+
+// txtStrings returns the TXT records of a name, treating a missing name as no records
+func (s *MailAuthService) txtStrings(ctx context.Context, name string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	records, err := s.dns.lookupTXT(ctx, name)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("TXT lookup for %s failed: %w", name, err)
+	}
+
+	texts := make([]string, 0, len(records))
+	for _, record := range records {
+		texts = append(texts, record.Value)
+	}
+	return texts, nil
+}
+
+// parseTagList parses a "tag=value; tag=value" list as used by DKIM, DMARC and BIMI
+func parseTagList(record string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed tag %q", part)
+		}
+		name = strings.TrimSpace(name)
+		if _, exists := tags[name]; exists {
+			return nil, fmt.Errorf("tag %s appears more than once", name)
+		}
+		tags[name] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// splitDMARCURIs splits a comma separated DMARC report URI list
+func splitDMARCURIs(value string) []string {
+	var uris []string
+	for _, uri := range strings.Split(value, ",") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// matchesMXPattern reports whether a host matches one of the MTA-STS mx patterns
+func matchesMXPattern(host string, patterns []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+		if strings.HasPrefix(pattern, "*.") {
+			// A wildcard matches exactly one leftmost label
+			if _, rest, ok := strings.Cut(host, "."); ok && rest == pattern[2:] {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// parentDomain returns the domain one label up, or "" for a top-level name
+func parentDomain(domain string) string {
+	_, parent, ok := strings.Cut(domain, ".")
+	if !ok || !strings.Contains(parent, ".") {
+		return ""
+	}
+	return parent
+}
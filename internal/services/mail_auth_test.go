@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// treeResolver publishes an SPF record under tree.example that includes two
+// further names, so the include tree doubles at every level
+type treeResolver struct {
+	*fakeResolver
+	queries int
+}
+
+func (r *treeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if !strings.HasSuffix(name, "tree.example") {
+		return r.fakeResolver.LookupTXT(ctx, name)
+	}
+	r.queries++
+	return []string{"v=spf1 include:a." + name + " include:b." + name + " -all"}, nil
+}
+
+func TestCheckSPFStopsAtLookupLimit(t *testing.T) {
+	resolver := &treeResolver{fakeResolver: &fakeResolver{}}
+	service := NewMailAuthService(NewIPAnalysisServiceWithResolver(resolver))
+
+	report := service.checkSPF(context.Background(), "tree.example")
+	if resolver.queries > spfMaxLookups+1 {
+		t.Errorf("walk made %d TXT queries, want at most %d", resolver.queries, spfMaxLookups+1)
+	}
+	if report.LookupCount <= spfMaxLookups || !strings.Contains(strings.Join(report.Errors, "\n"), "more than 10 DNS lookups") {
+		t.Errorf("lookups %d, errors %v", report.LookupCount, report.Errors)
+	}
+}
+
+func TestCheckSPFRepeatedInclude(t *testing.T) {
+	resolver := &fakeResolver{txt: map[string][]string{
+		"example.com":    {"v=spf1 include:shared.example include:other.example -all"},
+		"other.example":  {"v=spf1 include:shared.example -all"},
+		"shared.example": {"v=spf1 a:mail.example mx -all"},
+		"loop.example":   {"v=spf1 include:loop-b.example -all"},
+		"loop-b.example": {"v=spf1 include:loop.example -all"},
+	}}
+	service := NewMailAuthService(NewIPAnalysisServiceWithResolver(resolver))
+
+	report := service.checkSPF(context.Background(), "example.com")
+	// 2 includes at the top, 2 in shared.example, 1 include in other.example and shared.example again
+	if report.LookupCount != 7 {
+		t.Errorf("lookups = %d, want 7", report.LookupCount)
+	}
+	repeated := report.Includes[1].Includes[0]
+	if !repeated.Repeated || repeated.LookupCount != 2 || repeated.Record != "" {
+		t.Errorf("repeated include = %+v", repeated)
+	}
+	if len(report.Errors) != 0 {
+		t.Errorf("errors = %v", report.Errors)
+	}
+
+	report = service.checkSPF(context.Background(), "loop.example")
+	if !strings.Contains(strings.Join(report.Errors, "\n"), "forms a loop") {
+		t.Errorf("errors = %v, want a loop", report.Errors)
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSweepHostBits limits a sweep to 4096 addresses (an IPv4 /20 or IPv6 /116)
+	maxSweepHostBits     = 12
+	defaultSweepWorkers  = 10
+	maxSweepWorkers      = 50
+	defaultSweepRate     = 50
+	maxSweepRate         = 200
+	reverseLookupTimeout = 5 * time.Second
+)
+
+// ReverseSweepRequest describes a reverse DNS sweep over a CIDR range
+type ReverseSweepRequest struct {
+	CIDR        string `json:"cidr"`
+	Concurrency int    `json:"concurrency"` // simultaneous lookups, default 10
+	Rate        int    `json:"rate"`        // lookups started per second, default 50
+}
+
+// ReverseSweepEntry is the PTR result for a single address
+type ReverseSweepEntry struct {
+	IP        string   `json:"ip"`
+	Hostname  string   `json:"hostname"`
+	Hostnames []string `json:"hostnames,omitempty"`
+	Status    string   `json:"status"` // "ok", "nxdomain", "timeout" or "error"
+	Error     string   `json:"error,omitempty"`
+	Latency   float64  `json:"latency_ms"`
+}
+
+// ReverseSweepResult summarizes a reverse DNS sweep
+type ReverseSweepResult struct {
+	CIDR      string              `json:"cidr"`
+	Total     int                 `json:"total"`
+	Resolved  int                 `json:"resolved"`
+	NotFound  int                 `json:"not_found"`
+	Failed    int                 `json:"failed"`
+	Entries   []ReverseSweepEntry `json:"entries,omitempty"`
+	Timestamp time.Time           `json:"timestamp"`
+	SweepTime float64             `json:"sweep_time_ms"`
+}
+
+// SweepReverseDNS performs rate limited PTR lookups for every address in a CIDR.
+// If emit is set it is called with each entry as it completes, in completion
+// order; the returned result lists the entries sorted by address.
+func (s *IPAnalysisService) SweepReverseDNS(ctx context.Context, req ReverseSweepRequest, emit func(ReverseSweepEntry)) (*ReverseSweepResult, error) {
+	start := time.Now()
+
+	addrs, prefix, err := expandSweepCIDR(req.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := req.Concurrency
+	if workers <= 0 {
+		workers = defaultSweepWorkers
+	} else if workers > maxSweepWorkers {
+		workers = maxSweepWorkers
+	}
+	rate := req.Rate
+	if rate <= 0 {
+		rate = defaultSweepRate
+	} else if rate > maxSweepRate {
+		rate = maxSweepRate
+	}
+
+	result := &ReverseSweepResult{
+		CIDR:    prefix.String(),
+		Total:   len(addrs),
+		Entries: make([]ReverseSweepEntry, 0, len(addrs)),
+	}
+
+	resultChan := make(chan ReverseSweepEntry, workers)
+	semaphore := make(chan struct{}, workers)
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+
+	// Dispatch lookups at the configured rate; addresses skipped after
+	// cancellation are reported as errors so every address gets an entry
+	go func() {
+		for _, addr := range addrs {
+			select {
+			case <-ctx.Done():
+				resultChan <- ReverseSweepEntry{IP: addr.String(), Status: "error", Error: ctx.Err().Error()}
+				continue
+			case <-ticker.C:
+			}
+			semaphore <- struct{}{}
+			go func(addr netip.Addr) {
+				defer func() { <-semaphore }()
+				resultChan <- s.reverseLookup(ctx, addr)
+			}(addr)
+		}
+	}()
+
+	for range addrs {
+		entry := <-resultChan
+		switch entry.Status {
+		case "ok":
+			result.Resolved++
+		case "nxdomain":
+			result.NotFound++
+		default:
+			result.Failed++
+		}
+		if emit != nil {
+			emit(entry)
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	sort.Slice(result.Entries, func(i, j int) bool {
+		a, _ := netip.ParseAddr(result.Entries[i].IP)
+		b, _ := netip.ParseAddr(result.Entries[j].IP)
+		return a.Less(b)
+	})
+
+	result.Timestamp = time.Now()
+	result.SweepTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// reverseLookup resolves the PTR names of one address and classifies the outcome
+func (s *IPAnalysisService) reverseLookup(ctx context.Context, addr netip.Addr) ReverseSweepEntry {
+	entry := ReverseSweepEntry{IP: addr.String()}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, reverseLookupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	records, err := s.lookupPTR(lookupCtx, entry.IP)
+	entry.Latency = durationMs(time.Since(start))
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(records) > 0:
+		entry.Status = "ok"
+		for _, record := range records {
+			entry.Hostnames = append(entry.Hostnames, strings.TrimSuffix(record.Value, "."))
+		}
+		entry.Hostname = entry.Hostnames[0]
+	case err == nil || isNotFound(err):
+		entry.Status = "nxdomain"
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &dnsErr) && dnsErr.IsTimeout):
+		entry.Status = "timeout"
+		entry.Error = err.Error()
+	default:
+		entry.Status = "error"
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// expandSweepCIDR lists every address in a CIDR, enforcing the sweep size limit
+func expandSweepCIDR(cidr string) ([]netip.Addr, netip.Prefix, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		// A bare address sweeps just that address
+		addr, err := netip.ParseAddr(cidr)
+		if err != nil {
+			return nil, netip.Prefix{}, fmt.Errorf("invalid CIDR: %s", cidr)
+		}
+		cidr = fmt.Sprintf("%s/%d", addr.Unmap(), addr.Unmap().BitLen())
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, netip.Prefix{}, fmt.Errorf("invalid CIDR: %s", cidr)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > maxSweepHostBits {
+		return nil, prefix, fmt.Errorf("CIDR %s is too large: sweeps are limited to %d addresses", prefix, 1<<maxSweepHostBits)
+	}
+
+	addrs := make([]netip.Addr, 0, 1<<hostBits)
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+	return addrs, prefix, nil
+}
@@ -0,0 +1,675 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SPF results from RFC 7208 section 2.6
+const (
+	SPFPass      = "pass"
+	SPFFail      = "fail"
+	SPFSoftFail  = "softfail"
+	SPFNeutral   = "neutral"
+	SPFNone      = "none"
+	SPFPermError = "permerror"
+	SPFTempError = "temperror"
+)
+
+// spfMaxNameLookups bounds the MX and PTR names examined per mechanism (RFC 7208 section 4.6.4)
+const spfMaxNameLookups = 10
+
+// SPFCheckRequest represents a request to evaluate SPF for a sender IP
+type SPFCheckRequest struct {
+	IP     string `json:"ip"`
+	Domain string `json:"domain"`
+	Sender string `json:"sender"` // MAIL FROM address, defaults to postmaster@domain
+	HELO   string `json:"helo"`
+}
+
+// SPFCheckResult represents the outcome of check_host() for a sender IP
+type SPFCheckResult struct {
+	IP            string    `json:"ip"`
+	Domain        string    `json:"domain"`
+	Sender        string    `json:"sender"`
+	Result        string    `json:"result"`
+	Mechanism     string    `json:"mechanism,omitempty"`      // the mechanism that matched
+	MatchedDomain string    `json:"matched_domain,omitempty"` // the record containing the match
+	Explanation   string    `json:"explanation,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	LookupCount   int       `json:"lookup_count"`
+	VoidLookups   int       `json:"void_lookups"`
+	Trace         []string  `json:"trace"`
+	QueryTime     int       `json:"query_time_ms"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// spfError is an evaluation error carrying the SPF result it maps to
+type spfError struct {
+	result string
+	msg    string
+}
+
+func (e *spfError) Error() string {
+	return e.msg
+}
+
+// spfEvaluator holds the state of a single check_host() evaluation
+type spfEvaluator struct {
+	service *MailAuthService
+	ip      netip.Addr
+	sender  string
+	helo    string
+	now     time.Time
+	lookups int
+	voids   int
+	trace   []string
+}
+
+// spfMatch describes the mechanism that decided an evaluation
+type spfMatch struct {
+	mechanism string
+	domain    string
+}
+
+// CheckSPF evaluates whether an IP is authorized to send mail for a domain using check_host()
+func (s *MailAuthService) CheckSPF(ctx context.Context, req SPFCheckRequest) (*SPFCheckResult, error) {
+	ip, err := netip.ParseAddr(strings.TrimSpace(req.IP))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP address: %s", req.IP)
+	}
+	ip = ip.Unmap()
+
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	sender := strings.TrimSpace(req.Sender)
+	if domain == "" {
+		// Fall back to the domain of the sender address
+		if _, d, ok := strings.Cut(sender, "@"); ok {
+			domain = strings.ToLower(d)
+		}
+	}
+	if domain == "" {
+		return nil, fmt.Errorf("domain cannot be empty")
+	}
+	if sender == "" {
+		sender = "postmaster@" + domain
+	} else if !strings.Contains(sender, "@") {
+		sender = "postmaster@" + sender
+	}
+
+	start := time.Now()
+	result := &SPFCheckResult{
+		IP:        ip.String(),
+		Domain:    domain,
+		Sender:    sender,
+		Timestamp: start,
+	}
+
+	evaluator := &spfEvaluator{
+		service: s,
+		ip:      ip,
+		sender:  sender,
+		helo:    req.HELO,
+		now:     start,
+	}
+
+	outcome, match, explanation, err := evaluator.checkHost(ctx, domain, 0)
+	result.Result = outcome
+	if match != nil {
+		result.Mechanism = match.mechanism
+		result.MatchedDomain = match.domain
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	result.Explanation = explanation
+	result.LookupCount = evaluator.lookups
+	result.VoidLookups = evaluator.voids
+	result.Trace = evaluator.trace
+	result.QueryTime = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
+// checkHost implements check_host() from RFC 7208 section 4
+func (e *spfEvaluator) checkHost(ctx context.Context, domain string, depth int) (string, *spfMatch, string, error) {
+	if depth > spfMaxDepth {
+		return SPFPermError, nil, "", &spfError{SPFPermError, "include depth limit reached"}
+	}
+	if !validSPFDomain(domain) {
+		e.tracef("%s is not a valid domain", domain)
+		return SPFNone, nil, "", nil
+	}
+
+	text, err := e.service.spfRecord(ctx, domain)
+	if err != nil {
+		e.tracef("%s: %v", domain, err)
+		var evalErr *spfError
+		if errors.As(err, &evalErr) {
+			return evalErr.result, nil, "", err
+		}
+		return SPFTempError, nil, "", err
+	}
+	if text == "" {
+		e.tracef("%s has no SPF record", domain)
+		return SPFNone, nil, "", nil
+	}
+	e.tracef("%s: %s", domain, text)
+
+	record, err := parseSPF(text)
+	if err != nil {
+		return SPFPermError, nil, "", err
+	}
+
+	for _, mechanism := range record.Mechanisms {
+		matched, err := e.evaluateMechanism(ctx, domain, mechanism, depth)
+		if err != nil {
+			var evalErr *spfError
+			if errors.As(err, &evalErr) {
+				return evalErr.result, &spfMatch{mechanism: formatSPFMechanism(mechanism), domain: domain}, "", err
+			}
+			return SPFTempError, nil, "", err
+		}
+		if !matched {
+			continue
+		}
+
+		outcome := qualifierResult(mechanism.Qualifier)
+		e.tracef("%s matched %s -> %s", domain, formatSPFMechanism(mechanism), outcome)
+		explanation := ""
+		if outcome == SPFFail && record.Explanation != "" {
+			explanation = e.explanation(ctx, domain, record.Explanation)
+		}
+		return outcome, &spfMatch{mechanism: formatSPFMechanism(mechanism), domain: domain}, explanation, nil
+	}
+
+	if record.Redirect != "" {
+		if err := e.countLookup(); err != nil {
+			return SPFPermError, nil, "", err
+		}
+		target, err := e.expandDomain(ctx, record.Redirect, domain)
+		if err != nil {
+			return SPFPermError, nil, "", err
+		}
+		e.tracef("%s redirects to %s", domain, target)
+
+		outcome, match, explanation, err := e.checkHost(ctx, target, depth+1)
+		if outcome == SPFNone {
+			return SPFPermError, match, "", &spfError{SPFPermError, "redirect target " + target + " has no SPF record"}
+		}
+		return outcome, match, explanation, err
+	}
+
+	e.tracef("%s: no mechanism matched", domain)
+	return SPFNeutral, nil, "", nil
+}
+
+// evaluateMechanism reports whether a single mechanism matches the client IP
+func (e *spfEvaluator) evaluateMechanism(ctx context.Context, domain string, mechanism SPFMechanism, depth int) (bool, error) {
+	switch mechanism.Name {
+	case "all":
+		return true, nil
+
+	case "ip4", "ip6":
+		value := mechanism.Value
+		if !strings.Contains(value, "/") {
+			value += map[string]string{"ip4": "/32", "ip6": "/128"}[mechanism.Name]
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return false, &spfError{SPFPermError, "invalid network " + mechanism.Value}
+		}
+		return prefix.Contains(e.ip), nil
+
+	case "include":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := e.expandDomain(ctx, mechanism.Value, domain)
+		if err != nil {
+			return false, err
+		}
+
+		outcome, _, _, err := e.checkHost(ctx, target, depth+1)
+		switch outcome {
+		case SPFPass:
+			return true, nil
+		case SPFFail, SPFSoftFail, SPFNeutral:
+			return false, nil
+		case SPFTempError:
+			return false, &spfError{SPFTempError, fmt.Sprintf("include %s: %v", target, err)}
+		case SPFNone:
+			return false, &spfError{SPFPermError, "include target " + target + " has no SPF record"}
+		default:
+			return false, &spfError{SPFPermError, fmt.Sprintf("include %s: %v", target, err)}
+		}
+
+	case "a":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := e.targetDomain(ctx, mechanism, domain)
+		if err != nil {
+			return false, err
+		}
+		return e.matchHost(ctx, target, mechanism)
+
+	case "mx":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := e.targetDomain(ctx, mechanism, domain)
+		if err != nil {
+			return false, err
+		}
+
+		mxRecords, err := e.service.dns.resolver.LookupMX(ctx, target)
+		if err != nil {
+			if isNotFound(err) {
+				return false, e.countVoid()
+			}
+			return false, &spfError{SPFTempError, fmt.Sprintf("MX lookup for %s failed: %v", target, err)}
+		}
+		if len(mxRecords) > spfMaxNameLookups {
+			return false, &spfError{SPFPermError, fmt.Sprintf("%s has more than %d MX records", target, spfMaxNameLookups)}
+		}
+		for _, mx := range mxRecords {
+			matched, err := e.matchHost(ctx, strings.TrimSuffix(mx.Host, "."), mechanism)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+		return false, nil
+
+	case "ptr":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := e.targetDomain(ctx, mechanism, domain)
+		if err != nil {
+			return false, err
+		}
+
+		for _, name := range e.validatedNames(ctx) {
+			if name == target || strings.HasSuffix(name, "."+target) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "exists":
+		if err := e.countLookup(); err != nil {
+			return false, err
+		}
+		target, err := e.expandDomain(ctx, mechanism.Value, domain)
+		if err != nil {
+			return false, err
+		}
+
+		// exists always queries A records, even for IPv6 clients
+		ips, err := e.service.dns.resolver.LookupIP(ctx, "ip4", target)
+		if err != nil {
+			if isNotFound(err) {
+				return false, e.countVoid()
+			}
+			return false, &spfError{SPFTempError, fmt.Sprintf("A lookup for %s failed: %v", target, err)}
+		}
+		return len(ips) > 0, nil
+	}
+
+	return false, &spfError{SPFPermError, "unknown mechanism " + mechanism.Name}
+}
+
+// matchHost reports whether the client IP is within the prefix of any address of a host
+func (e *spfEvaluator) matchHost(ctx context.Context, host string, mechanism SPFMechanism) (bool, error) {
+	network, bits := "ip4", mechanism.IP4Prefix
+	if e.ip.Is6() {
+		network, bits = "ip6", mechanism.IP6Prefix
+	}
+
+	ips, err := e.service.dns.resolver.LookupIP(ctx, network, host)
+	if err != nil {
+		if isNotFound(err) {
+			return false, e.countVoid()
+		}
+		return false, &spfError{SPFTempError, fmt.Sprintf("address lookup for %s failed: %v", host, err)}
+	}
+
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+		prefix, err := addr.Unmap().Prefix(bits)
+		if err == nil && prefix.Contains(e.ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// validatedNames returns the PTR names of the client IP whose forward lookup includes the IP
+func (e *spfEvaluator) validatedNames(ctx context.Context) []string {
+	names, err := e.service.dns.resolver.LookupAddr(ctx, e.ip.String())
+	if err != nil {
+		if isNotFound(err) {
+			e.countVoid()
+		}
+		return nil
+	}
+	if len(names) > spfMaxNameLookups {
+		names = names[:spfMaxNameLookups]
+	}
+
+	network := "ip4"
+	if e.ip.Is6() {
+		network = "ip6"
+	}
+
+	var validated []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		ips, err := e.service.dns.resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if addr, ok := netip.AddrFromSlice(ip); ok && addr.Unmap() == e.ip {
+				validated = append(validated, name)
+				break
+			}
+		}
+	}
+	return validated
+}
+
+// explanation fetches and expands the exp= explanation string for a fail result
+func (e *spfEvaluator) explanation(ctx context.Context, domain, spec string) string {
+	target, err := e.expandDomain(ctx, spec, domain)
+	if err != nil {
+		return ""
+	}
+	texts, err := e.service.txtStrings(ctx, target)
+	if err != nil || len(texts) != 1 {
+		return ""
+	}
+	expanded, err := e.expandMacros(ctx, texts[0], domain, true)
+	if err != nil {
+		return ""
+	}
+	return expanded
+}
+
+// targetDomain returns the expanded domain-spec of a mechanism, or the current domain
+func (e *spfEvaluator) targetDomain(ctx context.Context, mechanism SPFMechanism, domain string) (string, error) {
+	if mechanism.Value == "" {
+		return domain, nil
+	}
+	return e.expandDomain(ctx, mechanism.Value, domain)
+}
+
+// expandDomain expands the macros of a domain-spec and truncates it to 253 characters
+func (e *spfEvaluator) expandDomain(ctx context.Context, spec, domain string) (string, error) {
+	expanded, err := e.expandMacros(ctx, spec, domain, false)
+	if err != nil {
+		return "", err
+	}
+	expanded = strings.ToLower(strings.TrimSuffix(expanded, "."))
+
+	// Drop leading labels until the name fits (RFC 7208 section 7.3)
+	for len(expanded) > 253 {
+		_, rest, ok := strings.Cut(expanded, ".")
+		if !ok {
+			break
+		}
+		expanded = rest
+	}
+	return expanded, nil
+}
+
+// expandMacros expands SPF macros as described in RFC 7208 section 7
+func (e *spfEvaluator) expandMacros(ctx context.Context, spec, domain string, explanation bool) (string, error) {
+	var out strings.Builder
+
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			out.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", &spfError{SPFPermError, "macro string ends with %"}
+		}
+
+		i++
+		switch spec[i] {
+		case '%':
+			out.WriteByte('%')
+		case '_':
+			out.WriteByte(' ')
+		case '-':
+			out.WriteString("%20")
+		case '{':
+			end := strings.IndexByte(spec[i:], '}')
+			if end < 0 {
+				return "", &spfError{SPFPermError, "unterminated macro in " + spec}
+			}
+			value, err := e.expandMacro(ctx, spec[i+1:i+end], domain, explanation)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(value)
+			i += end
+		default:
+			return "", &spfError{SPFPermError, fmt.Sprintf("invalid macro %%%c in %s", spec[i], spec)}
+		}
+	}
+
+	return out.String(), nil
+}
+
+// expandMacro expands a single "{letter transformers delimiters}" macro body
+func (e *spfEvaluator) expandMacro(ctx context.Context, body, domain string, explanation bool) (string, error) {
+	if body == "" {
+		return "", &spfError{SPFPermError, "empty macro"}
+	}
+
+	letter := body[0]
+	escape := letter >= 'A' && letter <= 'Z'
+	local, senderDomain, _ := strings.Cut(e.sender, "@")
+
+	var value string
+	switch letter | 0x20 {
+	case 's':
+		value = e.sender
+	case 'l':
+		value = local
+	case 'o':
+		value = senderDomain
+	case 'd':
+		value = domain
+	case 'i':
+		value = spfMacroIP(e.ip)
+	case 'p':
+		value = "unknown"
+		for _, name := range e.validatedNames(ctx) {
+			value = name
+			if name == domain || strings.HasSuffix(name, "."+domain) {
+				break
+			}
+		}
+	case 'v':
+		value = "in-addr"
+		if e.ip.Is6() {
+			value = "ip6"
+		}
+	case 'h':
+		value = e.helo
+	case 'c', 'r', 't':
+		if !explanation {
+			return "", &spfError{SPFPermError, fmt.Sprintf("macro %%{%c} is only allowed in explanations", letter)}
+		}
+		switch letter | 0x20 {
+		case 'c':
+			value = e.ip.String()
+		case 'r':
+			value = "unknown"
+		case 't':
+			value = strconv.FormatInt(e.now.Unix(), 10)
+		}
+	default:
+		return "", &spfError{SPFPermError, fmt.Sprintf("unknown macro letter %c", letter)}
+	}
+
+	// Parse the optional digit count, reverse flag and delimiter set
+	rest := body[1:]
+	digits := 0
+	for len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+		digits = digits*10 + int(rest[0]-'0')
+		rest = rest[1:]
+	}
+	reverse := false
+	if len(rest) > 0 && (rest[0] == 'r' || rest[0] == 'R') {
+		reverse = true
+		rest = rest[1:]
+	}
+	delimiters := "."
+	if rest != "" {
+		if strings.Trim(rest, ".-+,/_=") != "" {
+			return "", &spfError{SPFPermError, "invalid macro delimiters " + rest}
+		}
+		delimiters = rest
+	}
+
+	if digits > 0 || reverse || delimiters != "." {
+		parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delimiters, r) })
+		if reverse {
+			for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+				parts[i], parts[j] = parts[j], parts[i]
+			}
+		}
+		if digits > 0 && digits < len(parts) {
+			parts = parts[len(parts)-digits:]
+		}
+		value = strings.Join(parts, ".")
+	}
+
+	if escape {
+		value = spfURLEscape(value)
+	}
+	return value, nil
+}
+
+// spfURLEscape percent-encodes every byte outside the RFC 3986 unreserved set,
+// as uppercase macros require (RFC 7208 section 7.3)
+func spfURLEscape(value string) string {
+	const hexDigits = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0x0f])
+		}
+	}
+	return b.String()
+}
+
+// countLookup counts a DNS-querying term against the lookup limit
+func (e *spfEvaluator) countLookup() error {
+	e.lookups++
+	if e.lookups > spfMaxLookups {
+		return &spfError{SPFPermError, fmt.Sprintf("more than %d DNS lookups", spfMaxLookups)}
+	}
+	return nil
+}
+
+// countVoid counts a lookup that returned no records against the void lookup limit
+func (e *spfEvaluator) countVoid() error {
+	e.voids++
+	if e.voids > spfMaxVoidLookups {
+		return &spfError{SPFPermError, fmt.Sprintf("more than %d void DNS lookups", spfMaxVoidLookups)}
+	}
+	return nil
+}
+
+// tracef records an evaluation step
+func (e *spfEvaluator) tracef(format string, args ...interface{}) {
+	e.trace = append(e.trace, fmt.Sprintf(format, args...))
+}
+
+// qualifierResult maps a mechanism qualifier to its SPF result
+func qualifierResult(qualifier string) string {
+	switch qualifier {
+	case "-":
+		return SPFFail
+	case "~":
+		return SPFSoftFail
+	case "?":
+		return SPFNeutral
+	}
+	return SPFPass
+}
+
+// formatSPFMechanism renders a mechanism back to its record form
+func formatSPFMechanism(mechanism SPFMechanism) string {
+	var b strings.Builder
+	if mechanism.Qualifier != "+" {
+		b.WriteString(mechanism.Qualifier)
+	}
+	b.WriteString(mechanism.Name)
+	if mechanism.Value != "" {
+		b.WriteString(":" + mechanism.Value)
+	}
+	if mechanism.Name == "a" || mechanism.Name == "mx" {
+		if mechanism.IP4Prefix != 32 {
+			b.WriteString("/" + strconv.Itoa(mechanism.IP4Prefix))
+		}
+		if mechanism.IP6Prefix != 128 {
+			b.WriteString("//" + strconv.Itoa(mechanism.IP6Prefix))
+		}
+	}
+	return b.String()
+}
+
+// spfMacroIP renders an IP for the %{i} macro; IPv6 uses dotted nibbles
+func spfMacroIP(ip netip.Addr) string {
+	if ip.Is4() {
+		return ip.String()
+	}
+	const hexDigits = "0123456789abcdef"
+	var nibbles []string
+	for _, b := range ip.As16() {
+		nibbles = append(nibbles, string(hexDigits[b>>4]), string(hexDigits[b&0x0f]))
+	}
+	return strings.Join(nibbles, ".")
+}
+
+// validSPFDomain reports whether a name is usable as a check_host() domain
+func validSPFDomain(domain string) bool {
+	if domain == "" || len(domain) > 253 || !strings.Contains(domain, ".") {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+	}
+	return true
+}
+
+// isNotFound reports whether a lookup error means the name or record does not exist
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
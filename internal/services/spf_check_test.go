@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers lookups from fixed tables; missing names are NXDOMAIN
+type fakeResolver struct {
+	ips   map[string][]string
+	mx    map[string][]*net.MX
+	ns    map[string][]*net.NS
+	txt   map[string][]string
+	cname map[string]string
+	ptr   map[string][]string
+	fail  map[string]bool // names whose lookups fail with a server error
+}
+
+func (r *fakeResolver) lookupErr(name string) error {
+	if r.fail[name] {
+		return &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, value := range r.ips[host] {
+		ip := net.ParseIP(value)
+		if (network == "ip4" && ip.To4() == nil) || (network == "ip6" && ip.To4() != nil) {
+			continue
+		}
+		ips = append(ips, ip)
+	}
+	if len(ips) == 0 {
+		return nil, r.lookupErr(host)
+	}
+	return ips, nil
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, r.lookupErr(name)
+}
+
+func (r *fakeResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	if records, ok := r.ns[name]; ok {
+		return records, nil
+	}
+	return nil, r.lookupErr(name)
+}
+
+func (r *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if records, ok := r.txt[name]; ok {
+		return records, nil
+	}
+	return nil, r.lookupErr(name)
+}
+
+func (r *fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if target, ok := r.cname[host]; ok {
+		return target, nil
+	}
+	return "", r.lookupErr(host)
+}
+
+func (r *fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if names, ok := r.ptr[addr]; ok {
+		return names, nil
+	}
+	return nil, r.lookupErr(addr)
+}
+
+func TestCheckSPF(t *testing.T) {
+	resolver := &fakeResolver{
+		txt: map[string][]string{
+			"ip4.example":         {"v=spf1 ip4:192.0.2.0/24 -all"},
+			"ip6.example":         {"v=spf1 ip6:2001:db8::/32 -all"},
+			"a.example":           {"v=spf1 a/24 -all"},
+			"mx.example":          {"v=spf1 mx -all"},
+			"ptr.example":         {"v=spf1 ptr -all"},
+			"exists.example":      {"v=spf1 exists:%{ir}.allow.exists.example -all"},
+			"include.example":     {"v=spf1 include:ip4.example ~all"},
+			"badinclude.example":  {"v=spf1 include:none.example -all"},
+			"redirect.example":    {"v=spf1 redirect=ip4.example"},
+			"badredirect.example": {"v=spf1 redirect=none.example"},
+			"softfail.example":    {"v=spf1 ~all"},
+			"neutral.example":     {"v=spf1 ?all"},
+			"nomatch.example":     {"v=spf1 ip4:198.51.100.1"},
+			"exp.example":         {"v=spf1 -all exp=explain.exp.example"},
+			"explain.exp.example": {"%{i} is not one of %{d}'s designated mail servers"},
+			"twice.example":       {"v=spf1 -all", "v=spf1 +all"},
+			"unrelated.example":   {"google-site-verification=abc"},
+			"lookups.example": {"v=spf1 a:h1.example a:h2.example a:h3.example a:h4.example a:h5.example " +
+				"a:h6.example a:h7.example a:h8.example a:h9.example a:h10.example a:h11.example +all"},
+			"tenlookups.example": {"v=spf1 a:h1.example a:h2.example a:h3.example a:h4.example a:h5.example " +
+				"a:h6.example a:h7.example a:h8.example a:h9.example a:h10.example +all"},
+			"voids.example":    {"v=spf1 a:v1.example a:v2.example a:v3.example +all"},
+			"twovoids.example": {"v=spf1 a:v1.example a:v2.example +all"},
+			"temp.example":     {"v=spf1 a:broken.example -all"},
+		},
+		ips: map[string][]string{
+			"a.example":                       {"192.0.2.10"},
+			"mail.mx.example":                 {"192.0.2.20"},
+			"mail.ptr.example":                {"192.0.2.30"},
+			"30.2.0.192.allow.exists.example": {"127.0.0.2"},
+		},
+		mx: map[string][]*net.MX{
+			"mx.example": {{Host: "mail.mx.example.", Pref: 10}},
+		},
+		ptr: map[string][]string{
+			"192.0.2.30": {"mail.ptr.example."},
+		},
+		fail: map[string]bool{"broken.example": true},
+	}
+	for i := 1; i <= 11; i++ {
+		resolver.ips["h"+strconv.Itoa(i)+".example"] = []string{"198.51.100.200"}
+	}
+	service := NewMailAuthService(NewIPAnalysisServiceWithResolver(resolver))
+
+	tests := []struct {
+		name        string
+		ip          string
+		domain      string
+		want        string
+		mechanism   string
+		explanation string
+		lookups     int
+		voids       int
+	}{
+		{name: "ip4 match", ip: "192.0.2.1", domain: "ip4.example", want: SPFPass, mechanism: "ip4:192.0.2.0/24"},
+		{name: "ip4 miss", ip: "198.51.100.1", domain: "ip4.example", want: SPFFail, mechanism: "-all"},
+		{name: "ip6 match", ip: "2001:db8::1", domain: "ip6.example", want: SPFPass},
+		{name: "ip6 client against ip4", ip: "2001:db8::1", domain: "ip4.example", want: SPFFail},
+		{name: "a with cidr", ip: "192.0.2.99", domain: "a.example", want: SPFPass, mechanism: "a/24", lookups: 1},
+		{name: "mx", ip: "192.0.2.20", domain: "mx.example", want: SPFPass, mechanism: "mx", lookups: 1},
+		{name: "mx miss", ip: "192.0.2.21", domain: "mx.example", want: SPFFail, lookups: 1},
+		{name: "ptr", ip: "192.0.2.30", domain: "ptr.example", want: SPFPass, mechanism: "ptr", lookups: 1},
+		{name: "ptr unvalidated", ip: "192.0.2.31", domain: "ptr.example", want: SPFFail, lookups: 1, voids: 1},
+		{name: "exists with macro", ip: "192.0.2.30", domain: "exists.example", want: SPFPass, lookups: 1},
+		{name: "exists miss", ip: "192.0.2.31", domain: "exists.example", want: SPFFail, lookups: 1, voids: 1},
+		{name: "include pass", ip: "192.0.2.1", domain: "include.example", want: SPFPass, mechanism: "include:ip4.example", lookups: 1},
+		{name: "include fail falls through", ip: "198.51.100.1", domain: "include.example", want: SPFSoftFail, lookups: 1},
+		{name: "include without record", ip: "192.0.2.1", domain: "badinclude.example", want: SPFPermError, lookups: 1},
+		{name: "redirect", ip: "192.0.2.1", domain: "redirect.example", want: SPFPass, lookups: 1},
+		{name: "redirect without record", ip: "192.0.2.1", domain: "badredirect.example", want: SPFPermError, lookups: 1},
+		{name: "softfail", ip: "192.0.2.1", domain: "softfail.example", want: SPFSoftFail},
+		{name: "neutral", ip: "192.0.2.1", domain: "neutral.example", want: SPFNeutral},
+		{name: "no match", ip: "192.0.2.1", domain: "nomatch.example", want: SPFNeutral},
+		{name: "exp", ip: "192.0.2.1", domain: "exp.example", want: SPFFail,
+			explanation: "192.0.2.1 is not one of exp.example's designated mail servers"},
+		{name: "no record", ip: "192.0.2.1", domain: "unrelated.example", want: SPFNone},
+		{name: "no domain", ip: "192.0.2.1", domain: "missing.example", want: SPFNone},
+		{name: "two records", ip: "192.0.2.1", domain: "twice.example", want: SPFPermError},
+		{name: "ten lookups", ip: "192.0.2.1", domain: "tenlookups.example", want: SPFPass, mechanism: "all", lookups: 10},
+		{name: "eleven lookups", ip: "192.0.2.1", domain: "lookups.example", want: SPFPermError, lookups: 11},
+		{name: "two void lookups", ip: "192.0.2.1", domain: "twovoids.example", want: SPFPass, lookups: 2, voids: 2},
+		{name: "three void lookups", ip: "192.0.2.1", domain: "voids.example", want: SPFPermError, lookups: 3, voids: 3},
+		{name: "temporary failure", ip: "192.0.2.1", domain: "temp.example", want: SPFTempError, lookups: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.CheckSPF(context.Background(), SPFCheckRequest{IP: tt.ip, Domain: tt.domain})
+			if err != nil {
+				t.Fatalf("CheckSPF: %v", err)
+			}
+			if result.Result != tt.want {
+				t.Fatalf("result = %s (%s), want %s; trace %v", result.Result, result.Error, tt.want, result.Trace)
+			}
+			if tt.mechanism != "" && result.Mechanism != tt.mechanism {
+				t.Errorf("mechanism = %q, want %q", result.Mechanism, tt.mechanism)
+			}
+			if result.Explanation != tt.explanation {
+				t.Errorf("explanation = %q, want %q", result.Explanation, tt.explanation)
+			}
+			if result.LookupCount != tt.lookups {
+				t.Errorf("lookups = %d, want %d", result.LookupCount, tt.lookups)
+			}
+			if result.VoidLookups != tt.voids {
+				t.Errorf("void lookups = %d, want %d", result.VoidLookups, tt.voids)
+			}
+		})
+	}
+}
+
+func TestSPFMacroExpansion(t *testing.T) {
+	// Examples from RFC 7208 section 7.4
+	evaluator := &spfEvaluator{
+		service: NewMailAuthService(NewIPAnalysisServiceWithResolver(&fakeResolver{})),
+		ip:      netip.MustParseAddr("192.0.2.3"),
+		sender:  "strong-bad@email.example.com",
+		helo:    "mx.example.org",
+		now:     time.Unix(1700000000, 0),
+	}
+	const domain = "email.example.com"
+
+	tests := []struct {
+		spec        string
+		want        string
+		explanation bool
+	}{
+		{spec: "%{s}", want: "strong-bad@email.example.com"},
+		{spec: "%{o}", want: "email.example.com"},
+		{spec: "%{d}", want: "email.example.com"},
+		{spec: "%{d4}", want: "email.example.com"},
+		{spec: "%{d3}", want: "email.example.com"},
+		{spec: "%{d2}", want: "example.com"},
+		{spec: "%{d1}", want: "com"},
+		{spec: "%{dr}", want: "com.example.email"},
+		{spec: "%{d2r}", want: "example.email"},
+		{spec: "%{l}", want: "strong-bad"},
+		{spec: "%{l-}", want: "strong.bad"},
+		{spec: "%{lr}", want: "strong-bad"},
+		{spec: "%{lr-}", want: "bad.strong"},
+		{spec: "%{l1r-}", want: "strong"},
+		{spec: "%{ir}.%{v}._spf.%{d2}", want: "3.2.0.192.in-addr._spf.example.com"},
+		{spec: "%{lr-}.lp._spf.%{d2}", want: "bad.strong.lp._spf.example.com"},
+		{spec: "%{lr-}.lp.%{ir}.%{v}._spf.%{d2}", want: "bad.strong.lp.3.2.0.192.in-addr._spf.example.com"},
+		{spec: "%{ir}.%{v}.%{l1r-}.lp._spf.%{d2}", want: "3.2.0.192.in-addr.strong.lp._spf.example.com"},
+		{spec: "%{d2}.trusted-domains.example.net", want: "example.com.trusted-domains.example.net"},
+		{spec: "%{h}", want: "mx.example.org"},
+		{spec: "%%%_%-", want: "% %20"},
+		{spec: "%{S}", want: "strong-bad%40email.example.com"},
+		{spec: "%{c} at %{t}", want: "192.0.2.3 at 1700000000", explanation: true},
+	}
+
+	for _, tt := range tests {
+		got, err := evaluator.expandMacros(context.Background(), tt.spec, domain, tt.explanation)
+		if err != nil {
+			t.Errorf("%s: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+
+	// IPv6 addresses expand to dotted nibbles
+	evaluator.ip = netip.MustParseAddr("2001:db8::cb01")
+	got, err := evaluator.expandMacros(context.Background(), "%{ir}.%{v}._spf.%{d2}", domain, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1.0.b.c.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6._spf.example.com"
+	if got != want {
+		t.Errorf("IPv6 %%{ir} = %q, want %q", got, want)
+	}
+
+	for _, spec := range []string{"%{c}", "%{x}", "%", "%{d", "%a"} {
+		_, err := evaluator.expandMacros(context.Background(), spec, domain, false)
+		var evalErr *spfError
+		if !errors.As(err, &evalErr) || evalErr.result != SPFPermError {
+			t.Errorf("%s: error = %v, want permerror", spec, err)
+		}
+	}
+}
+
+func TestSPFURLEscape(t *testing.T) {
+	tests := map[string]string{
+		"simple-name_1.2~x": "simple-name_1.2~x",
+		"a b":               "a%20b",
+		"user+tag@host":     "user%2Btag%40host",
+		"a/b?c=d":           "a%2Fb%3Fc%3Dd",
+		"é":                 "%C3%A9",
+	}
+	for in, want := range tests {
+		if got := spfURLEscape(in); got != want {
+			t.Errorf("spfURLEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultCTSearchURL = "https://crt.sh/"
+	maxSubdomainWords  = 10000
+	maxSubdomainHosts  = 5000
+	maxCTResponseSize  = 32 << 20
+	wildcardProbeCount = 3
+)
+
+// defaultSubdomainWords is the built-in brute force wordlist
+var defaultSubdomainWords = []string{
+	"www", "mail", "smtp", "imap", "pop", "pop3", "webmail", "mx", "mx1", "mx2",
+	"ns", "ns1", "ns2", "ns3", "dns", "dns1", "dns2", "ftp", "sftp", "ssh",
+	"vpn", "remote", "gateway", "gw", "proxy", "cdn", "static", "assets", "media", "img",
+	"images", "files", "download", "downloads", "upload", "api", "api2", "graphql", "rest", "ws",
+	"app", "apps", "portal", "admin", "administrator", "dashboard", "panel", "cpanel", "manage", "console",
+	"dev", "development", "test", "testing", "qa", "uat", "stage", "staging", "preprod", "prod",
+	"beta", "alpha", "demo", "sandbox", "old", "new", "legacy", "backup", "bak", "archive",
+	"git", "gitlab", "github", "svn", "ci", "jenkins", "build", "deploy", "registry", "docker",
+	"k8s", "kube", "grafana", "prometheus", "kibana", "elastic", "logs", "monitor", "status", "metrics",
+	"auth", "login", "sso", "id", "identity", "oauth", "accounts", "account", "secure", "signin",
+	"shop", "store", "pay", "payments", "billing", "checkout", "cart", "crm", "erp", "hr",
+	"blog", "news", "docs", "wiki", "help", "support", "kb", "forum", "community", "careers",
+	"intranet", "internal", "corp", "office", "exchange", "autodiscover", "owa", "lync", "sip", "meet",
+	"db", "mysql", "postgres", "redis", "mongo", "sql", "search", "cache", "queue", "mq",
+	"m", "mobile", "web", "www2", "home", "cloud", "edge", "origin", "lb", "host",
+}
+
+// SubdomainRequest configures a subdomain discovery run
+type SubdomainRequest struct {
+	Domain   string   `json:"domain"`
+	Wordlist bool     `json:"wordlist"`            // brute force names from Words or the built-in list
+	Words    []string `json:"words,omitempty"`     // custom wordlist
+	AXFR     bool     `json:"axfr"`                // attempt zone transfers from each authoritative server
+	CT       bool     `json:"ct"`                  // query certificate transparency logs
+	CTExport string   `json:"ct_export,omitempty"` // crt.sh JSON, PEM certificates or one name per line
+	Resolve  bool     `json:"resolve"`             // resolve addresses for names found through AXFR and CT
+}
+
+// DiscoveredHost is a unique hostname found under the domain
+type DiscoveredHost struct {
+	Hostname string   `json:"hostname"`
+	IPs      []string `json:"ips"`
+	Sources  []string `json:"sources"`            // "wordlist", "axfr", "ct" or "ct-export"
+	Wildcard bool     `json:"wildcard,omitempty"` // seen only as a wildcard certificate name
+}
+
+// WildcardInfo reports whether the domain answers for random names
+type WildcardInfo struct {
+	Detected bool     `json:"detected"`
+	IPs      []string `json:"ips,omitempty"`
+}
+
+// AXFRAttempt records a zone transfer attempt against one name server
+type AXFRAttempt struct {
+	Server  string `json:"server"`
+	Address string `json:"address"`
+	Allowed bool   `json:"allowed"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SubdomainResult is the combined output of all discovery sources
+type SubdomainResult struct {
+	Domain     string           `json:"domain"`
+	Hosts      []DiscoveredHost `json:"hosts"`
+	Wildcard   *WildcardInfo    `json:"wildcard,omitempty"`
+	AXFR       []AXFRAttempt    `json:"axfr,omitempty"`
+	WordsTried int              `json:"words_tried"`
+	CTNames    int              `json:"ct_names"`
+	Errors     []string         `json:"errors"`
+	Timestamp  time.Time        `json:"timestamp"`
+	QueryTime  float64          `json:"query_time_ms"`
+}
+
+// SubdomainService discovers hostnames under a domain from DNS and certificate transparency data
+type SubdomainService struct {
+	dns         *IPAnalysisService
+	transfers   *ZoneTransferService
+	httpClient  *http.Client
+	ctSearchURL string
+}
+
+// NewSubdomainService creates a subdomain discovery service on top of the DNS lookup layer
+func NewSubdomainService(dns *IPAnalysisService) *SubdomainService {
+	return &SubdomainService{
+		dns:       dns,
+		transfers: NewZoneTransferService(dns),
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		ctSearchURL: defaultCTSearchURL,
+	}
+}
+
+// subdomainCollector accumulates hosts from concurrent sources
+type subdomainCollector struct {
+	mu     sync.Mutex
+	domain string
+	hosts  map[string]*DiscoveredHost
+}
+
+func (c *subdomainCollector) add(name, source string, ips []string) bool {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	wildcard := strings.HasPrefix(name, "*.")
+	name = strings.TrimPrefix(name, "*.")
+	if name == "" || !dns.IsSubDomain(c.domain, dns.Fqdn(name)) {
+		return false
+	}
+	if _, ok := dns.IsDomainName(name); !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	host, ok := c.hosts[name]
+	if !ok {
+		if len(c.hosts) >= maxSubdomainHosts {
+			return false
+		}
+		host = &DiscoveredHost{Hostname: name, IPs: []string{}, Sources: []string{}, Wildcard: wildcard}
+		c.hosts[name] = host
+	} else if !wildcard {
+		host.Wildcard = false
+	}
+	host.Sources = appendUnique(host.Sources, source)
+	for _, ip := range ips {
+		host.IPs = appendUnique(host.IPs, ip)
+	}
+	return true
+}
+
+// Discover runs the requested sources and returns the de-duplicated hostnames
+func (s *SubdomainService) Discover(ctx context.Context, req SubdomainRequest) (*SubdomainResult, error) {
+	start := time.Now()
+
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	if _, ok := dns.IsDomainName(domain); !ok || !strings.Contains(domain, ".") {
+		return nil, fmt.Errorf("invalid domain: %s", req.Domain)
+	}
+	if !req.Wordlist && !req.AXFR && !req.CT && req.CTExport == "" {
+		return nil, fmt.Errorf("no discovery sources selected")
+	}
+	if len(req.Words) > maxSubdomainWords {
+		return nil, fmt.Errorf("wordlist too large: %d words (maximum %d)", len(req.Words), maxSubdomainWords)
+	}
+
+	result := &SubdomainResult{
+		Domain: domain,
+		Errors: []string{},
+	}
+	collector := &subdomainCollector{domain: dns.Fqdn(domain), hosts: make(map[string]*DiscoveredHost)}
+	var mu sync.Mutex
+	addError := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	var wg sync.WaitGroup
+	if req.Wordlist {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			words := req.Words
+			if len(words) == 0 {
+				words = defaultSubdomainWords
+			}
+			result.Wildcard = s.detectWildcard(ctx, domain)
+			result.WordsTried = s.bruteForce(ctx, domain, words, result.Wildcard, collector)
+		}()
+	}
+	if req.AXFR {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempts, err := s.attemptAXFR(ctx, domain, collector)
+			if err != nil {
+				addError("AXFR: %v", err)
+			}
+			result.AXFR = attempts
+		}()
+	}
+	if req.CT {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names, err := s.queryCT(ctx, domain)
+			if err != nil {
+				addError("certificate transparency: %v", err)
+				return
+			}
+			added := 0
+			for _, name := range names {
+				if collector.add(name, "ct", nil) {
+					added++
+				}
+			}
+			mu.Lock()
+			result.CTNames += added
+			mu.Unlock()
+		}()
+	}
+	if req.CTExport != "" {
+		names, err := parseCTExport(req.CTExport)
+		if err != nil {
+			addError("CT export: %v", err)
+		}
+		added := 0
+		for _, name := range names {
+			if collector.add(name, "ct-export", nil) {
+				added++
+			}
+		}
+		mu.Lock()
+		result.CTNames += added
+		mu.Unlock()
+	}
+	wg.Wait()
+
+	if req.Resolve {
+		s.resolveHosts(ctx, collector)
+	}
+
+	result.Hosts = make([]DiscoveredHost, 0, len(collector.hosts))
+	for _, host := range collector.hosts {
+		sort.Strings(host.IPs)
+		sort.Strings(host.Sources)
+		result.Hosts = append(result.Hosts, *host)
+	}
+	sort.Slice(result.Hosts, func(i, j int) bool {
+		return result.Hosts[i].Hostname < result.Hosts[j].Hostname
+	})
+
+	result.Timestamp = time.Now()
+	result.QueryTime = durationMs(time.Since(start))
+	return result, nil
+}
+
+// detectWildcard resolves random labels to find wildcard records
+func (s *SubdomainService) detectWildcard(ctx context.Context, domain string) *WildcardInfo {
+	info := &WildcardInfo{}
+	for i := 0; i < wildcardProbeCount; i++ {
+		label, err := randomLabel(8)
+		if err != nil {
+			continue
+		}
+		ips := s.lookupAddresses(ctx, label+"."+domain)
+		if len(ips) > 0 {
+			info.Detected = true
+			for _, ip := range ips {
+				info.IPs = appendUnique(info.IPs, ip)
+			}
+		}
+	}
+	sort.Strings(info.IPs)
+	return info
+}
+
+// bruteForce resolves each word under the domain, skipping hits that only match the wildcard
+func (s *SubdomainService) bruteForce(ctx context.Context, domain string, words []string, wildcard *WildcardInfo, collector *subdomainCollector) int {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10) // Limit concurrent lookups
+
+	seen := make(map[string]bool)
+	tried := 0
+	for _, word := range words {
+		word = strings.Trim(strings.ToLower(strings.TrimSpace(word)), ".")
+		if word == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		tried++
+
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ips := s.lookupAddresses(ctx, host)
+			if len(ips) == 0 {
+				return
+			}
+			if wildcard != nil && wildcard.Detected && len(setDifference(ips, wildcard.IPs)) == 0 {
+				return
+			}
+			collector.add(host, "wordlist", ips)
+		}(word + "." + domain)
+	}
+	wg.Wait()
+	return tried
+}
+
+// attemptAXFR requests a zone transfer from every authoritative name server
+func (s *SubdomainService) attemptAXFR(ctx context.Context, domain string, collector *subdomainCollector) ([]AXFRAttempt, error) {
+	nsResult, err := s.dns.LookupDNS(ctx, domain, "NS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up name servers: %w", err)
+	}
+
+	var attempts []AXFRAttempt
+	for _, record := range nsResult.Records {
+		server := strings.TrimSuffix(record.Value, ".")
+		addrs := s.lookupAddresses(ctx, server)
+		if len(addrs) == 0 {
+			attempts = append(attempts, AXFRAttempt{Server: server, Error: "name server address not found"})
+			continue
+		}
+		for _, addr := range addrs {
+			attempts = append(attempts, s.transferZone(ctx, domain, server, addr, collector))
+		}
+	}
+	return attempts, nil
+}
+
+// transferZone performs one AXFR and adds the owner names it returns
+func (s *SubdomainService) transferZone(ctx context.Context, domain, server, addr string, collector *subdomainCollector) AXFRAttempt {
+	attempt := AXFRAttempt{Server: server, Address: addr}
+
+	result, err := s.transfers.TransferZone(ctx, ZoneTransferRequest{Zone: domain, Server: addr, Type: "AXFR"}, nil)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	attempt.Allowed = result.Allowed
+	attempt.Records = len(result.Records)
+	attempt.Error = result.Error
+	if !result.Allowed {
+		return attempt
+	}
+
+	addresses := make(map[string][]string)
+	for _, record := range result.Records {
+		if record.Type == "A" || record.Type == "AAAA" {
+			addresses[record.Name] = append(addresses[record.Name], record.Value)
+		} else if _, ok := addresses[record.Name]; !ok {
+			addresses[record.Name] = nil
+		}
+	}
+	for name, ips := range addresses {
+		collector.add(name, "axfr", ips)
+	}
+	return attempt
+}
+
+// queryCT fetches certificate names for the domain and its subdomains from crt.sh
+func (s *SubdomainService) queryCT(ctx context.Context, domain string) ([]string, error) {
+	query := url.Values{}
+	query.Set("q", "%."+domain)
+	query.Set("output", "json")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.ctSearchURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("CT search returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCTResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	return parseCTExport(string(body))
+}
+
+// ctLogEntry is the subset of a crt.sh JSON entry used for name extraction
+type ctLogEntry struct {
+	CommonName string `json:"common_name"`
+	NameValue  string `json:"name_value"`
+}
+
+// parseCTExport extracts certificate names from crt.sh JSON, PEM certificates or a plain name list
+func parseCTExport(content string) ([]string, error) {
+	content = strings.TrimSpace(content)
+	var names []string
+
+	switch {
+	case strings.HasPrefix(content, "["):
+		var entries []ctLogEntry
+		if err := json.Unmarshal([]byte(content), &entries); err != nil {
+			return nil, fmt.Errorf("invalid CT JSON: %w", err)
+		}
+		for _, entry := range entries {
+			names = append(names, entry.CommonName)
+			names = append(names, strings.Split(entry.NameValue, "\n")...)
+		}
+	case strings.Contains(content, "-----BEGIN CERTIFICATE-----"):
+		rest := []byte(content)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return names, fmt.Errorf("invalid certificate: %w", err)
+			}
+			names = append(names, cert.Subject.CommonName)
+			names = append(names, cert.DNSNames...)
+		}
+	default:
+		scanner := bufio.NewScanner(strings.NewReader(content))
+		for scanner.Scan() {
+			for _, field := range strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			}) {
+				names = append(names, field)
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// resolveHosts looks up addresses for hosts that were found without any
+func (s *SubdomainService) resolveHosts(ctx context.Context, collector *subdomainCollector) {
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 10) // Limit concurrent lookups
+
+	for _, host := range collector.hosts {
+		if len(host.IPs) > 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(host *DiscoveredHost) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ips := s.lookupAddresses(ctx, host.Hostname)
+			collector.mu.Lock()
+			host.IPs = append(host.IPs, ips...)
+			collector.mu.Unlock()
+		}(host)
+	}
+	wg.Wait()
+}
+
+// lookupAddresses returns the IPv4 and IPv6 addresses of a host through LookupDNS
+func (s *SubdomainService) lookupAddresses(ctx context.Context, host string) []string {
+	var ips []string
+	for _, recordType := range []string{"A", "AAAA"} {
+		result, err := s.dns.LookupDNS(ctx, host, recordType)
+		if err != nil {
+			continue
+		}
+		for _, record := range result.Records {
+			ips = appendUnique(ips, record.Value)
+		}
+	}
+	return ips
+}
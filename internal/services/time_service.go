@@ -0,0 +1,485 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the IANA database so zones resolve on hosts without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// maxTimeZones limits how many zones one conversion renders
+const maxTimeZones = 20
+
+// unixNumberPattern matches a Unix timestamp with an optional fraction
+var unixNumberPattern = regexp.MustCompile(`^[+-]?\d+(\.\d+)?$`)
+
+// Unix timestamp precisions, in nanoseconds per unit
+var unixUnits = []struct {
+	name  string
+	label string
+	nanos int64
+}{
+	{"s", "seconds", 1e9},
+	{"ms", "milliseconds", 1e6},
+	{"us", "microseconds", 1e3},
+	{"ns", "nanoseconds", 1},
+}
+
+// Layouts tried for date inputs, most specific first. Layouts without a zone
+// are read in the request's input timezone. All-digit inputs such as 20240105
+// are Unix timestamps; parseUnix lists their YYYYMMDD reading as an alternative.
+var timeInputLayouts = []struct {
+	name   string
+	layout string
+}{
+	{"RFC 3339", time.RFC3339Nano},
+	{"RFC 1123", time.RFC1123},
+	{"RFC 1123", time.RFC1123Z},
+	{"RFC 850", time.RFC850},
+	{"RFC 822", time.RFC822},
+	{"RFC 822", time.RFC822Z},
+	{"ANSI C", time.ANSIC},
+	{"Unix date", time.UnixDate},
+	{"Ruby date", time.RubyDate},
+	{"ISO 8601 basic", "20060102T150405Z0700"},
+	{"ISO 8601 basic", "20060102T150405"},
+	{"ISO 8601", "2006-01-02T15:04:05.999999999"},
+	{"ISO 8601", "2006-01-02T15:04"},
+	{"SQL", "2006-01-02 15:04:05.999999999Z07:00"},
+	{"SQL", "2006-01-02 15:04:05.999999999 -0700"},
+	{"SQL", "2006-01-02 15:04:05.999999999 MST"},
+	{"SQL", "2006-01-02 15:04:05.999999999"},
+	{"SQL", "2006-01-02 15:04"},
+	{"JavaScript", "Mon Jan 02 2006 15:04:05 GMT-0700"},
+	{"date", "2006-01-02"},
+	{"date", "2006/01/02 15:04:05"},
+	{"date", "2006/01/02 15:04"},
+	{"date", "2006/01/02"},
+	{"US date", "01/02/2006 15:04:05"},
+	{"US date", "01/02/2006 3:04:05 PM"},
+	{"US date", "01/02/2006 15:04"},
+	{"US date", "01/02/2006"},
+	{"date", "02 Jan 2006 15:04:05 MST"},
+	{"date", "02 Jan 2006 15:04:05"},
+	{"date", "02 Jan 2006"},
+	{"date", "2 January 2006"},
+	{"date", "Jan 2, 2006 15:04:05"},
+	{"date", "Jan 2, 2006 3:04:05 PM"},
+	{"date", "Jan 2, 2006 3:04 PM"},
+	{"date", "Jan 2, 2006"},
+	{"date", "January 2, 2006 15:04:05"},
+	{"date", "January 2, 2006 3:04 PM"},
+	{"date", "January 2, 2006"},
+	{"date", "Mon, 2 Jan 2006"},
+}
+
+// TimeService converts between Unix timestamps, date formats and timezones
+type TimeService struct{}
+
+// NewTimeService creates a new time conversion service
+func NewTimeService() *TimeService {
+	return &TimeService{}
+}
+
+// TimeConvertRequest is a timestamp or date to convert
+type TimeConvertRequest struct {
+	Input     string   `json:"input"`     // Unix timestamp, RFC 3339, RFC 1123, a common date format or "now"
+	Unit      string   `json:"unit"`      // "auto" (default), "s", "ms", "us" or "ns"
+	InputZone string   `json:"input_tz"`  // zone for dates without an offset, default UTC
+	Timezones []string `json:"timezones"` // IANA zones to render, default UTC
+}
+
+// UnixTimestamps is one instant at every Unix precision
+type UnixTimestamps struct {
+	Seconds      int64  `json:"seconds"`
+	Milliseconds int64  `json:"milliseconds"`
+	Microseconds int64  `json:"microseconds"`
+	Nanoseconds  string `json:"nanoseconds"` // a string, since it overflows int64 outside 1678-2262
+	Fractional   string `json:"fractional"`  // seconds with up to 9 decimal places
+}
+
+// TimeFormat is an instant rendered in one format
+type TimeFormat struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// TimeTransition is a change of UTC offset in a zone
+type TimeTransition struct {
+	Time         time.Time `json:"time"`
+	Offset       string    `json:"offset"` // offset in effect from this moment
+	Abbreviation string    `json:"abbreviation"`
+	DST          bool      `json:"dst"`
+}
+
+// TimeZoneView is an instant as seen in one timezone
+type TimeZoneView struct {
+	Timezone       string          `json:"timezone"`
+	Abbreviation   string          `json:"abbreviation"`
+	Offset         string          `json:"offset"` // such as +05:30
+	OffsetSeconds  int             `json:"offset_seconds"`
+	DST            bool            `json:"dst"`
+	DSTSavings     string          `json:"dst_savings,omitempty"` // how far DST moves the clock, such as +01:00
+	StandardOffset string          `json:"standard_offset"`
+	Previous       *TimeTransition `json:"previous_transition,omitempty"`
+	Next           *TimeTransition `json:"next_transition,omitempty"`
+	Formats        []TimeFormat    `json:"formats"`
+}
+
+// TimeConvertResult is an instant at every precision and in every requested zone
+type TimeConvertResult struct {
+	Input     string         `json:"input"`
+	InputKind string         `json:"input_kind"` // "unix", "now" or the matched date format
+	Unit      string         `json:"unit,omitempty"`
+	Detection string         `json:"detection"` // how the input was interpreted
+	Unix      UnixTimestamps `json:"unix"`
+	HTTPDate  string         `json:"http_date"`
+	Relative  string         `json:"relative"`
+	Zones     []TimeZoneView `json:"zones"`
+	Warnings  []string       `json:"warnings,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Convert parses the input and renders it in each requested timezone
+func (s *TimeService) Convert(req TimeConvertRequest) (*TimeConvertResult, error) {
+	input := strings.TrimSpace(req.Input)
+	if input == "" {
+		return nil, fmt.Errorf("input required")
+	}
+
+	inputZone, err := loadTimeZone(req.InputZone)
+	if err != nil {
+		return nil, err
+	}
+	zoneNames := req.Timezones
+	if len(zoneNames) == 0 {
+		zoneNames = []string{"UTC"}
+	}
+	if len(zoneNames) > maxTimeZones {
+		return nil, fmt.Errorf("at most %d timezones per request", maxTimeZones)
+	}
+	zones := make([]*time.Location, len(zoneNames))
+	for i, name := range zoneNames {
+		if zones[i], err = loadTimeZone(name); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	result := &TimeConvertResult{Input: input, Zones: []TimeZoneView{}}
+
+	var t time.Time
+	switch {
+	case strings.EqualFold(input, "now"):
+		t = now
+		result.InputKind = "now"
+		result.Detection = "current server time"
+	case unixNumberPattern.MatchString(input):
+		result.InputKind = "unix"
+		if t, err = s.parseUnix(input, req.Unit, inputZone, result); err != nil {
+			return nil, err
+		}
+	default:
+		if req.Unit != "" && req.Unit != "auto" {
+			return nil, fmt.Errorf("unit applies to Unix timestamps only")
+		}
+		if t, err = s.parseDate(input, inputZone, result); err != nil {
+			return nil, err
+		}
+	}
+	if t.Year() < 1 || t.Year() > 9999 {
+		return nil, fmt.Errorf("%s is outside years 1-9999", input)
+	}
+
+	result.Unix = unixTimestamps(t)
+	result.HTTPDate = t.UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT")
+	result.Relative = relativeTime(t, now)
+	for _, loc := range zones {
+		result.Zones = append(result.Zones, timeZoneView(t, loc))
+	}
+	result.Timestamp = now
+	return result, nil
+}
+
+// loadTimeZone resolves an IANA name. "Local" is refused since the server's zone means nothing to callers.
+func loadTimeZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return time.UTC, nil
+	case strings.EqualFold(name, "local"):
+		return nil, fmt.Errorf("timezone must be an IANA name such as Europe/Berlin, not Local")
+	case strings.EqualFold(name, "utc"), strings.EqualFold(name, "z"):
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return loc, nil
+}
+
+// parseUnix reads a timestamp in the requested unit, or guesses the unit from its magnitude
+func (s *TimeService) parseUnix(input, unit string, loc *time.Location, result *TimeConvertResult) (time.Time, error) {
+	intPart, frac, _ := strings.Cut(strings.TrimLeft(input, "+-"), ".")
+	if intPart = strings.TrimLeft(intPart, "0"); intPart == "" {
+		intPart = "0"
+	}
+
+	choice := -1
+	switch unit {
+	case "", "auto":
+	default:
+		for i, u := range unixUnits {
+			if u.name == unit {
+				choice = i
+			}
+		}
+		if choice < 0 {
+			return time.Time{}, fmt.Errorf("unit must be auto, s, ms, us or ns")
+		}
+	}
+
+	if choice < 0 {
+		// Up to 11 digits is seconds (until year 5138), and each 3 more digits
+		// is the next finer unit
+		switch n := len(intPart); {
+		case n <= 11:
+			choice = 0
+		case n <= 14:
+			choice = 1
+		case n <= 17:
+			choice = 2
+		default:
+			choice = 3
+		}
+		result.Detection = fmt.Sprintf("%d integer digits: read as %s", len(intPart), unixUnits[choice].label)
+		var alternatives []string
+		for i, u := range unixUnits {
+			if i == choice {
+				continue
+			}
+			if alt, err := unixTime(input, u.nanos); err == nil && alt.Year() >= 1 && alt.Year() <= 9999 {
+				alternatives = append(alternatives, fmt.Sprintf("as %s it would be %s", u.label, alt.UTC().Format("2006-01-02 15:04:05 UTC")))
+			} else {
+				alternatives = append(alternatives, fmt.Sprintf("as %s it would be out of range", u.label))
+			}
+		}
+		if len(input) == 8 {
+			if date, err := time.ParseInLocation("20060102", input, loc); err == nil {
+				alternatives = append(alternatives, fmt.Sprintf("as a YYYYMMDD date it would be %s; write it as %s to convert the date", date.Format("Monday 2 January 2006"), date.Format("2006-01-02")))
+			}
+		}
+		result.Detection += "; " + strings.Join(alternatives, ", ")
+	} else {
+		result.Detection = "read as " + unixUnits[choice].label + " as requested"
+	}
+	result.Unit = unixUnits[choice].name
+
+	t, err := unixTime(input, unixUnits[choice].nanos)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if kept := len(strconv.FormatInt(unixUnits[choice].nanos, 10)) - 1; len(frac) > kept {
+		result.Warnings = append(result.Warnings, "times have nanosecond precision; fractional digits beyond it were dropped")
+	}
+	return t, nil
+}
+
+// unixTime converts a decimal count of units since the epoch without going through float64
+func unixTime(input string, nanosPerUnit int64) (time.Time, error) {
+	total, ok := new(big.Rat).SetString(input)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", input)
+	}
+	total.Mul(total, new(big.Rat).SetInt64(nanosPerUnit))
+	nanos := new(big.Int).Quo(total.Num(), total.Denom()) // truncate below a nanosecond
+	sec, nsec := new(big.Int).DivMod(nanos, big.NewInt(1e9), new(big.Int))
+	if !sec.IsInt64() || sec.Int64() < -62135596800 || sec.Int64() > 253402300799 {
+		return time.Time{}, fmt.Errorf("%s is outside years 1-9999", input)
+	}
+	return time.Unix(sec.Int64(), nsec.Int64()).UTC(), nil
+}
+
+// parseDate tries each known layout, reading zone-less dates in loc
+func (s *TimeService) parseDate(input string, loc *time.Location, result *TimeConvertResult) (time.Time, error) {
+	// JavaScript's Date.toString appends the zone name in parentheses
+	if i := strings.Index(input, " ("); i > 0 && strings.HasSuffix(input, ")") {
+		input = input[:i]
+	}
+
+	for _, candidate := range timeInputLayouts {
+		t, err := time.ParseInLocation(candidate.layout, input, loc)
+		if err != nil {
+			continue
+		}
+		result.InputKind = candidate.name
+		result.Detection = fmt.Sprintf("parsed as %s (layout %q)", candidate.name, candidate.layout)
+
+		if !layoutHasZone(candidate.layout) {
+			result.Detection += " in " + loc.String()
+			result.Warnings = append(result.Warnings, wallClockWarnings(input, candidate.layout, t, loc)...)
+		} else if name, offset := t.Zone(); offset == 0 && strings.Contains(candidate.layout, "MST") &&
+			name != "UTC" && name != "GMT" && name != "UT" && !abbreviationIn(name, loc, t) {
+			// Go reads abbreviations the input timezone does not use as a zero offset
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"zone abbreviation %s is ambiguous and was read as UTC+00:00; use a numeric offset or set the input timezone", name))
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q: use a Unix timestamp, RFC 3339, RFC 1123 or a format such as 2006-01-02 15:04:05", input)
+}
+
+func layoutHasZone(layout string) bool {
+	return strings.Contains(layout, "Z07") || strings.Contains(layout, "-07") || strings.Contains(layout, "MST")
+}
+
+// abbreviationIn reports whether loc uses name around t
+func abbreviationIn(name string, loc *time.Location, t time.Time) bool {
+	for _, probe := range []time.Time{t, t.AddDate(0, -6, 0), t.AddDate(0, 6, 0)} {
+		if zone, _ := probe.In(loc).Zone(); zone == name {
+			return true
+		}
+	}
+	return false
+}
+
+// wallClockWarnings flags local times skipped or repeated by a DST change
+func wallClockWarnings(input, layout string, t time.Time, loc *time.Location) []string {
+	reparsed, err := time.Parse(layout, input)
+	if err != nil {
+		return nil
+	}
+	local := t.In(loc)
+	if local.Hour() != reparsed.Hour() || local.Minute() != reparsed.Minute() {
+		return []string{fmt.Sprintf("%s does not exist in %s because clocks skip it for daylight saving; it was read as %s",
+			reparsed.Format("15:04"), loc, local.Format("15:04 MST"))}
+	}
+
+	if alt, ok := otherOccurrence(t, loc); ok {
+		return []string{fmt.Sprintf("%s occurs twice in %s because clocks fall back; read as %s, the other occurrence is %s",
+			reparsed.Format("15:04"), loc, local.Format("15:04 MST (-07:00)"), alt.In(loc).Format("15:04 MST (-07:00)"))}
+	}
+	return nil
+}
+
+// otherOccurrence returns the second instant showing the same wall clock as t in loc,
+// which exists only during the hour repeated when clocks fall back
+func otherOccurrence(t time.Time, loc *time.Location) (time.Time, bool) {
+	// The same wall clock under the offset in effect before or after this zone period
+	local := t.In(loc)
+	_, offset := local.Zone()
+	start, end := local.ZoneBounds()
+	for _, edge := range []time.Time{start.Add(-time.Second), end} {
+		if edge.IsZero() || edge.Year() < 1 {
+			continue
+		}
+		_, other := edge.In(loc).Zone()
+		if other == offset {
+			continue
+		}
+		alt := t.Add(time.Duration(offset-other) * time.Second)
+		if _, altOffset := alt.In(loc).Zone(); altOffset == other && !alt.Equal(t) {
+			return alt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func unixTimestamps(t time.Time) UnixTimestamps {
+	nanos := new(big.Int).Mul(big.NewInt(t.Unix()), big.NewInt(1e9))
+	nanos.Add(nanos, big.NewInt(int64(t.Nanosecond())))
+
+	fractional := fmt.Sprintf("%d", t.Unix())
+	if ns := t.Nanosecond(); ns != 0 {
+		sec := t.Unix()
+		if sec < 0 {
+			// -1.5 is one and a half seconds before the epoch: sec -2, nsec 5e8
+			sec, ns = sec+1, 1e9-ns
+			fractional = "-" + strings.TrimRight(fmt.Sprintf("%d.%09d", -sec, ns), "0")
+		} else {
+			fractional = strings.TrimRight(fmt.Sprintf("%d.%09d", sec, ns), "0")
+		}
+	}
+
+	return UnixTimestamps{
+		Seconds:      t.Unix(),
+		Milliseconds: t.UnixMilli(),
+		Microseconds: t.UnixMicro(),
+		Nanoseconds:  nanos.String(),
+		Fractional:   fractional,
+	}
+}
+
+func timeZoneView(t time.Time, loc *time.Location) TimeZoneView {
+	local := t.In(loc)
+	abbreviation, offset := local.Zone()
+	view := TimeZoneView{
+		Timezone:      loc.String(),
+		Abbreviation:  abbreviation,
+		Offset:        formatUTCOffset(offset),
+		OffsetSeconds: offset,
+		DST:           local.IsDST(),
+	}
+
+	standard := offset
+	start, end := local.ZoneBounds()
+	if !start.IsZero() {
+		before := start.Add(-time.Second).In(loc)
+		view.Previous = transitionAt(start, loc)
+		if _, prior := before.Zone(); view.DST && !before.IsDST() {
+			standard = prior
+		}
+	}
+	if !end.IsZero() {
+		view.Next = transitionAt(end, loc)
+		if _, after := end.In(loc).Zone(); view.DST && !end.In(loc).IsDST() {
+			standard = after
+		}
+	}
+	view.StandardOffset = formatUTCOffset(standard)
+	if view.DST {
+		view.DSTSavings = formatUTCOffset(offset - standard)
+	}
+
+	year, week := local.ISOWeek()
+	view.Formats = []TimeFormat{
+		{"RFC 3339", local.Format(time.RFC3339Nano)},
+		{"ISO 8601 basic", local.Format("20060102T150405Z0700")},
+		{"ISO week date", fmt.Sprintf("%04d-W%02d-%d", year, week, (int(local.Weekday())+6)%7+1)},
+		{"Ordinal date", local.Format("2006-002")},
+		{"RFC 1123", local.Format(time.RFC1123)},
+		{"RFC 1123Z", local.Format(time.RFC1123Z)},
+		{"RFC 850", local.Format(time.RFC850)},
+		{"RFC 822Z", local.Format(time.RFC822Z)},
+		{"ANSI C", local.Format(time.ANSIC)},
+		{"Unix date", local.Format(time.UnixDate)},
+		{"SQL", local.Format("2006-01-02 15:04:05.999999999")},
+		{"Human", local.Format("Monday, January 2, 2006 at 3:04:05 PM MST")},
+		{"Kitchen", local.Format(time.Kitchen)},
+	}
+	return view
+}
+
+func transitionAt(at time.Time, loc *time.Location) *TimeTransition {
+	local := at.In(loc)
+	abbreviation, offset := local.Zone()
+	return &TimeTransition{Time: at.UTC(), Offset: formatUTCOffset(offset), Abbreviation: abbreviation, DST: local.IsDST()}
+}
+
+// formatUTCOffset renders seconds east of UTC as +hh:mm, with seconds for historical local mean time
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	if offset%60 != 0 {
+		return fmt.Sprintf("%c%02d:%02d:%02d", sign, offset/3600, offset/60%60, offset%60)
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/3600, offset/60%60)
+}
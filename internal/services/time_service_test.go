@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTimeConvertDigits(t *testing.T) {
+	tests := []struct {
+		input string
+		date  bool // the YYYYMMDD reading is listed
+	}{
+		{"20240105", true},
+		{"19991231", true},
+		{"20241305", false}, // no month 13
+		{"1704412800", false},
+	}
+	for _, tt := range tests {
+		result, err := NewTimeService().Convert(TimeConvertRequest{Input: tt.input})
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if result.InputKind != "unix" || result.Unit != "s" {
+			t.Errorf("%s: kind %s unit %s, want unix seconds", tt.input, result.InputKind, result.Unit)
+		}
+		if got := strings.Contains(result.Detection, "YYYYMMDD"); got != tt.date {
+			t.Errorf("%s: detection %q, want date alternative %v", tt.input, result.Detection, tt.date)
+		}
+	}
+
+	result, err := NewTimeService().Convert(TimeConvertRequest{Input: "20240105"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Detection, "Friday 5 January 2024; write it as 2024-01-05") {
+		t.Errorf("detection = %q", result.Detection)
+	}
+}
+
+func TestTimeConvertDates(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64 // Unix seconds
+	}{
+		{"2024-01-05", 1704412800},
+		{"2024-01-05T10:00:00+02:00", 1704441600},
+		{"Jan 5, 2024 3:04 PM", 1704467040},
+		{"05 Jan 2024", 1704412800},
+	}
+	for _, tt := range tests {
+		result, err := NewTimeService().Convert(TimeConvertRequest{Input: tt.input})
+		if err != nil {
+			t.Errorf("%s: %v", tt.input, err)
+			continue
+		}
+		if result.Unix.Seconds != tt.want {
+			t.Errorf("%s = %d, want %d", tt.input, result.Unix.Seconds, tt.want)
+		}
+	}
+
+	// Free-form text outside the known layouts is refused rather than guessed
+	for _, input := range []string{"March 5, 2024 3pm", "next tuesday", "5th of March"} {
+		if _, err := NewTimeService().Convert(TimeConvertRequest{Input: input}); err == nil {
+			t.Errorf("%s: parsed, want unrecognized date", input)
+		}
+	}
+}
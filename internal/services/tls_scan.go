@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds for the number of concurrent handshakes a scan performs
+const (
+	defaultScanConcurrency = 8
+	maxScanConcurrency     = 32
+)
+
+// scannedVersions lists the protocol versions probed by ScanTLS, newest first
+var scannedVersions = []uint16{
+	tls.VersionTLS13,
+	tls.VersionTLS12,
+	tls.VersionTLS11,
+	tls.VersionTLS10,
+}
+
+// TLSScanRequest represents a request to scan the TLS configuration of an endpoint
+type TLSScanRequest struct {
+	Host        string `json:"host"`
+	Port        string `json:"port"`
+	SNI         string `json:"sni"`
+	Concurrency int    `json:"concurrency"` // parallel handshakes, defaults to 8
+}
+
+// TLSScanResult represents the protocols and cipher suites an endpoint accepts
+type TLSScanResult struct {
+	Host       string               `json:"host"`
+	Port       string               `json:"port"`
+	SNI        string               `json:"sni,omitempty"`
+	Protocols  []TLSProtocolSupport `json:"protocols"`
+	Grade      string               `json:"grade"` // "A+", "A", "A-", "B", "C", "F"
+	Findings   []TLSFinding         `json:"findings"`
+	Handshakes int                  `json:"handshakes"`
+	ScanTime   int                  `json:"scan_time_ms"`
+	Timestamp  time.Time            `json:"timestamp"`
+}
+
+// TLSProtocolSupport represents the support for a single protocol version
+type TLSProtocolSupport struct {
+	Version      string               `json:"version"`
+	Supported    bool                 `json:"supported"`
+	CipherSuites []TLSCipherSuiteInfo `json:"cipher_suites,omitempty"`
+}
+
+// TLSCipherSuiteInfo represents an accepted cipher suite and its properties
+type TLSCipherSuiteInfo struct {
+	Name           string `json:"name"`
+	ID             string `json:"id"`
+	ForwardSecrecy bool   `json:"forward_secrecy"`
+	Strength       string `json:"strength"` // "strong", "legacy", "weak"
+}
+
+// TLSFinding represents a single issue found while grading a configuration
+type TLSFinding struct {
+	Severity string `json:"severity"` // "critical", "warning", "info"
+	Message  string `json:"message"`
+}
+
+// untestedSuitesNote is reported with every scan, since acceptance of suites
+// outside crypto/tls cannot be observed
+const untestedSuitesNote = "SSL 3.0 and cipher suites crypto/tls does not implement (DHE, EXPORT, NULL, single DES, CAMELLIA and others) were not tested"
+
+// scanProbe is a single handshake attempt made during a scan
+type scanProbe struct {
+	version uint16
+	suite   uint16 // zero lets the client offer its defaults (TLS 1.3)
+}
+
+// scanOutcome is the result of a single scan probe
+type scanOutcome struct {
+	probe    scanProbe
+	accepted bool
+	suite    uint16
+}
+
+// ScanTLS probes which TLS versions and cipher suites a server accepts and grades the configuration
+func (s *TLSInspectionService) ScanTLS(ctx context.Context, req TLSScanRequest) (*TLSScanResult, error) {
+	if req.Host == "" {
+		return nil, fmt.Errorf("host cannot be empty")
+	}
+	if req.Port == "" {
+		req.Port = "443"
+	}
+	if _, err := strconv.ParseUint(req.Port, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid port: %s", req.Port)
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultScanConcurrency
+	}
+	if concurrency > maxScanConcurrency {
+		concurrency = maxScanConcurrency
+	}
+
+	sni := req.SNI
+	if sni == "" && net.ParseIP(req.Host) == nil {
+		sni = req.Host
+	}
+	addr := net.JoinHostPort(req.Host, req.Port)
+
+	start := time.Now()
+	result := &TLSScanResult{
+		Host:      req.Host,
+		Port:      req.Port,
+		SNI:       sni,
+		Timestamp: start,
+	}
+
+	// Make sure the endpoint is reachable before fanning out
+	conn, err := s.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	conn.Close()
+
+	// Go does not allow TLS 1.3 suites to be configured, so 1.3 is probed with a
+	// single handshake and the negotiated suite is reported
+	probes := []scanProbe{{version: tls.VersionTLS13}}
+	for _, version := range scannedVersions[1:] {
+		for _, suite := range scanCipherSuites() {
+			if supportsVersion(suite, version) {
+				probes = append(probes, scanProbe{version: version, suite: suite.ID})
+			}
+		}
+	}
+
+	outcomes := make(chan scanOutcome, len(probes))
+	semaphore := make(chan struct{}, concurrency) // Limit concurrent handshakes
+
+	for _, probe := range probes {
+		go func(p scanProbe) {
+			semaphore <- struct{}{}        // Acquire semaphore
+			defer func() { <-semaphore }() // Release semaphore
+
+			config := &tls.Config{
+				ServerName:         sni,
+				InsecureSkipVerify: true,
+				MinVersion:         p.version,
+				MaxVersion:         p.version,
+			}
+			if p.suite != 0 {
+				config.CipherSuites = []uint16{p.suite}
+			}
+
+			state, _, err := s.handshake(ctx, addr, config)
+			outcomes <- scanOutcome{probe: p, accepted: err == nil, suite: state.CipherSuite}
+		}(probe)
+	}
+
+	accepted := make(map[uint16][]uint16)
+	for i := 0; i < len(probes); i++ {
+		outcome := <-outcomes
+		if outcome.accepted {
+			accepted[outcome.probe.version] = append(accepted[outcome.probe.version], outcome.suite)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("TLS scan cancelled: %w", err)
+	}
+
+	for _, version := range scannedVersions {
+		suites := accepted[version]
+		sort.Slice(suites, func(i, j int) bool { return suites[i] < suites[j] })
+
+		support := TLSProtocolSupport{
+			Version:   tls.VersionName(version),
+			Supported: len(suites) > 0,
+		}
+		for _, id := range suites {
+			support.CipherSuites = append(support.CipherSuites, describeCipherSuite(id))
+		}
+		result.Protocols = append(result.Protocols, support)
+	}
+
+	result.Grade, result.Findings = gradeTLSScan(result.Protocols)
+	result.Handshakes = len(probes)
+	result.ScanTime = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
+// scanCipherSuites returns every cipher suite implemented by crypto/tls
+func scanCipherSuites() []*tls.CipherSuite {
+	return append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+}
+
+// supportsVersion reports whether a cipher suite can be used with a protocol version
+func supportsVersion(suite *tls.CipherSuite, version uint16) bool {
+	for _, v := range suite.SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// describeCipherSuite classifies a cipher suite by strength and key exchange
+func describeCipherSuite(id uint16) TLSCipherSuiteInfo {
+	name := tls.CipherSuiteName(id)
+	info := TLSCipherSuiteInfo{
+		Name:     name,
+		ID:       fmt.Sprintf("0x%04X", id),
+		Strength: "strong",
+	}
+
+	// TLS 1.3 suites always use an ephemeral key exchange. Only suites crypto/tls
+	// implements are probed, so DHE, EXPORT, NULL and single DES never appear here.
+	info.ForwardSecrecy = strings.Contains(name, "_ECDHE_") || !strings.Contains(name, "_WITH_")
+
+	switch {
+	case strings.Contains(name, "_RC4_"), strings.Contains(name, "_3DES_"):
+		info.Strength = "weak"
+	case strings.Contains(name, "_CBC_"), !info.ForwardSecrecy:
+		info.Strength = "legacy"
+	}
+
+	return info
+}
+
+// gradeTLSScan grades a scanned configuration and lists the findings behind the grade
+func gradeTLSScan(protocols []TLSProtocolSupport) (string, []TLSFinding) {
+	grades := []string{"A+", "A", "A-", "B", "C", "F"}
+	grade := 0
+	capGrade := func(g string) {
+		for i, candidate := range grades {
+			if candidate == g && i > grade {
+				grade = i
+			}
+		}
+	}
+
+	findings := []TLSFinding{}
+	supported := make(map[string]bool)
+	var weak, cbc, withoutFS []string
+	var withFS int
+
+	for _, protocol := range protocols {
+		supported[protocol.Version] = protocol.Supported
+		for _, suite := range protocol.CipherSuites {
+			if suite.Strength == "weak" {
+				weak = appendUnique(weak, suite.Name)
+			} else if strings.Contains(suite.Name, "_CBC_") {
+				cbc = appendUnique(cbc, suite.Name)
+			}
+			if suite.ForwardSecrecy {
+				withFS++
+			} else {
+				withoutFS = appendUnique(withoutFS, suite.Name)
+			}
+		}
+	}
+
+	if !supported["TLS 1.2"] && !supported["TLS 1.3"] {
+		findings = append(findings, TLSFinding{Severity: "critical", Message: "server supports neither TLS 1.2 nor TLS 1.3"})
+		capGrade("F")
+	}
+	if !supported["TLS 1.3"] {
+		findings = append(findings, TLSFinding{Severity: "warning", Message: "TLS 1.3 is not supported"})
+		capGrade("A-")
+	}
+	for _, version := range []string{"TLS 1.0", "TLS 1.1"} {
+		if supported[version] {
+			findings = append(findings, TLSFinding{Severity: "warning", Message: version + " is enabled; it is deprecated by RFC 8996"})
+			capGrade("B")
+		}
+	}
+	if len(weak) > 0 {
+		findings = append(findings, TLSFinding{Severity: "critical", Message: "weak cipher suites accepted: " + strings.Join(weak, ", ")})
+		capGrade("C")
+	}
+	if len(withoutFS) > 0 {
+		if withFS == 0 {
+			findings = append(findings, TLSFinding{Severity: "critical", Message: "no cipher suite provides forward secrecy"})
+			capGrade("C")
+		} else {
+			findings = append(findings, TLSFinding{Severity: "warning", Message: "cipher suites without forward secrecy accepted: " + strings.Join(withoutFS, ", ")})
+			capGrade("B")
+		}
+	}
+	if len(cbc) > 0 {
+		findings = append(findings, TLSFinding{Severity: "info", Message: "legacy CBC mode cipher suites accepted: " + strings.Join(cbc, ", ")})
+		capGrade("A")
+	}
+	findings = append(findings, TLSFinding{Severity: "info", Message: untestedSuitesNote})
+
+	return grades[grade], findings
+}
+
+// appendUnique appends a value to a slice if it is not already present
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
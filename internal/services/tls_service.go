@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// TLSInspectionService provides TLS certificate and handshake inspection
+type TLSInspectionService struct {
+	dialer  *net.Dialer
+	rootCAs *x509.CertPool // nil uses the system roots
+}
+
+// NewTLSInspectionService creates a new TLS inspection service that only
+// connects to public addresses
+func NewTLSInspectionService() *TLSInspectionService {
+	return NewTLSInspectionServiceWithRoots(nil, false)
+}
+
+// NewTLSInspectionServiceWithRoots creates a TLS inspection service that verifies
+// certificates against the given roots instead of the system pool; allowPrivate
+// permits loopback, private and link-local targets
+func NewTLSInspectionServiceWithRoots(roots *x509.CertPool, allowPrivate bool) *TLSInspectionService {
+	return &TLSInspectionService{
+		dialer:  newProbeDialer(10*time.Second, allowPrivate),
+		rootCAs: roots,
+	}
+}
+
+// TLSInspectRequest represents a request to inspect a TLS endpoint
+type TLSInspectRequest struct {
+	Host string   `json:"host"`
+	Port string   `json:"port"`
+	SNI  string   `json:"sni"`  // defaults to Host when Host is a name
+	ALPN []string `json:"alpn"` // protocols offered during the handshake
+}
+
+// TLSInspectionResult represents the outcome of a TLS inspection
+type TLSInspectionResult struct {
+	Host          string            `json:"host"`
+	Port          string            `json:"port"`
+	SNI           string            `json:"sni,omitempty"`
+	RemoteAddr    string            `json:"remote_addr"`
+	Protocol      string            `json:"protocol"`
+	CipherSuite   string            `json:"cipher_suite"`
+	ALPN          string            `json:"alpn,omitempty"`
+	Chain         []CertificateInfo `json:"chain"`
+	OCSP          *OCSPStapleInfo   `json:"ocsp,omitempty"`
+	Verification  TLSVerification   `json:"verification"`
+	HandshakeTime int               `json:"handshake_time_ms"`
+	Timestamp     time.Time         `json:"timestamp"`
+}
+
+// CertificateInfo represents the details of a single certificate in a chain
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SerialNumber       string    `json:"serial_number"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	IPAddresses        []string  `json:"ip_addresses,omitempty"`
+	EmailAddresses     []string  `json:"email_addresses,omitempty"`
+	URIs               []string  `json:"uris,omitempty"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysRemaining      int       `json:"days_remaining"`
+	Expired            bool      `json:"expired"`
+	NotYetValid        bool      `json:"not_yet_valid"`
+	KeyType            string    `json:"key_type"` // "RSA", "ECDSA", "Ed25519"
+	KeySize            int       `json:"key_size"` // bits
+	KeyCurve           string    `json:"key_curve,omitempty"`
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+	SelfSigned         bool      `json:"self_signed"`
+	SHA256Fingerprint  string    `json:"sha256_fingerprint"`
+}
+
+// OCSPStapleInfo represents a stapled OCSP response
+type OCSPStapleInfo struct {
+	Stapled    bool      `json:"stapled"`
+	Status     string    `json:"status,omitempty"` // "good", "revoked", "unknown"
+	ProducedAt time.Time `json:"produced_at,omitzero"`
+	ThisUpdate time.Time `json:"this_update,omitzero"`
+	NextUpdate time.Time `json:"next_update,omitzero"`
+	RevokedAt  time.Time `json:"revoked_at,omitzero"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TLSVerification represents chain verification against the trusted roots
+type TLSVerification struct {
+	Verified       bool       `json:"verified"`
+	VerifiedChains [][]string `json:"verified_chains,omitempty"` // subjects, leaf first
+	Errors         []string   `json:"errors,omitempty"`
+	Warnings       []string   `json:"warnings,omitempty"`
+}
+
+// InspectTLS connects to host:port and reports the certificate chain and negotiated parameters
+func (s *TLSInspectionService) InspectTLS(ctx context.Context, req TLSInspectRequest) (*TLSInspectionResult, error) {
+	if req.Host == "" {
+		return nil, fmt.Errorf("host cannot be empty")
+	}
+	if req.Port == "" {
+		req.Port = "443"
+	}
+	if _, err := strconv.ParseUint(req.Port, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid port: %s", req.Port)
+	}
+
+	sni := req.SNI
+	if sni == "" && net.ParseIP(req.Host) == nil {
+		sni = req.Host
+	}
+
+	start := time.Now()
+	result := &TLSInspectionResult{
+		Host:      req.Host,
+		Port:      req.Port,
+		SNI:       sni,
+		Timestamp: start,
+	}
+
+	// Verification is done separately so that broken chains can still be reported
+	config := &tls.Config{
+		ServerName:         sni,
+		NextProtos:         req.ALPN,
+		InsecureSkipVerify: true,
+	}
+	state, remoteAddr, err := s.handshake(ctx, net.JoinHostPort(req.Host, req.Port), config)
+	if err != nil {
+		return nil, err
+	}
+	result.HandshakeTime = int(time.Since(start).Milliseconds())
+	result.RemoteAddr = remoteAddr
+	result.Protocol = tls.VersionName(state.Version)
+	result.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	result.ALPN = state.NegotiatedProtocol
+
+	for _, cert := range state.PeerCertificates {
+		result.Chain = append(result.Chain, describeCertificate(cert, start))
+	}
+	result.OCSP = describeOCSPStaple(state.OCSPResponse, state.PeerCertificates)
+
+	verifyName := sni
+	if verifyName == "" {
+		verifyName = req.Host
+	}
+	result.Verification = s.verifyChain(state.PeerCertificates, verifyName, start)
+
+	return result, nil
+}
+
+// handshake dials the address and completes a TLS handshake with the given config
+func (s *TLSInspectionService) handshake(ctx context.Context, addr string, config *tls.Config) (tls.ConnectionState, string, error) {
+	conn, err := s.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, "", fmt.Errorf("connection failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Bound the handshake by the dialer timeout as well as the context
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(s.dialer.Timeout))
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, "", fmt.Errorf("TLS handshake failed: %w", err)
+	}
+
+	return tlsConn.ConnectionState(), conn.RemoteAddr().String(), nil
+}
+
+// verifyChain verifies the presented chain and explains any failures
+func (s *TLSInspectionService) verifyChain(certs []*x509.Certificate, name string, now time.Time) TLSVerification {
+	verification := TLSVerification{}
+	if len(certs) == 0 {
+		verification.Errors = append(verification.Errors, "server did not present a certificate")
+		return verification
+	}
+
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         s.rootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	if err != nil {
+		verification.Errors = append(verification.Errors, explainVerifyError(err, leaf, len(certs)))
+	} else {
+		verification.Verified = true
+		for _, chain := range chains {
+			var subjects []string
+			for _, cert := range chain {
+				subjects = append(subjects, cert.Subject.String())
+			}
+			verification.VerifiedChains = append(verification.VerifiedChains, subjects)
+		}
+	}
+
+	verification.Warnings = append(verification.Warnings, chainWarnings(certs, now)...)
+	return verification
+}
+
+// explainVerifyError turns an x509 verification error into a human readable explanation
+func explainVerifyError(err error, leaf *x509.Certificate, chainLength int) string {
+	var hostErr x509.HostnameError
+	var authErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+
+	switch {
+	case errors.As(err, &hostErr):
+		valid := certificateNames(hostErr.Certificate)
+		if len(valid) == 0 {
+			return fmt.Sprintf("certificate is not valid for %s and lists no subject alternative names", hostErr.Host)
+		}
+		return fmt.Sprintf("certificate is not valid for %s; it covers %s", hostErr.Host, strings.Join(valid, ", "))
+	case errors.As(err, &authErr):
+		if leaf.CheckSignatureFrom(leaf) == nil {
+			return "certificate is self-signed and not trusted by the system roots"
+		}
+		if chainLength == 1 {
+			return fmt.Sprintf("certificate is signed by an unknown authority (%s); the server sent no intermediates, so it may be missing from the configured chain", leaf.Issuer)
+		}
+		return fmt.Sprintf("certificate chain does not lead to a trusted root (issuer %s); the server may be sending the wrong intermediates or using a private CA", leaf.Issuer)
+	case errors.As(err, &invalidErr):
+		switch invalidErr.Reason {
+		case x509.Expired:
+			return fmt.Sprintf("certificate %q is expired or not yet valid (valid %s to %s)",
+				invalidErr.Cert.Subject.CommonName,
+				invalidErr.Cert.NotBefore.Format(time.RFC3339),
+				invalidErr.Cert.NotAfter.Format(time.RFC3339))
+		case x509.NotAuthorizedToSign:
+			return fmt.Sprintf("certificate %q is used as an issuer but is not a CA", invalidErr.Cert.Subject.CommonName)
+		case x509.IncompatibleUsage:
+			return "certificate key usage does not permit TLS server authentication"
+		case x509.TooManyIntermediates:
+			return "certificate chain is longer than an issuer's path length constraint allows"
+		case x509.CANotAuthorizedForThisName:
+			return "an issuer's name constraints do not permit this certificate's names"
+		}
+		return fmt.Sprintf("certificate is invalid: %v", err)
+	}
+
+	return fmt.Sprintf("certificate verification failed: %v", err)
+}
+
+// chainWarnings reports ordering and lifetime problems in the presented chain
+func chainWarnings(certs []*x509.Certificate, now time.Time) []string {
+	var warnings []string
+
+	for i := 0; i < len(certs)-1; i++ {
+		if certs[i].CheckSignatureFrom(certs[i+1]) != nil {
+			warnings = append(warnings, fmt.Sprintf("certificate %d (%s) is not signed by the next certificate in the chain; the chain may be out of order", i, certs[i].Subject.CommonName))
+		}
+	}
+
+	if len(certs) > 1 {
+		last := certs[len(certs)-1]
+		if last.CheckSignatureFrom(last) == nil {
+			warnings = append(warnings, "server sends the root certificate; clients ignore it and it adds handshake overhead")
+		}
+	}
+
+	for i, cert := range certs {
+		remaining := cert.NotAfter.Sub(now)
+		if remaining > 0 && remaining < 30*24*time.Hour {
+			warnings = append(warnings, fmt.Sprintf("certificate %d (%s) expires in %d days", i, cert.Subject.CommonName, int(remaining.Hours()/24)))
+		}
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1, x509.MD5WithRSA, x509.MD2WithRSA:
+			if !(i == len(certs)-1 && cert.CheckSignatureFrom(cert) == nil) {
+				warnings = append(warnings, fmt.Sprintf("certificate %d (%s) uses the weak signature algorithm %s", i, cert.Subject.CommonName, cert.SignatureAlgorithm))
+			}
+		}
+	}
+
+	if len(certs) > 0 {
+		if key, ok := certs[0].PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+			warnings = append(warnings, fmt.Sprintf("leaf certificate uses a %d-bit RSA key; 2048 bits is the minimum", key.N.BitLen()))
+		}
+	}
+
+	return warnings
+}
+
+// describeCertificate extracts the inspection details for a certificate
+func describeCertificate(cert *x509.Certificate, now time.Time) CertificateInfo {
+	fingerprint := sha256.Sum256(cert.Raw)
+	info := CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SerialNumber:       formatSerial(cert.SerialNumber.Bytes()),
+		DNSNames:           cert.DNSNames,
+		EmailAddresses:     cert.EmailAddresses,
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysRemaining:      int(cert.NotAfter.Sub(now).Hours() / 24),
+		Expired:            now.After(cert.NotAfter),
+		NotYetValid:        now.Before(cert.NotBefore),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+		SelfSigned:         cert.CheckSignatureFrom(cert) == nil,
+		SHA256Fingerprint:  strings.ToUpper(hex.EncodeToString(fingerprint[:])),
+	}
+
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType = "RSA"
+		info.KeySize = key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType = "ECDSA"
+		info.KeySize = key.Curve.Params().BitSize
+		info.KeyCurve = key.Curve.Params().Name
+	case ed25519.PublicKey:
+		info.KeyType = "Ed25519"
+		info.KeySize = 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+
+	return info
+}
+
+// describeOCSPStaple parses a stapled OCSP response, if one was sent
+func describeOCSPStaple(staple []byte, certs []*x509.Certificate) *OCSPStapleInfo {
+	info := &OCSPStapleInfo{Stapled: len(staple) > 0}
+	if !info.Stapled {
+		return info
+	}
+
+	var issuer *x509.Certificate
+	if len(certs) > 1 {
+		issuer = certs[1]
+	}
+
+	resp, err := ocsp.ParseResponse(staple, issuer)
+	if err != nil {
+		info.Error = fmt.Sprintf("failed to parse OCSP response: %v", err)
+		return info
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		info.Status = "good"
+	case ocsp.Revoked:
+		info.Status = "revoked"
+		info.RevokedAt = resp.RevokedAt
+	default:
+		info.Status = "unknown"
+	}
+	info.ProducedAt = resp.ProducedAt
+	info.ThisUpdate = resp.ThisUpdate
+	info.NextUpdate = resp.NextUpdate
+
+	return info
+}
+
+// certificateNames lists the names a certificate is valid for
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// formatSerial renders a serial number as colon separated hex
+func formatSerial(b []byte) string {
+	if len(b) == 0 {
+		return "00"
+	}
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, ":")
+}
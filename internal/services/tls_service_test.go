@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// splitServerAddr returns the host and port of a test server
+func splitServerAddr(t *testing.T, server *httptest.Server) (string, string) {
+	t.Helper()
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func TestInspectTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := splitServerAddr(t, server)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	service := NewTLSInspectionServiceWithRoots(roots, true)
+
+	result, err := service.InspectTLS(context.Background(), TLSInspectRequest{Host: host, Port: port})
+	if err != nil {
+		t.Fatalf("InspectTLS: %v", err)
+	}
+	if result.Protocol != "TLS 1.3" {
+		t.Errorf("protocol = %q, want TLS 1.3", result.Protocol)
+	}
+	if len(result.Chain) != 1 {
+		t.Fatalf("chain length = %d, want 1", len(result.Chain))
+	}
+	if !result.Chain[0].SelfSigned || !result.Chain[0].IsCA {
+		t.Errorf("expected a self-signed CA leaf, got %+v", result.Chain[0])
+	}
+	if !result.Verification.Verified {
+		t.Errorf("expected chain to verify, errors: %v", result.Verification.Errors)
+	}
+	if result.OCSP == nil || result.OCSP.Stapled {
+		t.Errorf("expected no OCSP staple, got %+v", result.OCSP)
+	}
+}
+
+func TestInspectTLSUntrusted(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := splitServerAddr(t, server)
+
+	service := NewTLSInspectionServiceWithRoots(x509.NewCertPool(), true)
+	result, err := service.InspectTLS(context.Background(), TLSInspectRequest{Host: host, Port: port, SNI: "example.org"})
+	if err != nil {
+		t.Fatalf("InspectTLS: %v", err)
+	}
+	if result.Verification.Verified {
+		t.Fatal("expected verification to fail against an empty root pool")
+	}
+	if len(result.Verification.Errors) == 0 {
+		t.Error("expected a verification error explanation")
+	}
+}
+
+func TestScanTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+	host, port := splitServerAddr(t, server)
+
+	service := NewTLSInspectionServiceWithRoots(nil, true)
+	result, err := service.ScanTLS(context.Background(), TLSScanRequest{Host: host, Port: port})
+	if err != nil {
+		t.Fatalf("ScanTLS: %v", err)
+	}
+
+	supported := make(map[string]bool)
+	for _, protocol := range result.Protocols {
+		supported[protocol.Version] = protocol.Supported
+	}
+	want := map[string]bool{"TLS 1.3": true, "TLS 1.2": true, "TLS 1.1": false, "TLS 1.0": false}
+	for version, ok := range want {
+		if supported[version] != ok {
+			t.Errorf("%s supported = %v, want %v", version, supported[version], ok)
+		}
+	}
+	if result.Grade == "" || result.Grade == "F" {
+		t.Errorf("grade = %q, want a passing grade", result.Grade)
+	}
+}
+
+func TestTLSRefusesNonPublicTargets(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	host, port := splitServerAddr(t, server)
+
+	service := NewTLSInspectionService()
+	if _, err := service.InspectTLS(context.Background(), TLSInspectRequest{Host: host, Port: port}); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("InspectTLS error = %v, want ErrNonPublicAddress", err)
+	}
+	if _, err := service.ScanTLS(context.Background(), TLSScanRequest{Host: host, Port: port}); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("ScanTLS error = %v, want ErrNonPublicAddress", err)
+	}
+}
+
+func TestGradeTLSScan(t *testing.T) {
+	protocols := []TLSProtocolSupport{
+		{Version: "TLS 1.3", Supported: true, CipherSuites: []TLSCipherSuiteInfo{describeCipherSuite(tls.TLS_AES_128_GCM_SHA256)}},
+		{Version: "TLS 1.2", Supported: true, CipherSuites: []TLSCipherSuiteInfo{
+			describeCipherSuite(tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256),
+			describeCipherSuite(tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA),
+		}},
+	}
+	grade, findings := gradeTLSScan(protocols)
+	if grade != "C" {
+		t.Errorf("grade = %s, want C for an accepted 3DES suite", grade)
+	}
+	if last := findings[len(findings)-1]; last.Severity != "info" || last.Message != untestedSuitesNote {
+		t.Errorf("last finding = %+v, want the untested suites note", last)
+	}
+}
@@ -0,0 +1,814 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TOML 1.0 value patterns
+var (
+	tomlBareKey    = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tomlDecimalInt = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlPrefixInt  = regexp.MustCompile(`^0(x[0-9A-Fa-f](_?[0-9A-Fa-f])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+	tomlFloat      = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlDateTime   = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})?([Tt ]?)(\d{2}:\d{2}:\d{2}(\.\d+)?)?([Zz]|[+-]\d{2}:\d{2})?$`)
+)
+
+// tomlParser reads a TOML 1.0 document into ordered objects
+type tomlParser struct {
+	src     string
+	pos     int
+	line    int
+	root    *convObject
+	current *convObject
+	// tables declared with a [header], which may not be declared twice
+	declared map[*convObject]bool
+	// tables created by dotted keys, which a [header] may not declare
+	dotted map[*convObject]bool
+	// inline tables, which can no longer be extended
+	frozen map[*convObject]bool
+	// arrays created by [[header]] rather than by a value
+	tableArrays map[tomlArrayKey]bool
+}
+
+type tomlArrayKey struct {
+	parent *convObject
+	key    string
+}
+
+// parseTOML parses a TOML document
+func parseTOML(src string) (any, error) {
+	p := &tomlParser{
+		src:         strings.TrimPrefix(src, "\ufeff"),
+		line:        1,
+		root:        newConvObject(),
+		declared:    make(map[*convObject]bool),
+		dotted:      make(map[*convObject]bool),
+		frozen:      make(map[*convObject]bool),
+		tableArrays: make(map[tomlArrayKey]bool),
+	}
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return p.root, nil
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+		var err error
+		if p.src[p.pos] == '[' {
+			err = p.parseHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		if err := p.endLine(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipBlank skips whitespace, comments and newlines
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+}
+
+// endLine requires the rest of the line to be blank or a comment
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '#' {
+		p.skipComment()
+	}
+	if p.pos < len(p.src) && p.src[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return nil
+	}
+	if p.src[p.pos] != '\n' {
+		return fmt.Errorf("unexpected %q after value", p.src[p.pos])
+	}
+	return nil
+}
+
+func (p *tomlParser) parseHeader() error {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpace()
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return fmt.Errorf("expected %s to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(p.root, path[:len(path)-1], true)
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	existing, exists := parent.get(last)
+
+	if array {
+		table := newConvObject()
+		switch {
+		case !exists:
+			parent.set(last, []any{table})
+			p.tableArrays[tomlArrayKey{parent, last}] = true
+		case p.tableArrays[tomlArrayKey{parent, last}]:
+			parent.set(last, append(existing.([]any), table))
+		default:
+			return fmt.Errorf("cannot define %q as an array of tables; it is already defined", strings.Join(path, "."))
+		}
+		p.current = table
+		return nil
+	}
+
+	if !exists {
+		table := newConvObject()
+		parent.set(last, table)
+		p.declared[table] = true
+		p.current = table
+		return nil
+	}
+	table, ok := existing.(*convObject)
+	if !ok || p.declared[table] || p.frozen[table] || p.dotted[table] {
+		return fmt.Errorf("table %q is already defined", strings.Join(path, "."))
+	}
+	p.declared[table] = true
+	p.current = table
+	return nil
+}
+
+// descend walks a key path from obj, creating implicit tables. Headers may
+// step into the last table of an array of tables; dotted keys may not.
+func (p *tomlParser) descend(obj *convObject, path []string, header bool) (*convObject, error) {
+	for i, key := range path {
+		existing, ok := obj.get(key)
+		if !ok {
+			table := newConvObject()
+			obj.set(key, table)
+			if !header {
+				p.dotted[table] = true
+			}
+			obj = table
+			continue
+		}
+		switch v := existing.(type) {
+		case *convObject:
+			if p.frozen[v] || (!header && p.declared[v]) {
+				return nil, fmt.Errorf("cannot extend table %q", strings.Join(path[:i+1], "."))
+			}
+			obj = v
+		case []any:
+			if !header || !p.tableArrays[tomlArrayKey{obj, key}] {
+				return nil, fmt.Errorf("key %q is not a table", strings.Join(path[:i+1], "."))
+			}
+			obj = v[len(v)-1].(*convObject)
+		default:
+			return nil, fmt.Errorf("key %q is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return obj, nil
+}
+
+func (p *tomlParser) parseKeyValue(table *convObject) error {
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+		return fmt.Errorf("expected = after key %q", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipSpace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	parent, err := p.descend(table, path[:len(path)-1], false)
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, exists := parent.get(last); exists {
+		return fmt.Errorf("key %q is already defined", strings.Join(path, "."))
+	}
+	parent.set(last, value)
+	return nil
+}
+
+// parseKey reads a possibly dotted key
+func (p *tomlParser) parseKey() ([]string, error) {
+	var path []string
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("expected a key")
+		}
+		var key string
+		var err error
+		switch p.src[p.pos] {
+		case '"':
+			key, err = p.parseBasicString()
+		case '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for p.pos < len(p.src) && isTOMLBareKeyByte(p.src[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("invalid key character %q", p.src[p.pos])
+			}
+			key = p.src[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isTOMLBareKeyByte(b byte) bool {
+	return b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b == '_' || b == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("expected a value")
+	}
+	switch p.src[p.pos] {
+	case '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return p.parseMultilineString(`"""`)
+		}
+		return p.parseBasicString()
+	case '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return p.parseMultilineString("'''")
+		}
+		return p.parseLiteralString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_:+-.", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	// A date and time may be separated by a space
+	if p.pos-start == 10 && p.pos+3 < len(p.src) && p.src[p.pos] == ' ' && isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) && p.src[p.pos+3] == ':' {
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789:.+-Zz", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+	}
+	token := p.src[start:p.pos]
+	if token == "" {
+		return nil, fmt.Errorf("unexpected %q", p.src[p.pos])
+	}
+	return parseTOMLScalar(token)
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// parseTOMLScalar converts booleans, numbers and dates
+func parseTOMLScalar(token string) (any, error) {
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+
+	switch {
+	case tomlDecimalInt.MatchString(token):
+		digits := strings.TrimPrefix(strings.ReplaceAll(token, "_", ""), "+")
+		if _, err := strconv.ParseInt(digits, 10, 64); err != nil {
+			return nil, fmt.Errorf("integer %s out of range", token)
+		}
+		return json.Number(digits), nil
+	case tomlPrefixInt.MatchString(token):
+		n, err := strconv.ParseInt(token, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("integer %s out of range", token)
+		}
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	case tomlFloat.MatchString(token):
+		number := strings.TrimPrefix(strings.ReplaceAll(token, "_", ""), "+")
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return nil, fmt.Errorf("float %s out of range", token)
+		}
+		return json.Number(number), nil
+	}
+
+	if m := tomlDateTime.FindStringSubmatch(token); m != nil && (m[1] != "" || m[3] != "") {
+		date, sep, clock, offset := m[1], m[2], m[3], m[5]
+		localDate := date != "" && sep == "" && clock == "" && offset == ""
+		localTime := date == "" && sep == "" && clock != "" && offset == ""
+		dateTime := date != "" && sep != "" && clock != ""
+		valid := localDate || localTime || dateTime
+		if valid && date != "" {
+			_, err := time.Parse("2006-01-02", date)
+			valid = err == nil
+		}
+		if valid && clock != "" {
+			_, err := time.Parse("15:04:05", clock[:8])
+			valid = err == nil
+		}
+		if valid {
+			return convDateTime(token), nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return sb.String(), nil
+		case c == '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", fmt.Errorf("control character %U in string", rune(c))
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	start := p.pos
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == '\'' {
+			p.pos++
+			return p.src[start : p.pos-1], nil
+		}
+		if c < 0x20 && c != '\t' || c == 0x7f {
+			return "", fmt.Errorf("control character %U in string", rune(c))
+		}
+		p.pos++
+	}
+}
+
+// parseMultilineString reads multi-line basic and literal strings. A newline
+// right after the opening delimiter is trimmed, and in basic strings a
+// backslash at the end of a line trims the line break and following whitespace.
+func (p *tomlParser) parseMultilineString(delim string) (string, error) {
+	p.pos += 3
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") {
+		p.pos += 2
+		p.line++
+	} else if strings.HasPrefix(p.src[p.pos:], "\n") {
+		p.pos++
+		p.line++
+	}
+
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.src[p.pos:], delim) {
+			// Up to two quotes may directly precede the closing delimiter
+			quotes := 3
+			for quotes < 5 && p.pos+quotes < len(p.src) && p.src[p.pos+quotes] == delim[0] {
+				quotes++
+			}
+			sb.WriteString(p.src[p.pos : p.pos+quotes-3])
+			p.pos += quotes
+			return sb.String(), nil
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && delim == `"""`:
+			rest := p.pos + 1
+			for rest < len(p.src) && (p.src[rest] == ' ' || p.src[rest] == '\t') {
+				rest++
+			}
+			if rest < len(p.src) && (p.src[rest] == '\n' || strings.HasPrefix(p.src[rest:], "\r\n")) {
+				p.pos = rest
+				for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+					if p.src[p.pos] == '\n' {
+						p.line++
+					}
+					p.pos++
+				}
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\n':
+			p.line++
+			sb.WriteByte(c)
+			p.pos++
+		case c == '\r' && strings.HasPrefix(p.src[p.pos:], "\r\n"):
+			sb.WriteByte(c)
+			p.pos++
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return "", fmt.Errorf("control character %U in string", rune(c))
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	if p.pos+1 >= len(p.src) {
+		return fmt.Errorf("unterminated escape sequence")
+	}
+	c := p.src[p.pos+1]
+	p.pos += 2
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return fmt.Errorf("short \\%c escape", c)
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return fmt.Errorf("invalid escape \\%c%s", c, p.src[p.pos:p.pos+size])
+		}
+		sb.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseArray() (any, error) {
+	p.pos++
+	list := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.skipBlank()
+		if p.pos < len(p.src) && p.src[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.src) && p.src[p.pos] == ']' {
+			p.pos++
+			return list, nil
+		}
+		return nil, fmt.Errorf("expected , or ] in array")
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (any, error) {
+	p.pos++
+	table := newConvObject()
+	p.skipSpace()
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+		p.frozen[table] = true
+		return table, nil
+	}
+	for {
+		p.skipSpace()
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("unterminated inline table")
+		}
+		switch p.src[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.freeze(table)
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// freeze marks an inline table and the tables its dotted keys created as complete
+func (p *tomlParser) freeze(table *convObject) {
+	p.frozen[table] = true
+	for _, key := range table.keys {
+		if child, ok := table.values[key].(*convObject); ok {
+			p.freeze(child)
+		}
+	}
+}
+
+// writeTOML encodes an object as TOML. Nulls have no TOML representation and are dropped.
+func (c *converter) writeTOML(value any) ([]byte, error) {
+	obj, ok := value.(*convObject)
+	if !ok {
+		return nil, fmt.Errorf("TOML requires an object at the top level")
+	}
+	var buf bytes.Buffer
+	c.writeTOMLTable(&buf, nil, obj, false)
+	return bytes.TrimLeft(buf.Bytes(), "\n"), nil
+}
+
+func (c *converter) writeTOMLTable(buf *bytes.Buffer, path []string, obj *convObject, arrayElement bool) {
+	var tables []string
+	scalars := 0
+	for _, key := range obj.keys {
+		if isTOMLTable(obj.values[key]) || isTOMLTableArray(obj.values[key]) {
+			tables = append(tables, key)
+		} else if obj.values[key] != nil {
+			scalars++
+		}
+	}
+
+	if arrayElement {
+		fmt.Fprintf(buf, "\n[[%s]]\n", tomlPath(path))
+	} else if len(path) > 0 && (scalars > 0 || len(obj.keys) == 0) {
+		fmt.Fprintf(buf, "\n[%s]\n", tomlPath(path))
+	}
+
+	for _, key := range obj.keys {
+		value := obj.values[key]
+		if isTOMLTable(value) || isTOMLTableArray(value) {
+			continue
+		}
+		if value == nil {
+			c.warn("null value at %s dropped; TOML has no null", tomlPath(append(path, key)))
+			continue
+		}
+		buf.WriteString(tomlKey(key))
+		buf.WriteString(" = ")
+		c.writeTOMLValue(buf, append(path, key), value)
+		buf.WriteByte('\n')
+	}
+
+	for _, key := range tables {
+		childPath := append(append([]string{}, path...), key)
+		switch v := obj.values[key].(type) {
+		case *convObject:
+			c.writeTOMLTable(buf, childPath, v, false)
+		case []any:
+			for _, item := range v {
+				c.writeTOMLTable(buf, childPath, item.(*convObject), true)
+			}
+		}
+	}
+}
+
+func isTOMLTable(value any) bool {
+	_, ok := value.(*convObject)
+	return ok
+}
+
+// isTOMLTableArray reports whether a value is a non-empty array of objects
+func isTOMLTableArray(value any) bool {
+	list, ok := value.([]any)
+	if !ok || len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(*convObject); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *converter) writeTOMLValue(buf *bytes.Buffer, path []string, value any) {
+	switch v := value.(type) {
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		buf.WriteString(c.tomlNumber(path, v))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			buf.WriteString("nan")
+		case v > 0:
+			buf.WriteString("inf")
+		default:
+			buf.WriteString("-inf")
+		}
+	case convDateTime:
+		if _, err := parseTOMLScalar(string(v)); err == nil {
+			buf.WriteString(string(v))
+		} else {
+			c.warn("date %q at %s is not a TOML date; written as a string", string(v), tomlPath(path))
+			buf.WriteString(tomlString(string(v)))
+		}
+	case string:
+		buf.WriteString(tomlString(v))
+	case []any:
+		buf.WriteByte('[')
+		first := true
+		for i, item := range v {
+			if item == nil {
+				c.warn("null array element at %s[%d] dropped; TOML has no null", tomlPath(path), i)
+				continue
+			}
+			if !first {
+				buf.WriteString(", ")
+			}
+			first = false
+			c.writeTOMLValue(buf, path, item)
+		}
+		buf.WriteByte(']')
+	case *convObject:
+		buf.WriteByte('{')
+		first := true
+		for _, key := range v.keys {
+			if v.values[key] == nil {
+				c.warn("null value at %s dropped; TOML has no null", tomlPath(append(path, key)))
+				continue
+			}
+			if first {
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteString(", ")
+			}
+			first = false
+			buf.WriteString(tomlKey(key))
+			buf.WriteString(" = ")
+			c.writeTOMLValue(buf, append(path, key), v.values[key])
+		}
+		if !first {
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte('}')
+	}
+}
+
+// tomlNumber writes integers that fit in 64 bits as integers and everything else as floats
+func (c *converter) tomlNumber(path []string, n json.Number) string {
+	text := n.String()
+	if !strings.ContainsAny(text, ".eE") {
+		if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return text
+		}
+		c.warn("integer %s at %s exceeds the TOML range; written as a float", text, tomlPath(path))
+		return text + ".0"
+	}
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		c.warn("number %s at %s exceeds the float range; written as a string", text, tomlPath(path))
+		return tomlString(text)
+	}
+	return text
+}
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// tomlString writes a basic string, escaping quotes, backslashes and control characters
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
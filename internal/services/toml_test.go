@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+// tomlToJSON converts a TOML document to compact JSON
+func tomlToJSON(t *testing.T, src string) (string, error) {
+	t.Helper()
+	result, err := NewConvertService().Convert([]byte(src), ConvertOptions{From: "toml", To: "json"})
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, result.Output); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), nil
+}
+
+// tomlDump renders a parsed value with its types, so dates and strings differ
+func tomlDump(value any) string {
+	switch v := value.(type) {
+	case *convObject:
+		parts := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			parts[i] = fmt.Sprintf("%q:%s", key, tomlDump(v.values[key]))
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = tomlDump(item)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case convDateTime:
+		return "date(" + string(v) + ")"
+	case json.Number:
+		return "num(" + v.String() + ")"
+	case float64:
+		return fmt.Sprintf("float(%v)", v)
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// Examples from the TOML 1.0 specification
+var tomlSpecExamples = []struct {
+	name string
+	toml string
+	json string
+}{
+	{"document", `# This is a TOML document
+
+title = "TOML Example"
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00-08:00
+
+[database]
+enabled = true
+ports = [ 8000, 8001, 8002 ]
+data = [ ["delta", "phi"], [3.14] ]
+temp_targets = { cpu = 79.5, case = 72.0 }
+
+[servers]
+
+[servers.alpha]
+ip = "10.0.0.1"
+role = "frontend"
+
+[servers.beta]
+ip = "10.0.0.2"
+role = "backend"
+`, `{"title":"TOML Example","owner":{"name":"Tom Preston-Werner","dob":"1979-05-27T07:32:00-08:00"},"database":{"enabled":true,"ports":[8000,8001,8002],"data":[["delta","phi"],[3.14]],"temp_targets":{"cpu":79.5,"case":72.0}},"servers":{"alpha":{"ip":"10.0.0.1","role":"frontend"},"beta":{"ip":"10.0.0.2","role":"backend"}}}`},
+	{"keys", `key = "value"
+bare_key = "value"
+bare-key = "value"
+1234 = "value"
+"127.0.0.1" = "value"
+"character encoding" = "value"
+"ʎǝʞ" = "value"
+'key2' = "value"
+'quoted "value"' = "value"
+"" = "blank"
+`, `{"key":"value","bare_key":"value","bare-key":"value","1234":"value","127.0.0.1":"value","character encoding":"value","ʎǝʞ":"value","key2":"value","quoted \"value\"":"value","":"blank"}`},
+	{"dotted keys", `name = "Orange"
+physical.color = "orange"
+physical.shape = "round"
+site."google.com" = true
+fruit . flavor = "banana"
+3.14159 = "pi"
+`, `{"name":"Orange","physical":{"color":"orange","shape":"round"},"site":{"google.com":true},"fruit":{"flavor":"banana"},"3":{"14159":"pi"}}`},
+	{"basic strings", `str = "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."
+emoji = "\U0001F600"
+`, `{"str":"I'm a string. \"You can quote me\". Name\tJosé\nLocation\tSF.","emoji":"😀"}`},
+	{"multi-line strings", `str1 = """
+Roses are red
+Violets are blue"""
+str2 = """
+The quick brown \
+
+
+  fox jumps over \
+    the lazy dog."""
+str3 = """Here are two quotation marks: "". Simple enough."""
+str4 = """Here are fifteen quotation marks: ""\"""\"""\"""\"""\"."""
+str5 = """"This," she said, "is just a pointless statement.""""
+`, `{"str1":"Roses are red\nViolets are blue","str2":"The quick brown fox jumps over the lazy dog.","str3":"Here are two quotation marks: \"\". Simple enough.","str4":"Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\".","str5":"\"This,\" she said, \"is just a pointless statement.\""}`},
+	{"literal strings", `winpath  = 'C:\Users\nodejs\templates'
+quoted   = 'Tom "Dubs" Preston-Werner'
+regex    = '<\i\c*\s*>'
+regex2 = '''I [dw]on't need \d{2} apples'''
+lines  = '''
+The first newline is
+trimmed in raw strings.
+'''
+quot15 = '''Here are fifteen quotation marks: """""""""""""""'''
+apos15 = "Here are fifteen apostrophes: '''''''''''''''"
+str = ''''That,' she said, 'is still pointless.''''
+`, `{"winpath":"C:\\Users\\nodejs\\templates","quoted":"Tom \"Dubs\" Preston-Werner","regex":"<\\i\\c*\\s*>","regex2":"I [dw]on't need \\d{2} apples","lines":"The first newline is\ntrimmed in raw strings.\n","quot15":"Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\"","apos15":"Here are fifteen apostrophes: '''''''''''''''","str":"'That,' she said, 'is still pointless.'"}`},
+	{"integers", `int1 = +99
+int2 = 42
+int3 = 0
+int4 = -17
+int5 = 1_000
+int6 = 5_349_221
+int7 = 53_49_221
+int8 = 1_2_3_4_5
+hex1 = 0xDEADBEEF
+hex2 = 0xdeadbeef
+hex3 = 0xdead_beef
+oct1 = 0o01234567
+oct2 = 0o755
+bin1 = 0b11010110
+max = 9_223_372_036_854_775_807
+min = -9_223_372_036_854_775_808
+`, `{"int1":99,"int2":42,"int3":0,"int4":-17,"int5":1000,"int6":5349221,"int7":5349221,"int8":12345,"hex1":3735928559,"hex2":3735928559,"hex3":3735928559,"oct1":342391,"oct2":493,"bin1":214,"max":9223372036854775807,"min":-9223372036854775808}`},
+	{"floats", `flt1 = +1.0
+flt2 = 3.1415
+flt3 = -0.01
+flt4 = 5e+22
+flt5 = 1e06
+flt6 = -2E-2
+flt7 = 6.626e-34
+flt8 = 224_617.445_991_228
+`, `{"flt1":1.0,"flt2":3.1415,"flt3":-0.01,"flt4":5e+22,"flt5":1e06,"flt6":-2E-2,"flt7":6.626e-34,"flt8":224617.445991228}`},
+	{"dates", `odt1 = 1979-05-27T07:32:00Z
+odt2 = 1979-05-27T00:32:00-07:00
+odt3 = 1979-05-27T00:32:00.999999-07:00
+odt4 = 1979-05-27 07:32:00Z
+ldt1 = 1979-05-27T07:32:00
+ldt2 = 1979-05-27T00:32:00.999999
+ld1 = 1979-05-27
+lt1 = 07:32:00
+lt2 = 00:32:00.999999
+`, `{"odt1":"1979-05-27T07:32:00Z","odt2":"1979-05-27T00:32:00-07:00","odt3":"1979-05-27T00:32:00.999999-07:00","odt4":"1979-05-27 07:32:00Z","ldt1":"1979-05-27T07:32:00","ldt2":"1979-05-27T00:32:00.999999","ld1":"1979-05-27","lt1":"07:32:00","lt2":"00:32:00.999999"}`},
+	{"arrays", `integers = [ 1, 2, 3 ]
+colors = [ "red", "yellow", "green" ]
+nested_arrays_of_ints = [ [ 1, 2 ], [3, 4, 5] ]
+nested_mixed_array = [ [ 1, 2 ], ["a", "b", "c"] ]
+string_array = [ "all", 'strings', """are the same""", '''type''' ]
+numbers = [ 0.1, 0.2, 0.5, 1, 2, 5 ]
+contributors = [
+  "Foo Bar <foo@example.com>",
+  { name = "Baz Qux", email = "bazqux@example.com", url = "https://example.com/bazqux" }
+]
+integers2 = [
+  1, 2, 3
+]
+integers3 = [
+  1,
+  2, # this is ok
+]
+`, `{"integers":[1,2,3],"colors":["red","yellow","green"],"nested_arrays_of_ints":[[1,2],[3,4,5]],"nested_mixed_array":[[1,2],["a","b","c"]],"string_array":["all","strings","are the same","type"],"numbers":[0.1,0.2,0.5,1,2,5],"contributors":["Foo Bar <foo@example.com>",{"name":"Baz Qux","email":"bazqux@example.com","url":"https://example.com/bazqux"}],"integers2":[1,2,3],"integers3":[1,2]}`},
+	{"tables", `[table-1]
+key1 = "some string"
+key2 = 123
+
+[table-2]
+key1 = "another string"
+key2 = 456
+
+[dog."tater.man"]
+type.name = "pug"
+
+[x.y.z.w]
+[x]
+
+[fruit]
+apple.color = "red"
+apple.taste.sweet = true
+
+[fruit.apple.texture]
+smooth = true
+`, `{"table-1":{"key1":"some string","key2":123},"table-2":{"key1":"another string","key2":456},"dog":{"tater.man":{"type":{"name":"pug"}}},"x":{"y":{"z":{"w":{}}}},"fruit":{"apple":{"color":"red","taste":{"sweet":true},"texture":{"smooth":true}}}}`},
+	{"inline tables", `name = { first = "Tom", last = "Preston-Werner" }
+point = { x = 1, y = 2 }
+animal = { type.name = "pug" }
+empty = {}
+`, `{"name":{"first":"Tom","last":"Preston-Werner"},"point":{"x":1,"y":2},"animal":{"type":{"name":"pug"}},"empty":{}}`},
+	{"arrays of tables", `[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]  # empty table within the array
+
+[[products]]
+name = "Nail"
+sku = 284758393
+
+color = "gray"
+
+[[fruits]]
+name = "apple"
+
+[fruits.physical]  # subtable
+color = "red"
+shape = "round"
+
+[[fruits.varieties]]  # nested array of tables
+name = "red delicious"
+
+[[fruits.varieties]]
+name = "granny smith"
+
+[[fruits]]
+name = "banana"
+
+[[fruits.varieties]]
+name = "plantain"
+
+points = [ { x = 1, y = 2, z = 3 },
+           { x = 7, y = 8, z = 9 } ]
+`, `{"products":[{"name":"Hammer","sku":738594937},{},{"name":"Nail","sku":284758393,"color":"gray"}],"fruits":[{"name":"apple","physical":{"color":"red","shape":"round"},"varieties":[{"name":"red delicious"},{"name":"granny smith"}]},{"name":"banana","varieties":[{"name":"plantain","points":[{"x":1,"y":2,"z":3},{"x":7,"y":8,"z":9}]}]}]}`},
+}
+
+func TestParseTOMLSpecExamples(t *testing.T) {
+	for _, tt := range tomlSpecExamples {
+		got, err := tomlToJSON(t, tt.toml)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.json {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.json)
+		}
+	}
+}
+
+// Writing a parsed document and parsing it again gives the same values and types
+func TestTOMLRoundTrip(t *testing.T) {
+	for _, tt := range tomlSpecExamples {
+		first, err := parseTOML(tt.toml)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		c := &converter{}
+		written, err := c.writeTOML(first)
+		if err != nil {
+			t.Errorf("%s: write: %v", tt.name, err)
+			continue
+		}
+		second, err := parseTOML(string(written))
+		if err != nil {
+			t.Errorf("%s: reparse: %v\n%s", tt.name, err, written)
+			continue
+		}
+		if tomlDump(first) != tomlDump(second) {
+			t.Errorf("%s: round trip changed the document:\n%s\n%s\n%s", tt.name, written, tomlDump(first), tomlDump(second))
+		}
+	}
+}
+
+func TestParseTOMLSpecialFloats(t *testing.T) {
+	value, err := parseTOML("a = inf\nb = +inf\nc = -inf\nd = nan\ne = -nan\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := value.(*convObject)
+	if obj.values["a"] != math.Inf(1) || obj.values["b"] != math.Inf(1) || obj.values["c"] != math.Inf(-1) {
+		t.Errorf("infinities = %v %v %v", obj.values["a"], obj.values["b"], obj.values["c"])
+	}
+	for _, key := range []string{"d", "e"} {
+		if f, ok := obj.values[key].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s = %v, want nan", key, obj.values[key])
+		}
+	}
+
+	written, err := (&converter{}).writeTOML(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = inf\nb = inf\nc = -inf\nd = nan\ne = nan\n"; string(written) != want {
+		t.Errorf("written:\n%s\nwant:\n%s", written, want)
+	}
+}
+
+// Documents the specification marks as invalid
+func TestParseTOMLInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+	}{
+		{"key without value", "key = # INVALID\n"},
+		{"two pairs on a line", "first = \"Tom\" last = \"Preston-Werner\"\n"},
+		{"empty bare key", "= \"no key name\"\n"},
+		{"duplicate key", "name = \"Tom\"\nname = \"Pradyun\"\n"},
+		{"duplicate quoted key", "spelling = \"favorite\"\n\"spelling\" = \"favourite\"\n"},
+		{"value extended by dotted key", "fruit.apple = 1\nfruit.apple.smooth = true\n"},
+		{"bad escape", "str = \"C:\\Users\"\n"},
+		{"unterminated string", "str = \"abc\n"},
+		{"newline in literal string", "str = 'abc\ndef'\n"},
+		{"leading zero", "int = 0123\n"},
+		{"leading zero float", "flt = 03.14\n"},
+		{"double underscore", "int = 1__000\n"},
+		{"trailing underscore", "int = 1_000_\n"},
+		{"dot without digits", "flt = .7\n"},
+		{"trailing dot", "flt = 7.\n"},
+		{"exponent dot", "flt = 3.e+20\n"},
+		{"integer overflow", "int = 9_223_372_036_854_775_808\n"},
+		{"invalid date", "d = 1979-02-30\n"},
+		{"invalid time", "t = 24:00:00\n"},
+		{"duplicate table", "[fruit]\napple = \"red\"\n\n[fruit]\norange = \"orange\"\n"},
+		{"table over value", "[fruit]\napple = \"red\"\n\n[fruit.apple]\ntexture = \"smooth\"\n"},
+		{"header over dotted table", "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n\n[fruit.apple]\n"},
+		{"header over dotted subtable", "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n\n[fruit.apple.taste]\n"},
+		{"extend inline table", "[product]\ntype = { name = \"Nail\" }\ntype.edible = false\n"},
+		{"redefine dotted table inline", "[product]\ntype.name = \"Nail\"\ntype = { edible = false }\n"},
+		{"inline table across lines", "point = { x = 1,\n y = 2 }\n"},
+		{"inline table trailing comma", "point = { x = 1, }\n"},
+		{"append to static array", "fruits = []\n\n[[fruits]]\n"},
+		{"table over array of tables", "[[fruits]]\nname = \"apple\"\n\n[fruits]\n"},
+		{"array of tables over table", "[fruit.physical]\ncolor = \"red\"\n\n[[fruit.physical]]\n"},
+		{"unclosed header", "[table\n"},
+		{"unterminated array", "a = [1, 2\n"},
+	}
+	for _, tt := range tests {
+		if value, err := parseTOML(tt.toml); err == nil {
+			t.Errorf("%s: parsed as %s, want an error", tt.name, tomlDump(value))
+		}
+	}
+}
+
+func TestWriteTOML(t *testing.T) {
+	input := `{"title":"x","empty":{},"list":[],"nulls":[1,null],"nested":{"a":{"b":1},"items":[{"k":"v"},{"k":"w","sub":{"z":true}}]},"quoted key":"\u0001\"\\","skipped":null}`
+	result, err := NewConvertService().Convert([]byte(input), ConvertOptions{From: "json", To: "toml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title = "x"
+list = []
+nulls = [1]
+"quoted key" = "\u0001\"\\"
+
+[empty]
+
+[nested.a]
+b = 1
+
+[[nested.items]]
+k = "v"
+
+[[nested.items]]
+k = "w"
+
+[nested.items.sub]
+z = true
+`
+	if string(result.Output) != want {
+		t.Errorf("output:\n%s\nwant:\n%s", result.Output, want)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("warnings = %v, want the two dropped nulls", result.Warnings)
+	}
+	if _, err := NewConvertService().Convert([]byte(`[1]`), ConvertOptions{From: "json", To: "toml"}); err == nil {
+		t.Error("top-level array written as TOML")
+	}
+}
@@ -0,0 +1,1061 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	zoneMaxIncludeDepth = 10
+	zoneMaxRecords      = 50000
+	// zoneMaxInputBytes bounds the text read for one zone, counting an
+	// $INCLUDE file again every time it is included and at least
+	// zoneMinReadCost for each read, so tiny files cannot be included millions of times
+	zoneMaxInputBytes = 64 << 20
+	zoneMinReadCost   = 4096
+)
+
+// ZoneService parses and lints RFC 1035 master (zone) files
+type ZoneService struct {
+	dns *IPAnalysisService
+}
+
+// NewZoneService creates a new zone file service using dns for external target checks
+func NewZoneService(dns *IPAnalysisService) *ZoneService {
+	return &ZoneService{dns: dns}
+}
+
+// ZoneParseRequest represents a zone file to parse and lint
+type ZoneParseRequest struct {
+	Content        string            `json:"content"`
+	Origin         string            `json:"origin,omitempty"`
+	DefaultTTL     int               `json:"default_ttl,omitempty"`
+	Includes       map[string]string `json:"includes,omitempty"`        // $INCLUDE file name -> contents
+	ResolveTargets bool              `json:"resolve_targets,omitempty"` // Resolve out-of-zone MX/NS targets
+}
+
+// ZoneRecord is a DNS record parsed from a zone file along with its source position
+type ZoneRecord struct {
+	DNSRecord
+	Class string `json:"class"`
+	File  string `json:"file,omitempty"`
+	Line  int    `json:"line"`
+}
+
+// ZoneIssue represents a syntax error or lint finding in a zone file
+type ZoneIssue struct {
+	Severity string `json:"severity"` // "error", "warning", "info"
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+	Name     string `json:"name,omitempty"`
+	Type     string `json:"type,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// ZoneParseResult represents the parsed records and lint findings for a zone file
+type ZoneParseResult struct {
+	Origin    string         `json:"origin"`
+	Records   []ZoneRecord   `json:"records"`
+	Issues    []ZoneIssue    `json:"issues"`
+	Summary   map[string]int `json:"summary"`
+	Errors    int            `json:"errors"`
+	Warnings  int            `json:"warnings"`
+	Valid     bool           `json:"valid"`
+	Timestamp time.Time      `json:"timestamp"`
+	ParseTime int            `json:"parse_time_ms"`
+}
+
+// zoneToken is a single field of a zone file entry
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+// zoneEntry is one logical line of a zone file with parentheses folded
+type zoneEntry struct {
+	tokens []zoneToken
+	line   int
+	indent bool // Entry starts with whitespace and inherits the previous owner
+}
+
+// zoneParser holds the state carried between entries while reading a zone file
+type zoneParser struct {
+	includes   map[string]string
+	origin     string
+	apex       string
+	ttl        int // $TTL, or -1 when unset
+	lastTTL    int
+	defaultTTL int
+	lastOwner  string
+	lastClass  string
+	file       string
+	stack      []string
+	records    []ZoneRecord
+	issues     []ZoneIssue
+	ctx        context.Context
+	read       int   // bytes read so far, including every $INCLUDE
+	err        error // input budget exhausted or request cancelled
+}
+
+// ParseZone parses a zone file and lints the resulting records
+func (s *ZoneService) ParseZone(ctx context.Context, req ZoneParseRequest) (*ZoneParseResult, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("zone content cannot be empty")
+	}
+
+	start := time.Now()
+	p := &zoneParser{
+		includes:   req.Includes,
+		ttl:        -1,
+		lastTTL:    -1,
+		defaultTTL: req.DefaultTTL,
+		lastClass:  "IN",
+		ctx:        ctx,
+	}
+	if origin := strings.TrimSpace(req.Origin); origin != "" {
+		if !strings.HasSuffix(origin, ".") {
+			origin += "."
+		}
+		p.origin = strings.ToLower(origin)
+		p.apex = p.origin
+	}
+
+	p.parse(req.Content)
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.lint()
+	if req.ResolveTargets && s.dns != nil {
+		s.checkExternalTargets(ctx, p)
+	}
+
+	result := &ZoneParseResult{
+		Origin:    p.apex,
+		Records:   p.records,
+		Issues:    p.issues,
+		Summary:   make(map[string]int),
+		Timestamp: start,
+	}
+	if result.Records == nil {
+		result.Records = []ZoneRecord{}
+	}
+	if result.Issues == nil {
+		result.Issues = []ZoneIssue{}
+	}
+	for _, rec := range p.records {
+		result.Summary[rec.Type]++
+	}
+	for _, issue := range p.issues {
+		switch issue.Severity {
+		case "error":
+			result.Errors++
+		case "warning":
+			result.Warnings++
+		}
+	}
+	result.Valid = result.Errors == 0
+	result.ParseTime = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
+// tokenizeZone splits zone file content into entries, folding parenthesized
+// continuations and stripping comments. It stops when ctx is cancelled.
+func tokenizeZone(ctx context.Context, content string) ([]zoneEntry, []ZoneIssue, error) {
+	var entries []zoneEntry
+	var issues []ZoneIssue
+
+	line := 1
+	parens := 0
+	parenLine := 0
+	current := zoneEntry{line: 1}
+	atLineStart := true
+	var token strings.Builder
+	inToken, inQuote := false, false
+	quoteLine := 0
+
+	flushToken := func(quoted bool) {
+		if inToken || quoted {
+			current.tokens = append(current.tokens, zoneToken{text: token.String(), quoted: quoted})
+		}
+		token.Reset()
+		inToken = false
+	}
+	flushEntry := func() {
+		if len(current.tokens) > 0 {
+			entries = append(entries, current)
+		}
+		current = zoneEntry{line: line}
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		if inQuote {
+			switch c {
+			case '\\':
+				token.WriteByte(c)
+				if i+1 < len(content) {
+					i++
+					if content[i] == '\n' {
+						line++
+					}
+					token.WriteByte(content[i])
+				}
+			case '"':
+				inQuote = false
+				flushToken(true)
+			case '\n':
+				line++
+				token.WriteByte(c)
+			default:
+				token.WriteByte(c)
+			}
+			continue
+		}
+
+		if atLineStart {
+			atLineStart = false
+			if parens == 0 && (c == ' ' || c == '\t') {
+				current.indent = true
+			}
+		}
+
+		switch c {
+		case ';':
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case '\\':
+			inToken = true
+			token.WriteByte(c)
+			if i+1 < len(content) {
+				i++
+				token.WriteByte(content[i])
+			}
+		case '"':
+			flushToken(false)
+			inQuote = true
+			quoteLine = line
+		case '(':
+			flushToken(false)
+			if parens == 0 {
+				parenLine = line
+			}
+			parens++
+		case ')':
+			flushToken(false)
+			if parens == 0 {
+				issues = append(issues, ZoneIssue{Severity: "error", Rule: "syntax", Message: "unbalanced closing parenthesis", Line: line})
+			} else {
+				parens--
+			}
+		case ' ', '\t', '\r':
+			flushToken(false)
+		case '\n':
+			if err := ctx.Err(); err != nil {
+				return nil, nil, err
+			}
+			flushToken(false)
+			line++
+			atLineStart = true
+			if parens == 0 {
+				flushEntry()
+			}
+		default:
+			inToken = true
+			token.WriteByte(c)
+		}
+	}
+
+	if inQuote {
+		issues = append(issues, ZoneIssue{Severity: "error", Rule: "syntax", Message: "unterminated quoted string", Line: quoteLine})
+		flushToken(true)
+	} else {
+		flushToken(false)
+	}
+	if parens > 0 {
+		issues = append(issues, ZoneIssue{Severity: "error", Rule: "syntax", Message: "unbalanced opening parenthesis", Line: parenLine})
+	}
+	flushEntry()
+
+	return entries, issues, nil
+}
+
+// parse reads content as the current file, recursing into $INCLUDE directives
+func (p *zoneParser) parse(content string) {
+	if p.err != nil {
+		return
+	}
+	if p.read += max(len(content), zoneMinReadCost); p.read > zoneMaxInputBytes {
+		p.err = fmt.Errorf("zone and its $INCLUDE files exceed %d bytes of input; check for files included many times", zoneMaxInputBytes)
+		return
+	}
+	entries, issues, err := tokenizeZone(p.ctx, content)
+	if err != nil {
+		p.err = err
+		return
+	}
+	for i := range issues {
+		issues[i].File = p.file
+	}
+	p.issues = append(p.issues, issues...)
+
+	for _, entry := range entries {
+		if p.err != nil {
+			return
+		}
+		if len(p.records) >= zoneMaxRecords {
+			p.addIssue("error", "limit", fmt.Sprintf("zone exceeds %d records; remaining entries were not parsed", zoneMaxRecords), "", "", entry.line)
+			return
+		}
+
+		first := entry.tokens[0]
+		if !entry.indent && !first.quoted && strings.HasPrefix(first.text, "$") {
+			p.directive(entry)
+			continue
+		}
+		p.record(entry)
+	}
+}
+
+// directive handles $ORIGIN, $TTL, $INCLUDE and $GENERATE control entries
+func (p *zoneParser) directive(entry zoneEntry) {
+	name := strings.ToUpper(entry.tokens[0].text)
+	args := entry.tokens[1:]
+
+	switch name {
+	case "$ORIGIN":
+		if len(args) != 1 {
+			p.addIssue("error", "syntax", "$ORIGIN requires exactly one domain name", "", "", entry.line)
+			return
+		}
+		origin, err := p.qualify(args[0].text)
+		if err != nil {
+			p.addIssue("error", "syntax", fmt.Sprintf("invalid $ORIGIN: %v", err), "", "", entry.line)
+			return
+		}
+		p.origin = origin
+		if p.apex == "" {
+			p.apex = origin
+		}
+	case "$TTL":
+		if len(args) != 1 {
+			p.addIssue("error", "syntax", "$TTL requires exactly one value", "", "", entry.line)
+			return
+		}
+		ttl, err := parseZoneTTL(args[0].text)
+		if err != nil {
+			p.addIssue("error", "syntax", fmt.Sprintf("invalid $TTL: %v", err), "", "", entry.line)
+			return
+		}
+		p.ttl = ttl
+	case "$INCLUDE":
+		if len(args) < 1 || len(args) > 2 {
+			p.addIssue("error", "syntax", "$INCLUDE requires a file name and an optional origin", "", "", entry.line)
+			return
+		}
+		p.include(args, entry.line)
+	case "$GENERATE":
+		p.addIssue("warning", "unsupported", "$GENERATE is not supported; generated records were not checked", "", "", entry.line)
+	default:
+		p.addIssue("error", "syntax", fmt.Sprintf("unknown directive %s", entry.tokens[0].text), "", "", entry.line)
+	}
+}
+
+// include parses an $INCLUDE file from the provided includes, restoring the
+// origin afterwards as required by RFC 1035 section 5.1
+func (p *zoneParser) include(args []zoneToken, line int) {
+	fileName := args[0].text
+	if len(p.stack) >= zoneMaxIncludeDepth {
+		p.addIssue("error", "include", fmt.Sprintf("$INCLUDE nesting exceeds %d levels", zoneMaxIncludeDepth), "", "", line)
+		return
+	}
+	for _, open := range p.stack {
+		if open == fileName {
+			p.addIssue("error", "include", fmt.Sprintf("$INCLUDE loop detected for %s", fileName), "", "", line)
+			return
+		}
+	}
+	content, ok := p.includes[fileName]
+	if !ok {
+		p.addIssue("error", "include", fmt.Sprintf("included file %s was not provided", fileName), "", "", line)
+		return
+	}
+
+	savedOrigin, savedFile := p.origin, p.file
+	if len(args) == 2 {
+		origin, err := p.qualify(args[1].text)
+		if err != nil {
+			p.addIssue("error", "syntax", fmt.Sprintf("invalid $INCLUDE origin: %v", err), "", "", line)
+			return
+		}
+		p.origin = origin
+	}
+
+	p.stack = append(p.stack, fileName)
+	p.file = fileName
+	p.parse(content)
+	p.stack = p.stack[:len(p.stack)-1]
+	p.origin, p.file = savedOrigin, savedFile
+}
+
+// record parses a resource record entry: [owner] [ttl] [class] type rdata
+func (p *zoneParser) record(entry zoneEntry) {
+	tokens := entry.tokens
+	idx := 0
+
+	var owner string
+	if entry.indent {
+		if p.lastOwner == "" {
+			p.addIssue("error", "syntax", "record has no owner name and there is no previous owner", "", "", entry.line)
+			return
+		}
+		owner = p.lastOwner
+	} else {
+		var err error
+		owner, err = p.qualify(tokens[0].text)
+		if err != nil {
+			p.addIssue("error", "syntax", fmt.Sprintf("invalid owner name: %v", err), tokens[0].text, "", entry.line)
+			return
+		}
+		p.checkTrailingDot("owner name", tokens[0].text, owner, "", entry.line)
+		idx = 1
+	}
+	p.lastOwner = owner
+
+	ttl := -1
+	class := ""
+	for idx < len(tokens) {
+		text := strings.ToUpper(tokens[idx].text)
+		if class == "" && isZoneClass(text) {
+			class = text
+			idx++
+			continue
+		}
+		if ttl < 0 && text != "" && text[0] >= '0' && text[0] <= '9' {
+			value, err := parseZoneTTL(text)
+			if err != nil {
+				p.addIssue("error", "syntax", fmt.Sprintf("invalid TTL: %v", err), owner, "", entry.line)
+				return
+			}
+			ttl = value
+			idx++
+			continue
+		}
+		break
+	}
+	if idx >= len(tokens) {
+		p.addIssue("error", "syntax", "missing record type", owner, "", entry.line)
+		return
+	}
+
+	rrtype := strings.ToUpper(tokens[idx].text)
+	if !isZoneType(rrtype) {
+		p.addIssue("error", "syntax", fmt.Sprintf("unknown record type %q", tokens[idx].text), owner, "", entry.line)
+		return
+	}
+
+	if class == "" {
+		class = p.lastClass
+	}
+	p.lastClass = class
+
+	value, err := p.rdata(rrtype, tokens[idx+1:], owner, entry.line)
+	if err != nil {
+		p.addIssue("error", "syntax", fmt.Sprintf("invalid %s record: %v", rrtype, err), owner, rrtype, entry.line)
+		return
+	}
+
+	if ttl >= 0 {
+		p.lastTTL = ttl
+	} else {
+		switch {
+		case p.ttl >= 0:
+			ttl = p.ttl
+		case p.lastTTL >= 0:
+			ttl = p.lastTTL
+		case rrtype == "SOA":
+			// BIND falls back to the SOA minimum field when no $TTL is set
+			fields := strings.Fields(value)
+			ttl, _ = strconv.Atoi(fields[len(fields)-1])
+			p.lastTTL = ttl
+			p.addIssue("warning", "missing-ttl", "no $TTL directive; using the SOA minimum as the default TTL", owner, rrtype, entry.line)
+		case p.defaultTTL > 0:
+			ttl = p.defaultTTL
+		default:
+			p.addIssue("error", "missing-ttl", "record has no TTL and no $TTL or previous TTL is available", owner, rrtype, entry.line)
+			ttl = 0
+		}
+	}
+
+	if p.apex == "" {
+		p.apex = owner
+	}
+
+	p.records = append(p.records, ZoneRecord{
+		DNSRecord: DNSRecord{
+			Name:  owner,
+			Type:  rrtype,
+			Value: value,
+			TTL:   ttl,
+		},
+		Class: class,
+		File:  p.file,
+		Line:  entry.line,
+	})
+}
+
+// rdata validates and normalizes the record data for rrtype, qualifying any
+// domain names against the current origin
+func (p *zoneParser) rdata(rrtype string, tokens []zoneToken, owner string, line int) (string, error) {
+	// Generic RFC 3597 encoding is accepted for any type
+	if len(tokens) > 0 && tokens[0].text == `\#` && !tokens[0].quoted {
+		return joinZoneTokens(tokens), nil
+	}
+
+	name := func(field string, tok zoneToken) (string, error) {
+		if _, err := netip.ParseAddr(strings.TrimSuffix(tok.text, ".")); err == nil && (rrtype == "MX" || rrtype == "NS" || rrtype == "CNAME" || rrtype == "SRV") {
+			p.addIssue("error", "target-is-address", fmt.Sprintf("%s target %s is an IP address; %s records must point to a host name", rrtype, tok.text, rrtype), owner, rrtype, line)
+		}
+		qualified, err := p.qualify(tok.text)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", field, err)
+		}
+		p.checkTrailingDot(rrtype+" "+field, tok.text, qualified, rrtype, line)
+		return qualified, nil
+	}
+
+	switch rrtype {
+	case "A", "AAAA":
+		if len(tokens) != 1 {
+			return "", fmt.Errorf("expected one address, got %d fields", len(tokens))
+		}
+		addr, err := netip.ParseAddr(tokens[0].text)
+		if err != nil || (rrtype == "A") != addr.Is4() {
+			return "", fmt.Errorf("%q is not a valid %s address", tokens[0].text, map[string]string{"A": "IPv4", "AAAA": "IPv6"}[rrtype])
+		}
+		return addr.String(), nil
+
+	case "NS", "CNAME", "PTR", "DNAME":
+		if len(tokens) != 1 {
+			return "", fmt.Errorf("expected one domain name, got %d fields", len(tokens))
+		}
+		return name("target", tokens[0])
+
+	case "MX", "AFSDB":
+		if len(tokens) != 2 {
+			return "", fmt.Errorf("expected preference and host, got %d fields", len(tokens))
+		}
+		pref, err := parseZoneUint(tokens[0].text, 16)
+		if err != nil {
+			return "", fmt.Errorf("preference: %v", err)
+		}
+		host, err := name("target", tokens[1])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s", pref, host), nil
+
+	case "SRV":
+		if len(tokens) != 4 {
+			return "", fmt.Errorf("expected priority, weight, port and target, got %d fields", len(tokens))
+		}
+		var nums [3]uint64
+		for i, field := range []string{"priority", "weight", "port"} {
+			value, err := parseZoneUint(tokens[i].text, 16)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", field, err)
+			}
+			nums[i] = value
+		}
+		target, err := name("target", tokens[3])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d %s", nums[0], nums[1], nums[2], target), nil
+
+	case "SOA":
+		if len(tokens) != 7 {
+			return "", fmt.Errorf("expected 7 fields (mname rname serial refresh retry expire minimum), got %d", len(tokens))
+		}
+		mname, err := name("mname", tokens[0])
+		if err != nil {
+			return "", err
+		}
+		rname, err := name("rname", tokens[1])
+		if err != nil {
+			return "", err
+		}
+		serial, err := parseZoneUint(tokens[2].text, 32)
+		if err != nil {
+			return "", fmt.Errorf("serial: %v", err)
+		}
+		var timers [4]int
+		for i, field := range []string{"refresh", "retry", "expire", "minimum"} {
+			value, err := parseZoneTTL(tokens[3+i].text)
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", field, err)
+			}
+			timers[i] = value
+		}
+		return fmt.Sprintf("%s %s %d %d %d %d %d", mname, rname, serial, timers[0], timers[1], timers[2], timers[3]), nil
+
+	case "TXT", "SPF":
+		if len(tokens) == 0 {
+			return "", fmt.Errorf("expected at least one character string")
+		}
+		var value strings.Builder
+		for _, tok := range tokens {
+			text, err := unescapeZoneString(tok.text)
+			if err != nil {
+				return "", err
+			}
+			if len(text) > 255 {
+				return "", fmt.Errorf("character string is %d bytes; the maximum is 255", len(text))
+			}
+			value.WriteString(text)
+		}
+		return value.String(), nil
+
+	case "CAA":
+		if len(tokens) != 3 {
+			return "", fmt.Errorf("expected flags, tag and value, got %d fields", len(tokens))
+		}
+		flags, err := parseZoneUint(tokens[0].text, 8)
+		if err != nil {
+			return "", fmt.Errorf("flags: %v", err)
+		}
+		value, err := unescapeZoneString(tokens[2].text)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s %q", flags, strings.ToLower(tokens[1].text), value), nil
+
+	case "RP":
+		if len(tokens) != 2 {
+			return "", fmt.Errorf("expected mailbox and TXT domain, got %d fields", len(tokens))
+		}
+		mbox, err := name("mailbox", tokens[0])
+		if err != nil {
+			return "", err
+		}
+		txt, err := name("txt", tokens[1])
+		if err != nil {
+			return "", err
+		}
+		return mbox + " " + txt, nil
+
+	case "SVCB", "HTTPS":
+		if len(tokens) < 2 {
+			return "", fmt.Errorf("expected priority and target, got %d fields", len(tokens))
+		}
+		priority, err := parseZoneUint(tokens[0].text, 16)
+		if err != nil {
+			return "", fmt.Errorf("priority: %v", err)
+		}
+		target, err := name("target", tokens[1])
+		if err != nil {
+			return "", err
+		}
+		value := fmt.Sprintf("%d %s", priority, target)
+		if len(tokens) > 2 {
+			value += " " + joinZoneTokens(tokens[2:])
+		}
+		return value, nil
+
+	default:
+		if len(tokens) == 0 {
+			return "", fmt.Errorf("missing record data")
+		}
+		return joinZoneTokens(tokens), nil
+	}
+}
+
+// qualify converts a possibly relative domain name into a lowercase FQDN
+func (p *zoneParser) qualify(name string) (string, error) {
+	if name == "@" {
+		if p.origin == "" {
+			return "", fmt.Errorf("@ used with no $ORIGIN set")
+		}
+		return p.origin, nil
+	}
+
+	if !isAbsoluteZoneName(name) {
+		if p.origin == "" {
+			return "", fmt.Errorf("relative name %q used with no $ORIGIN set", name)
+		}
+		if p.origin == "." {
+			name += "."
+		} else {
+			name += "." + p.origin
+		}
+	}
+
+	name = strings.ToLower(name)
+	if name != "." {
+		if len(name) > 254 {
+			return "", fmt.Errorf("name %q exceeds 255 octets", name)
+		}
+		for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+			if label == "" {
+				return "", fmt.Errorf("name %q contains an empty label", name)
+			}
+			if len(label) > 63 {
+				return "", fmt.Errorf("label %q exceeds 63 octets", label)
+			}
+		}
+	}
+	return name, nil
+}
+
+// checkTrailingDot flags relative names that repeat the origin, which almost
+// always means a fully-qualified name was written without its trailing dot
+func (p *zoneParser) checkTrailingDot(field, raw, qualified, rrtype string, line int) {
+	if raw == "@" || isAbsoluteZoneName(raw) || p.origin == "" || p.origin == "." {
+		return
+	}
+	origin := strings.TrimSuffix(p.origin, ".")
+	lower := strings.ToLower(raw)
+	if lower == origin || strings.HasSuffix(lower, "."+origin) {
+		p.addIssue("warning", "missing-trailing-dot",
+			fmt.Sprintf("%s %q has no trailing dot and expands to %s; did you mean %s.?", field, raw, qualified, lower),
+			qualified, rrtype, line)
+	}
+}
+
+// lint checks the parsed records for common zone configuration mistakes
+func (p *zoneParser) lint() {
+	byName := make(map[string][]int)
+	var soa []int
+	for i, rec := range p.records {
+		byName[rec.Name] = append(byName[rec.Name], i)
+		if rec.Type == "SOA" {
+			soa = append(soa, i)
+		}
+	}
+
+	var issues []ZoneIssue
+	add := func(severity, rule, message string, rec ZoneRecord) {
+		issues = append(issues, ZoneIssue{Severity: severity, Rule: rule, Message: message, Name: rec.Name, Type: rec.Type, File: rec.File, Line: rec.Line})
+	}
+
+	// SOA placement determines the zone apex
+	switch {
+	case len(p.records) == 0:
+	case len(soa) == 0:
+		issues = append(issues, ZoneIssue{Severity: "error", Rule: "missing-soa", Message: "zone has no SOA record", Name: p.apex})
+	default:
+		p.apex = p.records[soa[0]].Name
+		for _, i := range soa[1:] {
+			add("error", "multiple-soa", fmt.Sprintf("additional SOA record; the zone SOA is at line %d", p.records[soa[0]].Line), p.records[i])
+		}
+	}
+
+	if len(p.records) > 0 {
+		hasNS := false
+		for _, i := range byName[p.apex] {
+			if p.records[i].Type == "NS" {
+				hasNS = true
+				break
+			}
+		}
+		if !hasNS {
+			issues = append(issues, ZoneIssue{Severity: "warning", Rule: "missing-ns", Message: "zone apex has no NS records", Name: p.apex})
+		}
+	}
+
+	seen := make(map[string]int)
+	rrsetTTL := make(map[string]int)
+	for i, rec := range p.records {
+		if !isInZone(rec.Name, p.apex) {
+			add("warning", "out-of-zone", fmt.Sprintf("%s is outside the zone %s and will be ignored by most servers", rec.Name, p.apex), rec)
+		}
+
+		key := rec.Name + "|" + rec.Class + "|" + rec.Type + "|" + rec.Value
+		if first, ok := seen[key]; ok {
+			add("warning", "duplicate-record", fmt.Sprintf("duplicate of the record at line %d", p.records[first].Line), rec)
+		} else {
+			seen[key] = i
+		}
+
+		setKey := rec.Name + "|" + rec.Class + "|" + rec.Type
+		if first, ok := rrsetTTL[setKey]; ok {
+			if p.records[first].TTL != rec.TTL {
+				add("warning", "ttl-mismatch", fmt.Sprintf("TTL %d differs from %d at line %d; RFC 2181 requires one TTL per RRset", rec.TTL, p.records[first].TTL, p.records[first].Line), rec)
+			}
+		} else {
+			rrsetTTL[setKey] = i
+		}
+	}
+
+	// CNAME placement rules from RFC 1034 section 3.6.2 and RFC 1912
+	for name, indexes := range byName {
+		var cnames, others []int
+		for _, i := range indexes {
+			switch p.records[i].Type {
+			case "CNAME":
+				cnames = append(cnames, i)
+			case "RRSIG", "NSEC", "NSEC3":
+			default:
+				others = append(others, i)
+			}
+		}
+		if len(cnames) == 0 {
+			continue
+		}
+		if name == p.apex {
+			add("error", "cname-apex", "CNAME at the zone apex conflicts with the required SOA and NS records", p.records[cnames[0]])
+		}
+		for _, i := range cnames[1:] {
+			add("error", "multiple-cname", fmt.Sprintf("%s has more than one CNAME record", name), p.records[i])
+		}
+		if len(others) > 0 && name != p.apex {
+			var types []string
+			for _, i := range others {
+				types = appendUnique(types, p.records[i].Type)
+			}
+			add("error", "cname-coexist", fmt.Sprintf("CNAME at %s cannot coexist with other data (%s)", name, strings.Join(types, ", ")), p.records[cnames[0]])
+		}
+	}
+
+	// MX and NS targets inside the zone must have address records
+	for _, rec := range p.records {
+		target := zoneTarget(rec)
+		if target == "" || !isInZone(target, p.apex) {
+			continue
+		}
+		hasAddress, hasCNAME := false, false
+		for _, i := range byName[target] {
+			switch p.records[i].Type {
+			case "A", "AAAA":
+				hasAddress = true
+			case "CNAME":
+				hasCNAME = true
+			}
+		}
+		switch {
+		case hasAddress:
+		case hasCNAME:
+			add("error", "target-is-cname", fmt.Sprintf("%s target %s is a CNAME; RFC 2181 requires it to have address records", rec.Type, target), rec)
+		default:
+			add("error", "dangling-target", fmt.Sprintf("%s target %s has no A or AAAA record in the zone", rec.Type, target), rec)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	p.issues = append(p.issues, issues...)
+}
+
+// checkExternalTargets resolves MX and NS targets outside the zone
+func (s *ZoneService) checkExternalTargets(ctx context.Context, p *zoneParser) {
+	var targets []string
+	for _, rec := range p.records {
+		if target := zoneTarget(rec); target != "" && !isInZone(target, p.apex) {
+			targets = appendUnique(targets, target)
+		}
+	}
+
+	type targetResult struct {
+		target string
+		err    error
+	}
+	resultChan := make(chan targetResult, len(targets))
+	semaphore := make(chan struct{}, 10) // Limit concurrent lookups
+
+	for _, target := range targets {
+		go func(target string) {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			_, err := s.dns.resolver.LookupIP(lookupCtx, "ip", target)
+			resultChan <- targetResult{target: target, err: err}
+		}(target)
+	}
+
+	failures := make(map[string]error)
+	for range targets {
+		result := <-resultChan
+		if result.err != nil {
+			failures[result.target] = result.err
+		}
+	}
+
+	for _, rec := range p.records {
+		target := zoneTarget(rec)
+		err, failed := failures[target]
+		if target == "" || !failed {
+			continue
+		}
+		issue := ZoneIssue{Name: rec.Name, Type: rec.Type, File: rec.File, Line: rec.Line}
+		if isNotFound(err) {
+			issue.Severity, issue.Rule = "error", "dangling-target"
+			issue.Message = fmt.Sprintf("%s target %s does not resolve to any address", rec.Type, target)
+		} else {
+			issue.Severity, issue.Rule = "info", "unresolved-target"
+			issue.Message = fmt.Sprintf("could not resolve %s target %s: %v", rec.Type, target, err)
+		}
+		p.issues = append(p.issues, issue)
+	}
+}
+
+func (p *zoneParser) addIssue(severity, rule, message, name, rrtype string, line int) {
+	p.issues = append(p.issues, ZoneIssue{Severity: severity, Rule: rule, Message: message, Name: name, Type: rrtype, File: p.file, Line: line})
+}
+
+// zoneTarget returns the host an MX or NS record points to, or "" for other
+// records and null MX (RFC 7505)
+func zoneTarget(rec ZoneRecord) string {
+	switch rec.Type {
+	case "NS":
+		return rec.Value
+	case "MX":
+		fields := strings.Fields(rec.Value)
+		if len(fields) == 2 && fields[1] != "." {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// parseZoneTTL parses a TTL in seconds or BIND unit notation such as 1h30m or 2W
+func parseZoneTTL(value string) (int, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty TTL")
+	}
+	if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return int(n), nil
+	}
+
+	units := map[byte]uint64{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	var total, current uint64
+	digits := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= '0' && c <= '9':
+			current = current*10 + uint64(c-'0')
+			digits = true
+		case units[c|0x20] != 0 && digits:
+			total += current * units[c|0x20]
+			current, digits = 0, false
+		default:
+			return 0, fmt.Errorf("%q is not a valid TTL", value)
+		}
+		if total+current > 1<<31-1 {
+			return 0, fmt.Errorf("%q exceeds the maximum TTL", value)
+		}
+	}
+	if digits {
+		return 0, fmt.Errorf("%q mixes plain seconds with unit notation", value)
+	}
+	return int(total), nil
+}
+
+func parseZoneUint(value string, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(value, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a %d-bit unsigned integer", value, bits)
+	}
+	return n, nil
+}
+
+// unescapeZoneString decodes \X and \DDD escapes in a character string
+func unescapeZoneString(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' {
+			out.WriteByte(value[i])
+			continue
+		}
+		if i+3 < len(value) && isDigits(value[i+1:i+4]) {
+			n, _ := strconv.Atoi(value[i+1 : i+4])
+			if n > 255 {
+				return "", fmt.Errorf("invalid escape \\%s", value[i+1:i+4])
+			}
+			out.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		if i+1 < len(value) {
+			i++
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String(), nil
+}
+
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func joinZoneTokens(tokens []zoneToken) string {
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		if tok.quoted {
+			parts[i] = `"` + tok.text + `"`
+		} else {
+			parts[i] = tok.text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// isAbsoluteZoneName reports whether name ends in an unescaped dot
+func isAbsoluteZoneName(name string) bool {
+	if !strings.HasSuffix(name, ".") {
+		return false
+	}
+	backslashes := 0
+	for i := len(name) - 2; i >= 0 && name[i] == '\\'; i-- {
+		backslashes++
+	}
+	return backslashes%2 == 0
+}
+
+// isInZone reports whether name is at or below the zone apex
+func isInZone(name, apex string) bool {
+	return apex == "" || apex == "." || name == apex || strings.HasSuffix(name, "."+apex)
+}
+
+func isZoneClass(value string) bool {
+	switch value {
+	case "IN", "CH", "HS", "CS", "NONE", "ANY":
+		return true
+	}
+	return false
+}
+
+func isZoneType(value string) bool {
+	switch value {
+	case "A", "AAAA", "AFSDB", "APL", "CAA", "CDNSKEY", "CDS", "CERT", "CNAME", "CSYNC",
+		"DHCID", "DLV", "DNAME", "DNSKEY", "DS", "EUI48", "EUI64", "HINFO", "HIP", "HTTPS",
+		"IPSECKEY", "KEY", "KX", "LOC", "MX", "NAPTR", "NS", "NSEC", "NSEC3", "NSEC3PARAM",
+		"OPENPGPKEY", "PTR", "RP", "RRSIG", "SMIMEA", "SOA", "SPF", "SRV", "SSHFP", "SVCB",
+		"TLSA", "TXT", "URI", "ZONEMD":
+		return true
+	}
+	if strings.HasPrefix(value, "TYPE") {
+		_, err := strconv.ParseUint(value[4:], 10, 16)
+		return err == nil
+	}
+	return false
+}
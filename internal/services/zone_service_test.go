@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// zoneHeader is an SOA and NS preamble shared by the lint fixtures
+const zoneHeader = `$ORIGIN example.com.
+$TTL 3600
+@ IN SOA ns1 hostmaster 1 3600 900 604800 300
+  IN NS ns1
+ns1 IN A 192.0.2.1
+`
+
+// parseZoneFixture parses a zone without external lookups
+func parseZoneFixture(t *testing.T, req ZoneParseRequest) *ZoneParseResult {
+	t.Helper()
+	result, err := NewZoneService(nil).ParseZone(context.Background(), req)
+	if err != nil {
+		t.Fatalf("ParseZone: %v", err)
+	}
+	return result
+}
+
+// findZoneRecord returns the first record with the given name and type
+func findZoneRecord(result *ZoneParseResult, name, rrtype string) *ZoneRecord {
+	for i, rec := range result.Records {
+		if rec.Name == name && rec.Type == rrtype {
+			return &result.Records[i]
+		}
+	}
+	return nil
+}
+
+// zoneRules returns the rules of the issues found in a zone
+func zoneRules(result *ZoneParseResult) map[string]int {
+	rules := make(map[string]int)
+	for _, issue := range result.Issues {
+		rules[issue.Rule]++
+	}
+	return rules
+}
+
+func TestParseZoneDirectives(t *testing.T) {
+	result := parseZoneFixture(t, ZoneParseRequest{
+		Content: `$ORIGIN example.com.
+$TTL 1h
+@ IN SOA ns1 hostmaster (
+        2024010101 ; serial
+        3600       ; refresh
+        900 604800 300 )
+  IN NS ns1
+ns1 IN A 192.0.2.1 ; trailing comment
+txt 300 IN TXT "semi; colon" "(paren)"
+$INCLUDE hosts.zone sub
+after IN A 192.0.2.3
+$ORIGIN other.example.com.
+www IN A 192.0.2.4
+$TTL 60
+short IN A 192.0.2.5
+`,
+		Includes: map[string]string{
+			"hosts.zone": "host IN A 192.0.2.2\n",
+		},
+	})
+
+	if result.Origin != "example.com." {
+		t.Errorf("origin = %q", result.Origin)
+	}
+	if !result.Valid {
+		t.Errorf("expected a valid zone, issues: %+v", result.Issues)
+	}
+
+	soa := findZoneRecord(result, "example.com.", "SOA")
+	if soa == nil || soa.Value != "ns1.example.com. hostmaster.example.com. 2024010101 3600 900 604800 300" || soa.TTL != 3600 {
+		t.Errorf("SOA = %+v", soa)
+	}
+	if ns := findZoneRecord(result, "example.com.", "NS"); ns == nil || ns.Value != "ns1.example.com." || ns.Line != 7 {
+		t.Errorf("NS should inherit the apex owner: %+v", ns)
+	}
+	if txt := findZoneRecord(result, "txt.example.com.", "TXT"); txt == nil || txt.Value != "semi; colon(paren)" || txt.TTL != 300 {
+		t.Errorf("TXT = %+v", txt)
+	}
+	if host := findZoneRecord(result, "host.sub.example.com.", "A"); host == nil || host.File != "hosts.zone" || host.Line != 1 {
+		t.Errorf("included record = %+v", host)
+	}
+	if after := findZoneRecord(result, "after.example.com.", "A"); after == nil {
+		t.Error("origin was not restored after $INCLUDE")
+	}
+	if www := findZoneRecord(result, "www.other.example.com.", "A"); www == nil {
+		t.Error("$ORIGIN did not change the origin")
+	}
+	if short := findZoneRecord(result, "short.other.example.com.", "A"); short == nil || short.TTL != 60 {
+		t.Errorf("$TTL not applied: %+v", short)
+	}
+}
+
+func TestParseZoneSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    string
+	}{
+		{"unbalanced open", zoneHeader + "x IN TXT ( \"a\"\n", "syntax"},
+		{"unbalanced close", zoneHeader + "x IN A 192.0.2.9 )\n", "syntax"},
+		{"unterminated quote", zoneHeader + "x IN TXT \"open\n", "syntax"},
+		{"unknown directive", zoneHeader + "$BOGUS x\n", "syntax"},
+		{"missing include", zoneHeader + "$INCLUDE missing.zone\n", "include"},
+		{"generate", zoneHeader + "$GENERATE 1-10 host$ A 192.0.2.$\n", "unsupported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseZoneFixture(t, ZoneParseRequest{Content: tt.content})
+			if zoneRules(result)[tt.rule] == 0 {
+				t.Errorf("expected a %s issue, got %+v", tt.rule, result.Issues)
+			}
+		})
+	}
+}
+
+func TestParseZoneIncludeLoop(t *testing.T) {
+	result := parseZoneFixture(t, ZoneParseRequest{
+		Content:  zoneHeader + "$INCLUDE a.zone\n",
+		Includes: map[string]string{"a.zone": "$INCLUDE b.zone\n", "b.zone": "$INCLUDE a.zone\n"},
+	})
+	if zoneRules(result)["include"] != 1 {
+		t.Errorf("expected one include loop issue, got %+v", result.Issues)
+	}
+}
+
+// Four levels of 100 includes would read the comment-only leaf 100 million times
+func TestParseZoneIncludeBudget(t *testing.T) {
+	includes := map[string]string{"l4.zone": "; nothing here\n"}
+	for level := 3; level >= 0; level-- {
+		includes[fmt.Sprintf("l%d.zone", level)] = strings.Repeat(fmt.Sprintf("$INCLUDE l%d.zone\n", level+1), 100)
+	}
+	req := ZoneParseRequest{Content: zoneHeader + "$INCLUDE l0.zone\n", Includes: includes}
+
+	start := time.Now()
+	_, err := NewZoneService(nil).ParseZone(context.Background(), req)
+	if err == nil || !strings.Contains(err.Error(), "bytes of input") {
+		t.Fatalf("err = %v, want the input budget error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("parsing took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewZoneService(nil).ParseZone(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled parse: err = %v", err)
+	}
+}
+
+func TestLintZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    string
+	}{
+		{"cname-apex", zoneHeader + "@ IN CNAME other.example.net.\n", "cname-apex"},
+		{"cname-coexist", zoneHeader + "www IN CNAME host.example.net.\nwww IN TXT \"x\"\n", "cname-coexist"},
+		{"missing-trailing-dot", zoneHeader + "www IN CNAME host.example.com\n", "missing-trailing-dot"},
+		{"dangling-target", zoneHeader + "@ IN MX 10 mail\n", "dangling-target"},
+		{"duplicate-record", zoneHeader + "www IN A 192.0.2.7\nwww IN A 192.0.2.7\n", "duplicate-record"},
+		{"ttl-mismatch", zoneHeader + "www 300 IN A 192.0.2.7\nwww 600 IN A 192.0.2.8\n", "ttl-mismatch"},
+		{"multiple-soa", zoneHeader + "@ IN SOA ns1 hostmaster 2 3600 900 604800 300\n", "multiple-soa"},
+		{"target-is-cname", zoneHeader + "@ IN MX 10 mail\nmail IN CNAME ns1\n", "target-is-cname"},
+		{"out-of-zone", zoneHeader + "www.example.net. IN A 192.0.2.7\n", "out-of-zone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseZoneFixture(t, ZoneParseRequest{Content: tt.content})
+			rules := zoneRules(result)
+			if rules[tt.rule] != 1 {
+				t.Errorf("expected one %s issue, got %+v", tt.rule, result.Issues)
+			}
+		})
+	}
+
+	// The shared header on its own is clean
+	if result := parseZoneFixture(t, ZoneParseRequest{Content: zoneHeader}); len(result.Issues) != 0 {
+		t.Errorf("header fixture has issues: %+v", result.Issues)
+	}
+}
+
+func TestLintZoneMissingSOA(t *testing.T) {
+	result := parseZoneFixture(t, ZoneParseRequest{Content: "www IN A 192.0.2.1\n", Origin: "example.com"})
+	rules := zoneRules(result)
+	if rules["missing-soa"] != 1 || rules["missing-ns"] != 1 {
+		t.Errorf("expected missing-soa and missing-ns, got %+v", result.Issues)
+	}
+	if result.Valid {
+		t.Error("zone without an SOA should not be valid")
+	}
+}
+
+func TestLintZoneExternalTargets(t *testing.T) {
+	resolver := &fakeResolver{ips: map[string][]string{"mx.example.net.": {"192.0.2.50"}}}
+	service := NewZoneService(NewIPAnalysisServiceWithResolver(resolver))
+	result, err := service.ParseZone(context.Background(), ZoneParseRequest{
+		Content:        zoneHeader + "@ IN MX 10 mx.example.net.\n@ IN MX 20 gone.example.net.\n",
+		ResolveTargets: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dangling []string
+	for _, issue := range result.Issues {
+		if issue.Rule == "dangling-target" {
+			dangling = append(dangling, issue.Message)
+		}
+	}
+	if len(dangling) != 1 {
+		t.Errorf("expected one external dangling target, got %v", result.Issues)
+	}
+}
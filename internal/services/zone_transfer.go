@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	zoneTransferTimeout = 60 * time.Second
+	maxTransferRecords  = 500000
+	defaultTSIGAlgo     = "hmac-sha256"
+)
+
+// tsigAlgorithms maps accepted TSIG algorithm names to their canonical form
+var tsigAlgorithms = map[string]string{
+	"hmac-md5":    dns.HmacMD5,
+	"hmac-sha1":   dns.HmacSHA1,
+	"hmac-sha224": dns.HmacSHA224,
+	"hmac-sha256": dns.HmacSHA256,
+	"hmac-sha384": dns.HmacSHA384,
+	"hmac-sha512": dns.HmacSHA512,
+}
+
+// ZoneTransferRequest describes an AXFR or IXFR request to one name server
+type ZoneTransferRequest struct {
+	Zone          string `json:"zone"`
+	Server        string `json:"server"`
+	Type          string `json:"type"`                     // "AXFR" (default) or "IXFR"
+	Serial        uint32 `json:"serial,omitempty"`         // IXFR: the serial the client already has
+	TSIGName      string `json:"tsig_name,omitempty"`      // TSIG key name
+	TSIGSecret    string `json:"tsig_secret,omitempty"`    // base64 TSIG secret
+	TSIGAlgorithm string `json:"tsig_algorithm,omitempty"` // default hmac-sha256
+}
+
+// ZoneTransferChange is one IXFR difference sequence
+type ZoneTransferChange struct {
+	FromSerial uint32      `json:"from_serial"`
+	ToSerial   uint32      `json:"to_serial"`
+	Deleted    []DNSRecord `json:"deleted"`
+	Added      []DNSRecord `json:"added"`
+}
+
+// ZoneTransferResult reports the outcome of a zone transfer
+type ZoneTransferResult struct {
+	Zone         string               `json:"zone"`
+	Server       string               `json:"server"`
+	Type         string               `json:"type"`
+	Allowed      bool                 `json:"allowed"`
+	Rcode        string               `json:"rcode,omitempty"`
+	Error        string               `json:"error,omitempty"`
+	TSIG         bool                 `json:"tsig"`
+	Serial       uint32               `json:"serial"`
+	Incremental  bool                 `json:"incremental"` // IXFR answered with differences rather than a full zone
+	UpToDate     bool                 `json:"up_to_date"`  // IXFR: the server has nothing newer than Serial
+	Records      []DNSRecord          `json:"records"`
+	Changes      []ZoneTransferChange `json:"changes,omitempty"`
+	Messages     int                  `json:"messages"`
+	ZoneFile     string               `json:"-"`
+	Timestamp    time.Time            `json:"timestamp"`
+	TransferTime float64              `json:"transfer_time_ms"`
+}
+
+// ZoneTransferService requests zone transfers from name servers
+type ZoneTransferService struct {
+	dns        *IPAnalysisService
+	dialer     *net.Dialer
+	maxRecords int
+}
+
+// NewZoneTransferService creates a zone transfer client that only connects to
+// public addresses
+func NewZoneTransferService(dns *IPAnalysisService) *ZoneTransferService {
+	return NewZoneTransferServiceWithOptions(dns, false)
+}
+
+// NewZoneTransferServiceWithOptions creates a zone transfer client; allowPrivate
+// permits loopback, private and link-local servers
+func NewZoneTransferServiceWithOptions(dns *IPAnalysisService, allowPrivate bool) *ZoneTransferService {
+	return &ZoneTransferService{
+		dns:        dns,
+		dialer:     newProbeDialer(dnsQueryTimeout, allowPrivate),
+		maxRecords: maxTransferRecords,
+	}
+}
+
+// TransferZone performs an AXFR or IXFR. Refused or failed transfers are
+// reported in the result; an error is returned only for invalid requests and
+// for servers on non-public addresses.
+// If emit is set it is called with every record as it is received.
+func (s *ZoneTransferService) TransferZone(ctx context.Context, req ZoneTransferRequest, emit func(DNSRecord)) (*ZoneTransferResult, error) {
+	start := time.Now()
+
+	zone := strings.ToLower(strings.TrimSpace(req.Zone))
+	if _, ok := dns.IsDomainName(zone); !ok || zone == "" {
+		return nil, fmt.Errorf("invalid zone: %s", req.Zone)
+	}
+	zone = dns.Fqdn(zone)
+
+	server, err := normalizeDNSServer(req.Server)
+	if err != nil {
+		return nil, err
+	}
+
+	transferType := strings.ToUpper(strings.TrimSpace(req.Type))
+	if transferType == "" {
+		transferType = "AXFR"
+	}
+
+	msg := new(dns.Msg)
+	switch transferType {
+	case "AXFR":
+		msg.SetAxfr(zone)
+	case "IXFR":
+		msg.SetIxfr(zone, req.Serial, ".", ".")
+	default:
+		return nil, fmt.Errorf("unsupported transfer type: %s", req.Type)
+	}
+
+	transfer := &dns.Transfer{
+		DialTimeout:  dnsQueryTimeout,
+		ReadTimeout:  zoneTransferTimeout,
+		WriteTimeout: dnsQueryTimeout,
+	}
+
+	result := &ZoneTransferResult{
+		Zone:    zone,
+		Server:  server,
+		Type:    transferType,
+		Records: []DNSRecord{},
+	}
+
+	if req.TSIGName != "" || req.TSIGSecret != "" {
+		keyName, algorithm, err := parseTSIG(req)
+		if err != nil {
+			return nil, err
+		}
+		msg.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+		transfer.TsigSecret = map[string]string{keyName: req.TSIGSecret}
+		result.TSIG = true
+	}
+
+	// Dial with the request context so a cancelled request stops the transfer
+	dialCtx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	conn, err := s.dialer.DialContext(dialCtx, "tcp", server)
+	cancel()
+	if errors.Is(err, ErrNonPublicAddress) {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to connect: %v", err)
+		return s.finish(result, start), nil
+	}
+	transfer.Conn = &dns.Conn{Conn: conn}
+	defer transfer.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	envelopes, err := transfer.In(msg, server)
+	if err != nil {
+		result.Error = err.Error()
+		return s.finish(result, start), nil
+	}
+
+	var rrs []dns.RR
+receive:
+	for envelope := range envelopes {
+		result.Messages++
+		if envelope.Error != nil {
+			result.Error = envelope.Error.Error()
+			if rcode := transferRcode(envelope.Error); rcode != "" {
+				result.Rcode = rcode
+			}
+			break
+		}
+		for _, rr := range envelope.RR {
+			if len(rrs) >= s.maxRecords {
+				result.Error = fmt.Sprintf("transfer exceeds %d records", s.maxRecords)
+				break receive
+			}
+			rrs = append(rrs, rr)
+			if emit != nil {
+				emit(rrToDNSRecord(rr))
+			}
+		}
+	}
+	// Closing the connection stops a server that keeps streaming past the
+	// record limit; draining lets the transfer goroutine exit
+	conn.Close()
+	for range envelopes {
+	}
+	if ctx.Err() != nil && result.Error == "" {
+		result.Error = ctx.Err().Error()
+	}
+
+	if len(rrs) > 0 && result.Error == "" {
+		result.Allowed = true
+		result.Rcode = dns.RcodeToString[dns.RcodeSuccess]
+		if soa, ok := rrs[0].(*dns.SOA); ok {
+			result.Serial = soa.Serial
+		}
+		if transferType == "IXFR" {
+			s.parseIXFR(result, rrs)
+		} else {
+			s.setRecords(result, rrs)
+		}
+	}
+
+	return s.finish(result, start), nil
+}
+
+// parseIXFR splits an IXFR response into difference sequences, falling back to a full zone
+func (s *ZoneTransferService) parseIXFR(result *ZoneTransferResult, rrs []dns.RR) {
+	if len(rrs) == 1 {
+		// A single SOA means the client is current (RFC 1995 section 4)
+		result.UpToDate = true
+		s.setRecords(result, rrs)
+		return
+	}
+	if _, ok := rrs[1].(*dns.SOA); !ok {
+		// The server sent the whole zone instead of differences
+		s.setRecords(result, rrs)
+		return
+	}
+
+	result.Incremental = true
+	result.ZoneFile = s.zoneFile(result, rrs)
+
+	// Each sequence is: old SOA, deleted records, new SOA, added records
+	var change *ZoneTransferChange
+	deleting := false
+	for _, rr := range rrs[1 : len(rrs)-1] {
+		if soa, ok := rr.(*dns.SOA); ok {
+			if deleting {
+				change.ToSerial = soa.Serial
+			} else {
+				result.Changes = append(result.Changes, ZoneTransferChange{FromSerial: soa.Serial, Deleted: []DNSRecord{}, Added: []DNSRecord{}})
+				change = &result.Changes[len(result.Changes)-1]
+			}
+			deleting = !deleting
+			continue
+		}
+		if deleting {
+			change.Deleted = append(change.Deleted, rrToDNSRecord(rr))
+		} else if change != nil {
+			change.Added = append(change.Added, rrToDNSRecord(rr))
+		}
+	}
+}
+
+// setRecords stores a full zone, dropping the trailing SOA that closes the transfer
+func (s *ZoneTransferService) setRecords(result *ZoneTransferResult, rrs []dns.RR) {
+	if len(rrs) > 1 {
+		if _, ok := rrs[len(rrs)-1].(*dns.SOA); ok {
+			rrs = rrs[:len(rrs)-1]
+		}
+	}
+	for _, rr := range rrs {
+		result.Records = append(result.Records, rrToDNSRecord(rr))
+	}
+	result.ZoneFile = s.zoneFile(result, rrs)
+}
+
+// zoneFile renders the transferred records in RFC 1035 master file format
+func (s *ZoneTransferService) zoneFile(result *ZoneTransferResult, rrs []dns.RR) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "; %s transfer of %s from %s at %s\n", result.Type, result.Zone, result.Server, time.Now().UTC().Format(time.RFC3339))
+	if result.Incremental {
+		b.WriteString("; incremental response: records between SOA markers are deletions, then additions\n")
+	}
+	fmt.Fprintf(&b, "$ORIGIN %s\n", result.Zone)
+	for _, rr := range rrs {
+		b.WriteString(rr.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (s *ZoneTransferService) finish(result *ZoneTransferResult, start time.Time) *ZoneTransferResult {
+	result.Timestamp = time.Now()
+	result.TransferTime = durationMs(time.Since(start))
+	return result
+}
+
+// parseTSIG validates the TSIG parameters and returns the key name and algorithm
+func parseTSIG(req ZoneTransferRequest) (string, string, error) {
+	if req.TSIGName == "" || req.TSIGSecret == "" {
+		return "", "", fmt.Errorf("TSIG requires both a key name and a secret")
+	}
+	if _, err := base64.StdEncoding.DecodeString(req.TSIGSecret); err != nil {
+		return "", "", fmt.Errorf("TSIG secret must be base64: %v", err)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(req.TSIGAlgorithm))
+	if name == "" {
+		name = defaultTSIGAlgo
+	}
+	algorithm, ok := tsigAlgorithms[strings.TrimSuffix(name, ".")]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported TSIG algorithm: %s", req.TSIGAlgorithm)
+	}
+	return dns.Fqdn(strings.ToLower(strings.TrimSpace(req.TSIGName))), algorithm, nil
+}
+
+// transferRcode extracts the response code or TSIG failure from a miekg transfer error
+func transferRcode(err error) string {
+	var rcode int
+	if _, scanErr := fmt.Sscanf(err.Error(), "dns: bad xfr rcode: %d", &rcode); scanErr == nil {
+		if name, ok := dns.RcodeToString[rcode]; ok {
+			return name
+		}
+		return fmt.Sprintf("RCODE%d", rcode)
+	}
+	if errors.Is(err, dns.ErrSig) || errors.Is(err, dns.ErrNoSig) || errors.Is(err, dns.ErrSecret) || errors.Is(err, dns.ErrTime) {
+		return "BADSIG"
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testTSIGName   = "xfr-key."
+	testTSIGSecret = "c2VjcmV0LWtleS1mb3ItdGVzdHM="
+)
+
+// testZoneRRs parses zone file lines into records
+func testZoneRRs(t *testing.T, lines ...string) []dns.RR {
+	t.Helper()
+	var rrs []dns.RR
+	for _, line := range lines {
+		rr, err := dns.NewRR(line)
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs
+}
+
+// startTransferServer serves zone transfers for example.test. over TCP
+func startTransferServer(t *testing.T, secret string, handler dns.HandlerFunc) string {
+	t.Helper()
+	started := make(chan struct{})
+	server := &dns.Server{
+		Addr:              "127.0.0.1:0",
+		Net:               "tcp",
+		Handler:           handler,
+		TsigSecret:        map[string]string{testTSIGName: secret},
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go server.ListenAndServe()
+	<-started
+	t.Cleanup(func() { server.Shutdown() })
+	return server.Listener.Addr().String()
+}
+
+// transferHandler streams the given records, split over messages of two records,
+// refusing unsigned requests when requireTSIG is set
+func transferHandler(t *testing.T, requireTSIG bool, answer func(req *dns.Msg) []dns.RR) dns.HandlerFunc {
+	return func(w dns.ResponseWriter, req *dns.Msg) {
+		if req.IsTsig() != nil && w.TsigStatus() != nil || requireTSIG && req.IsTsig() == nil {
+			resp := new(dns.Msg)
+			resp.SetRcode(req, dns.RcodeNotAuth)
+			w.WriteMsg(resp)
+			return
+		}
+		if !strings.EqualFold(req.Question[0].Name, "example.test.") {
+			resp := new(dns.Msg)
+			resp.SetRcode(req, dns.RcodeRefused)
+			w.WriteMsg(resp)
+			return
+		}
+
+		rrs := answer(req)
+		ch := make(chan *dns.Envelope)
+		transfer := new(dns.Transfer)
+		done := make(chan error, 1)
+		go func() { done <- transfer.Out(w, req, ch) }()
+		for i := 0; i < len(rrs); i += 2 {
+			ch <- &dns.Envelope{RR: rrs[i:min(i+2, len(rrs))]}
+		}
+		close(ch)
+		if err := <-done; err != nil {
+			t.Logf("transfer out: %v", err)
+		}
+		w.Close()
+	}
+}
+
+// testZone is the current version of example.test. at serial 3
+func testZone(t *testing.T) []dns.RR {
+	return testZoneRRs(t,
+		"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 3 3600 900 604800 300",
+		"example.test. 3600 IN NS ns1.example.test.",
+		"ns1.example.test. 3600 IN A 192.0.2.1",
+		"www.example.test. 300 IN A 192.0.2.10",
+		"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 3 3600 900 604800 300",
+	)
+}
+
+func TestTransferZoneAXFR(t *testing.T) {
+	server := startTransferServer(t, testTSIGSecret, transferHandler(t, false, func(*dns.Msg) []dns.RR { return testZone(t) }))
+
+	var streamed int
+	result, err := NewZoneTransferServiceWithOptions(nil, true).TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server}, func(DNSRecord) { streamed++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Allowed || result.Error != "" {
+		t.Fatalf("transfer not allowed: %+v", result)
+	}
+	if result.Serial != 3 || len(result.Records) != 4 || streamed != 5 {
+		t.Errorf("serial %d, records %d, streamed %d", result.Serial, len(result.Records), streamed)
+	}
+	if result.Messages != 3 {
+		t.Errorf("messages = %d, want 3", result.Messages)
+	}
+	if !strings.Contains(result.ZoneFile, "$ORIGIN example.test.") || !strings.Contains(result.ZoneFile, "www.example.test.\t300\tIN\tA\t192.0.2.10") {
+		t.Errorf("zone file:\n%s", result.ZoneFile)
+	}
+}
+
+func TestTransferZoneIXFR(t *testing.T) {
+	handler := transferHandler(t, false, func(req *dns.Msg) []dns.RR {
+		switch req.Ns[0].(*dns.SOA).Serial {
+		case 3:
+			return testZone(t)[:1]
+		case 2:
+			return testZoneRRs(t,
+				"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 3 3600 900 604800 300",
+				"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 2 3600 900 604800 300",
+				"www.example.test. 300 IN A 192.0.2.9",
+				"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 3 3600 900 604800 300",
+				"www.example.test. 300 IN A 192.0.2.10",
+				"example.test. 3600 IN SOA ns1.example.test. hostmaster.example.test. 3 3600 900 604800 300",
+			)
+		default:
+			return testZone(t)
+		}
+	})
+	server := startTransferServer(t, testTSIGSecret, handler)
+	service := NewZoneTransferServiceWithOptions(nil, true)
+
+	t.Run("up to date", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server, Type: "IXFR", Serial: 3}, nil)
+		if !result.Allowed || !result.UpToDate || result.Incremental {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	t.Run("incremental", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server, Type: "IXFR", Serial: 2}, nil)
+		if !result.Allowed || !result.Incremental || len(result.Changes) != 1 {
+			t.Fatalf("result = %+v", result)
+		}
+		change := result.Changes[0]
+		if change.FromSerial != 2 || change.ToSerial != 3 {
+			t.Errorf("serials %d -> %d", change.FromSerial, change.ToSerial)
+		}
+		if len(change.Deleted) != 1 || change.Deleted[0].Value != "192.0.2.9" || len(change.Added) != 1 || change.Added[0].Value != "192.0.2.10" {
+			t.Errorf("change = %+v", change)
+		}
+	})
+
+	t.Run("full zone fallback", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server, Type: "IXFR", Serial: 1}, nil)
+		if !result.Allowed || result.Incremental || result.UpToDate || len(result.Records) != 4 {
+			t.Errorf("result = %+v", result)
+		}
+	})
+}
+
+func TestTransferZoneRefused(t *testing.T) {
+	server := startTransferServer(t, testTSIGSecret, transferHandler(t, false, func(*dns.Msg) []dns.RR { return testZone(t) }))
+
+	result, err := NewZoneTransferServiceWithOptions(nil, true).TransferZone(context.Background(), ZoneTransferRequest{Zone: "other.test", Server: server}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Allowed || result.Rcode != "REFUSED" || len(result.Records) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestTransferZoneNonPublicServer(t *testing.T) {
+	server := startTransferServer(t, testTSIGSecret, transferHandler(t, false, func(*dns.Msg) []dns.RR { return testZone(t) }))
+
+	_, err := NewZoneTransferService(nil).TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server}, nil)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("err = %v, want ErrNonPublicAddress", err)
+	}
+}
+
+func TestTransferZoneTSIG(t *testing.T) {
+	server := startTransferServer(t, testTSIGSecret, transferHandler(t, true, func(*dns.Msg) []dns.RR { return testZone(t) }))
+	service := NewZoneTransferServiceWithOptions(nil, true)
+
+	t.Run("signed", func(t *testing.T) {
+		result, err := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server, TSIGName: testTSIGName, TSIGSecret: testTSIGSecret}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || !result.TSIG || len(result.Records) != 4 {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server}, nil)
+		if result.Allowed || result.Rcode != "NOTAUTH" {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	// The server rejects the key with an unsigned reply, which the client reports as BADSIG
+	t.Run("wrong secret", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server, TSIGName: testTSIGName, TSIGSecret: "d3Jvbmctc2VjcmV0"}, nil)
+		if result.Allowed || result.Rcode != "BADSIG" {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	// A server signing with a different key fails verification on the client
+	other := startTransferServer(t, "b3RoZXItc2VjcmV0", func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = testZone(t)
+		resp.SetTsig(testTSIGName, dns.HmacSHA256, 300, time.Now().Unix())
+		w.WriteMsg(resp)
+	})
+	t.Run("bad signature", func(t *testing.T) {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: other, TSIGName: testTSIGName, TSIGSecret: testTSIGSecret}, nil)
+		if result.Allowed || result.Rcode != "BADSIG" {
+			t.Errorf("result = %+v", result)
+		}
+	})
+}
+
+func TestTransferZoneRecordLimit(t *testing.T) {
+	// A hostile server that never sends the closing SOA
+	server := startTransferServer(t, testTSIGSecret, func(w dns.ResponseWriter, req *dns.Msg) {
+		for i := 0; ; i++ {
+			resp := new(dns.Msg)
+			resp.SetReply(req)
+			if i == 0 {
+				resp.Answer = testZone(t)[:1]
+			}
+			for j := 0; j < 50; j++ {
+				rr, _ := dns.NewRR(fmt.Sprintf("h%d-%d.example.test. 300 IN A 192.0.2.1", i, j))
+				resp.Answer = append(resp.Answer, rr)
+			}
+			if err := w.WriteMsg(resp); err != nil {
+				return
+			}
+		}
+	})
+
+	service := NewZoneTransferServiceWithOptions(nil, true)
+	service.maxRecords = 1000
+
+	done := make(chan *ZoneTransferResult, 1)
+	go func() {
+		result, _ := service.TransferZone(context.Background(), ZoneTransferRequest{Zone: "example.test", Server: server}, nil)
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		if result.Allowed || !strings.Contains(result.Error, "exceeds 1000 records") {
+			t.Errorf("result = %+v", result)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("transfer did not stop at the record limit")
+	}
+}
@@ -78,22 +78,51 @@ func DefineRoutes(r *chi.Mux, cache *replay.Cache) {
 
 	// Tool page routes (for direct URL access)
 	r.Get("/unix-time", routes.ToolPageHandler("unix-time"))
+	r.Get("/cron", routes.ToolPageHandler("cron"))
 	r.Get("/json-validator", routes.ToolPageHandler("json-validator"))
 	r.Get("/ip", routes.ToolPageHandler("ip"))
 	r.Get("/css-linter", routes.ToolPageHandler("css-linter"))
 	r.Get("/dns-leak", routes.ToolPageHandler("dns-leak"))
+	r.Get("/zone-linter", routes.ToolPageHandler("zone-linter"))
+	r.Get("/jwt", routes.ToolPageHandler("jwt"))
 
 	// Dynamically load tool content
 	r.Get("/tools/unix-time", routes.ToolContentHandler("unix-time"))
+	r.Get("/tools/cron", routes.ToolContentHandler("cron"))
 	r.Get("/tools/json-validator", routes.ToolContentHandler("json-validator"))
 	r.Get("/tools/ip", routes.ToolContentHandler("ip"))
 	r.Get("/tools/css-linter", routes.ToolContentHandler("css-linter"))
 	r.Get("/tools/dns-leak", routes.ToolContentHandler("dns-leak"))
+	r.Get("/tools/zone-linter", routes.ToolContentHandler("zone-linter"))
+	r.Get("/tools/jwt", routes.ToolContentHandler("jwt"))
 	r.Get("/tools/index", routes.ToolContentHandler("index"))
 
+	// DNS-over-HTTPS endpoint (RFC 8484)
+	routes.RegisterDoHRoutes(r)
+
 	// API routes
 	r.Route("/api", func(r chi.Router) {
 		// Register IP/DNS API routes
 		routes.RegisterIPAPIRoutes(r, cache)
+		// Register TLS inspection API routes
+		routes.RegisterTLSAPIRoutes(r)
+		// Register HTTP probe API routes
+		routes.RegisterHTTPAPIRoutes(r)
+		// Register email authentication API routes
+		routes.RegisterMailAPIRoutes(r)
+		// Register zone file API routes
+		routes.RegisterZoneAPIRoutes(r)
+		// Register DNS leak test API routes
+		routes.RegisterDNSLeakAPIRoutes(r)
+		// Register JSON validation and formatting API routes
+		routes.RegisterJSONAPIRoutes(r)
+		// Register data format conversion API routes
+		routes.RegisterConvertAPIRoutes(r)
+		// Register JWT decoding and verification API routes
+		routes.RegisterJWTAPIRoutes(r)
+		// Register time conversion API routes
+		routes.RegisterTimeAPIRoutes(r)
+		// Register cron expression API routes
+		routes.RegisterCronAPIRoutes(r)
 	})
 }
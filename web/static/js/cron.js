@@ -0,0 +1,270 @@
+// Cron Parser Tool JavaScript
+(function() {
+    'use strict';
+
+    // Prevent multiple initializations
+    if (window.CronParser) {
+        return;
+    }
+
+    class CronParser {
+        constructor() {
+            this.parseTimer = null;
+            this.initializeElements();
+            this.bindEvents();
+            this.populateTimezones();
+        }
+
+        initializeElements() {
+            // Input elements
+            this.expressionInput = document.getElementById('cron-input');
+            this.dialectSelect = document.getElementById('cron-dialect');
+            this.timezoneInput = document.getElementById('cron-timezone');
+            this.timezoneList = document.getElementById('cron-timezones');
+            this.countInput = document.getElementById('cron-count');
+
+            // Button elements
+            this.clearBtn = document.getElementById('cron-clear-btn');
+            this.exampleBtns = document.querySelectorAll('.cron-example');
+
+            // Output elements
+            this.result = document.getElementById('cron-result');
+            this.resultMessage = document.getElementById('cron-result-message');
+            this.detection = document.getElementById('cron-detection');
+            this.warnings = document.getElementById('cron-warnings');
+            this.warningsList = document.getElementById('cron-warnings-list');
+            this.fields = document.getElementById('cron-fields');
+            this.fieldsBody = document.getElementById('cron-fields-body');
+            this.runs = document.getElementById('cron-runs');
+            this.runsZone = document.getElementById('cron-runs-zone');
+            this.runsList = document.getElementById('cron-runs-list');
+        }
+
+        bindEvents() {
+            [this.expressionInput, this.timezoneInput, this.countInput].forEach(input => {
+                if (input) {
+                    input.addEventListener('input', () => this.scheduleParse());
+                }
+            });
+
+            if (this.dialectSelect) {
+                this.dialectSelect.addEventListener('change', () => this.scheduleParse());
+            }
+
+            if (this.clearBtn) {
+                this.clearBtn.addEventListener('click', () => this.clearAll());
+            }
+
+            this.exampleBtns.forEach(button => {
+                button.addEventListener('click', () => {
+                    this.expressionInput.value = button.dataset.expression;
+                    this.dialectSelect.value = 'auto';
+                    this.parse();
+                });
+            });
+        }
+
+        populateTimezones() {
+            if (!this.timezoneInput) return;
+
+            this.timezoneInput.value = Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC';
+            if (this.timezoneList && typeof Intl.supportedValuesOf === 'function') {
+                Intl.supportedValuesOf('timeZone').forEach(zone => {
+                    const option = document.createElement('option');
+                    option.value = zone;
+                    this.timezoneList.appendChild(option);
+                });
+            }
+        }
+
+        scheduleParse() {
+            clearTimeout(this.parseTimer);
+            this.parseTimer = setTimeout(() => this.parse(), 300);
+        }
+
+        async parse() {
+            clearTimeout(this.parseTimer);
+            const expression = this.expressionInput ? this.expressionInput.value.trim() : '';
+            if (!expression) {
+                this.clearOutputs();
+                if (this.result) {
+                    this.result.style.display = 'none';
+                }
+                return;
+            }
+
+            const params = new URLSearchParams({
+                expr: expression,
+                dialect: this.dialectSelect.value,
+                tz: this.timezoneInput.value.trim(),
+                count: this.countInput.value || '10',
+            });
+
+            try {
+                const response = await fetch(`/api/cron/parse?${params}`);
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+                }
+
+                const data = await response.json();
+
+                // Ignore responses for an expression the user has since changed
+                if (this.expressionInput.value.trim() !== expression) {
+                    return;
+                }
+                this.displayResult(data);
+            } catch (error) {
+                if (this.expressionInput.value.trim() !== expression) {
+                    return;
+                }
+                this.clearOutputs();
+                this.showResult(false, error.message, '');
+            }
+        }
+
+        displayResult(data) {
+            this.showResult(true, data.description, data.detection);
+            this.displayWarnings(data.warnings || []);
+            this.displayFields(data.fields || []);
+            this.displayRuns(data);
+        }
+
+        displayWarnings(warnings) {
+            if (!this.warningsList) return;
+
+            this.warningsList.innerHTML = '';
+            this.warnings.style.display = warnings.length ? 'block' : 'none';
+            warnings.forEach(warning => {
+                const item = document.createElement('div');
+                item.className = 'p-3 rounded border-l-4 bg-yellow-900/20 border-yellow-500 text-white text-sm';
+                item.textContent = warning;
+                this.warningsList.appendChild(item);
+            });
+        }
+
+        displayFields(fields) {
+            if (!this.fieldsBody) return;
+
+            this.fieldsBody.innerHTML = '';
+            this.fields.style.display = fields.length ? 'block' : 'none';
+            fields.forEach(field => {
+                const row = document.createElement('tr');
+                row.className = 'border-t border-[#101e23] align-top';
+
+                [
+                    { text: field.name, className: 'py-1 pr-4 whitespace-nowrap' },
+                    { text: field.value, className: 'py-1 pr-4 font-mono' },
+                    { text: field.values || '', className: 'py-1 pr-4 font-mono break-all text-[#90bbcb]' },
+                    { text: field.description, className: 'py-1 text-[#90bbcb]' },
+                ].forEach(cell => {
+                    const td = document.createElement('td');
+                    td.className = cell.className;
+                    td.textContent = cell.text;
+                    row.appendChild(td);
+                });
+
+                this.fieldsBody.appendChild(row);
+            });
+        }
+
+        displayRuns(data) {
+            if (!this.runsList) return;
+
+            this.runsList.innerHTML = '';
+            this.runs.style.display = 'block';
+            this.runsZone.textContent = `(${data.timezone})`;
+
+            if (!data.runs.length) {
+                const empty = document.createElement('li');
+                empty.className = 'text-[#90bbcb] text-sm';
+                empty.textContent = 'No upcoming runs';
+                this.runsList.appendChild(empty);
+                return;
+            }
+
+            data.runs.forEach(run => {
+                const item = document.createElement('li');
+                item.className = run.dst
+                    ? 'p-3 rounded border-l-4 bg-yellow-900/20 border-yellow-500'
+                    : 'p-3 rounded bg-[#101e23]';
+
+                const line = document.createElement('div');
+                line.className = 'flex flex-wrap justify-between gap-2';
+
+                const time = document.createElement('span');
+                time.className = 'text-white font-mono text-sm';
+                time.textContent = run.local;
+
+                const relative = document.createElement('span');
+                relative.className = 'text-[#90bbcb] text-sm';
+                relative.textContent = run.dst ? `${run.relative} • DST ${run.dst}` : run.relative;
+
+                line.appendChild(time);
+                line.appendChild(relative);
+                item.appendChild(line);
+
+                if (run.note) {
+                    const note = document.createElement('div');
+                    note.className = 'text-yellow-400 text-xs mt-1';
+                    note.textContent = run.note;
+                    item.appendChild(note);
+                }
+
+                this.runsList.appendChild(item);
+            });
+        }
+
+        showResult(isValid, message, detection) {
+            if (this.result) {
+                this.result.style.display = 'block';
+                this.result.className = isValid
+                    ? 'p-4 rounded-lg bg-green-900/30 border border-green-600/50'
+                    : 'p-4 rounded-lg bg-red-900/30 border border-red-600/50';
+            }
+
+            if (this.resultMessage) {
+                this.resultMessage.textContent = message;
+                this.resultMessage.className = isValid
+                    ? 'text-green-400 font-medium'
+                    : 'text-red-400 font-medium';
+            }
+
+            if (this.detection) {
+                this.detection.textContent = detection;
+            }
+        }
+
+        clearOutputs() {
+            [this.warnings, this.fields, this.runs].forEach(element => {
+                if (element) {
+                    element.style.display = 'none';
+                }
+            });
+        }
+
+        clearAll() {
+            if (this.expressionInput) {
+                this.expressionInput.value = '';
+            }
+            clearTimeout(this.parseTimer);
+            this.clearOutputs();
+            if (this.result) {
+                this.result.style.display = 'none';
+            }
+        }
+    }
+
+    // Store the class globally to prevent redeclaration
+    window.CronParser = CronParser;
+
+    // Function to initialize the parser
+    window.initCronParser = function() {
+        if (window.cronParserInstance) {
+            window.cronParserInstance = null;
+        }
+
+        if (document.querySelector('.cron-parser-container')) {
+            window.cronParserInstance = new CronParser();
+        }
+    };
+})();
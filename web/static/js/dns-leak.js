@@ -0,0 +1,247 @@
+// DNS Leak Test Tool JavaScript
+(function() {
+    'use strict';
+
+    // Prevent multiple initializations
+    if (window.DNSLeakTest) {
+        return;
+    }
+
+    // How long to wait for each probe lookup before moving on
+    const PROBE_TIMEOUT_MS = 4000;
+    // Time for slower resolvers to reach the authoritative server after the probes finish
+    const SETTLE_DELAY_MS = 1500;
+
+    class DNSLeakTest {
+        constructor() {
+            this.token = null;
+            this.initializeElements();
+            this.bindEvents();
+        }
+
+        initializeElements() {
+            // Controls
+            this.startBtn = document.getElementById('leak-start-btn');
+            this.refreshBtn = document.getElementById('leak-refresh-btn');
+            this.status = document.getElementById('leak-status');
+            this.progress = document.getElementById('leak-progress');
+            this.progressBar = document.getElementById('leak-progress-bar');
+
+            // Output elements
+            this.result = document.getElementById('leak-result');
+            this.resultMessage = document.getElementById('leak-result-message');
+            this.summary = document.getElementById('leak-summary');
+            this.clientIP = document.getElementById('leak-client-ip');
+            this.clientISP = document.getElementById('leak-client-isp');
+            this.resolverCount = document.getElementById('leak-resolver-count');
+            this.probeCount = document.getElementById('leak-probe-count');
+            this.notes = document.getElementById('leak-notes');
+            this.notesList = document.getElementById('leak-notes-list');
+            this.resolvers = document.getElementById('leak-resolvers');
+            this.resolversBody = document.getElementById('leak-resolvers-body');
+        }
+
+        bindEvents() {
+            if (this.startBtn) {
+                this.startBtn.addEventListener('click', () => this.runTest());
+            }
+
+            if (this.refreshBtn) {
+                this.refreshBtn.addEventListener('click', () => this.loadResults());
+            }
+        }
+
+        async runTest() {
+            this.startBtn.disabled = true;
+            this.startBtn.textContent = 'Testing...';
+            this.refreshBtn.style.display = 'none';
+            this.setProgress(0);
+            this.setStatus('Starting test...');
+
+            try {
+                const response = await fetch('/api/dns-leak/start', { method: 'POST' });
+                if (!response.ok) {
+                    throw new Error(await response.text() || `HTTP ${response.status}`);
+                }
+
+                const test = await response.json();
+                this.token = test.token;
+
+                let completed = 0;
+                this.setStatus(`Resolving ${test.probes.length} probe hostnames...`);
+                await Promise.all(test.probes.map(host => this.probe(host).then(() => {
+                    completed++;
+                    this.setProgress(completed / test.probes.length * 100);
+                })));
+
+                this.setStatus('Waiting for resolvers...');
+                await new Promise(resolve => setTimeout(resolve, SETTLE_DELAY_MS));
+                await this.loadResults();
+                this.refreshBtn.style.display = '';
+            } catch (error) {
+                this.showResult(false, `DNS leak test failed: ${error.message}`);
+                this.setStatus('Failed');
+            } finally {
+                this.startBtn.disabled = false;
+                this.startBtn.textContent = 'Run Leak Test';
+                this.progress.style.display = 'none';
+            }
+        }
+
+        // probe makes the browser resolve a hostname; the request itself is expected to fail
+        probe(host) {
+            const controller = new AbortController();
+            const timer = setTimeout(() => controller.abort(), PROBE_TIMEOUT_MS);
+            return fetch(`https://${host}/`, { mode: 'no-cors', cache: 'no-store', signal: controller.signal })
+                .catch(() => {})
+                .finally(() => clearTimeout(timer));
+        }
+
+        async loadResults() {
+            if (!this.token) return;
+
+            this.setStatus('Analyzing resolvers...');
+            try {
+                const response = await fetch(`/api/dns-leak/results/${encodeURIComponent(this.token)}`);
+                if (!response.ok) {
+                    throw new Error(await response.text() || `HTTP ${response.status}`);
+                }
+
+                const data = await response.json();
+                this.displayResult(data);
+                this.setStatus(`Test ${data.token} - expires ${new Date(data.expires_at).toLocaleTimeString()}`);
+            } catch (error) {
+                this.showResult(false, `Failed to load results: ${error.message}`);
+                this.setStatus('Failed');
+            }
+        }
+
+        displayResult(data) {
+            if (!data.resolvers.length) {
+                this.showResult(false, 'No resolver queries were received yet. Try refreshing the results in a few seconds.');
+            } else if (data.leak) {
+                this.showResult(false, `Possible DNS leak: ${data.resolvers.length} resolver${data.resolvers.length === 1 ? '' : 's'} seen, some outside your network.`);
+            } else {
+                this.showResult(true, `No leak detected: all ${data.resolvers.length} resolver${data.resolvers.length === 1 ? '' : 's'} belong to your network.`);
+            }
+
+            this.displaySummary(data);
+            this.displayNotes(data.notes);
+            this.displayResolvers(data.resolvers);
+        }
+
+        displaySummary(data) {
+            if (!this.summary) return;
+
+            this.summary.style.display = 'block';
+            this.clientIP.textContent = data.client_ip || '-';
+            this.clientISP.textContent = this.providerName(data.client);
+            this.resolverCount.textContent = data.resolvers.length;
+            this.probeCount.textContent = `${data.probes_seen} / ${data.probes_sent}`;
+        }
+
+        displayNotes(notes) {
+            if (!this.notesList) return;
+
+            this.notesList.innerHTML = '';
+            if (!notes.length) {
+                this.notes.style.display = 'none';
+                return;
+            }
+            this.notes.style.display = 'block';
+
+            notes.forEach(note => {
+                const item = document.createElement('li');
+                item.textContent = note;
+                this.notesList.appendChild(item);
+            });
+        }
+
+        displayResolvers(resolvers) {
+            if (!this.resolversBody) return;
+
+            this.resolversBody.innerHTML = '';
+            if (!resolvers.length) {
+                this.resolvers.style.display = 'none';
+                return;
+            }
+            this.resolvers.style.display = 'block';
+
+            resolvers.forEach(resolver => {
+                const info = resolver.info || {};
+                const geo = info.geolocation || {};
+                const location = [geo.city, geo.country].filter(Boolean).join(', ');
+
+                const row = document.createElement('tr');
+                row.className = 'border-t border-[#101e23]';
+                const values = [
+                    resolver.ip,
+                    (info.dns && info.dns.hostname) || '-',
+                    this.providerName(info),
+                    (info.isp && info.isp.asn) || '-',
+                    location || '-',
+                    resolver.queries,
+                    resolver.same_network ? 'Yours' : 'External',
+                ];
+                values.forEach((value, index) => {
+                    const cell = document.createElement('td');
+                    cell.className = index === 0 || index === 1 ? 'py-1 pr-4 font-mono break-all' : 'py-1 pr-4 whitespace-nowrap';
+                    if (index === 6) {
+                        cell.className += resolver.same_network ? ' text-green-400' : ' text-yellow-400';
+                    }
+                    cell.textContent = value;
+                    row.appendChild(cell);
+                });
+                this.resolversBody.appendChild(row);
+            });
+        }
+
+        providerName(info) {
+            if (!info || !info.isp) return '-';
+            return info.isp.organization || info.isp.provider || info.isp.asn_name || '-';
+        }
+
+        setStatus(message) {
+            if (this.status) {
+                this.status.textContent = message;
+            }
+        }
+
+        setProgress(percent) {
+            if (this.progress) {
+                this.progress.style.display = 'block';
+                this.progressBar.style.width = `${percent}%`;
+            }
+        }
+
+        showResult(isValid, message) {
+            if (this.result) {
+                this.result.style.display = 'block';
+                this.result.className = isValid
+                    ? 'p-4 rounded-lg bg-green-900/30 border border-green-600/50'
+                    : 'p-4 rounded-lg bg-red-900/30 border border-red-600/50';
+            }
+
+            if (this.resultMessage) {
+                this.resultMessage.textContent = message;
+                this.resultMessage.className = isValid
+                    ? 'text-green-400 font-medium'
+                    : 'text-red-400 font-medium';
+            }
+        }
+    }
+
+    // Store the class globally to prevent redeclaration
+    window.DNSLeakTest = DNSLeakTest;
+
+    // Function to initialize the leak test
+    window.initDNSLeakTest = function() {
+        if (window.dnsLeakTestInstance) {
+            window.dnsLeakTestInstance = null;
+        }
+
+        if (document.querySelector('.dns-leak-container')) {
+            window.dnsLeakTestInstance = new DNSLeakTest();
+        }
+    };
+})();
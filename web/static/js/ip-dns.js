@@ -34,6 +34,8 @@
             // DNS Analysis elements
             this.domainInput = document.getElementById('domain-input');
             this.recordTypeSelect = document.getElementById('record-type');
+            this.dnsTransportSelect = document.getElementById('dns-transport');
+            this.dnsServerInput = document.getElementById('dns-server');
             this.dnsLookupBtn = document.getElementById('dns-lookup-btn');
             this.dnsResults = document.getElementById('dns-results');
             this.dnsLoading = document.getElementById('dns-loading');
@@ -378,7 +380,9 @@
                     },
                     body: JSON.stringify({
                         domain: domain,
-                        type: recordType
+                        type: recordType,
+                        transport: this.dnsTransportSelect ? this.dnsTransportSelect.value : 'system',
+                        server: this.dnsServerInput ? this.dnsServerInput.value.trim() : ''
                     })
                 });
 
@@ -597,6 +601,7 @@
                         <h3 class="text-white text-lg font-semibold">DNS Lookup Results</h3>
                         <div class="flex gap-2 items-center">
                             <span class="text-[#90bbcb] text-sm">Query time: ${data.query_time_ms || 0}ms</span>
+                            <span class="text-[#90bbcb] text-sm">Latency: ${(data.latency_ms || 0).toFixed(1)}ms</span>
                         </div>
                     </div>
                     
@@ -605,12 +610,26 @@
                             <span class="text-[#90bbcb] text-sm">Domain:</span>
                             <span class="text-white text-sm font-mono">${data.domain || 'Unknown'}</span>
                         </div>
+                        <div class="flex justify-between items-center mt-1">
+                            <span class="text-[#90bbcb] text-sm">Transport:</span>
+                            <span class="text-white text-sm font-mono">${data.transport || 'system'}${data.server ? ' via ' + data.server : ''}</span>
+                        </div>
                         <div class="flex justify-between items-center mt-1">
                             <span class="text-[#90bbcb] text-sm">Records found:</span>
                             <span class="text-white text-sm">${records.length}</span>
                         </div>
                     </div>
 
+                    ${data.statuses && data.statuses.length > 0 ? `
+                    <div class="flex flex-wrap gap-2 mb-4">
+                        ${data.statuses.map(status => `
+                            <span class="px-2 py-1 rounded text-xs font-mono ${status.status === 'ok' ? 'bg-green-600 text-white' : status.status === 'NODATA' ? 'bg-[#315968] text-[#90bbcb]' : 'bg-red-600 text-white'}" title="${status.error || status.note || ''}">
+                                ${status.type}: ${status.status}${status.inferred ? '?' : ''} (${status.query_time_ms.toFixed(1)}ms)
+                            </span>
+                        `).join('')}
+                    </div>
+                    ` : ''}
+
                     ${records.length === 0 ? `
                     <div class="text-center py-8">
                         <div class="text-[#90bbcb] text-sm">No records found for this domain and record type.</div>
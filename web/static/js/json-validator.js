@@ -25,12 +25,24 @@
             this.minifyBtn = document.getElementById('minify-btn');
             this.convertBtn = document.getElementById('convert-btn');
             this.clearBtn = document.getElementById('clear-btn');
+            this.validateSchemaBtn = document.getElementById('validate-schema-btn');
+            this.runQueryBtn = document.getElementById('run-query-btn');
             
             // Output elements
             this.validationResults = document.getElementById('validation-results');
             this.validationMessage = document.getElementById('validation-message');
             this.formattedOutput = document.getElementById('formatted-output');
             this.analysisResults = document.getElementById('analysis-results');
+            this.schemaResults = document.getElementById('schema-results');
+            this.queryResults = document.getElementById('query-results');
+
+            // Schema elements
+            this.schemaInput = document.getElementById('schema-input');
+            this.ignoreFormatsCheckbox = document.getElementById('ignore-formats');
+
+            // Query elements
+            this.queryInput = document.getElementById('query-input');
+            this.queryLanguage = document.getElementById('query-language');
             
             // Settings elements
             this.indentSelect = document.getElementById('indent-select');
@@ -46,6 +58,7 @@
             this.jsonKeys = document.getElementById('json-keys');
             this.jsonDepth = document.getElementById('json-depth');
             this.jsonType = document.getElementById('json-type');
+            this.jsonHazards = document.getElementById('json-hazards');
         }
 
         bindEvents() {
@@ -70,6 +83,22 @@
                 this.clearBtn.addEventListener('click', () => this.clearAll());
             }
 
+            if (this.validateSchemaBtn) {
+                this.validateSchemaBtn.addEventListener('click', () => this.validateSchema());
+            }
+
+            if (this.runQueryBtn) {
+                this.runQueryBtn.addEventListener('click', () => this.runQuery());
+            }
+
+            if (this.queryInput) {
+                this.queryInput.addEventListener('keydown', (e) => {
+                    if (e.key === 'Enter') {
+                        this.runQuery();
+                    }
+                });
+            }
+
             // Input events
             if (this.jsonInput) {
                 this.jsonInput.addEventListener('input', () => this.handleInputChange());
@@ -239,6 +268,18 @@
                 return;
             }
 
+            const format = this.formatSelect ? this.formatSelect.value : 'formatted';
+            if (['json-schema', 'go', 'typescript'].includes(format)) {
+                this.inferTypes(input, format);
+                return;
+            }
+
+            const serverFormats = { 'xml': 'xml', 'yaml': 'yaml', 'toml': 'toml', 'csv': 'csv', 'tsv': 'tsv', 'url-encoded': 'query' };
+            if (serverFormats[format]) {
+                this.convertOnServer(input, serverFormats[format], format);
+                return;
+            }
+
             let parsed;
             try {
                 parsed = JSON.parse(input);
@@ -248,23 +289,9 @@
                 return;
             }
 
-            const format = this.formatSelect ? this.formatSelect.value : 'formatted';
-            
             try {
                 let converted;
                 switch (format) {
-                    case 'xml':
-                        converted = this.jsonToXML(parsed);
-                        break;
-                    case 'yaml':
-                        converted = this.jsonToYAML(parsed);
-                        break;
-                    case 'csv':
-                        converted = this.jsonToCSV(parsed);
-                        break;
-                    case 'url-encoded':
-                        converted = this.jsonToURLEncoded(parsed);
-                        break;
                     case 'base64':
                         converted = this.jsonToBase64(parsed);
                         break;
@@ -292,128 +319,71 @@
             }
         }
 
-        jsonToXML(obj, rootName = 'root') {
-            const self = this; // Store reference to this
-            let xml = '<?xml version="1.0" encoding="UTF-8"?>\n';
-            
-            function convertValue(value, key) {
-                if (value === null) return `<${key}></${key}>`;
-                if (typeof value === 'object' && !Array.isArray(value)) {
-                    let result = `<${key}>`;
-                    for (const [k, v] of Object.entries(value)) {
-                        result += convertValue(v, k);
-                    }
-                    result += `</${key}>`;
-                    return result;
-                } else if (Array.isArray(value)) {
-                    return value.map(item => convertValue(item, key)).join('');
-                } else {
-                    return `<${key}>${self.escapeXML(String(value))}</${key}>`;
-                }
-            }
-            
-            xml += convertValue(obj, rootName);
-            return xml;
-        }
+        async convertOnServer(input, to, label) {
+            try {
+                const response = await fetch(`/api/convert?from=json&to=${to}`, {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: input
+                });
 
-        jsonToYAML(obj, indent = 0) {
-            const spaces = '  '.repeat(indent);
-            let yaml = '';
-            
-            if (Array.isArray(obj)) {
-                for (const item of obj) {
-                    if (typeof item === 'object' && item !== null) {
-                        yaml += `${spaces}- `;
-                        yaml += this.jsonToYAML(item, indent + 1).trim() + '\n';
-                    } else {
-                        yaml += `${spaces}- ${this.yamlValue(item)}\n`;
-                    }
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP error! status: ${response.status}`);
                 }
-            } else if (typeof obj === 'object' && obj !== null) {
-                for (const [key, value] of Object.entries(obj)) {
-                    if (typeof value === 'object' && value !== null) {
-                        yaml += `${spaces}${key}:\n`;
-                        yaml += this.jsonToYAML(value, indent + 1);
-                    } else {
-                        yaml += `${spaces}${key}: ${this.yamlValue(value)}\n`;
-                    }
+
+                const converted = await response.text();
+                const warnings = response.headers.get('X-Conversion-Warnings');
+                this.clearErrors();
+                this.showFormattedOutput(converted);
+                this.showValidationResult(true, `JSON converted to ${label} successfully!`);
+                if (warnings) {
+                    this.showError('Conversion warnings: ' + warnings);
                 }
-            } else {
-                return this.yamlValue(obj);
+            } catch (error) {
+                this.showError('Error converting JSON: ' + error.message);
             }
-            
-            return yaml;
         }
 
-        jsonToCSV(obj) {
-            if (!Array.isArray(obj)) {
-                // If it's a single object, wrap it in an array
-                if (typeof obj === 'object' && obj !== null) {
-                    obj = [obj];
-                } else {
-                    throw new Error('CSV conversion requires an array of objects or a single object');
-                }
-            }
-            
-            if (obj.length === 0) return '';
-            
-            // Get all unique keys
-            const keys = new Set();
-            obj.forEach(item => {
-                if (typeof item === 'object' && item !== null) {
-                    Object.keys(item).forEach(key => keys.add(key));
+        async inferTypes(input, format) {
+            // Accept one document, or one sample per line to merge several
+            let samples;
+            try {
+                JSON.parse(input);
+                samples = [input];
+            } catch (error) {
+                samples = input.split('\n').map(line => line.trim()).filter(line => line);
+                try {
+                    samples.forEach(sample => JSON.parse(sample));
+                } catch (lineError) {
+                    this.showValidationResult(false, `Invalid JSON: ${this.parseJSONError(error)}`);
+                    this.showError('Cannot convert invalid JSON: ' + error.message);
+                    return;
                 }
-            });
-            
-            if (keys.size === 0) {
-                // Handle array of primitives
-                return obj.map((item, index) => `"${index}","${String(item).replace(/"/g, '""')}"`).join('\n');
             }
-            
-            const headers = Array.from(keys);
-            let csv = headers.map(h => `"${h}"`).join(',') + '\n';
-            
-            obj.forEach(item => {
-                const row = headers.map(header => {
-                    const value = item && typeof item === 'object' ? item[header] : '';
-                    let cellValue = '';
-                    if (value === null || value === undefined) {
-                        cellValue = '';
-                    } else if (typeof value === 'object') {
-                        cellValue = JSON.stringify(value);
-                    } else {
-                        cellValue = String(value);
-                    }
-                    return `"${cellValue.replace(/"/g, '""')}"`;
+
+            try {
+                const response = await fetch('/api/json/infer', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: `{"samples":[${samples.join(',')}]}`
                 });
-                csv += row.join(',') + '\n';
-            });
-            
-            return csv;
-        }
 
-        jsonToURLEncoded(obj) {
-            const params = new URLSearchParams();
-            
-            function addParam(key, value) {
-                if (value === null || value === undefined) {
-                    params.append(key, '');
-                } else if (typeof value === 'object') {
-                    params.append(key, JSON.stringify(value));
-                } else {
-                    params.append(key, String(value));
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP error! status: ${response.status}`);
                 }
+
+                const data = await response.json();
+                const outputs = {
+                    'json-schema': JSON.stringify(data.schema, null, 2),
+                    'go': data.go,
+                    'typescript': data.typescript
+                };
+                this.clearErrors();
+                this.showFormattedOutput(outputs[format]);
+                this.showValidationResult(true, `Types inferred from ${data.samples} sample${data.samples === 1 ? '' : 's'}!`);
+            } catch (error) {
+                this.showError('Error inferring types: ' + error.message);
             }
-            
-            if (typeof obj === 'object' && !Array.isArray(obj)) {
-                for (const [key, value] of Object.entries(obj)) {
-                    addParam(key, value);
-                }
-            } else {
-                params.append('data', JSON.stringify(obj));
-            }
-            
-            return params.toString();
         }
 
         jsonToBase64(obj) {
@@ -421,19 +391,6 @@
             return btoa(unescape(encodeURIComponent(jsonString)));
         }
 
-        yamlValue(value) {
-            if (value === null) return 'null';
-            if (typeof value === 'string') {
-                // Check if string needs quotes
-                if (value.includes('\n') || value.includes(':') || value.includes('-') || 
-                    value.trim() !== value || /^[0-9]/.test(value)) {
-                    return `"${value.replace(/"/g, '\\"')}"`;
-                }
-                return value;
-            }
-            return String(value);
-        }
-
         escapeXML(str) {
             return str.replace(/[<>&'"]/g, function (c) {
                 switch (c) {
@@ -499,9 +456,79 @@
             if (this.analysisResults) {
                 this.analysisResults.style.display = 'block';
             }
+
+            // JSON.parse hides big integers, duplicate keys and bad encodings, so
+            // hazards come from the server once typing pauses
+            clearTimeout(this.analysisTimer);
+            this.analysisTimer = setTimeout(() => this.analyzeOnServer(), 400);
+        }
+
+        async analyzeOnServer() {
+            const input = this.jsonInput ? this.jsonInput.value : '';
+            if (!input.trim()) return;
+
+            try {
+                const response = await fetch('/api/json/analyze', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: input
+                });
+
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP error! status: ${response.status}`);
+                }
+
+                const data = await response.json();
+                if (!data.valid || !this.jsonInput || this.jsonInput.value !== input) return;
+                this.displayHazards(data);
+            } catch (error) {
+                console.error('Error analyzing JSON:', error);
+            }
+        }
+
+        displayHazards(data) {
+            if (this.jsonKeys) this.jsonKeys.textContent = data.keys.toString();
+            if (this.jsonDepth) this.jsonDepth.textContent = data.max_depth.toString();
+            if (!this.jsonHazards) return;
+
+            const labels = {
+                unsafe_integer: 'Unsafe integer',
+                precision_loss: 'Precision loss',
+                number_overflow: 'Number overflow',
+                duplicate_key: 'Duplicate key',
+                invalid_utf8: 'Invalid UTF-8',
+                lone_surrogate: 'Lone surrogate'
+            };
+            const hazards = data.hazards || [];
+            const total = Object.values(data.hazard_counts || {}).reduce((sum, count) => sum + count, 0);
+
+            if (total === 0) {
+                this.jsonHazards.innerHTML = '<div class="text-green-400 font-medium">No interoperability hazards found</div>';
+                return;
+            }
+
+            const renderHazard = (hazard) => `
+                <li class="border-b border-[#315968] py-2">
+                    <div class="flex justify-between text-xs mb-1">
+                        <span class="text-yellow-400 font-medium">${labels[hazard.type] || this.escapeXML(hazard.type)}</span>
+                        <span class="text-[#90bbcb] font-mono">Line ${hazard.line}, Column ${hazard.column}</span>
+                    </div>
+                    ${hazard.path ? `<div class="text-[#90bbcb] text-xs font-mono mb-1">${this.escapeXML(hazard.path)}</div>` : ''}
+                    <div class="text-white text-sm">${this.escapeXML(hazard.message)}</div>
+                </li>
+            `;
+
+            this.jsonHazards.innerHTML = `
+                <div class="text-yellow-400 font-medium mb-2">
+                    ${total} hazard${total === 1 ? '' : 's'} found${data.hazards_truncated ? ` &middot; showing first ${hazards.length}` : ''}
+                </div>
+                <ul class="max-h-72 overflow-y-auto">${hazards.map(renderHazard).join('')}</ul>
+            `;
         }
 
         clearAnalysis() {
+            clearTimeout(this.analysisTimer);
+            if (this.jsonHazards) this.jsonHazards.innerHTML = '';
             if (this.jsonSize) this.jsonSize.textContent = '-';
             if (this.jsonKeys) this.jsonKeys.textContent = '-';
             if (this.jsonDepth) this.jsonDepth.textContent = '-';
@@ -575,6 +602,144 @@
             return message;
         }
 
+        async validateSchema() {
+            const input = this.jsonInput ? this.jsonInput.value.trim() : '';
+            const schema = this.schemaInput ? this.schemaInput.value.trim() : '';
+
+            if (!input || !schema) {
+                this.showValidationResult(false, 'Please enter both JSON and a schema');
+                return;
+            }
+
+            // Check syntax locally so the raw text can be sent with numbers intact
+            try {
+                JSON.parse(input);
+            } catch (error) {
+                this.showValidationResult(false, `Invalid JSON: ${this.parseJSONError(error)}`);
+                return;
+            }
+            try {
+                JSON.parse(schema);
+            } catch (error) {
+                this.showValidationResult(false, `Invalid schema: ${this.parseJSONError(error)}`);
+                return;
+            }
+
+            const ignoreFormats = this.ignoreFormatsCheckbox ? this.ignoreFormatsCheckbox.checked : false;
+            const body = `{"schema":${schema},"instance":${input},"ignore_formats":${ignoreFormats}}`;
+
+            try {
+                const response = await fetch('/api/json/schema', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: body
+                });
+
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP error! status: ${response.status}`);
+                }
+
+                const data = await response.json();
+                this.clearErrors();
+                this.displaySchemaResults(data);
+            } catch (error) {
+                console.error('Error validating against schema:', error);
+                this.showError(error.message);
+            }
+        }
+
+        displaySchemaResults(data) {
+            if (!this.schemaResults) return;
+
+            const errors = data.errors || [];
+            const warnings = (data.warnings || []).map(warning => `
+                <div class="text-yellow-400 text-sm mb-2">${this.escapeXML(warning)}</div>
+            `).join('');
+
+            const renderError = (error) => `
+                <li class="border-b border-[#315968] py-2">
+                    <div class="text-red-300 text-sm">${this.escapeXML(error.message)}</div>
+                    <div class="text-[#90bbcb] text-xs font-mono mt-1">
+                        at ${this.escapeXML(error.instance_location || '/')} &middot; ${this.escapeXML(error.keyword)} &middot; ${this.escapeXML(error.keyword_location || '#')}
+                    </div>
+                    ${error.causes && error.causes.length > 0 ? `
+                    <ul class="ml-4 mt-1">${error.causes.map(renderError).join('')}</ul>
+                    ` : ''}
+                </li>
+            `;
+
+            this.schemaResults.innerHTML = `
+                ${warnings}
+                ${data.valid ? `
+                <div class="text-green-400 font-medium">Valid! The JSON matches the schema.</div>
+                ` : `
+                <div class="text-red-400 font-medium mb-2">${errors.length} schema violation${errors.length === 1 ? '' : 's'}</div>
+                <ul>${errors.map(renderError).join('')}</ul>
+                `}
+            `;
+            this.schemaResults.style.display = 'block';
+        }
+
+        async runQuery() {
+            const input = this.jsonInput ? this.jsonInput.value.trim() : '';
+            const query = this.queryInput ? this.queryInput.value.trim() : '';
+
+            if (!input || !query) {
+                this.showValidationResult(false, 'Please enter both JSON and a query');
+                return;
+            }
+
+            try {
+                JSON.parse(input);
+            } catch (error) {
+                this.showValidationResult(false, `Invalid JSON: ${this.parseJSONError(error)}`);
+                return;
+            }
+
+            const language = this.queryLanguage ? this.queryLanguage.value : '';
+            const body = `{"document":${input},"query":${JSON.stringify(query)},"language":${JSON.stringify(language)}}`;
+
+            try {
+                const response = await fetch('/api/json/query', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: body
+                });
+
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP error! status: ${response.status}`);
+                }
+
+                const data = await response.json();
+                this.clearErrors();
+                this.displayQueryResults(data);
+            } catch (error) {
+                console.error('Error running query:', error);
+                this.showError(error.message);
+            }
+        }
+
+        displayQueryResults(data) {
+            if (!this.queryResults) return;
+
+            const results = data.results || [];
+            const language = data.language === 'jq' ? 'jq' : 'JSONPath';
+            const renderMatch = (match) => `
+                <li class="border-b border-[#315968] py-2">
+                    ${match.path ? `<div class="text-[#90bbcb] text-xs font-mono mb-1">${this.escapeXML(match.path)}</div>` : ''}
+                    <pre class="text-white text-sm font-mono whitespace-pre-wrap break-all">${this.escapeXML(JSON.stringify(match.value, null, 2))}</pre>
+                </li>
+            `;
+
+            this.queryResults.innerHTML = `
+                <div class="text-green-400 font-medium mb-2">
+                    ${data.count} result${data.count === 1 ? '' : 's'} (${language}, ${data.query_time_ms.toFixed(2)} ms)${data.truncated ? ` &middot; showing first ${results.length}` : ''}
+                </div>
+                <ul class="max-h-96 overflow-y-auto">${results.map(renderMatch).join('')}</ul>
+            `;
+            this.queryResults.style.display = 'block';
+        }
+
         showValidationResult(isValid, message) {
             // Clear any existing timeout
             if (this.validationTimeout) {
@@ -658,6 +823,12 @@
                 this.validationTimeout = null;
             }
             this.clearAnalysis();
+            if (this.schemaResults) {
+                this.schemaResults.style.display = 'none';
+            }
+            if (this.queryResults) {
+                this.queryResults.style.display = 'none';
+            }
         }
 
         copyToClipboard(button) {
@@ -48,6 +48,7 @@
 
             // Error elements
             this.timestampError = document.getElementById('timestamp-error');
+            this.timestampDetection = document.getElementById('timestamp-detection');
             this.dateError = document.getElementById('date-error');
         }
 
@@ -315,36 +316,62 @@
         convertTimestampToDate() {
             const input = this.timestampInput.value.trim();
             this.clearError('timestamp');
+            clearTimeout(this.convertTimer);
 
             if (!input) {
                 this.clearOutputs(['utc', 'local']);
+                this.showDetection('');
                 return;
             }
 
+            this.convertTimer = setTimeout(() => this.convertOnServer(input), 300);
+        }
+
+        async convertOnServer(input) {
+            const localZone = Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC';
+            const params = new URLSearchParams({ input: input, in: localZone, tz: `UTC,${localZone}` });
+
             try {
-                const timestamp = this.parseTimestamp(input);
-                const date = new Date(timestamp);
+                const response = await fetch(`/api/time/convert?${params}`);
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || `HTTP ${response.status}`);
+                }
+                const data = await response.json();
 
-                if (isNaN(date.getTime())) {
-                    throw new Error('Invalid timestamp');
+                // Ignore responses for input the user has since changed
+                if (this.timestampInput.value.trim() !== input) {
+                    return;
                 }
 
-                // UTC time
+                const human = zone => {
+                    const format = zone && zone.formats.find(f => f.name === 'Human');
+                    return format ? `${format.value} (UTC${zone.offset})` : '';
+                };
                 if (this.utcOutput) {
-                    this.utcOutput.textContent = this.formatFullDate(date, true);
+                    this.utcOutput.textContent = human(data.zones[0]);
                 }
-
-                // Local time
                 if (this.localOutput) {
-                    this.localOutput.textContent = this.formatFullDate(date, false);
+                    this.localOutput.textContent = human(data.zones[1] || data.zones[0]);
                 }
 
+                const notes = [data.detection, data.relative].concat(data.warnings || []);
+                this.showDetection(notes.filter(Boolean).join(' \u2022 '));
             } catch (error) {
-                this.showError('timestamp', 'Invalid timestamp format');
+                if (this.timestampInput.value.trim() !== input) {
+                    return;
+                }
+                this.showError('timestamp', error.message.replace(/^Conversion failed: /, ''));
                 this.clearOutputs(['utc', 'local']);
+                this.showDetection('');
             }
         }
 
+        showDetection(message) {
+            if (!this.timestampDetection) return;
+            this.timestampDetection.textContent = message;
+            this.timestampDetection.style.display = message ? 'block' : 'none';
+        }
+
         convertDateToTimestamp() {
             this.clearError('date');
 
@@ -386,31 +413,6 @@
             }
         }
 
-        parseTimestamp(input) {
-            const num = parseFloat(input);
-            
-            if (isNaN(num)) {
-                throw new Error('Not a valid number');
-            }
-
-            // Auto-detect format based on length
-            const str = input.toString();
-            
-            if (str.length <= 10) {
-                // Seconds
-                return num * 1000;
-            } else if (str.length <= 13) {
-                // Milliseconds
-                return num;
-            } else if (str.length <= 16) {
-                // Microseconds
-                return num / 1000;
-            } else {
-                // Nanoseconds
-                return num / 1000000;
-            }
-        }
-
         formatFullDate(date, isUTC = false) {
             const options = {
                 weekday: 'long',
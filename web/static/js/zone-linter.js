@@ -0,0 +1,265 @@
+// DNS Zone Linter Tool JavaScript
+(function() {
+    'use strict';
+
+    // Prevent multiple initializations
+    if (window.ZoneLinter) {
+        return;
+    }
+
+    const SAMPLE_ZONE = `$ORIGIN example.com.
+$TTL 3600
+@       IN  SOA ns1.example.com. hostmaster.example.com. (
+                2024010101 ; serial
+                7200       ; refresh
+                3600       ; retry
+                1209600    ; expire
+                300 )      ; minimum
+        IN  NS  ns1
+        IN  NS  ns2.example.com
+        IN  MX  10 mail
+        IN  MX  20 backup-mx.example.net.
+ns1     IN  A   192.0.2.1
+mail    IN  A   192.0.2.10
+www     IN  CNAME web
+www     IN  TXT "site verification"
+web     IN  A   192.0.2.20
+web     IN  A   192.0.2.20
+`;
+
+    class ZoneLinter {
+        constructor() {
+            this.initializeElements();
+            this.bindEvents();
+
+            // Load sample zone if input is empty
+            if (this.zoneInput && !this.zoneInput.value.trim()) {
+                this.zoneInput.value = SAMPLE_ZONE;
+            }
+        }
+
+        initializeElements() {
+            // Input elements
+            this.zoneInput = document.getElementById('zone-input');
+            this.originInput = document.getElementById('zone-origin');
+            this.defaultTTLInput = document.getElementById('zone-default-ttl');
+            this.resolveTargetsCheckbox = document.getElementById('zone-resolve-targets');
+
+            // Button elements
+            this.lintBtn = document.getElementById('zone-lint-btn');
+            this.sampleBtn = document.getElementById('zone-sample-btn');
+            this.clearBtn = document.getElementById('zone-clear-btn');
+
+            // Output elements
+            this.result = document.getElementById('zone-result');
+            this.resultMessage = document.getElementById('zone-result-message');
+            this.issues = document.getElementById('zone-issues');
+            this.issuesList = document.getElementById('zone-issues-list');
+            this.records = document.getElementById('zone-records');
+            this.recordsBody = document.getElementById('zone-records-body');
+
+            // Summary elements
+            this.summary = document.getElementById('zone-summary');
+            this.summaryOrigin = document.getElementById('zone-summary-origin');
+            this.summaryRecords = document.getElementById('zone-summary-records');
+            this.summaryErrors = document.getElementById('zone-summary-errors');
+            this.summaryWarnings = document.getElementById('zone-summary-warnings');
+            this.summaryTypes = document.getElementById('zone-summary-types');
+        }
+
+        bindEvents() {
+            if (this.lintBtn) {
+                this.lintBtn.addEventListener('click', () => this.lintZone());
+            }
+
+            if (this.sampleBtn) {
+                this.sampleBtn.addEventListener('click', () => {
+                    this.zoneInput.value = SAMPLE_ZONE;
+                    this.lintZone();
+                });
+            }
+
+            if (this.clearBtn) {
+                this.clearBtn.addEventListener('click', () => this.clearAll());
+            }
+        }
+
+        async lintZone() {
+            const content = this.zoneInput ? this.zoneInput.value : '';
+            if (!content.trim()) {
+                this.showResult(false, 'Please enter a zone file to lint');
+                return;
+            }
+
+            this.lintBtn.disabled = true;
+            this.lintBtn.textContent = 'Linting...';
+
+            try {
+                const response = await fetch('/api/zone/lint', {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify({
+                        content: content,
+                        origin: this.originInput ? this.originInput.value.trim() : '',
+                        default_ttl: this.defaultTTLInput ? parseInt(this.defaultTTLInput.value, 10) || 0 : 0,
+                        resolve_targets: this.resolveTargetsCheckbox ? this.resolveTargetsCheckbox.checked : false,
+                    }),
+                });
+
+                if (!response.ok) {
+                    throw new Error(await response.text() || `HTTP ${response.status}`);
+                }
+
+                const data = await response.json();
+                this.displayResult(data);
+            } catch (error) {
+                this.showResult(false, `Zone lint failed: ${error.message}`);
+            } finally {
+                this.lintBtn.disabled = false;
+                this.lintBtn.textContent = 'Lint Zone';
+            }
+        }
+
+        displayResult(data) {
+            if (data.errors === 0 && data.warnings === 0) {
+                this.showResult(true, `Zone is clean: ${data.records.length} records parsed with no issues.`);
+            } else {
+                this.showResult(data.valid, `Found ${data.errors} error${data.errors === 1 ? '' : 's'} and ${data.warnings} warning${data.warnings === 1 ? '' : 's'} in ${data.records.length} records.`);
+            }
+
+            this.displaySummary(data);
+            this.displayIssues(data.issues);
+            this.displayRecords(data.records);
+        }
+
+        displaySummary(data) {
+            if (!this.summary) return;
+
+            this.summary.style.display = 'block';
+            this.summaryOrigin.textContent = data.origin || '-';
+            this.summaryRecords.textContent = data.records.length;
+            this.summaryErrors.textContent = data.errors;
+            this.summaryWarnings.textContent = data.warnings;
+
+            this.summaryTypes.innerHTML = '';
+            Object.keys(data.summary).sort().forEach(type => {
+                const badge = document.createElement('span');
+                badge.className = 'px-2 py-1 rounded bg-[#101e23] text-[#90bbcb] text-xs font-mono';
+                badge.textContent = `${type}: ${data.summary[type]}`;
+                this.summaryTypes.appendChild(badge);
+            });
+        }
+
+        displayIssues(issues) {
+            if (!this.issuesList) return;
+
+            this.issuesList.innerHTML = '';
+            if (!issues.length) {
+                this.issues.style.display = 'none';
+                return;
+            }
+            this.issues.style.display = 'block';
+
+            const colors = {
+                error: { border: 'bg-red-900/20 border-red-500', text: 'text-red-400' },
+                warning: { border: 'bg-yellow-900/20 border-yellow-500', text: 'text-yellow-400' },
+                info: { border: 'bg-blue-900/20 border-blue-500', text: 'text-blue-400' },
+            };
+
+            issues.forEach(issue => {
+                const color = colors[issue.severity] || colors.info;
+
+                const issueItem = document.createElement('div');
+                issueItem.className = `p-3 rounded border-l-4 ${color.border}`;
+
+                const issueHeader = document.createElement('div');
+                issueHeader.className = 'flex justify-between items-start mb-1';
+
+                const issueTitle = document.createElement('div');
+                issueTitle.className = `font-semibold ${color.text}`;
+                issueTitle.textContent = `${issue.rule} ${issue.severity}`;
+
+                const issueLine = document.createElement('div');
+                issueLine.className = 'text-xs text-[#90bbcb]';
+                issueLine.textContent = issue.line ? `${issue.file ? issue.file + ' ' : ''}Line ${issue.line}` : '';
+
+                issueHeader.appendChild(issueTitle);
+                issueHeader.appendChild(issueLine);
+
+                const issueMessage = document.createElement('div');
+                issueMessage.className = 'text-white text-sm';
+                issueMessage.textContent = issue.message;
+
+                issueItem.appendChild(issueHeader);
+                issueItem.appendChild(issueMessage);
+                this.issuesList.appendChild(issueItem);
+            });
+        }
+
+        displayRecords(records) {
+            if (!this.recordsBody) return;
+
+            this.recordsBody.innerHTML = '';
+            if (!records.length) {
+                this.records.style.display = 'none';
+                return;
+            }
+            this.records.style.display = 'block';
+
+            records.forEach(record => {
+                const row = document.createElement('tr');
+                row.className = 'border-t border-[#101e23]';
+                [record.line, record.name, record.ttl, record.type, record.value].forEach((value, index) => {
+                    const cell = document.createElement('td');
+                    cell.className = index === 4 ? 'py-1 break-all' : 'py-1 pr-4 whitespace-nowrap';
+                    cell.textContent = value;
+                    row.appendChild(cell);
+                });
+                this.recordsBody.appendChild(row);
+            });
+        }
+
+        showResult(isValid, message) {
+            if (this.result) {
+                this.result.style.display = 'block';
+                this.result.className = isValid
+                    ? 'p-4 rounded-lg bg-green-900/30 border border-green-600/50'
+                    : 'p-4 rounded-lg bg-red-900/30 border border-red-600/50';
+            }
+
+            if (this.resultMessage) {
+                this.resultMessage.textContent = message;
+                this.resultMessage.className = isValid
+                    ? 'text-green-400 font-medium'
+                    : 'text-red-400 font-medium';
+            }
+        }
+
+        clearAll() {
+            if (this.zoneInput) {
+                this.zoneInput.value = '';
+            }
+            [this.result, this.summary, this.issues, this.records].forEach(element => {
+                if (element) {
+                    element.style.display = 'none';
+                }
+            });
+        }
+    }
+
+    // Store the class globally to prevent redeclaration
+    window.ZoneLinter = ZoneLinter;
+
+    // Function to initialize the linter
+    window.initZoneLinter = function() {
+        if (window.zoneLinterInstance) {
+            window.zoneLinterInstance = null;
+        }
+
+        if (document.querySelector('.zone-linter-container')) {
+            window.zoneLinterInstance = new ZoneLinter();
+        }
+    };
+})();